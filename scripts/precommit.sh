@@ -20,3 +20,6 @@ errcheck -ignoretests -ignore 'Close' ./...
 # swaggoの実行
 # https://github.com/swaggo
 swag init -g "app/main.go"
+
+# V1のドキュメントからV2のドキュメントを生成
+go run ./cmd/apidoc
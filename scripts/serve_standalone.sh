@@ -0,0 +1,12 @@
+#! /bin/sh -eux
+
+ROOT_DIR="$(cd $(dirname ${BASH_SOURCE:-$0}); pwd)/.."
+
+cd "$ROOT_DIR/server/src"
+
+# Datastoreエミュレータを利用する場合は事前に起動しておく
+# gcloud beta emulators datastore start --host-port=localhost:8081
+export DATASTORE_EMULATOR_HOST="${DATASTORE_EMULATOR_HOST:-localhost:8081}"
+export DATASTORE_PROJECT_ID="${DATASTORE_PROJECT_ID:-gaego-gin}"
+
+go run ./cmd/server
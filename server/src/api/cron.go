@@ -0,0 +1,261 @@
+package api
+
+//go:generate sh -c "go run ../cmd/cronyaml > ../app/cron.yaml"
+
+import (
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"gaego-gin/server/src/search"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cronJob は定期的に実行するメンテナンス
+//
+// 各メンテナンスは何度実行しても結果が変わらないようにする
+type cronJob struct {
+	// name はパスとJobの種類に利用する名前
+	name string
+	// schedule はcron.yamlのschedule
+	schedule string
+	// description はcron.yamlのdescription
+	description string
+	run         jobs.Func
+}
+
+// cronJobs は定期的に実行するメンテナンスの一覧。cron.yamlはこの一覧から生成する
+var cronJobs = []*cronJob{
+	{
+		name:        "purge-jobs",
+		schedule:    "every 24 hours",
+		description: "purge finished jobs older than 7 days",
+		run:         purgeJobs,
+	},
+	{
+		name:        "purge-hoge-changes",
+		schedule:    "every 1 hours",
+		description: "purge hoge change log entries older than 24 hours",
+		run:         purgeHogeChanges,
+	},
+	{
+		name:        "compact-hoge-changes",
+		schedule:    "every 1 hours",
+		description: "remove hoge change log entries older than 1 hour that are superseded by a later change",
+		run:         compactHogeChanges,
+	},
+	{
+		name:        "purge-deleted-hoges",
+		schedule:    "every 24 hours",
+		description: "purge hoges deleted more than 30 days ago",
+		run:         purgeDeletedHoges,
+	},
+	{
+		name:        "recount-hoges",
+		schedule:    "every 24 hours",
+		description: "recount hoges for the stats",
+		run:         recountHoges,
+	},
+	{
+		name:        "sweep-outbox",
+		schedule:    "every 1 minutes",
+		description: "redeliver pending outbox events",
+		run:         sweepOutbox,
+	},
+	{
+		name:        "purge-outbox",
+		schedule:    "every 24 hours",
+		description: "purge delivered or failed outbox events older than 7 days",
+		run:         purgeOutbox,
+	},
+	{
+		name:        "reindex-search",
+		schedule:    "every 24 hours",
+		description: "rebuild the search index from datastore",
+		run:         reindexSearch,
+	},
+}
+
+// cronJobTypePrefix はメンテナンスのJobの種類の接頭辞
+const cronJobTypePrefix = "cron."
+
+func init() {
+	for _, job := range cronJobs {
+		jobs.Register(cronJobTypePrefix+job.name, job.run)
+	}
+}
+
+// CronAPI はcronから実行するメンテナンスを管理する
+type CronAPI struct{}
+
+// SetupCron はcronから実行するメンテナンスのハンドリングを行う
+//
+// rgは"/cron"に登録する
+func SetupCron(rg *gin.RouterGroup) {
+	api := &CronAPI{}
+
+	for _, job := range cronJobs {
+		rg.GET("/"+job.name, api.run(job))
+	}
+}
+
+// run はメンテナンスをJobとして登録し、202と共にJobを返す
+//
+// 結果はJobのResultに保存する
+func (api *CronAPI) run(job *cronJob) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		g := ds.FromRequest(c.Request)
+
+		j, err := model.NewJob(cronJobTypePrefix+job.name, nil, nil)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		if err := jobs.Enqueue(g, j); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, j)
+	}
+}
+
+// WriteCronYAML はcronJobsからcron.yamlを生成する
+func WriteCronYAML(w io.Writer, basePath string) error {
+	lines := []string{
+		"# このファイルはgo generate ./api/で生成する。変更する場合はapi/cron.goのcronJobsを編集する",
+		"cron:",
+	}
+	for _, job := range cronJobs {
+		lines = append(lines,
+			fmt.Sprintf("- description: %s", job.description),
+			fmt.Sprintf("  url: %s/%s", strings.TrimSuffix(basePath, "/"), job.name),
+			fmt.Sprintf("  schedule: %s", job.schedule),
+		)
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// purgeResult は古いentityを削除するメンテナンスの結果
+type purgeResult struct {
+	Before time.Time `json:"before"`
+	Purged int       `json:"purged"`
+}
+
+// purgeJobs は完了してからmodel.JobRetentionを経過したJobを削除する
+func purgeJobs(g ds.Client, job *model.Job) (interface{}, error) {
+	store := &model.JobStore{}
+
+	before := time.Now().Add(-model.JobRetention)
+
+	purged, err := store.PurgeFinished(g, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return &purgeResult{Before: before, Purged: purged}, nil
+}
+
+// purgeHogeChanges はmodel.HogeChangeRetentionを経過した変更履歴を削除する
+func purgeHogeChanges(g ds.Client, job *model.Job) (interface{}, error) {
+	store := &model.HogeChangeStore{}
+
+	before := time.Now().Add(-model.HogeChangeRetention)
+
+	purged, err := store.Purge(g, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return &purgeResult{Before: before, Purged: purged}, nil
+}
+
+// compactResult は変更履歴を圧縮するメンテナンスの結果
+type compactResult struct {
+	Before    time.Time `json:"before"`
+	Compacted int       `json:"compacted"`
+}
+
+// compactHogeChanges はmodel.HogeChangeCompactAfterを経過した変更履歴を、Hoge毎に最後の変更のみ残して削除する
+func compactHogeChanges(g ds.Client, job *model.Job) (interface{}, error) {
+	store := &model.HogeChangeStore{}
+
+	before := time.Now().Add(-model.HogeChangeCompactAfter)
+
+	compacted, err := store.Compact(g, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return &compactResult{Before: before, Compacted: compacted}, nil
+}
+
+// purgeDeletedHoges は削除してからmodel.DeletedHogeRetentionを経過したHogeを削除する
+func purgeDeletedHoges(g ds.Client, job *model.Job) (interface{}, error) {
+	store := &model.DeletedHogeStore{}
+
+	before := time.Now().Add(-model.DeletedHogeRetention)
+
+	purged, err := store.Purge(g, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return &purgeResult{Before: before, Purged: purged}, nil
+}
+
+// recountHoges は全てのHogeを数え直し、model.HogeStatsを更新する
+func recountHoges(g ds.Client, job *model.Job) (interface{}, error) {
+	store := &model.HogeStatsStore{}
+
+	return store.Recount(g, func(done int) error {
+		return jobs.Progress(g, job, done, 0)
+	})
+}
+
+// sweepOutboxResult はsweep-outboxの結果
+type sweepOutboxResult struct {
+	Finished int `json:"finished"`
+}
+
+// sweepOutbox は配信待ちのまま残ったイベントを再配信する
+func sweepOutbox(g ds.Client, job *model.Job) (interface{}, error) {
+	finished, err := outbox.Sweep(g)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sweepOutboxResult{Finished: finished}, nil
+}
+
+// purgeOutbox は配信を終えてからmodel.OutboxRetentionを経過したイベントを削除する
+func purgeOutbox(g ds.Client, job *model.Job) (interface{}, error) {
+	store := &model.OutboxStore{}
+
+	before := time.Now().Add(-model.OutboxRetention)
+
+	purged, err := store.PurgeFinished(g, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return &purgeResult{Before: before, Purged: purged}, nil
+}
+
+// reindexSearch は全てのHogeを検索のインデックスに追加し、存在しないHogeをインデックスから削除する
+//
+// インデックスが設定されていない場合はsearch.ErrNoIndexで失敗する
+func reindexSearch(g ds.Client, job *model.Job) (interface{}, error) {
+	return search.Reindex(g, func(done int) error {
+		return jobs.Progress(g, job, done, 0)
+	})
+}
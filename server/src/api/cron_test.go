@@ -0,0 +1,289 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/search"
+	"gaego-gin/server/src/search/searchtest"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mjibson/goon"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestWriteCronYAML(t *testing.T) {
+	b, err := ioutil.ReadFile("../app/cron.yaml")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	buf := &bytes.Buffer{}
+	if err := api.WriteCronYAML(buf, "/cron"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if buf.String() != string(b) {
+		t.Errorf("app/cron.yaml is outdated. run `go generate ./api/`\nactual:\n%s\nexpected:\n%s", b, buf.String())
+	}
+}
+
+func TestCronAPI(t *testing.T) {
+	inst, err := aetest.NewInstance(&aetest.Options{AppID: "unittest", StronglyConsistentDatastore: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	adminHelper := NewAdminTestHelper(inst)
+	g := ds.NewGoon(goon.FromContext(adminHelper.ctx))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	rg := r.Group("/cron")
+	rg.Use(middleware.Cron())
+	api.SetupCron(rg)
+
+	request := func(t *testing.T, path string, cron bool) *httptest.ResponseRecorder {
+		req, err := inst.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if cron {
+			req.Header.Set("X-Appengine-Cron", "true")
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("cron以外からのリクエストの場合、403エラーとなること", func(t *testing.T) {
+		w := request(t, "/cron/purge-jobs", false)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusForbidden, w.Body.Bytes())
+	})
+
+	t.Run("purge-jobs: 保持期間を過ぎた完了済みのJobのみ削除され、再度実行しても結果が変わらないこと", func(t *testing.T) {
+		defer adminHelper.ClearEntity(t, model.Job{})
+
+		old := time.Now().Add(-model.JobRetention - time.Hour)
+		for _, v := range []struct {
+			id        string
+			status    model.JobStatus
+			updatedAt time.Time
+		}{
+			{"old-succeeded", model.JobSucceeded, old},
+			{"old-failed", model.JobFailed, old},
+			{"old-running", model.JobRunning, old},
+			{"recent-succeeded", model.JobSucceeded, time.Now()},
+		} {
+			job := &model.Job{ID: v.id, Type: "test.echo", Status: v.status, CreatedAt: v.updatedAt, UpdatedAt: v.updatedAt}
+			if err := g.Put(job); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		for _, expected := range []int{2, 0} {
+			w := request(t, "/cron/purge-jobs", true)
+
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusAccepted, w.Body.Bytes())
+
+			job := &model.Job{}
+			if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+				t.Fatal(err.Error())
+			}
+			AssertEquals(t, "job.Status", job.Status, model.JobSucceeded)
+
+			result := &struct {
+				Purged int `json:"purged"`
+			}{}
+			if err := json.Unmarshal(job.Result, result); err != nil {
+				t.Fatal(err.Error())
+			}
+			AssertEquals(t, "result.Purged", result.Purged, expected)
+		}
+
+		store := &model.JobStore{}
+		for id, exists := range map[string]bool{"old-succeeded": false, "old-failed": false, "old-running": true, "recent-succeeded": true} {
+			_, err := store.Get(g, id)
+			AssertEquals(t, id+" exists", err == nil, exists)
+		}
+	})
+	t.Run("purge-hoge-changes: 保持期間を過ぎた変更履歴のみ削除されること", func(t *testing.T) {
+		defer adminHelper.ClearEntity(t, model.HogeChange{})
+
+		old := time.Now().Add(-model.HogeChangeRetention - time.Hour)
+		for _, v := range []*model.HogeChange{
+			{ID: "old", Position: "old", CreatedAt: old},
+			{ID: "recent", Position: "recent", CreatedAt: time.Now()},
+		} {
+			if err := g.Put(v); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		w := request(t, "/cron/purge-hoge-changes", true)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusAccepted, w.Body.Bytes())
+
+		job := &model.Job{}
+		if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "job.Status", job.Status, model.JobSucceeded)
+
+		for id, exists := range map[string]bool{"old": false, "recent": true} {
+			err := g.Get(&model.HogeChange{ID: id})
+			AssertEquals(t, id+" exists", err == nil, exists)
+		}
+	})
+	t.Run("compact-hoge-changes: 圧縮する期間を過ぎた変更履歴のうち、Hoge毎に最後の変更のみ残ること", func(t *testing.T) {
+		defer adminHelper.ClearEntity(t, model.HogeChange{})
+
+		old := time.Now().Add(-model.HogeChangeCompactAfter - time.Hour)
+		for _, v := range []*model.HogeChange{
+			{ID: "old1", Position: "00000000000000000001-a", HogeID: "hoge1", CreatedAt: old},
+			{ID: "old2", Position: "00000000000000000002-a", HogeID: "hoge1", CreatedAt: old},
+			{ID: "old3", Position: "00000000000000000003-a", HogeID: "hoge2", CreatedAt: old},
+		} {
+			if err := g.Put(v); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		for _, expected := range []int{1, 0} {
+			w := request(t, "/cron/compact-hoge-changes", true)
+
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusAccepted, w.Body.Bytes())
+
+			job := &model.Job{}
+			if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+				t.Fatal(err.Error())
+			}
+			AssertEquals(t, "job.Status", job.Status, model.JobSucceeded)
+
+			result := &struct {
+				Compacted int `json:"compacted"`
+			}{}
+			if err := json.Unmarshal(job.Result, result); err != nil {
+				t.Fatal(err.Error())
+			}
+			AssertEquals(t, "result.Compacted", result.Compacted, expected)
+		}
+
+		for id, exists := range map[string]bool{"old1": false, "old2": true, "old3": true} {
+			err := g.Get(&model.HogeChange{ID: id})
+			AssertEquals(t, id+" exists", err == nil, exists)
+		}
+	})
+	t.Run("purge-deleted-hoges: 保持期間を過ぎた削除済みのHogeのみ削除されること", func(t *testing.T) {
+		defer adminHelper.ClearEntity(t, model.DeletedHoge{})
+
+		old := time.Now().Add(-model.DeletedHogeRetention - time.Hour)
+		for _, v := range []*model.DeletedHoge{
+			{ID: "old", DeletedAt: old},
+			{ID: "recent", DeletedAt: time.Now()},
+		} {
+			if err := g.Put(v); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		w := request(t, "/cron/purge-deleted-hoges", true)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusAccepted, w.Body.Bytes())
+
+		job := &model.Job{}
+		if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "job.Status", job.Status, model.JobSucceeded)
+
+		for id, exists := range map[string]bool{"old": false, "recent": true} {
+			err := g.Get(&model.DeletedHoge{ID: id})
+			AssertEquals(t, id+" exists", err == nil, exists)
+		}
+	})
+	t.Run("recount-hoges: Hogeの件数が数え直され、保存されること", func(t *testing.T) {
+		defer adminHelper.ClearEntity(t, model.Hoge{})
+		defer adminHelper.ClearEntity(t, model.HogeStats{})
+
+		for _, id := range []string{"hoge1", "hoge2", "hoge3"} {
+			if err := g.Put(&model.Hoge{ID: id}); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		w := request(t, "/cron/recount-hoges", true)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusAccepted, w.Body.Bytes())
+
+		job := &model.Job{}
+		if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "job.Status", job.Status, model.JobSucceeded)
+
+		store := &model.HogeStatsStore{}
+		stats, err := store.Get(g)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "stats.Total", stats.Total, 3)
+	})
+	t.Run("reindex-search: インデックスにないHogeが追加され、存在しないHogeが削除されること", func(t *testing.T) {
+		defer adminHelper.ClearEntity(t, model.Hoge{})
+
+		defer func(index search.Index) { search.DefaultIndex = index }(search.DefaultIndex)
+		search.DefaultIndex = searchtest.NewMemory()
+
+		if err := g.Put(&model.Hoge{ID: "hoge1", Value: "imported zebra"}); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := search.DefaultIndex.Put(adminHelper.ctx, &model.Hoge{ID: "stale", Value: "zebra"}); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		w := request(t, "/cron/reindex-search", true)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusAccepted, w.Body.Bytes())
+
+		job := &model.Job{}
+		if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "job.Status", job.Status, model.JobSucceeded)
+
+		result := &search.ReindexResult{}
+		if err := json.Unmarshal(job.Result, result); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "Indexed", result.Indexed, 1)
+		AssertEquals(t, "Removed", result.Removed, 1)
+	})
+
+	t.Run("reindex-search: インデックスが設定されていない場合、Jobが失敗となること", func(t *testing.T) {
+		defer func(index search.Index) { search.DefaultIndex = index }(search.DefaultIndex)
+		search.DefaultIndex = nil
+
+		w := request(t, "/cron/reindex-search", true)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusAccepted, w.Body.Bytes())
+
+		job := &model.Job{}
+		if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "job.Status", job.Status, model.JobFailed)
+		AssertEquals(t, "job.Error", job.Error, search.ErrNoIndex.Error())
+	})
+}
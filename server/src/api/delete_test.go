@@ -0,0 +1,99 @@
+package api_test
+
+import (
+	"context"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordingQueue は登録されたJobを実行せずに記録するjobs.Queue
+type recordingQueue struct {
+	jobs []*model.Job
+}
+
+func (q *recordingQueue) Enqueue(g ds.Client, job *model.Job) error {
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func TestHogeAPI_Delete_Events(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newHandler := func(store *dstest.Store) http.Handler {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Request = ds.WithFactory(c.Request, func(r *http.Request) ds.Client {
+				return store.Client(r.Context())
+			})
+			c.Next()
+		})
+		api.SetupHoge(r.Group("/api"))
+
+		return middleware.CustomMethods(r)
+	}
+
+	defer func(q jobs.Queue) { jobs.DefaultQueue = q }(jobs.DefaultQueue)
+
+	t.Run("存在しないHogeを削除した場合、200となり、イベントが配信されないこと", func(t *testing.T) {
+		queue := &recordingQueue{}
+		jobs.DefaultQueue = queue
+
+		store := dstest.NewStore()
+
+		w := httptest.NewRecorder()
+		newHandler(store).ServeHTTP(w, httptest.NewRequest("DELETE", "/api/hoge/missing", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "OutboxEvent", store.Len("OutboxEvent"), 0)
+		AssertEquals(t, "HogeChange", store.Len("HogeChange"), 0)
+		AssertEquals(t, "jobs", len(queue.jobs), 0)
+	})
+
+	t.Run("存在するHogeを削除した場合、削除したHogeの値を含むイベントが1件配信されること", func(t *testing.T) {
+		queue := &recordingQueue{}
+		jobs.DefaultQueue = queue
+
+		store := dstest.NewStore()
+		g := store.Client(context.Background())
+
+		createdAt := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+		if err := g.Put(&model.Hoge{ID: "hoge", Value: "hogehoge", CreatedAt: createdAt, UpdatedAt: createdAt}); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		w := httptest.NewRecorder()
+		newHandler(store).ServeHTTP(w, httptest.NewRequest("DELETE", "/api/hoge/hoge", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "OutboxEvent", store.Len("OutboxEvent"), 1)
+		AssertEquals(t, "HogeChange", store.Len("HogeChange"), 1)
+		AssertEquals(t, "jobs", len(queue.jobs), 1)
+
+		outboxStore := &model.OutboxStore{}
+		events, err := outboxStore.ListPending(g, time.Now().Add(time.Hour), 10)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(events) != 1 {
+			t.Fatalf("events: unexpected, actual: `%d`", len(events))
+		}
+
+		event, err := events[0].Bind()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "Type", event.Type, model.HogeDeleted)
+		AssertEquals(t, "Hoge.Value", event.Hoge.Value, "hogehoge")
+		AssertEquals(t, "Hoge.CreatedAt", event.Hoge.CreatedAt.Equal(createdAt), true)
+	})
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/search"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+var log = logger.New("api")
+
+// errorIDHeader は5xxのエラーIDを返すレスポンスヘッダー
+const errorIDHeader = "X-Error-Id"
+
+var debugMode = false
+
+// SetDebug はデバッグモードを設定する
+//
+// デバッグモードでは5xxのレスポンスに元のエラーを含める。ローカルでの開発時のみ有効にする
+func SetDebug(on bool) {
+	debugMode = on
+}
+
+// respondError はエラーに対応するステータスコードでエラーレスポンスを返す
+//
+// リクエストのcontextがタイムアウトまたはキャンセルされている場合は、そのエラーを優先する
+// 5xxの場合は元のエラーとスタックトレースをエラーIDと共にログに出力し、クライアントにはステータスコードの説明とエラーIDのみを返す
+func respondError(c *gin.Context, err error) {
+	ctx := c.Request.Context()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
+	code := errorStatusCode(err)
+	if code < http.StatusInternalServerError {
+		c.String(code, err.Error())
+		return
+	}
+
+	respondInternalError(c, code, err, nil)
+}
+
+// Recovery はハンドラのpanicを500のエラーレスポンスとし、panicした箇所のスタックトレースをエラーIDと共にログに出力する
+//
+// http.ErrAbortHandlerはnet/httpに接続を切断させるため、そのままpanicする
+// レスポンスを書き込み始めていた場合は、ログの出力のみ行う
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			err, ok := v.(error)
+			if !ok {
+				err = fmt.Errorf("panic: %v", v)
+			}
+
+			// recoverした時点ではpanicした箇所の呼び出し元がスタックに残っている
+			stack := debug.Stack()
+
+			if c.Writer.Written() {
+				logInternalError(c, http.StatusInternalServerError, err, stack)
+				c.Abort()
+				return
+			}
+
+			respondInternalError(c, http.StatusInternalServerError, err, stack)
+			c.Abort()
+		}()
+
+		c.Next()
+	}
+}
+
+// respondInternalError は5xxのエラーをログに出力し、ステータスコードの説明とエラーIDのみを返す
+//
+// stackがnilの場合は呼び出した時点のスタックトレースを出力する
+func respondInternalError(c *gin.Context, code int, err error, stack []byte) {
+	if stack == nil {
+		stack = debug.Stack()
+	}
+
+	id := logInternalError(c, code, err, stack)
+
+	msg := http.StatusText(code)
+	if debugMode {
+		msg = err.Error()
+	}
+
+	c.Header(errorIDHeader, id)
+	c.String(code, fmt.Sprintf("%s (error id: %s)", msg, id))
+}
+
+// logInternalError は5xxのエラーをスタックトレースと共に新しいエラーIDでログに出力し、エラーIDを返す
+func logInternalError(c *gin.Context, code int, err error, stack []byte) string {
+	id := newErrorID()
+	log.With("errorId", id).Errorf(c.Request.Context(), "%s %s: %d: %v\n%s", c.Request.Method, c.Request.URL.Path, code, err, stack)
+
+	return id
+}
+
+// errorStatusCode はエラーに対応するHTTPステータスコードを返す
+//
+// APIのエラーと全文検索のエラー以外はmodel.ClassifyErrorの分類をgRPCと共通で用いる
+// リトライの上限に達した場合、トランザクションの競合は409、一時的なエラーは503とする
+// 既に存在する場合はgRPCのAlreadyExistsと合わせて409とする
+func errorStatusCode(err error) int {
+	switch err.(type) {
+	case *importFileError, *fieldMaskError, *requestBodyError:
+		return http.StatusBadRequest
+	}
+
+	switch err {
+	case search.ErrInvalidQuery, search.ErrInvalidCursor:
+		return http.StatusBadRequest
+	case errUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case errRequestBodyTooLarge:
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return errorKindStatusCodes[model.ClassifyError(err)]
+}
+
+// errorKindStatusCodes はエラーの分類毎のHTTPステータスコード
+var errorKindStatusCodes = map[model.ErrorKind]int{
+	model.ErrorInternal:         http.StatusInternalServerError,
+	model.ErrorInvalidArgument:  http.StatusBadRequest,
+	model.ErrorNotFound:         http.StatusNotFound,
+	model.ErrorAlreadyExists:    http.StatusConflict,
+	model.ErrorAborted:          http.StatusConflict,
+	model.ErrorUnavailable:      http.StatusServiceUnavailable,
+	model.ErrorDeadlineExceeded: http.StatusGatewayTimeout,
+	model.ErrorCanceled:         http.StatusServiceUnavailable,
+}
+
+// newErrorID はログとレスポンスを関連付けるためのエラーIDを生成する
+func newErrorID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, func(r *http.Request) ds.Client {
+			return &failingClient{ctx: r.Context()}
+		})
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+
+	t.Run("トランザクションの競合がリトライの上限に達した場合、409エラーとなること", func(t *testing.T) {
+		policy := model.DefaultRetryPolicy
+		model.DefaultRetryPolicy = &model.RetryPolicy{MaxAttempts: 2, Retryable: model.IsRetryable}
+		defer func() { model.DefaultRetryPolicy = policy }()
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/hoge/hoge", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusConflict, w.Body.Bytes())
+	})
+
+	t.Run("5xxの場合、元のエラーを含めずにエラーIDを返すこと", func(t *testing.T) {
+		api.SetDebug(false)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/hoge/hoge", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusInternalServerError, w.Body.Bytes())
+
+		id := w.Header().Get("X-Error-Id")
+		AssertEquals(t, "X-Error-Id", id != "", true)
+		AssertEquals(t, "body", w.Body.String(), "Internal Server Error (error id: "+id+")")
+	})
+
+	t.Run("5xxの場合、元のエラーとスタックトレースをエラーIDと共にログに出力すること", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger.SetSink(logger.NewJSONSink(buf))
+		defer logger.SetSink(logger.NewJSONSink(os.Stdout))
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/hoge/hoge", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusInternalServerError, w.Body.Bytes())
+
+		entry := struct {
+			Message string            `json:"message"`
+			Labels  map[string]string `json:"logging.googleapis.com/labels"`
+		}{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: `%s`", err.Error(), buf.String())
+		}
+
+		AssertEquals(t, "errorId", entry.Labels["errorId"], w.Header().Get("X-Error-Id"))
+		AssertEquals(t, "error", strings.Contains(entry.Message, "transaction collision"), true)
+		AssertEquals(t, "stack", strings.Contains(entry.Message, "api.(*HogeAPI).Get("), true)
+	})
+
+	t.Run("デバッグモードの場合、5xxのレスポンスに元のエラーを含めること", func(t *testing.T) {
+		api.SetDebug(true)
+		defer api.SetDebug(false)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/hoge/hoge", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusInternalServerError, w.Body.Bytes())
+		AssertEquals(t, "body", strings.HasPrefix(w.Body.String(), "transaction collision"), true)
+	})
+}
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(api.Recovery())
+	r.GET("/panic", func(c *gin.Context) {
+		panic("broken")
+	})
+	r.GET("/panic-after-write", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("broken")
+	})
+	r.GET("/abort", func(c *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	t.Run("panicした場合、元のエラーを含めずにエラーIDと共に500エラーとなること", func(t *testing.T) {
+		api.SetDebug(false)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusInternalServerError, w.Body.Bytes())
+
+		id := w.Header().Get("X-Error-Id")
+		AssertEquals(t, "X-Error-Id", id != "", true)
+		AssertEquals(t, "body", w.Body.String(), "Internal Server Error (error id: "+id+")")
+	})
+
+	t.Run("レスポンスを書き込み始めていた場合、エラーレスポンスを追加しないこと", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/panic-after-write", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "body", w.Body.String(), "partial")
+	})
+
+	t.Run("http.ErrAbortHandlerの場合、そのままpanicすること", func(t *testing.T) {
+		defer func() {
+			AssertEquals(t, "recover", recover(), http.ErrAbortHandler)
+		}()
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	})
+}
+
+// failingClient は全ての操作が失敗するClient
+type failingClient struct {
+	ds.Client
+	ctx context.Context
+}
+
+func (c *failingClient) Context() context.Context {
+	return c.ctx
+}
+
+func (c *failingClient) Get(dst interface{}) error {
+	return errors.New("transaction collision for entity group")
+}
+
+func (c *failingClient) RunInTransaction(f func(tg ds.Client) error) error {
+	return ds.ErrConcurrentTransaction
+}
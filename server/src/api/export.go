@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"gaego-gin/server/src/model"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// exportContentTypes は書き出す形式毎のContent-Type
+var exportContentTypes = map[string]string{
+	formatNDJSON: "application/x-ndjson",
+	formatCSV:    "text/csv; charset=utf-8",
+}
+
+// exportStatusTrailer は書き出しの結果(completeまたはaborted)を返すトレーラー
+const exportStatusTrailer = "X-Export-Status"
+
+// exportMediaTypes はAcceptヘッダーのメディアタイプに対応する書き出す形式
+var exportMediaTypes = map[string]string{
+	"application/x-ndjson": formatNDJSON,
+	"application/ndjson":   formatNDJSON,
+	"application/*":        formatNDJSON,
+	"*/*":                  formatNDJSON,
+	"text/csv":             formatCSV,
+	"text/*":               formatCSV,
+}
+
+// negotiateExportFormat はformatパラメータ、またはAcceptヘッダーから書き出す形式を決定する
+//
+// formatパラメータが不正な場合は400、Acceptヘッダーに対応する形式が含まれない場合は406を返し、falseを返す
+func negotiateExportFormat(c *gin.Context) (string, bool) {
+	if f := c.Query("format"); f != "" {
+		if _, ok := exportContentTypes[f]; !ok {
+			c.String(http.StatusBadRequest, "format must be ndjson or csv")
+			return "", false
+		}
+
+		return f, true
+	}
+
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return formatNDJSON, true
+	}
+
+	for _, v := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+
+		if f, ok := exportMediaTypes[mt]; ok {
+			return f, true
+		}
+	}
+
+	c.String(http.StatusNotAcceptable, "supported media types are application/x-ndjson and text/csv")
+	return "", false
+}
+
+// exportWriter はHogeを1件ずつ書き出す
+type exportWriter interface {
+	// Write はHogeを書き出す。cursorが空でない場合は再開用のページトークンを含める
+	Write(hoge *model.Hoge, cursor string) error
+	// Flush はバッファされた内容をクライアントに送信する
+	Flush() error
+}
+
+// newExportWriter はステータスコードとContent-Typeを書き込み、formatの形式で書き出すexportWriterを生成する
+func newExportWriter(c *gin.Context, format string) (exportWriter, error) {
+	c.Header("Content-Type", exportContentTypes[format])
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Trailer", exportStatusTrailer)
+	c.Status(http.StatusOK)
+
+	if format == formatCSV {
+		w := &csvExportWriter{w: csv.NewWriter(c.Writer), rw: c.Writer}
+		if err := w.w.Write([]string{"id", "value", "createdAt", "updatedAt", "cursor"}); err != nil {
+			return nil, err
+		}
+
+		return w, nil
+	}
+
+	return &ndjsonExportWriter{enc: json.NewEncoder(c.Writer), rw: c.Writer}, nil
+}
+
+// abortExport は書き出しを始めた後のエラーをログとc.Errorsに記録し、レスポンスが不完全であることを伝える
+//
+// ステータスコードは変更できないため、トレーラーにabortedを設定し、HTTP/1.1では接続を切断する
+// panicせずにハンドラから戻るため、ミドルウェアはリクエストのメトリクスとspanを記録できる
+func abortExport(c *gin.Context, err error) {
+	log.Warningf(c.Request.Context(), "export aborted: %v", err)
+	c.Error(err) // nolint: errcheck
+	c.Writer.Header().Set(exportStatusTrailer, "aborted")
+
+	if conn, ok := hijack(c.Writer); ok {
+		conn.Close() // nolint: errcheck
+	}
+}
+
+// hijack は接続を乗っ取る。HTTP/2などで乗っ取れない場合はfalseを返す
+//
+// gin.ResponseWriterのHijackは元のResponseWriterがhttp.Hijackerでない場合にpanicするため、recoverする
+func hijack(w gin.ResponseWriter) (conn net.Conn, ok bool) {
+	defer func() {
+		if recover() != nil {
+			conn, ok = nil, false
+		}
+	}()
+
+	conn, _, err := w.Hijack()
+	return conn, err == nil
+}
+
+// exportRecord はNDJSONの1行
+type exportRecord struct {
+	*model.Hoge
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+	rw  gin.ResponseWriter
+}
+
+func (w *ndjsonExportWriter) Write(hoge *model.Hoge, cursor string) error {
+	return w.enc.Encode(&exportRecord{Hoge: hoge, Cursor: cursor})
+}
+
+func (w *ndjsonExportWriter) Flush() error {
+	w.rw.Flush()
+	return nil
+}
+
+type csvExportWriter struct {
+	w  *csv.Writer
+	rw gin.ResponseWriter
+}
+
+func (w *csvExportWriter) Write(hoge *model.Hoge, cursor string) error {
+	return w.w.Write([]string{
+		hoge.ID,
+		hoge.Value,
+		hoge.CreatedAt.Format(time.RFC3339Nano),
+		hoge.UpdatedAt.Format(time.RFC3339Nano),
+		cursor,
+	})
+}
+
+func (w *csvExportWriter) Flush() error {
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		return err
+	}
+
+	w.rw.Flush()
+	return nil
+}
@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingGetMultiClient はfailAfter回目より後のGetMultiでエラーを返すds.Client
+type failingGetMultiClient struct {
+	*dstest.Client
+	calls     *int32
+	failAfter int32
+}
+
+func (c *failingGetMultiClient) GetMulti(dst interface{}) error {
+	if c.failAfter > 0 && atomic.AddInt32(c.calls, 1) > c.failAfter {
+		return errors.New("broken")
+	}
+
+	return c.Client.GetMulti(dst)
+}
+
+func TestHogeAPI_Export_Abort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := dstest.NewStore()
+	for i := 0; i < model.MaxHogeListLimit+1; i++ {
+		if err := store.Client(context.Background()).Put(&model.Hoge{ID: fmt.Sprintf("hoge%03d", i)}); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	newHandler := func(failAfter int32) http.Handler {
+		var calls int32
+
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Request = ds.WithFactory(c.Request, func(r *http.Request) ds.Client {
+				return &failingGetMultiClient{Client: store.Client(r.Context()), calls: &calls, failAfter: failAfter}
+			})
+			c.Next()
+		})
+		api.SetupHoge(r.Group("/api"))
+
+		return middleware.CustomMethods(r)
+	}
+
+	t.Run("全件を書き出した場合、トレーラーがcompleteとなること", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newHandler(0).ServeHTTP(w, httptest.NewRequest("GET", "/api/hoge:export", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, nil)
+		AssertEquals(t, "X-Export-Status", w.Result().Trailer.Get("X-Export-Status"), "complete")
+	})
+
+	t.Run("書き出しの途中でエラーとなった場合、panicせずにトレーラーがabortedとなること", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newHandler(1).ServeHTTP(w, httptest.NewRequest("GET", "/api/hoge:export", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, nil)
+		AssertEquals(t, "X-Export-Status", w.Result().Trailer.Get("X-Export-Status"), "aborted")
+	})
+
+	t.Run("HTTP/1.1の場合、接続を切断すること", func(t *testing.T) {
+		srv := httptest.NewServer(newHandler(1))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/api/hoge:export")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer resp.Body.Close() // nolint: errcheck
+
+		if _, err := ioutil.ReadAll(resp.Body); err == nil {
+			t.Error("expected the response body to be truncated")
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldMask はレスポンスに含めるJSONのフィールド
+//
+// キーはJSONのフィールド名、値は入れ子のフィールドのfieldMaskで、nilの場合はフィールド全体を含める
+type fieldMask map[string]fieldMask
+
+// fieldMaskError はfieldsの形式が不正、または存在しないフィールドを指定した場合のエラー
+type fieldMaskError struct {
+	msg string
+}
+
+func (e *fieldMaskError) Error() string {
+	return "invalid fields: " + e.msg
+}
+
+// parseFieldMask はGoogle APIのfieldsと同じ形式のフィールドの指定を解釈する
+//
+// フィールドはカンマで区切り、入れ子のフィールドは"a/b"または"a(b,c)"で指定する
+// 同じフィールドを全体と入れ子の両方で指定した場合は全体を含める
+func parseFieldMask(s string) (fieldMask, error) {
+	p := &fieldMaskParser{s: s}
+
+	m, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.s) {
+		return nil, &fieldMaskError{msg: fmt.Sprintf("unexpected %q", p.s[p.pos])}
+	}
+
+	return m, nil
+}
+
+type fieldMaskParser struct {
+	s   string
+	pos int
+}
+
+// parse はカンマ区切りのフィールドを")"または終端まで読み込む
+func (p *fieldMaskParser) parse(depth int) (fieldMask, error) {
+	m := fieldMask{}
+
+	for {
+		if err := p.item(m); err != nil {
+			return nil, err
+		}
+
+		if p.pos == len(p.s) {
+			if depth > 0 {
+				return nil, &fieldMaskError{msg: "missing )"}
+			}
+
+			return m, nil
+		}
+
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case ')':
+			if depth == 0 {
+				return nil, &fieldMaskError{msg: "unexpected )"}
+			}
+
+			return m, nil
+		default:
+			return nil, &fieldMaskError{msg: fmt.Sprintf("unexpected %q", p.s[p.pos])}
+		}
+	}
+}
+
+// item は1つのフィールドと入れ子のフィールドを読み込み、mに追加する
+func (p *fieldMaskParser) item(m fieldMask) error {
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(",/()", rune(p.s[p.pos])) {
+		p.pos++
+	}
+
+	name := strings.TrimSpace(p.s[start:p.pos])
+	if name == "" {
+		return &fieldMaskError{msg: "empty field name"}
+	}
+
+	var sub fieldMask
+	if p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '/':
+			p.pos++
+			sub = fieldMask{}
+			if err := p.item(sub); err != nil {
+				return err
+			}
+
+		case '(':
+			p.pos++
+			var err error
+			if sub, err = p.parse(1); err != nil {
+				return err
+			}
+			p.pos++
+		}
+	}
+
+	m.merge(name, sub)
+	return nil
+}
+
+// merge はnameのフィールドをsubと共にmに追加する
+func (m fieldMask) merge(name string, sub fieldMask) {
+	old, ok := m[name]
+	switch {
+	case !ok:
+		m[name] = sub
+	case old == nil || sub == nil:
+		m[name] = nil
+	default:
+		for k, v := range sub {
+			old.merge(k, v)
+		}
+	}
+}
+
+// validate はmのフィールドがtのJSONのフィールドとして存在するか検証する
+func (m fieldMask) validate(t reflect.Type) error {
+	return m.validatePath(t, "")
+}
+
+func (m fieldMask) validatePath(t reflect.Type, prefix string) error {
+	t = elemType(t)
+
+	fields := jsonFields(t)
+	for name, sub := range m {
+		ft, ok := fields[name]
+		if !ok {
+			return &fieldMaskError{msg: fmt.Sprintf("unknown field %q", prefix+name)}
+		}
+
+		if sub == nil {
+			continue
+		}
+
+		if err := sub.validatePath(ft, prefix+name+"/"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// elemType はポインタ、スライス、配列の要素の型を返す
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// jsonFields はtをJSONに変換した場合のフィールド名とフィールドの型を返す
+//
+// 構造体以外やtime.Timeのように独自にJSONに変換する型はフィールドを持たないものとする
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	if t.Kind() != reflect.Struct || t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return fields
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fields[name] = f.Type
+	}
+
+	return fields
+}
+
+// apply はJSONから変換したvのうち、mのフィールドのみを残した値を返す。配列は各要素に適用する
+func (m fieldMask) apply(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(m))
+		for name, sub := range m {
+			fv, ok := v[name]
+			if !ok {
+				continue
+			}
+
+			if sub != nil {
+				fv = sub.apply(fv)
+			}
+			out[name] = fv
+		}
+
+		return out
+
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = m.apply(e)
+		}
+
+		return out
+	}
+
+	return v
+}
+
+// bindFieldMask はfieldsパラメータをtの型のフィールドの指定として読み込む
+//
+// fieldsが指定されていない場合はnilを返す。不正な場合は400を返し、okはfalseとなる
+func bindFieldMask(c *gin.Context, t interface{}) (m fieldMask, ok bool) {
+	s := c.Query("fields")
+	if s == "" {
+		return nil, true
+	}
+
+	m, err := parseFieldMask(s)
+	if err == nil {
+		err = m.validate(reflect.TypeOf(t))
+	}
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return m, true
+}
@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	"encoding/json"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHogeAPI_Get_Fields(t *testing.T) {
+	g := dstest.NewClient()
+	if err := g.Put(&model.Hoge{
+		ID:        "hoge",
+		Value:     "hogehoge",
+		CreatedAt: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+	}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, g.Store().Factory())
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+
+	get := func(t *testing.T, fields string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/hoge/hoge?fields="+url.QueryEscape(fields), nil)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	keys := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		var v map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		var keys []string
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		return strings.Join(keys, ",")
+	}
+
+	for fields, expected := range map[string]string{
+		"id,value":            "id,value",
+		" value , createdAt ": "createdAt,value",
+		"id,id":               "id",
+		"":                    "createdAt,id,updatedAt,value",
+	} {
+		t.Run("fields="+fields+" の場合、指定したフィールドのみが返ること", func(t *testing.T) {
+			w := get(t, fields)
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+			AssertEquals(t, "fields", keys(t, w), expected)
+		})
+	}
+
+	t.Run("指定したフィールドの値が変わらないこと", func(t *testing.T) {
+		w := get(t, "value,createdAt")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		hoge := &model.Hoge{}
+		if err := json.Unmarshal(w.Body.Bytes(), hoge); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "Value", hoge.Value, "hogehoge")
+		AssertEquals(t, "CreatedAt", hoge.CreatedAt.Equal(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)), true)
+	})
+
+	for _, fields := range []string{"unknown", "id,Value", "id,", ",id", "id(", "id)", "list(id)", "value/length", "createdAt(year)", "id,(value)"} {
+		t.Run("fields="+fields+" の場合、400エラーとなること", func(t *testing.T) {
+			w := get(t, fields)
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/tracing"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+)
+
+// リクエストボディとレスポンスの形式
+const (
+	mimeJSON     = "application/json"
+	mimeMsgPack  = "application/msgpack"
+	mimeProtobuf = "application/x-protobuf"
+)
+
+// maxRequestBodySize はリクエストボディの上限のバイト数。Datastoreのエンティティの上限に合わせる
+const maxRequestBodySize = 1 << 20
+
+// hogeFormats はHogeのメッセージを返すAPIが対応する形式。先頭をデフォルトとする
+//
+// Protocol Buffersはビルドできる環境のみ対応する(protobufFormats)
+var hogeFormats = append([]string{mimeJSON, mimeMsgPack}, protobufFormats...)
+
+// objectFormats はProtocol Buffersのメッセージを定義していないレスポンスを返すAPIが対応する形式
+var objectFormats = []string{mimeJSON, mimeMsgPack}
+
+// formatMediaTypes はメディアタイプに対応する形式。別名も受け付ける
+var formatMediaTypes = map[string]string{
+	"application/json":       mimeJSON,
+	"application/msgpack":    mimeMsgPack,
+	"application/x-msgpack":  mimeMsgPack,
+	"application/x-protobuf": mimeProtobuf,
+	"application/protobuf":   mimeProtobuf,
+}
+
+// errUnsupportedMediaType はリクエストボディの形式に対応していない場合のエラー
+var errUnsupportedMediaType = errors.New("supported content types are " + strings.Join(hogeFormats, ", "))
+
+// errRequestBodyTooLarge はリクエストボディがmaxRequestBodySizeを超える場合のエラー
+var errRequestBodyTooLarge = fmt.Errorf("request body must not exceed %d bytes", maxRequestBodySize)
+
+// msgpackHandle はMessagePackの変換の設定
+//
+// MessagePackはJSONと同じ構造とし、JSONと同じフィールド名と値の表現を利用する
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	h.RawToString = true
+	return h
+}()
+
+// negotiateFormat はAcceptヘッダーからoffersのうちレスポンスの形式を決定する
+//
+// qの値が大きいメディアタイプを優先し、ワイルドカードとAcceptヘッダーがない場合はoffersの先頭とする
+// 対応する形式が含まれない場合は406を返し、falseを返す
+// レスポンスの形式がAcceptにより変わるため、キャッシュ向けにVary: Acceptを付与する
+func negotiateFormat(c *gin.Context, offers ...string) (string, bool) {
+	c.Writer.Header().Add("Vary", "Accept")
+
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return offers[0], true
+	}
+
+	best, bestQ := "", 0.0
+	for _, v := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+
+		f := ""
+		switch mt {
+		case "*/*", "application/*":
+			f = offers[0]
+		default:
+			for _, offer := range offers {
+				if formatMediaTypes[mt] == offer {
+					f = offer
+				}
+			}
+		}
+
+		if f != "" {
+			best, bestQ = f, q
+		}
+	}
+
+	if best == "" {
+		c.String(http.StatusNotAcceptable, fmt.Sprintf("supported media types are %s", strings.Join(offers, ", ")))
+		return "", false
+	}
+
+	return best, true
+}
+
+// bindHoge はContent-Typeの形式のリクエストボディをhogeに読み込む
+//
+// Content-Typeがない場合はJSONとする。対応しない形式の場合はerrUnsupportedMediaType、
+// maxRequestBodySizeを超える場合はerrRequestBodyTooLargeを返す
+func bindHoge(c *gin.Context, hoge *model.Hoge) error {
+	format := mimeJSON
+	if ct := c.GetHeader("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return errUnsupportedMediaType
+		}
+
+		var ok bool
+		if format, ok = formatMediaTypes[mt]; !ok {
+			return errUnsupportedMediaType
+		}
+	}
+
+	b, err := readBody(c)
+	if err != nil {
+		return err
+	}
+
+	return decode(c, format, func() error {
+		return decodeHoge(format, b, hoge)
+	})
+}
+
+// decodeHoge はformatのbをhogeに読み込む
+func decodeHoge(format string, b []byte, hoge *model.Hoge) error {
+	switch format {
+	case mimeProtobuf:
+		return unmarshalHogeProto(b, hoge)
+
+	case mimeMsgPack:
+		var obj interface{}
+		if err := codec.NewDecoderBytes(b, msgpackHandle).Decode(&obj); err != nil {
+			return &requestBodyError{err: err}
+		}
+
+		var err error
+		if b, err = json.Marshal(obj); err != nil {
+			return &requestBodyError{err: err}
+		}
+	}
+
+	if len(b) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(b, hoge); err != nil {
+		return &requestBodyError{err: err}
+	}
+
+	return nil
+}
+
+// decode はリクエストボディのデコードをspanとして記録してfを実行する
+func decode(c *gin.Context, format string, f func() error) error {
+	_, end := tracing.Start(c.Request.Context(), "decode "+format)
+	err := f()
+	end(err)
+
+	return err
+}
+
+// readBody はmaxRequestBodySizeまでのリクエストボディを読み込む
+func readBody(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestBodySize)
+
+	b, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		// MaxBytesReaderは上限まで読み込んだ後にエラーを返す
+		if len(b) >= maxRequestBodySize {
+			return nil, errRequestBodyTooLarge
+		}
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// requestBodyError はリクエストボディの形式が不正な場合のエラー
+type requestBodyError struct {
+	err error
+}
+
+func (e *requestBodyError) Error() string {
+	return "invalid request body: " + e.err.Error()
+}
+
+// respond はvをformatの形式で返す。mがnilでない場合はmのフィールドのみを返す
+//
+// Protocol Buffersではmに含まれないフィールドをゼロ値とするため、レスポンスに含まれない
+func respond(c *gin.Context, code int, format string, v interface{}, m fieldMask) {
+	switch format {
+	case mimeProtobuf:
+		if m != nil {
+			var err error
+			if v, err = maskTyped(v, m); err != nil {
+				respondError(c, err)
+				return
+			}
+		}
+
+		b, err := marshalProto(v)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.Data(code, mimeProtobuf, b)
+
+	case mimeMsgPack:
+		obj, err := maskedObject(v, m)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		var b []byte
+		if err := codec.NewEncoderBytes(&b, msgpackHandle).Encode(obj); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.Data(code, mimeMsgPack, b)
+
+	default:
+		if m == nil {
+			c.JSON(code, v)
+			return
+		}
+
+		obj, err := maskedObject(v, m)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.JSON(code, obj)
+	}
+}
+
+// maskedObject はvをJSONと同じ構造に変換し、mがnilでない場合はmのフィールドのみを残す
+//
+// 数値は整数の場合はint64、それ以外はfloat64とする
+func maskedObject(v interface{}, m fieldMask) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// 整数の精度を保つため、数値はjson.Numberとして読み込む
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var obj interface{}
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	if m != nil {
+		obj = m.apply(obj)
+	}
+
+	return convertNumbers(obj), nil
+}
+
+// maskTyped はvのうちmに含まれないフィールドをゼロ値としたvと同じ型の値を返す
+func maskTyped(v interface{}, m fieldMask) (interface{}, error) {
+	obj, err := maskedObject(v, m)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	masked := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	if err := json.Unmarshal(b, masked); err != nil {
+		return nil, err
+	}
+
+	return masked, nil
+}
+
+// convertNumbers はjson.Numberを整数の場合はint64、それ以外はfloat64に変換する
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+
+		f, _ := v.Float64() // nolint: errcheck
+		return f
+
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = convertNumbers(e)
+		}
+
+	case []interface{}:
+		for i, e := range v {
+			v[i] = convertNumbers(e)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,54 @@
+//go:build !appengine
+// +build !appengine
+
+package api
+
+import (
+	"fmt"
+	"gaego-gin/server/src/hogepb"
+	"gaego-gin/server/src/model"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// protobufFormats はHogeのメッセージを返すAPIがJSON、MessagePackに加えて対応する形式
+var protobufFormats = []string{mimeProtobuf}
+
+// unmarshalHogeProto はProtocol BuffersのHogeのメッセージをhogeに読み込む
+func unmarshalHogeProto(b []byte, hoge *model.Hoge) error {
+	x := &hogepb.Hoge{}
+	if err := proto.Unmarshal(b, x); err != nil {
+		return &requestBodyError{err: err}
+	}
+
+	*hoge = *x.Model()
+	return nil
+}
+
+// marshalProto はvを対応するProtocol Buffersのメッセージに変換して返す
+func marshalProto(v interface{}) ([]byte, error) {
+	return proto.Marshal(newProtoMessage(v))
+}
+
+// newProtoMessage はvを対応するProtocol Buffersのメッセージに変換する。nilの場合は空のメッセージとする
+func newProtoMessage(v interface{}) proto.Message {
+	switch v := v.(type) {
+	case *model.Hoge:
+		return hogepb.NewHoge(v)
+	case *model.HogeListResp:
+		return hogepb.NewHogeListResp(v)
+	case *model.HogeSearchResp:
+		return hogepb.NewHogeSearchResp(v)
+	case *HogeV2:
+		return hogepb.NewHoge(v.toModel())
+	case *HogeListRespV2:
+		return hogepb.NewHogeListResp(v.toModel())
+	case *HogeSearchRespV2:
+		return hogepb.NewHogeSearchResp(v.toModel())
+	case nil:
+		return &emptypb.Empty{}
+	}
+
+	panic(fmt.Sprintf("api: no protobuf message for %T", v))
+}
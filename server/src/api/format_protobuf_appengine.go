@@ -0,0 +1,26 @@
+//go:build appengine
+// +build appengine
+
+package api
+
+import (
+	"gaego-gin/server/src/model"
+)
+
+// 第1世代のApp Engine(go1.9)ではProtocol Buffersのランタイムをビルドできないため、
+// Protocol Buffersの形式には対応せず、JSONとMessagePackのみとする
+
+// protobufFormats はHogeのメッセージを返すAPIがJSON、MessagePackに加えて対応する形式
+var protobufFormats []string
+
+// unmarshalHogeProto はProtocol Buffersに対応しないため、errUnsupportedMediaTypeを返す
+func unmarshalHogeProto(b []byte, hoge *model.Hoge) error {
+	return errUnsupportedMediaType
+}
+
+// marshalProto はProtocol Buffersに対応しないため、errUnsupportedMediaTypeを返す
+//
+// negotiateFormatはprotobufFormatsに含まれない形式を選択しないため、呼び出されない
+func marshalProto(v interface{}) ([]byte, error) {
+	return nil, errUnsupportedMediaType
+}
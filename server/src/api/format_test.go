@@ -0,0 +1,186 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/hogepb"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHogeAPI_Format(t *testing.T) {
+	factory := dstest.NewStore().Factory()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, factory)
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+	handler := middleware.CustomMethods(r)
+
+	msgpack := &codec.MsgpackHandle{WriteExt: true}
+	msgpack.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	msgpack.RawToString = true
+
+	encode := func(t *testing.T, format string, hoge *model.Hoge) []byte {
+		var b []byte
+		var err error
+
+		switch format {
+		case "application/msgpack":
+			err = codec.NewEncoderBytes(&b, msgpack).Encode(map[string]interface{}{"id": hoge.ID, "value": hoge.Value})
+		case "application/x-protobuf":
+			b, err = proto.Marshal(hogepb.NewHoge(hoge))
+		default:
+			b, err = json.Marshal(hoge)
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return b
+	}
+
+	decode := func(t *testing.T, format string, b []byte) *model.Hoge {
+		switch format {
+		case "application/msgpack":
+			var obj interface{}
+			if err := codec.NewDecoderBytes(b, msgpack).Decode(&obj); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			var err error
+			if b, err = json.Marshal(obj); err != nil {
+				t.Fatal(err.Error())
+			}
+
+		case "application/x-protobuf":
+			x := &hogepb.Hoge{}
+			if err := proto.Unmarshal(b, x); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			return x.Model()
+		}
+
+		hoge := &model.Hoge{}
+		if err := json.Unmarshal(b, hoge); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return hoge
+	}
+
+	request := func(t *testing.T, method, path, contentType, accept string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		return w
+	}
+
+	for _, format := range []string{"application/json", "application/msgpack", "application/x-protobuf"} {
+		t.Run(format+"で作成、取得、更新、削除できること", func(t *testing.T) {
+			hoge := &model.Hoge{ID: "hoge", Value: "hogehoge"}
+
+			w := request(t, "POST", "/api/hoge", format, format, encode(t, format, hoge))
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+			AssertEquals(t, "Content-Type", strings.Split(w.Header().Get("Content-Type"), ";")[0], format)
+
+			created := decode(t, format, w.Body.Bytes())
+			AssertEquals(t, "ID", created.ID, "hoge")
+			AssertEquals(t, "Value", created.Value, "hogehoge")
+			AssertEquals(t, "CreatedAt", created.CreatedAt.IsZero(), false)
+
+			w = request(t, "GET", "/api/hoge/hoge", "", format, nil)
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+			got := decode(t, format, w.Body.Bytes())
+			AssertEquals(t, "ID", got.ID, "hoge")
+			AssertEquals(t, "Value", got.Value, "hogehoge")
+			AssertEquals(t, "CreatedAt", got.CreatedAt.Equal(created.CreatedAt), true)
+
+			w = request(t, "PUT", "/api/hoge/hoge", format, format, encode(t, format, &model.Hoge{ID: "hoge", Value: "fugafuga"}))
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+			AssertEquals(t, "Value", decode(t, format, w.Body.Bytes()).Value, "fugafuga")
+
+			w = request(t, "DELETE", "/api/hoge/hoge", "", format, nil)
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+			w = request(t, "GET", "/api/hoge/hoge", "", format, nil)
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusNotFound, w.Body.Bytes())
+		})
+	}
+
+	t.Run("Acceptのqの値が大きい形式で返ること", func(t *testing.T) {
+		w := request(t, "POST", "/api/hoge", "application/json", "application/json;q=0.5, application/x-protobuf;q=0.9, text/html", encode(t, "application/json", &model.Hoge{ID: "q", Value: "q"}))
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "Content-Type", w.Header().Get("Content-Type"), "application/x-protobuf")
+		AssertEquals(t, "ID", decode(t, "application/x-protobuf", w.Body.Bytes()).ID, "q")
+
+		w = request(t, "GET", "/api/hoge/q", "", "text/html, */*;q=0.1", nil)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "Content-Type", w.Header().Get("Content-Type"), "application/json; charset=utf-8")
+		AssertEquals(t, "Vary", w.Header().Get("Vary"), "Accept")
+	})
+
+	t.Run("fieldsを指定した場合、Protocol Buffersでは指定しないフィールドがゼロ値となること", func(t *testing.T) {
+		w := request(t, "GET", "/api/hoge/q?fields=id", "", "application/x-protobuf", nil)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		got := decode(t, "application/x-protobuf", w.Body.Bytes())
+		AssertEquals(t, "ID", got.ID, "q")
+		AssertEquals(t, "Value", got.Value, "")
+		AssertEquals(t, "CreatedAt", got.CreatedAt.IsZero(), true)
+	})
+
+	t.Run("Acceptに対応する形式が含まれない場合、406エラーとなり保存されないこと", func(t *testing.T) {
+		for _, accept := range []string{"text/html", "application/x-protobuf;q=0"} {
+			w := request(t, "POST", "/api/hoge", "application/json", accept, encode(t, "application/json", &model.Hoge{ID: "406"}))
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusNotAcceptable, w.Body.Bytes())
+		}
+
+		w := request(t, "GET", "/api/hoge/406", "", "", nil)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusNotFound, w.Body.Bytes())
+	})
+
+	t.Run("Content-Typeに対応しない場合、415エラーとなること", func(t *testing.T) {
+		w := request(t, "POST", "/api/hoge", "text/plain", "", []byte("hoge"))
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusUnsupportedMediaType, w.Body.Bytes())
+	})
+
+	t.Run("リクエストボディが上限を超える場合、413エラーとなること", func(t *testing.T) {
+		for _, format := range []string{"application/json", "application/x-protobuf"} {
+			w := request(t, "POST", "/api/hoge", format, "", bytes.Repeat([]byte{' '}, 1<<20+1))
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusRequestEntityTooLarge, w.Body.Bytes())
+		}
+	})
+
+	t.Run("リクエストボディが不正な場合、400エラーとなること", func(t *testing.T) {
+		for _, format := range []string{"application/json", "application/msgpack", "application/x-protobuf"} {
+			w := request(t, "POST", "/api/hoge", format, "", []byte{0xff, 0xff})
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+		}
+	})
+}
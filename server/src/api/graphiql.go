@@ -0,0 +1,72 @@
+//go:build !appengine
+// +build !appengine
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// graphiqlScripts はGraphiQLのページで読み込むスクリプト。CDNの内容が変わらないようにバージョンを固定する
+var graphiqlScripts = []string{
+	"https://unpkg.com/react@18.3.1/umd/react.production.min.js",
+	"https://unpkg.com/react-dom@18.3.1/umd/react-dom.production.min.js",
+	"https://unpkg.com/graphiql@3.7.1/graphiql.min.js",
+}
+
+// graphiqlStylesheet はGraphiQLのページで読み込むスタイルシート
+const graphiqlStylesheet = "https://unpkg.com/graphiql@3.7.1/graphiql.min.css"
+
+// graphiqlPage はGraphiQLのページ。GraphiQLはCDNから読み込む
+const graphiqlPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>GraphiQL</title>
+  <link rel="stylesheet" href="%s">
+  <style>body { height: 100vh; margin: 0; } #graphiql { height: 100vh; }</style>
+</head>
+<body>
+  <div id="graphiql"></div>
+%s  <script>%s</script>
+</body>
+</html>
+`
+
+// graphiqlInit はGraphiQLを表示するインラインのスクリプト
+const graphiqlInit = `
+    ReactDOM.createRoot(document.getElementById('graphiql')).render(
+      React.createElement(GraphiQL, {
+        fetcher: GraphiQL.createFetcher({ url: %q }),
+      }),
+    );
+  `
+
+// GraphiQL はendpointのGraphQLのAPIを実行するGraphiQLのページを返すハンドラを返す
+//
+// Content-Security-Policyでスクリプトをバージョンを固定したURLとページのインラインのスクリプトのみに制限する
+func GraphiQL(endpoint string) gin.HandlerFunc {
+	script := fmt.Sprintf(graphiqlInit, endpoint)
+
+	var tags strings.Builder
+	for _, src := range graphiqlScripts {
+		fmt.Fprintf(&tags, "  <script crossorigin src=\"%s\"></script>\n", src)
+	}
+
+	page := []byte(fmt.Sprintf(graphiqlPage, graphiqlStylesheet, tags.String(), script))
+
+	sum := sha256.Sum256([]byte(script))
+	csp := fmt.Sprintf("script-src %s 'sha256-%s'; connect-src 'self'; object-src 'none'; base-uri 'none'",
+		strings.Join(graphiqlScripts, " "), base64.StdEncoding.EncodeToString(sum[:]))
+
+	return func(c *gin.Context) {
+		c.Header("Content-Security-Policy", csp)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	}
+}
@@ -0,0 +1,549 @@
+//go:build !appengine
+// +build !appengine
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// GraphQLLoadWait はhogeクエリのIDをまとめてGetMultiで取得するまでの待機時間
+var GraphQLLoadWait = time.Millisecond
+
+// graphqlMaxDepth はクエリのネストの上限
+const graphqlMaxDepth = 10
+
+// graphqlMaxCost は1つのリクエストでDatastoreを参照するフィールドのコストの合計の上限
+//
+// エイリアスで同じフィールドを繰り返すクエリを制限する。hogeとミューテーションは1、hogesは取得件数、
+// totalCountはgraphqlCountCostとし、上限を超えたフィールドは実行せずに400のエラーとする
+const graphqlMaxCost = 200
+
+// graphqlCountCost は件数を数えるキーのみのクエリのコスト
+const graphqlCountCost = model.MaxHogeListLimit
+
+// graphqlSchema はHogeのGraphQLのスキーマ
+const graphqlSchema = `
+schema {
+  query: Query
+  mutation: Mutation
+}
+
+scalar Time
+
+type Query {
+  # IDのHogeを取得する。存在しない場合はnull
+  hoge(id: ID!): Hoge
+  # Hogeの一覧を取得する。afterにはpageInfo.endCursor、beforeにはpageInfo.startCursorを指定する
+  hoges(first: Int, after: String, last: Int, before: String, sort: String, value: String): HogeConnection!
+}
+
+type Mutation {
+  createHoge(input: HogeInput!): Hoge!
+  updateHoge(input: HogeInput!): Hoge!
+  # 削除したHogeのIDを返す
+  deleteHoge(id: ID!): ID!
+}
+
+type Hoge {
+  id: ID!
+  value: String!
+  createdAt: Time!
+  updatedAt: Time!
+}
+
+input HogeInput {
+  id: ID!
+  value: String!
+}
+
+type HogeConnection {
+  edges: [HogeEdge!]!
+  nodes: [Hoge!]!
+  pageInfo: PageInfo!
+  # sortとvalueの条件に一致するHogeの総数。指定した場合のみ数え、1000件を超える場合は1000とする
+  totalCount: Int!
+}
+
+type HogeEdge {
+  node: Hoge!
+}
+
+type PageInfo {
+  hasNextPage: Boolean!
+  hasPreviousPage: Boolean!
+  # 前のページのページトークン
+  startCursor: String
+  # 次のページのページトークン
+  endCursor: String
+}
+`
+
+// errInvalidConnectionArgs はhogesの引数の組み合わせが不正な場合のエラー
+var errInvalidConnectionArgs = errors.New("first and last, or after and before cannot be specified together")
+
+// errQueryCostExceeded はクエリのコストがgraphqlMaxCostを超えた場合のエラー
+var errQueryCostExceeded = fmt.Errorf("query cost exceeds the limit of %d", graphqlMaxCost)
+
+var hogeGraphQL = graphql.MustParseSchema(graphqlSchema, &graphqlResolver{}, graphql.MaxDepth(graphqlMaxDepth))
+
+// GraphQLAPI はGraphQLのAPIを管理する
+type GraphQLAPI struct{}
+
+// SetupGraphQL はGraphQLのAPIのハンドリングを行う
+func SetupGraphQL(rg *gin.RouterGroup) {
+	api := &GraphQLAPI{}
+
+	rg.POST("/graphql", api.Query)
+}
+
+// GraphQLRequest はGraphQLのリクエストボディ
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query はGraphQLのクエリとミューテーションを実行する
+// @Description GraphQLのクエリとミューテーションを実行する。スキーマは/graphiqlのGraphiQLで参照できる。
+// @Description エラーはerrorsに含め、extensionsのstatusとcodeにHTTPのAPIと同じステータスコードとその名前を返す。
+// @Description 1つのリクエストに含まれるhogeクエリは、まとめてDatastoreから取得する。
+// @Description Datastoreを参照するフィールドのコスト(hogeとミューテーションは1、hogesは取得件数、totalCountは100)の合計が200を超えた場合、超えたフィールドはstatus 400のエラーとなる。
+// @Tags GraphQL
+// @Summary GraphQL
+// @Accept  json
+// @Produce  json
+// @Param  request body api.GraphQLRequest true "query, operationName and variables"
+// @Success 200 {object} object
+// @Failure 400 {string} string
+// @Router /graphql [post]
+func (api *GraphQLAPI) Query(c *gin.Context) {
+	req := &GraphQLRequest{}
+	if err := decode(c, mimeJSON, func() error { return c.ShouldBindJSON(req) }); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g := ds.FromRequest(c.Request)
+
+	ctx := context.WithValue(c.Request.Context(), graphqlContextKey{}, &graphqlContext{
+		c:      c,
+		g:      g,
+		loader: newHogeLoader(g, GraphQLLoadWait),
+	})
+
+	c.JSON(http.StatusOK, hogeGraphQL.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+type graphqlContextKey struct{}
+
+// graphqlContext はリゾルバーで共有するリクエスト毎の値
+type graphqlContext struct {
+	c      *gin.Context
+	g      ds.Client
+	loader *hogeLoader
+	cost   int32
+}
+
+// spend はcostを加算し、合計がgraphqlMaxCostを超える場合はerrQueryCostExceededを返す
+//
+// フィールドは並行して解決するため、アトミックに加算する
+func (gc *graphqlContext) spend(cost int) error {
+	if atomic.AddInt32(&gc.cost, int32(cost)) > graphqlMaxCost {
+		return errQueryCostExceeded
+	}
+
+	return nil
+}
+
+func fromGraphQLContext(ctx context.Context) *graphqlContext {
+	return ctx.Value(graphqlContextKey{}).(*graphqlContext)
+}
+
+// graphqlError はリゾルバーのエラー。extensionsにHTTPのAPIと同じステータスコードを含める
+type graphqlError struct {
+	message string
+	status  int
+	errorID string
+}
+
+func (e *graphqlError) Error() string {
+	return e.message
+}
+
+// Extensions はエラーのextensionsを返す
+func (e *graphqlError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{
+		"status": e.status,
+		"code":   strings.ToUpper(strings.Replace(http.StatusText(e.status), " ", "_", -1)),
+	}
+	if e.errorID != "" {
+		ext["errorId"] = e.errorID
+	}
+
+	return ext
+}
+
+// newGraphQLError はrespondErrorと同様に、エラーに対応するステータスコードのgraphqlErrorを返す
+//
+// 5xxの場合は元のエラーをエラーIDと共にログに出力し、クライアントにはステータスコードの説明とエラーIDのみを返す
+func newGraphQLError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
+	code := graphqlStatusCode(err)
+	if code < http.StatusInternalServerError {
+		return &graphqlError{message: err.Error(), status: code}
+	}
+
+	c := fromGraphQLContext(ctx).c
+
+	id := newErrorID()
+	log.With("errorId", id).Errorf(ctx, "%s %s: %d: %v", c.Request.Method, c.Request.URL.Path, code, err)
+
+	msg := http.StatusText(code)
+	if debugMode {
+		msg = err.Error()
+	}
+
+	return &graphqlError{message: fmt.Sprintf("%s (error id: %s)", msg, id), status: code, errorID: id}
+}
+
+// graphqlStatusCode はerrorStatusCodeに加えて、GraphQLの引数のエラーを400とする
+func graphqlStatusCode(err error) int {
+	if err == errInvalidConnectionArgs || err == errQueryCostExceeded {
+		return http.StatusBadRequest
+	}
+
+	return errorStatusCode(err)
+}
+
+// graphqlResolver はQueryとMutationのリゾルバー
+type graphqlResolver struct{}
+
+// Hoge はIDのHogeを取得する
+//
+// 同じリクエストの他のhogeクエリとまとめてGetMultiで取得する
+func (r *graphqlResolver) Hoge(ctx context.Context, args struct{ ID graphql.ID }) (*hogeResolver, error) {
+	if args.ID == "" {
+		return nil, newGraphQLError(ctx, model.ErrIDRequired)
+	}
+
+	gc := fromGraphQLContext(ctx)
+	if err := gc.spend(1); err != nil {
+		return nil, newGraphQLError(ctx, err)
+	}
+
+	hoge, err := gc.loader.Load(string(args.ID))
+	if err == ds.ErrNoSuchEntity {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, newGraphQLError(ctx, err)
+	}
+
+	return &hogeResolver{hoge: hoge}, nil
+}
+
+// hogesArgs はhogesの引数
+type hogesArgs struct {
+	First  *int32
+	After  *string
+	Last   *int32
+	Before *string
+	Sort   *string
+	Value  *string
+}
+
+// Hoges はHogeStore.Listのページを返す
+func (r *graphqlResolver) Hoges(ctx context.Context, args hogesArgs) (*hogeConnectionResolver, error) {
+	if (args.First != nil && args.Last != nil) || (args.After != nil && args.Before != nil) {
+		return nil, newGraphQLError(ctx, errInvalidConnectionArgs)
+	}
+
+	hq := model.HogeQuery{}
+	if args.First != nil {
+		hq.Limit = int(*args.First)
+	}
+	if args.Last != nil {
+		hq.Limit = int(*args.Last)
+	}
+	if args.After != nil {
+		hq.Cursor = *args.After
+	}
+	if args.Before != nil {
+		hq.Cursor = *args.Before
+	}
+	if args.Sort != nil {
+		hq.Sort = *args.Sort
+	}
+	if args.Value != nil {
+		hq.Value = *args.Value
+	}
+	if hq.Limit <= 0 && (args.First != nil || args.Last != nil) {
+		return nil, newGraphQLError(ctx, model.ErrInvalidLimit)
+	}
+
+	cost := hq.Limit
+	switch {
+	case cost == 0:
+		cost = model.DefaultHogeListLimit
+	case cost > model.MaxHogeListLimit:
+		cost = model.MaxHogeListLimit
+	}
+	if err := fromGraphQLContext(ctx).spend(cost); err != nil {
+		return nil, newGraphQLError(ctx, err)
+	}
+
+	return &hogeConnectionResolver{hq: hq}, nil
+}
+
+// CreateHoge はHogeを新規作成する
+func (r *graphqlResolver) CreateHoge(ctx context.Context, args struct{ Input hogeInput }) (*hogeResolver, error) {
+	return r.save(ctx, args.Input, "hoge.insert", model.HogeCreated, (*model.Hoge).Insert)
+}
+
+// UpdateHoge はHogeを更新する
+func (r *graphqlResolver) UpdateHoge(ctx context.Context, args struct{ Input hogeInput }) (*hogeResolver, error) {
+	return r.save(ctx, args.Input, "hoge.update", model.HogeUpdated, (*model.Hoge).Update)
+}
+
+// save はHTTPのAPIと同じトランザクションでputでHogeを保存し、変更をtypのイベントとして保存する
+func (r *graphqlResolver) save(ctx context.Context, input hogeInput, name string, typ model.EventType, put func(*model.Hoge, ds.Client) error) (*hogeResolver, error) {
+	gc := fromGraphQLContext(ctx)
+
+	if input.ID == "" {
+		return nil, newGraphQLError(ctx, model.ErrIDRequired)
+	}
+	if err := gc.spend(1); err != nil {
+		return nil, newGraphQLError(ctx, err)
+	}
+
+	hoge := &model.Hoge{ID: string(input.ID), Value: input.Value}
+
+	if err := model.RunInTransaction(gc.g, name, func(tg ds.Client) error {
+		if err := put(hoge, tg); err != nil {
+			return err
+		}
+
+		return outbox.Publish(tg, typ, hoge)
+
+	}); err != nil {
+		return nil, newGraphQLError(ctx, err)
+	}
+
+	gc.loader.Clear(hoge.ID)
+	gc.loader.Prime(hoge)
+
+	return &hogeResolver{hoge: hoge}, nil
+}
+
+// DeleteHoge はHogeを削除する
+func (r *graphqlResolver) DeleteHoge(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	gc := fromGraphQLContext(ctx)
+
+	id := string(args.ID)
+	if id == "" {
+		return "", newGraphQLError(ctx, model.ErrIDRequired)
+	}
+	if err := gc.spend(1); err != nil {
+		return "", newGraphQLError(ctx, err)
+	}
+
+	store := &model.HogeStore{}
+
+	if err := model.RunInTransaction(gc.g, "hoge.delete", func(tg ds.Client) error {
+		deleted, err := store.Delete(tg, id)
+		if err != nil {
+			return err
+		}
+		// 存在しないHogeの場合は変更がないため、イベントを配信しない。イベントには削除したHogeの値を含める
+		if deleted == nil {
+			return nil
+		}
+
+		return outbox.Publish(tg, model.HogeDeleted, deleted)
+
+	}); err != nil {
+		return "", newGraphQLError(ctx, err)
+	}
+
+	gc.loader.Clear(id)
+
+	return args.ID, nil
+}
+
+// hogeInput はcreateHogeとupdateHogeの入力
+type hogeInput struct {
+	ID    graphql.ID
+	Value string
+}
+
+// hogeResolver はHogeのリゾルバー
+type hogeResolver struct {
+	hoge *model.Hoge
+}
+
+func (r *hogeResolver) ID() graphql.ID {
+	return graphql.ID(r.hoge.ID)
+}
+
+func (r *hogeResolver) Value() string {
+	return r.hoge.Value
+}
+
+func (r *hogeResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.hoge.CreatedAt}
+}
+
+func (r *hogeResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.hoge.UpdatedAt}
+}
+
+// hogeConnectionResolver はHogeConnectionのリゾルバー
+//
+// edges, nodes, pageInfoのいずれかを参照した場合に1回のみHogeStore.Listを実行する
+type hogeConnectionResolver struct {
+	hq   model.HogeQuery
+	once sync.Once
+	resp *model.HogeListResp
+	err  error
+}
+
+// page はhqのページを取得し、取得したHogeをhogeクエリで再利用する
+func (r *hogeConnectionResolver) page(ctx context.Context) (*model.HogeListResp, error) {
+	r.once.Do(func() {
+		gc := fromGraphQLContext(ctx)
+
+		store := &model.HogeStore{}
+
+		if err := model.Retry(ctx, "hoge.list", func() error {
+			var err error
+			r.resp, err = store.List(gc.g, r.hq)
+			return err
+
+		}); err != nil {
+			r.err = newGraphQLError(ctx, err)
+			return
+		}
+
+		for _, hoge := range r.resp.List {
+			gc.loader.Prime(hoge)
+		}
+	})
+
+	return r.resp, r.err
+}
+
+func (r *hogeConnectionResolver) Edges(ctx context.Context) ([]*hogeEdgeResolver, error) {
+	resp, err := r.page(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := make([]*hogeEdgeResolver, len(resp.List))
+	for i, hoge := range resp.List {
+		edges[i] = &hogeEdgeResolver{node: &hogeResolver{hoge: hoge}}
+	}
+
+	return edges, nil
+}
+
+func (r *hogeConnectionResolver) Nodes(ctx context.Context) ([]*hogeResolver, error) {
+	resp, err := r.page(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*hogeResolver, len(resp.List))
+	for i, hoge := range resp.List {
+		nodes[i] = &hogeResolver{hoge: hoge}
+	}
+
+	return nodes, nil
+}
+
+func (r *hogeConnectionResolver) PageInfo(ctx context.Context) (*pageInfoResolver, error) {
+	resp, err := r.page(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pageInfoResolver{resp: resp}, nil
+}
+
+// TotalCount はCursorを除いた条件でHogeの総数を数える。総数はmodel.MaxHogeCountで打ち切る
+func (r *hogeConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	gc := fromGraphQLContext(ctx)
+	if err := gc.spend(graphqlCountCost); err != nil {
+		return 0, newGraphQLError(ctx, err)
+	}
+
+	store := &model.HogeStore{}
+
+	hq := model.HogeQuery{Limit: 1, Sort: r.hq.Sort, Value: r.hq.Value, Count: true}
+
+	var resp *model.HogeListResp
+	if err := model.Retry(ctx, "hoge.count", func() error {
+		var err error
+		resp, err = store.List(gc.g, hq)
+		return err
+
+	}); err != nil {
+		return 0, newGraphQLError(ctx, err)
+	}
+
+	return int32(*resp.Total), nil
+}
+
+// hogeEdgeResolver はHogeEdgeのリゾルバー
+type hogeEdgeResolver struct {
+	node *hogeResolver
+}
+
+func (r *hogeEdgeResolver) Node() *hogeResolver {
+	return r.node
+}
+
+// pageInfoResolver はPageInfoのリゾルバー
+type pageInfoResolver struct {
+	resp *model.HogeListResp
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.resp.Cursor != ""
+}
+
+func (r *pageInfoResolver) HasPreviousPage() bool {
+	return r.resp.PrevCursor != ""
+}
+
+func (r *pageInfoResolver) StartCursor() *string {
+	if r.resp.PrevCursor == "" {
+		return nil
+	}
+
+	return &r.resp.PrevCursor
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	if r.resp.Cursor == "" {
+		return nil
+	}
+
+	return &r.resp.Cursor
+}
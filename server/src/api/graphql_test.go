@@ -0,0 +1,246 @@
+//go:build !appengine
+// +build !appengine
+
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// countingClient はGetMultiの呼び出し毎の件数を記録するClient
+type countingClient struct {
+	*dstest.Client
+	mu    *sync.Mutex
+	calls *[]int
+}
+
+func (c *countingClient) GetMulti(dst interface{}) error {
+	c.mu.Lock()
+	*c.calls = append(*c.calls, reflect.ValueOf(dst).Len())
+	c.mu.Unlock()
+
+	return c.Client.GetMulti(dst)
+}
+
+// graphqlResponse はGraphQLのレスポンス
+type graphqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func TestGraphQLAPI(t *testing.T) {
+	var mu sync.Mutex
+	var calls []int
+	store := dstest.NewStore()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, func(r *http.Request) ds.Client {
+			return &countingClient{Client: store.Client(r.Context()), mu: &mu, calls: &calls}
+		})
+		c.Next()
+	})
+	api.SetupGraphQL(r.Group("/api"))
+	r.GET("/graphiql", api.GraphiQL("/api/graphql"))
+
+	request := func(t *testing.T, query string, variables map[string]interface{}) *graphqlResponse {
+		body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		req := httptest.NewRequest("POST", "/api/graphql", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		resp := &graphqlResponse{}
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return resp
+	}
+
+	errorStatus := func(t *testing.T, resp *graphqlResponse) interface{} {
+		if len(resp.Errors) != 1 {
+			t.Fatalf("unexpected errors: %+v", resp.Errors)
+		}
+
+		return resp.Errors[0].Extensions["status"]
+	}
+
+	create := `mutation ($id: ID!, $value: String!) { createHoge(input: {id: $id, value: $value}) { id value createdAt } }`
+
+	t.Run("createHogeでHogeを作成できること", func(t *testing.T) {
+		for _, id := range []string{"hoge1", "hoge2", "hoge3"} {
+			resp := request(t, create, map[string]interface{}{"id": id, "value": "value-" + id})
+			AssertEquals(t, "len(errors)", len(resp.Errors), 0)
+
+			hoge := resp.Data["createHoge"].(map[string]interface{})
+			AssertEquals(t, "id", hoge["id"], id)
+			AssertEquals(t, "value", hoge["value"], "value-"+id)
+			AssertEquals(t, "createdAt", hoge["createdAt"] != "", true)
+		}
+	})
+
+	t.Run("複数のhogeクエリを1回のGetMultiで取得し、存在しない場合はnullとなること", func(t *testing.T) {
+		calls = nil
+
+		resp := request(t, `{
+			a: hoge(id: "hoge1") { id value }
+			b: hoge(id: "hoge3") { value }
+			c: hoge(id: "missing") { id }
+			d: hoge(id: "hoge1") { createdAt }
+		}`, nil)
+		AssertEquals(t, "len(errors)", len(resp.Errors), 0)
+
+		AssertEquals(t, "a.value", resp.Data["a"].(map[string]interface{})["value"], "value-hoge1")
+		AssertEquals(t, "b.value", resp.Data["b"].(map[string]interface{})["value"], "value-hoge3")
+		AssertEquals(t, "c", resp.Data["c"], nil)
+		AssertEquals(t, "len(d)", len(resp.Data["d"].(map[string]interface{})), 1)
+
+		AssertEquals(t, "len(calls)", len(calls), 1)
+		AssertEquals(t, "calls[0]", calls[0], 3)
+	})
+
+	t.Run("hogesでページと総数を取得できること", func(t *testing.T) {
+		resp := request(t, `{
+			hoges(first: 2) {
+				nodes { id }
+				edges { node { value } }
+				pageInfo { hasNextPage hasPreviousPage startCursor endCursor }
+				totalCount
+			}
+		}`, nil)
+		AssertEquals(t, "len(errors)", len(resp.Errors), 0)
+
+		hoges := resp.Data["hoges"].(map[string]interface{})
+		nodes := hoges["nodes"].([]interface{})
+		AssertEquals(t, "len(nodes)", len(nodes), 2)
+		AssertEquals(t, "nodes[0].id", nodes[0].(map[string]interface{})["id"], "hoge1")
+		AssertEquals(t, "nodes[1].id", nodes[1].(map[string]interface{})["id"], "hoge2")
+
+		edges := hoges["edges"].([]interface{})
+		AssertEquals(t, "edges[1].node.value", edges[1].(map[string]interface{})["node"].(map[string]interface{})["value"], "value-hoge2")
+
+		pageInfo := hoges["pageInfo"].(map[string]interface{})
+		AssertEquals(t, "hasNextPage", pageInfo["hasNextPage"], true)
+		AssertEquals(t, "hasPreviousPage", pageInfo["hasPreviousPage"], false)
+		AssertEquals(t, "startCursor", pageInfo["startCursor"], nil)
+		AssertEquals(t, "endCursor", pageInfo["endCursor"] != nil, true)
+
+		AssertEquals(t, "totalCount", hoges["totalCount"], float64(3))
+	})
+
+	t.Run("hogesの引数が不正な場合、status 400のエラーとなること", func(t *testing.T) {
+		for _, query := range []string{
+			`{ hoges(first: 1, last: 1) { totalCount } }`,
+			`{ hoges(after: "a", before: "b") { totalCount } }`,
+			`{ hoges(first: 0) { totalCount } }`,
+			`{ hoges(sort: "value") { nodes { id } } }`,
+			`{ hoges(after: "invalid") { nodes { id } } }`,
+		} {
+			resp := request(t, query, nil)
+			AssertEquals(t, query, errorStatus(t, resp), float64(http.StatusBadRequest))
+			AssertEquals(t, query, resp.Errors[0].Extensions["code"], "BAD_REQUEST")
+		}
+	})
+
+	t.Run("updateHogeとdeleteHogeでHogeを更新、削除できること", func(t *testing.T) {
+		resp := request(t, `mutation { updateHoge(input: {id: "hoge2", value: "updated"}) { id value } }`, nil)
+		AssertEquals(t, "len(errors)", len(resp.Errors), 0)
+		AssertEquals(t, "value", resp.Data["updateHoge"].(map[string]interface{})["value"], "updated")
+
+		resp = request(t, `mutation { deleteHoge(id: "hoge3") }`, nil)
+		AssertEquals(t, "len(errors)", len(resp.Errors), 0)
+		AssertEquals(t, "deleteHoge", resp.Data["deleteHoge"], "hoge3")
+
+		resp = request(t, `{ a: hoge(id: "hoge2") { value } b: hoge(id: "hoge3") { value } }`, nil)
+		AssertEquals(t, "a.value", resp.Data["a"].(map[string]interface{})["value"], "updated")
+		AssertEquals(t, "b", resp.Data["b"], nil)
+	})
+
+	t.Run("HTTPのAPIと同じステータスコードのエラーとなること", func(t *testing.T) {
+		resp := request(t, create, map[string]interface{}{"id": "hoge1", "value": ""})
+		AssertEquals(t, "duplicate", errorStatus(t, resp), float64(http.StatusConflict))
+
+		resp = request(t, `mutation { updateHoge(input: {id: "missing", value: ""}) { id } }`, nil)
+		AssertEquals(t, "missing", errorStatus(t, resp), float64(http.StatusNotFound))
+		AssertEquals(t, "missing", resp.Errors[0].Extensions["code"], "NOT_FOUND")
+
+		resp = request(t, create, map[string]interface{}{"id": "", "value": ""})
+		AssertEquals(t, "empty id", errorStatus(t, resp), float64(http.StatusBadRequest))
+
+		resp = request(t, `mutation { updateHoge(input: {id: "", value: ""}) { id } }`, nil)
+		AssertEquals(t, "update empty id", errorStatus(t, resp), float64(http.StatusBadRequest))
+	})
+
+	t.Run("コストの合計が上限を超えた場合、超えたフィールドはstatus 400のエラーとなること", func(t *testing.T) {
+		calls = nil
+
+		resp := request(t, `{
+			a: hoges(first: 100) { totalCount }
+			b: hoges(first: 100) { totalCount }
+		}`, nil)
+		if len(resp.Errors) == 0 {
+			t.Fatal("expected errors")
+		}
+		for _, e := range resp.Errors {
+			AssertEquals(t, e.Message, e.Extensions["status"], float64(http.StatusBadRequest))
+		}
+
+		var query strings.Builder
+		query.WriteString("{")
+		for i := 0; i < 201; i++ {
+			fmt.Fprintf(&query, " h%d: hoge(id: \"hoge1\") { id }", i)
+		}
+		query.WriteString(" }")
+
+		calls = nil
+
+		resp = request(t, query.String(), nil)
+		AssertEquals(t, "len(errors)", len(resp.Errors), 1)
+		AssertEquals(t, "status", resp.Errors[0].Extensions["status"], float64(http.StatusBadRequest))
+		AssertEquals(t, "len(calls)", len(calls), 1)
+	})
+
+	t.Run("リクエストボディが不正な場合、400エラーとなること", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/graphql", strings.NewReader("{"))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+	})
+
+	t.Run("GraphiQLのページがエンドポイントを参照すること", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/graphiql", nil))
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "Content-Type", w.Header().Get("Content-Type"), "text/html; charset=utf-8")
+		AssertEquals(t, "endpoint", strings.Contains(w.Body.String(), `"/api/graphql"`), true)
+		AssertEquals(t, "pinned", strings.Contains(w.Body.String(), "graphiql@3.7.1/graphiql.min.js"), true)
+		AssertEquals(t, "Content-Security-Policy", strings.Contains(w.Header().Get("Content-Security-Policy"), "script-src https://unpkg.com/react@18.3.1/"), true)
+	})
+}
@@ -2,6 +2,7 @@ package api_test
 
 import (
 	"context"
+	"gaego-gin/server/src/ds"
 	"gaego-gin/server/src/model"
 	"testing"
 
@@ -48,7 +49,7 @@ func (h *AdminTestHelper) ClearEntity(t *testing.T, src interface{}) {
 /* Kind別のentity作成 */
 
 func (h *AdminTestHelper) createHoge(t *testing.T, v *model.Hoge) *model.Hoge {
-	g := goon.FromContext(h.ctx)
+	g := ds.NewGoon(goon.FromContext(h.ctx))
 
 	if err := v.Insert(g); err != nil {
 		t.Fatal(err.Error())
@@ -1,26 +1,46 @@
 package api
 
 import (
+	"bytes"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/middleware"
 	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"path"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mjibson/goon"
-	"google.golang.org/appengine"
-	"google.golang.org/appengine/datastore"
 )
 
 // HogeAPI はHogeのAPIを管理する
-type HogeAPI struct{}
+type HogeAPI struct {
+	basePath string
+	view     hogeView
+}
 
-// SetupHoge はHogeのAPIのハンドリングを行う
+// SetupHoge はV1のHogeのAPIのハンドリングを行う
 func SetupHoge(rg *gin.RouterGroup) {
-	api := &HogeAPI{}
+	api := &HogeAPI{basePath: rg.BasePath(), view: v1View{}}
+
+	setupHoge(rg, api)
 
+	// 非同期のインポートをJobとして実行する前のクライアントのため、V1のみに登録する
+	rg.GET("/imports/:id", api.GetImport)
+}
+
+func setupHoge(rg *gin.RouterGroup, api *HogeAPI) {
 	rg.GET("/hoge/:id", api.Get)
 	rg.GET("/hoge", api.List)
+	rg.GET(middleware.CustomMethodPath("/hoge", "export"), middleware.Streaming(), api.Export)
+	setupWatch(rg, api)
+	rg.GET(middleware.CustomMethodPath("/hoge", "search"), api.Search)
 	rg.POST("/hoge", api.Insert)
+	rg.POST(middleware.CustomMethodPath("/hoge", "import"), api.Import)
 	rg.PUT("/hoge/:id", api.Update)
 	rg.DELETE("/hoge/:id", api.Delete)
 }
@@ -31,10 +51,16 @@ func SetupHoge(rg *gin.RouterGroup) {
 // @Summary Hoge 1件取得
 // @Accept  json
 // @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/x-protobuf
 // @Param  id path string true "Hoge.ID"
+// @Param  fields query string false "comma separated fields to include (e.g. id,value). nested fields are selected with a/b or a(b,c)"
 // @Success 200 {object} model.Hoge
 // @Failure 400 {string} string
+// @Failure 406 {string} string
 // @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
 // @Router /hoge/{id} [get]
 func (api *HogeAPI) Get(c *gin.Context) {
 	id := c.Param("id")
@@ -43,22 +69,26 @@ func (api *HogeAPI) Get(c *gin.Context) {
 		return
 	}
 
-	ctx := appengine.NewContext(c.Request)
-	g := goon.FromContext(ctx)
+	format, ok := negotiateFormat(c, hogeFormats...)
+	if !ok {
+		return
+	}
+
+	mask, ok := bindFieldMask(c, api.view.hogeType())
+	if !ok {
+		return
+	}
+
+	g := ds.FromRequest(c.Request)
 
 	store := &model.HogeStore{}
 	hoge, err := store.Get(g, id)
 	if err != nil {
-		if err == datastore.ErrNoSuchEntity {
-			c.String(http.StatusNotFound, err.Error())
-			return
-		}
-
-		c.String(http.StatusInternalServerError, err.Error())
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, hoge)
+	respond(c, http.StatusOK, format, api.view.hoge(hoge), mask)
 }
 
 // List はHogeの一覧を取得する
@@ -67,14 +97,33 @@ func (api *HogeAPI) Get(c *gin.Context) {
 // @Summary Hoge 一覧取得
 // @Accept  json
 // @Produce  json
-// @Param  cursor query string false "start cursor"
-// @Param  limit query string false "query limit"
+// @Produce  application/msgpack
+// @Produce  application/x-protobuf
+// @Param  cursor query string false "page token returned as cursor or prevCursor"
+// @Param  limit query int false "page size (1-100, default 10). values above 100 are treated as 100"
+// @Param  sort query string false "sort order (id, -id, createdAt, -createdAt, updatedAt, -updatedAt)"
+// @Param  value query string false "filter by value"
+// @Param  count query bool false "include the total number of matching entities, capped at 1000 (totalCapped is true when capped)"
+// @Param  fields query string false "comma separated fields of each Hoge in list to include (e.g. id,value). cursor, prevCursor, total and totalCapped are always included"
 // @Success 200 {object} model.HogeListResp
+// @Header 200 {string} Link "RFC 8288 links to the next and previous pages (rel=next, rel=prev)"
 // @Failure 400 {string} string
+// @Failure 406 {string} string
 // @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
 // @Router /hoge [get]
 func (api *HogeAPI) List(c *gin.Context) {
-	cursor := c.Query("cursor")
+	format, ok := negotiateFormat(c, hogeFormats...)
+	if !ok {
+		return
+	}
+
+	hq := model.HogeQuery{
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+		Value:  c.Query("value"),
+	}
 
 	limit := 0
 	if c.Query("limit") != "" {
@@ -84,19 +133,259 @@ func (api *HogeAPI) List(c *gin.Context) {
 			c.String(http.StatusBadRequest, err.Error())
 			return
 		}
+		if limit <= 0 {
+			c.String(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", model.MaxHogeListLimit))
+			return
+		}
+	}
+	hq.Limit = limit
+
+	if c.Query("count") != "" {
+		var err error
+		hq.Count, err = strconv.ParseBool(c.Query("count"))
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	mask, ok := bindFieldMask(c, api.view.hogeType())
+	if !ok {
+		return
+	}
+	if mask != nil {
+		// 一覧の各Hogeに適用し、ページングのフィールドは常に含める
+		mask = api.view.listMask(mask)
+	}
+
+	g := ds.FromRequest(c.Request)
+
+	store := &model.HogeStore{}
+
+	var resp *model.HogeListResp
+	if err := model.Retry(g.Context(), "hoge.list", func() error {
+		var err error
+		resp, err = store.List(g, hq)
+		return err
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var links []link
+	if resp.Cursor != "" {
+		links = append(links, link{rel: "next", cursor: resp.Cursor})
+	}
+	if resp.PrevCursor != "" {
+		links = append(links, link{rel: "prev", cursor: resp.PrevCursor})
+	}
+	setLinkHeader(c, links...)
+
+	respond(c, http.StatusOK, format, api.view.list(resp), mask)
+}
+
+// Export はHogeを全件書き出す
+// @Description 条件に一致するHogeを全件、NDJSONまたはCSVで逐次書き出す。
+// @Description 各バッチの最後の行には再開用のcursorを含めるため、切断された場合は最後に受信したcursorを指定して再開できる。
+// @Description 書き出しの結果はX-Export-Statusトレーラー(completeまたはaborted)で返す。途中でエラーとなった場合、HTTP/1.1では接続を切断する。
+// @Tags Hoge
+// @Summary Hoge 全件書き出し
+// @Produce  application/x-ndjson
+// @Produce  text/csv
+// @Param  format query string false "output format (ndjson or csv). defaults to the Accept header, then ndjson"
+// @Param  cursor query string false "cursor to resume from"
+// @Param  sort query string false "sort order (id, -id, createdAt, -createdAt, updatedAt, -updatedAt)"
+// @Param  value query string false "filter by value"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 406 {string} string
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Router /hoge:export [get]
+func (api *HogeAPI) Export(c *gin.Context) {
+	format, ok := negotiateExportFormat(c)
+	if !ok {
+		return
+	}
+
+	hq := model.HogeQuery{
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+		Value:  c.Query("value"),
+	}
+
+	g := ds.FromRequest(c.Request)
+
+	store := &model.HogeStore{}
+
+	var w exportWriter
+	err := store.Export(g, hq, func(hoge *model.Hoge, cursor string) error {
+		if w == nil {
+			var err error
+			if w, err = newExportWriter(c, format); err != nil {
+				return err
+			}
+		}
+
+		if err := w.Write(hoge, cursor); err != nil {
+			return err
+		}
+
+		// 再開できる位置まで書き出したところで送信する
+		if cursor != "" {
+			return w.Flush()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if w == nil {
+			respondError(c, err)
+			return
+		}
+
+		abortExport(c, err)
+		return
+	}
+
+	if w == nil {
+		if w, err = newExportWriter(c, format); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		abortExport(c, err)
+		return
+	}
+
+	c.Writer.Header().Set(exportStatusTrailer, "complete")
+}
+
+// Import はHogeを一括で登録する
+// @Description NDJSONまたはCSVで指定したHogeを一括で登録する。各行はHogeの新規作成と同じ規則で検証する。
+// @Description async=trueの場合はJobとして実行し、202と共に状態を取得するURL(/jobs/{id})をLocationヘッダーに返す。
+// @Tags Hoge
+// @Summary Hoge 一括登録
+// @Accept  application/x-ndjson
+// @Accept  text/csv
+// @Produce  json
+// @Produce  application/msgpack
+// @Param  format query string false "input format (ndjson or csv). defaults to the Content-Type header"
+// @Param  conflict query string false "what to do when a Hoge with the same id exists (skip, overwrite or fail). defaults to skip"
+// @Param  dryRun query bool false "report what would change without saving"
+// @Param  async query bool false "run the import as a background job"
+// @Success 200 {object} model.ImportReport
+// @Success 202 {object} model.Job
+// @Failure 400 {string} string
+// @Failure 406 {string} string
+// @Failure 409 {object} model.ImportReport
+// @Failure 413 {string} string
+// @Failure 415 {string} string
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /hoge:import [post]
+func (api *HogeAPI) Import(c *gin.Context) {
+	resFormat, ok := negotiateFormat(c, objectFormats...)
+	if !ok {
+		return
+	}
+
+	format, ok := negotiateImportFormat(c)
+	if !ok {
+		return
+	}
+
+	conflict, err := model.ParseConflictPolicy(c.Query("conflict"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts := model.ImportOptions{Conflict: conflict, Publish: outbox.PublishEvents}
+
+	async := false
+	for name, v := range map[string]*bool{"dryRun": &opts.DryRun, "async": &async} {
+		if c.Query(name) == "" {
+			continue
+		}
+
+		if *v, err = strconv.ParseBool(c.Query(name)); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
 	}
 
-	ctx := appengine.NewContext(c.Request)
-	g := goon.FromContext(ctx)
+	g := ds.FromRequest(c.Request)
+
+	if async {
+		api.importAsync(c, g, format, opts, resFormat)
+		return
+	}
+
+	r, err := newImportReader(format, c.Request.Body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
 	store := &model.HogeStore{}
-	resp, err := store.List(g, cursor, limit)
+
+	report, err := store.Import(g, r, opts)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	code := http.StatusOK
+	if report.Aborted {
+		code = http.StatusConflict
+	}
+
+	respond(c, code, resFormat, report, nil)
+}
+
+// importAsync はインポートするファイルをJobとして保存し、実行を登録する
+//
+// 登録したJobはresFormatの形式で返す
+func (api *HogeAPI) importAsync(c *gin.Context, g ds.Client, format string, opts model.ImportOptions, resFormat string) {
+	payload, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, model.MaxJobPayloadSize+1))
 	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+		respondError(c, err)
+		return
+	}
+	if len(payload) > model.MaxJobPayloadSize {
+		c.String(http.StatusRequestEntityTooLarge, fmt.Sprintf("async import is limited to %d bytes", model.MaxJobPayloadSize))
+		return
+	}
+
+	// ヘッダーなどファイル全体の形式の誤りはJobの実行を待たずに返す
+	if _, err := newImportReader(format, bytes.NewReader(payload)); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	job, err := model.NewJob(importJobType, &importParams{
+		Format:   format,
+		Conflict: opts.Conflict,
+		DryRun:   opts.DryRun,
+	}, payload)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := jobs.Enqueue(g, job); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Location", path.Join(api.basePath, "jobs", job.ID))
+	respond(c, http.StatusAccepted, resFormat, job, nil)
 }
 
 // Insert はHogeを新規作成する
@@ -104,16 +393,30 @@ func (api *HogeAPI) List(c *gin.Context) {
 // @Tags Hoge
 // @Summary Hoge 新規作成
 // @Accept  json
+// @Accept  application/msgpack
+// @Accept  application/x-protobuf
 // @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/x-protobuf
 // @Param  hoge body model.Hoge true "新規作成するHoge"
 // @Success 200 {object} model.Hoge
 // @Failure 400 {string} string
+// @Failure 406 {string} string
+// @Failure 409 {string} string
+// @Failure 415 {string} string
 // @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
 // @Router /hoge [post]
 func (api *HogeAPI) Insert(c *gin.Context) {
+	format, ok := negotiateFormat(c, hogeFormats...)
+	if !ok {
+		return
+	}
+
 	hoge := &model.Hoge{}
-	if err := c.BindJSON(hoge); err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+	if err := bindHoge(c, hoge); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -122,20 +425,23 @@ func (api *HogeAPI) Insert(c *gin.Context) {
 		return
 	}
 
-	ctx := appengine.NewContext(c.Request)
-	g := goon.FromContext(ctx)
+	g := ds.FromRequest(c.Request)
 
-	if err := g.RunInTransaction(func(tg *goon.Goon) error {
+	if err := model.RunInTransaction(g, "hoge.insert", func(tg ds.Client) error {
 		hoge := hoge
 
-		return hoge.Insert(tg)
+		if err := hoge.Insert(tg); err != nil {
+			return err
+		}
 
-	}, &datastore.TransactionOptions{XG: true}); err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+		return outbox.Publish(tg, model.HogeCreated, hoge)
+
+	}); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, hoge)
+	respond(c, http.StatusOK, format, api.view.hoge(hoge), nil)
 }
 
 // Update はHogeを更新する
@@ -143,17 +449,31 @@ func (api *HogeAPI) Insert(c *gin.Context) {
 // @Tags Hoge
 // @Summary Hoge 更新
 // @Accept  json
+// @Accept  application/msgpack
+// @Accept  application/x-protobuf
 // @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/x-protobuf
 // @Param  hoge body model.Hoge true "更新するHoge"
 // @Success 200 {object} model.Hoge
 // @Failure 400 {string} string
 // @Failure 404 {string} string
+// @Failure 406 {string} string
+// @Failure 409 {string} string
+// @Failure 415 {string} string
 // @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
 // @Router /hoge/{id} [put]
 func (api *HogeAPI) Update(c *gin.Context) {
+	format, ok := negotiateFormat(c, hogeFormats...)
+	if !ok {
+		return
+	}
+
 	hoge := &model.Hoge{}
-	if err := c.BindJSON(hoge); err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+	if err := bindHoge(c, hoge); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -162,31 +482,23 @@ func (api *HogeAPI) Update(c *gin.Context) {
 		return
 	}
 
-	ctx := appengine.NewContext(c.Request)
-	g := goon.FromContext(ctx)
+	g := ds.FromRequest(c.Request)
 
-	statusCode := 0
-	if err := g.RunInTransaction(func(tg *goon.Goon) error {
+	if err := model.RunInTransaction(g, "hoge.update", func(tg ds.Client) error {
 		hoge := hoge
 
 		if err := hoge.Update(tg); err != nil {
-			if err == datastore.ErrNoSuchEntity {
-				statusCode = http.StatusNotFound
-				return err
-			}
-
-			statusCode = http.StatusInternalServerError
 			return err
 		}
 
-		return nil
+		return outbox.Publish(tg, model.HogeUpdated, hoge)
 
-	}, &datastore.TransactionOptions{XG: true}); err != nil {
-		c.String(statusCode, err.Error())
+	}); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, hoge)
+	respond(c, http.StatusOK, format, api.view.hoge(hoge), nil)
 }
 
 // Delete はHogeを削除する
@@ -195,10 +507,16 @@ func (api *HogeAPI) Update(c *gin.Context) {
 // @Summary Hoge 削除
 // @Accept  json
 // @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/x-protobuf
 // @Param  id path string true "Hoge.ID"
 // @Success 200 {null} null
 // @Failure 400 {string} string
+// @Failure 406 {string} string
+// @Failure 409 {string} string
 // @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
 // @Router /hoge/{id} [delete]
 func (api *HogeAPI) Delete(c *gin.Context) {
 	id := c.Param("id")
@@ -207,18 +525,31 @@ func (api *HogeAPI) Delete(c *gin.Context) {
 		return
 	}
 
-	ctx := appengine.NewContext(c.Request)
-	g := goon.FromContext(ctx)
+	format, ok := negotiateFormat(c, hogeFormats...)
+	if !ok {
+		return
+	}
+
+	g := ds.FromRequest(c.Request)
 
 	store := &model.HogeStore{}
 
-	if err := g.RunInTransaction(func(tg *goon.Goon) error {
-		return store.Delete(tg, id)
+	if err := model.RunInTransaction(g, "hoge.delete", func(tg ds.Client) error {
+		deleted, err := store.Delete(tg, id)
+		if err != nil {
+			return err
+		}
+		// 存在しないHogeの場合は変更がないため、イベントを配信しない。イベントには削除したHogeの値を含める
+		if deleted == nil {
+			return nil
+		}
+
+		return outbox.Publish(tg, model.HogeDeleted, deleted)
 
-	}, &datastore.TransactionOptions{XG: true}); err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+	}); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, nil)
+	respond(c, http.StatusOK, format, nil, nil)
 }
@@ -3,20 +3,25 @@ package api_test
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/hogepb"
+	"gaego-gin/server/src/middleware"
 	"gaego-gin/server/src/model"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mjibson/goon"
 	"google.golang.org/appengine/aetest"
-	"google.golang.org/appengine/datastore"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestHogeAPI_Get(t *testing.T) {
@@ -89,6 +94,385 @@ func TestHogeAPI_List(t *testing.T) {
 		AssertEquals(t, "len(resp.List)", len(resp.List), 2)
 		AssertEquals(t, "resp.Cursor", resp.Cursor, "")
 	})
+
+	t.Run("limitが0以下の場合、400エラーとなること", func(t *testing.T) {
+		for _, limit := range []int{0, -1} {
+			code, _, body := helper.requestListWithQuery(t, url.Values{"limit": {fmt.Sprintf("%d", limit)}})
+
+			AssertHTTPStatusCodeEquals(t, code, http.StatusBadRequest, body)
+		}
+	})
+
+	t.Run("limitが上限を超える場合、上限の件数として扱われること", func(t *testing.T) {
+		code, resp, body := helper.requestList(t, "", model.MaxHogeListLimit+1)
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+		AssertEquals(t, "len(resp.List)", len(resp.List), 5)
+		AssertEquals(t, "resp.Cursor", resp.Cursor, "")
+	})
+
+	t.Run("count=trueの場合、総数が返ること", func(t *testing.T) {
+		code, resp, body := helper.requestListWithQuery(t, url.Values{"limit": {"2"}, "count": {"true"}})
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+		AssertEquals(t, "len(resp.List)", len(resp.List), 2)
+		AssertEquals(t, "resp.Total", *resp.Total, 5)
+
+		code, resp, body = helper.requestListWithQuery(t, url.Values{"value": {"hogehoge2"}, "count": {"true"}})
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+		AssertEquals(t, "resp.Total", *resp.Total, 1)
+	})
+
+	t.Run("countを指定しない場合、総数が返らないこと", func(t *testing.T) {
+		code, resp, body := helper.requestList(t, "", 2)
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+		AssertEquals(t, "resp.Total", resp.Total == nil, true)
+	})
+
+	t.Run("fieldsを指定した場合、各Hogeの指定したフィールドとページングのフィールドが返ること", func(t *testing.T) {
+		w := helper.serveList(t, url.Values{"limit": {"2"}, "count": {"true"}, "fields": {"id,value"}})
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		var resp struct {
+			List   []map[string]interface{} `json:"list"`
+			Cursor string                   `json:"cursor"`
+			Total  int                      `json:"total"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		AssertEquals(t, "len(resp.List)", len(resp.List), 2)
+		AssertEquals(t, "resp.Cursor", resp.Cursor != "", true)
+		AssertEquals(t, "resp.Total", resp.Total, 5)
+
+		for idx, v := range resp.List {
+			AssertEquals(t, fmt.Sprintf("len(resp.List[%d])", idx), len(v), 2)
+			AssertEquals(t, fmt.Sprintf("resp.List[%d].id", idx), v["id"], fmt.Sprintf("hoge%d", idx))
+			AssertEquals(t, fmt.Sprintf("resp.List[%d].value", idx), v["value"], fmt.Sprintf("hogehoge%d", idx))
+		}
+	})
+
+	t.Run("fieldsに存在しないフィールドを指定した場合、400エラーとなること", func(t *testing.T) {
+		code, _, body := helper.requestListWithQuery(t, url.Values{"fields": {"id,cursor"}})
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusBadRequest, body)
+	})
+
+	t.Run("AcceptにProtocol Buffersを指定した場合、HogeListRespのメッセージが返ること", func(t *testing.T) {
+		r, err := helper.inst.NewRequest("GET", "/api/hoge?limit=2&count=true", nil)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		r.Header.Set("Accept", "application/x-protobuf")
+
+		w := httptest.NewRecorder()
+		helper.initializeHandler().ServeHTTP(w, r)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "Content-Type", w.Header().Get("Content-Type"), "application/x-protobuf")
+
+		x := &hogepb.HogeListResp{}
+		if err := proto.Unmarshal(w.Body.Bytes(), x); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		resp := x.Model()
+		AssertEquals(t, "len(resp.List)", len(resp.List), 2)
+		AssertEquals(t, "resp.Cursor", resp.Cursor != "", true)
+		AssertEquals(t, "resp.Total", *resp.Total, 5)
+
+		for idx, v := range resp.List {
+			AssertEquals(t, fmt.Sprintf("resp.List[%d].ID", idx), v.ID, fmt.Sprintf("hoge%d", idx))
+			AssertEquals(t, fmt.Sprintf("resp.List[%d].CreatedAt", idx), v.CreatedAt.IsZero(), false)
+		}
+	})
+
+	t.Run("次のページが存在する場合、Linkヘッダーにrel=nextが返ること", func(t *testing.T) {
+		w := helper.serveList(t, url.Values{"limit": {"3"}, "value": {""}})
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		resp := &model.HogeListResp{}
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		next := url.Values{"cursor": {resp.Cursor}, "limit": {"3"}, "value": {""}}
+		AssertEquals(t, "Link", w.Header().Get("Link"), fmt.Sprintf(`</api/hoge?%s>; rel="next"`, next.Encode()))
+
+		w = helper.serveList(t, next)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		resp = &model.HogeListResp{}
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		prev := url.Values{"cursor": {resp.PrevCursor}, "limit": {"3"}, "value": {""}}
+		AssertEquals(t, "Link", w.Header().Get("Link"), fmt.Sprintf(`</api/hoge?%s>; rel="prev"`, prev.Encode()))
+	})
+
+	t.Run("前後のページを行き来した場合、同じ内容のページが返ること", func(t *testing.T) {
+		ids := func(resp *model.HogeListResp) string {
+			var ids []string
+			for _, v := range resp.List {
+				ids = append(ids, v.ID)
+			}
+			return strings.Join(ids, ",")
+		}
+
+		for _, tc := range []struct {
+			sort  string
+			pages []string
+		}{
+			{"id", []string{"hoge0,hoge1", "hoge2,hoge3", "hoge4"}},
+			{"-id", []string{"hoge4,hoge3", "hoge2,hoge1", "hoge0"}},
+		} {
+			query := func(cursor string) url.Values {
+				return url.Values{"cursor": {cursor}, "limit": {"2"}, "sort": {tc.sort}}
+			}
+
+			// 先頭のページには前のページが存在しない
+			code, resp, body := helper.requestListWithQuery(t, query(""))
+			AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+			AssertEquals(t, "ids", ids(resp), tc.pages[0])
+			AssertEquals(t, "resp.PrevCursor", resp.PrevCursor, "")
+
+			code, resp, body = helper.requestListWithQuery(t, query(resp.Cursor))
+			AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+			AssertEquals(t, "ids", ids(resp), tc.pages[1])
+
+			// 末尾のページには次のページが存在しない
+			code, resp, body = helper.requestListWithQuery(t, query(resp.Cursor))
+			AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+			AssertEquals(t, "ids", ids(resp), tc.pages[2])
+			AssertEquals(t, "resp.Cursor", resp.Cursor, "")
+			AssertEquals(t, "resp.PrevCursor", resp.PrevCursor != "", true)
+
+			code, resp, body = helper.requestListWithQuery(t, query(resp.PrevCursor))
+			AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+			AssertEquals(t, "ids", ids(resp), tc.pages[1])
+			AssertEquals(t, "resp.Cursor", resp.Cursor != "", true)
+
+			// 前のページを辿って先頭に戻った場合も前のページは存在しない
+			code, resp, body = helper.requestListWithQuery(t, query(resp.PrevCursor))
+			AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+			AssertEquals(t, "ids", ids(resp), tc.pages[0])
+			AssertEquals(t, "resp.PrevCursor", resp.PrevCursor, "")
+
+			// 前のページから次のページに戻れる
+			code, resp, body = helper.requestListWithQuery(t, query(resp.Cursor))
+			AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+			AssertEquals(t, "ids", ids(resp), tc.pages[1])
+		}
+	})
+
+	t.Run("sortとvalueを指定した場合、条件に一致するHogeが並び替えて返ること", func(t *testing.T) {
+		code, resp, body := helper.requestListWithQuery(t, url.Values{"sort": {"-id"}})
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+		AssertEquals(t, "len(resp.List)", len(resp.List), 5)
+		AssertEquals(t, "resp.List[0].ID", resp.List[0].ID, "hoge4")
+
+		code, resp, body = helper.requestListWithQuery(t, url.Values{"value": {"hogehoge2"}})
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
+		AssertEquals(t, "len(resp.List)", len(resp.List), 1)
+		AssertEquals(t, "resp.List[0].ID", resp.List[0].ID, "hoge2")
+	})
+
+	t.Run("sortが不正な場合、400エラーとなること", func(t *testing.T) {
+		code, _, body := helper.requestListWithQuery(t, url.Values{"sort": {"value"}})
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusBadRequest, body)
+	})
+
+	t.Run("Cursorが改ざんされた場合、400エラーとなること", func(t *testing.T) {
+		_, resp, _ := helper.requestList(t, "", 3)
+
+		code, _, body := helper.requestList(t, resp.Cursor+"x", 3)
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusBadRequest, body)
+	})
+
+	t.Run("Cursorを異なる条件で利用した場合、400エラーとなること", func(t *testing.T) {
+		_, resp, _ := helper.requestList(t, "", 3)
+
+		code, _, body := helper.requestListWithQuery(t, url.Values{
+			"cursor": {resp.Cursor},
+			"limit":  {"3"},
+			"sort":   {"-id"},
+		})
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusBadRequest, body)
+	})
+}
+
+func TestHogeAPI_Export(t *testing.T) {
+	inst, err := aetest.NewInstance(&aetest.Options{AppID: "unittest", StronglyConsistentDatastore: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	adminHelper := NewAdminTestHelper(inst)
+	helper := newHogeTestHelper(inst, adminHelper.ctx)
+
+	adminHelper.createHoge(t, &model.Hoge{ID: "hoge0", Value: "hogehoge0"})
+	adminHelper.createHoge(t, &model.Hoge{ID: "hoge1", Value: "hoge,hoge1"})
+	adminHelper.createHoge(t, &model.Hoge{ID: "hoge2", Value: "hogehoge2"})
+
+	t.Run("NDJSONで全件が書き出されること", func(t *testing.T) {
+		w := helper.requestExport(t, url.Values{}, "")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "Content-Type", w.Header().Get("Content-Type"), "application/x-ndjson")
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		AssertEquals(t, "len(lines)", len(lines), 3)
+
+		for idx, line := range lines {
+			v := &model.Hoge{}
+			if err := json.Unmarshal([]byte(line), v); err != nil {
+				t.Fatal(err.Error())
+			}
+			AssertEquals(t, fmt.Sprintf("lines[%d].ID", idx), v.ID, fmt.Sprintf("hoge%d", idx))
+		}
+	})
+
+	t.Run("CSVで全件が書き出されること", func(t *testing.T) {
+		for _, tc := range []struct {
+			query  url.Values
+			accept string
+		}{
+			{url.Values{"format": {"csv"}}, ""},
+			{url.Values{}, "text/csv"},
+			{url.Values{}, "image/png, text/*;q=0.5"},
+		} {
+			w := helper.requestExport(t, tc.query, tc.accept)
+
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+			AssertEquals(t, "Content-Type", w.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+
+			records, err := csv.NewReader(w.Body).ReadAll()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			AssertEquals(t, "len(records)", len(records), 4)
+			AssertEquals(t, "records[0][0]", records[0][0], "id")
+			AssertEquals(t, "records[2][1]", records[2][1], "hoge,hoge1")
+		}
+	})
+
+	t.Run("valueを指定した場合、条件に一致するHogeのみ書き出されること", func(t *testing.T) {
+		w := helper.requestExport(t, url.Values{"value": {"hogehoge2"}}, "")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "lines", strings.Count(w.Body.String(), "\n"), 1)
+	})
+
+	t.Run("formatが不正な場合、400エラーとなること", func(t *testing.T) {
+		w := helper.requestExport(t, url.Values{"format": {"xml"}}, "")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+	})
+
+	t.Run("Acceptに対応する形式が含まれない場合、406エラーとなること", func(t *testing.T) {
+		w := helper.requestExport(t, url.Values{}, "image/png")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusNotAcceptable, w.Body.Bytes())
+	})
+
+	t.Run("cursorが不正な場合、400エラーとなること", func(t *testing.T) {
+		w := helper.requestExport(t, url.Values{"cursor": {"invalid"}}, "")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+	})
+}
+
+func TestHogeAPI_Import(t *testing.T) {
+	inst, err := aetest.NewInstance(&aetest.Options{AppID: "unittest", StronglyConsistentDatastore: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	adminHelper := NewAdminTestHelper(inst)
+	helper := newHogeTestHelper(inst, adminHelper.ctx)
+
+	adminHelper.createHoge(t, &model.Hoge{ID: "hoge0", Value: "hogehoge0"})
+
+	body := "id,value\nhoge0,updated\nhoge1,created\n,invalid\n"
+
+	t.Run("Hogeが一括で登録され、行毎の結果が返ること", func(t *testing.T) {
+		w := helper.request(t, "POST", "/api/hoge:import", "text/csv", body)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		report := &model.ImportReport{}
+		if err := json.Unmarshal(w.Body.Bytes(), report); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "report.Created", report.Created, 1)
+		AssertEquals(t, "report.Skipped", report.Skipped, 1)
+		AssertEquals(t, "report.Failed", report.Failed, 1)
+
+		_, hoge, _ := helper.requestGet(t, "hoge1")
+		AssertEquals(t, "hoge1.Value", hoge.Value, "created")
+		_, hoge, _ = helper.requestGet(t, "hoge0")
+		AssertEquals(t, "hoge0.Value", hoge.Value, "hogehoge0")
+	})
+
+	t.Run("dryRunの場合、保存されずに結果のみ返ること", func(t *testing.T) {
+		w := helper.request(t, "POST", "/api/hoge:import?dryRun=true&conflict=overwrite", "text/csv", "id,value\nhoge0,dryrun\nhoge9,dryrun\n")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		report := &model.ImportReport{}
+		if err := json.Unmarshal(w.Body.Bytes(), report); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "report.DryRun", report.DryRun, true)
+		AssertEquals(t, "report.Updated", report.Updated, 1)
+		AssertEquals(t, "report.Created", report.Created, 1)
+
+		code, _, _ := helper.requestGet(t, "hoge9")
+		AssertEquals(t, "code", code, http.StatusNotFound)
+	})
+
+	t.Run("asyncの場合、Jobとして実行され、状態を取得できること", func(t *testing.T) {
+		w := helper.request(t, "POST", "/api/hoge:import?async=true&conflict=overwrite", "text/csv", body)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusAccepted, w.Body.Bytes())
+
+		job := &model.Job{}
+		if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "Location", w.Header().Get("Location"), "/api/jobs/"+job.ID)
+
+		w = helper.request(t, "GET", "/api/jobs/"+job.ID, "", "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		job = &model.Job{}
+		if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "job.Status", job.Status, model.JobSucceeded)
+		AssertEquals(t, "job.Progress", job.Progress, 3)
+
+		report := &model.ImportReport{}
+		if err := json.Unmarshal(job.Result, report); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "report.Updated", report.Updated, 2)
+
+		_, hoge, _ := helper.requestGet(t, "hoge0")
+		AssertEquals(t, "hoge0.Value", hoge.Value, "updated")
+	})
 }
 
 func TestHogeAPI_Insert(t *testing.T) {
@@ -114,7 +498,7 @@ func TestHogeAPI_Insert(t *testing.T) {
 		AssertEquals(t, "resp.ID", resp.ID, "hoge")
 		AssertEquals(t, "resp.Value", resp.Value, "hogehoge")
 
-		g := goon.FromContext(adminHelper.ctx)
+		g := ds.NewGoon(goon.FromContext(adminHelper.ctx))
 
 		store := &model.HogeStore{}
 		hoge, err := store.Get(g, resp.ID)
@@ -135,6 +519,24 @@ func TestHogeAPI_Insert(t *testing.T) {
 
 		AssertHTTPStatusCodeEquals(t, code, http.StatusBadRequest, body)
 	})
+
+	t.Run("同じIDのHogeが既に存在する場合、409エラーとなること", func(t *testing.T) {
+		defer adminHelper.ClearEntity(t, model.Hoge{})
+
+		adminHelper.createHoge(t, &model.Hoge{
+			ID:    "hoge",
+			Value: "hogehoge",
+		})
+
+		v := &model.Hoge{
+			ID:    "hoge",
+			Value: "hogehoge",
+		}
+
+		code, _, body := helper.requestInsert(t, v)
+
+		AssertHTTPStatusCodeEquals(t, code, http.StatusConflict, body)
+	})
 }
 
 func TestHogeAPI_Update(t *testing.T) {
@@ -162,7 +564,7 @@ func TestHogeAPI_Update(t *testing.T) {
 		AssertEquals(t, "resp.ID", resp.ID, "hoge")
 		AssertEquals(t, "resp.Value", resp.Value, "updated")
 
-		g := goon.FromContext(adminHelper.ctx)
+		g := ds.NewGoon(goon.FromContext(adminHelper.ctx))
 
 		store := &model.HogeStore{}
 		hoge, err := store.Get(g, resp.ID)
@@ -209,11 +611,11 @@ func TestHogeAPI_Delete(t *testing.T) {
 
 		AssertHTTPStatusCodeEquals(t, code, http.StatusOK, body)
 
-		g := goon.FromContext(adminHelper.ctx)
+		g := ds.NewGoon(goon.FromContext(adminHelper.ctx))
 
 		store := &model.HogeStore{}
 		if _, err := store.Get(g, v.ID); err != nil {
-			if err == datastore.ErrNoSuchEntity {
+			if err == ds.ErrNoSuchEntity {
 				// OK!
 			} else {
 				t.Fatal(err.Error())
@@ -242,8 +644,9 @@ func (h *hogeTestHelper) initializeHandler() http.Handler {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 	api.SetupHoge(r.Group("/api"))
+	api.SetupJob(r.Group("/api"))
 
-	return r
+	return middleware.CustomMethods(r)
 }
 
 func (h *hogeTestHelper) requestGet(t *testing.T, id string) (code int, v *model.Hoge, body []byte) {
@@ -281,34 +684,81 @@ func (h *hogeTestHelper) requestList(t *testing.T, cursor string, limit int) (co
 	vs := url.Values{}
 	vs.Add("cursor", cursor)
 	vs.Add("limit", fmt.Sprintf("%d", limit))
-	path := fmt.Sprintf("/api/hoge?%s", vs.Encode())
 
-	r, err := h.inst.NewRequest("GET", path, nil)
+	return h.requestListWithQuery(t, vs)
+}
+
+func (h *hogeTestHelper) requestListWithQuery(t *testing.T, vs url.Values) (code int, v *model.HogeListResp, body []byte) {
+	w := h.serveList(t, vs)
+
+	body, err := ioutil.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if w.Code != http.StatusOK {
+		return w.Code, nil, body
+	}
+
+	v = &model.HogeListResp{}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return w.Code, v, body
+}
+
+func (h *hogeTestHelper) request(t *testing.T, method, path, contentType, body string) *httptest.ResponseRecorder {
+	r, err := h.inst.NewRequest(method, path, strings.NewReader(body))
 	if err != nil {
 		t.Fatal(err.Error())
 	}
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
 
 	handler := h.initializeHandler()
 
 	w := httptest.NewRecorder()
 	handler.ServeHTTP(w, r)
 
-	body, err = ioutil.ReadAll(w.Body)
+	return w
+}
+
+func (h *hogeTestHelper) requestExport(t *testing.T, vs url.Values, accept string) *httptest.ResponseRecorder {
+	path := fmt.Sprintf("/api/hoge:export?%s", vs.Encode())
+
+	r, err := h.inst.NewRequest("GET", path, nil)
 	if err != nil {
 		t.Fatal(err.Error())
 	}
-
-	if w.Code != http.StatusOK {
-		return w.Code, nil, body
+	if accept != "" {
+		r.Header.Set("Accept", accept)
 	}
 
-	v = &model.HogeListResp{}
-	err = json.Unmarshal(body, v)
+	handler := h.initializeHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func (h *hogeTestHelper) serveList(t *testing.T, vs url.Values) *httptest.ResponseRecorder {
+	path := fmt.Sprintf("/api/hoge?%s", vs.Encode())
+
+	r, err := h.inst.NewRequest("GET", path, nil)
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	return w.Code, v, body
+	handler := h.initializeHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
 }
 
 func (h *hogeTestHelper) requestInsert(t *testing.T, dst *model.Hoge) (code int, v *model.Hoge, body []byte) {
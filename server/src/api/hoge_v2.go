@@ -0,0 +1,139 @@
+package api
+
+import (
+	"gaego-gin/server/src/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HogeV2 はV2のHogeのレスポンス
+type HogeV2 struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+	// CreateTime は作成日時。V1のcreatedAt
+	CreateTime time.Time `json:"createTime"`
+	// UpdateTime は更新日時。V1のupdatedAt
+	UpdateTime time.Time `json:"updateTime"`
+}
+
+// HogeListRespV2 はV2のHoge一覧取得のレスポンス
+type HogeListRespV2 struct {
+	Items []*HogeV2 `json:"items"`
+	// NextPageToken は次のページのページトークン。次のページが存在しない場合は省略する
+	NextPageToken string `json:"nextPageToken,omitempty"`
+	// PrevPageToken は前のページのページトークン。前のページが存在しない場合は省略する
+	PrevPageToken string `json:"prevPageToken,omitempty"`
+	// TotalSize は条件に一致するHogeの総数。countがtrueの場合のみ設定する
+	TotalSize *int `json:"totalSize,omitempty"`
+	// TotalSizeCapped がtrueの場合は、総数が上限を超えるためTotalSizeを上限で打ち切ったことを表す
+	TotalSizeCapped bool `json:"totalSizeCapped,omitempty"`
+}
+
+// HogeSearchRespV2 はV2のHoge検索のレスポンス
+type HogeSearchRespV2 struct {
+	Items []*HogeV2 `json:"items"`
+	// NextPageToken は次のページのカーソル。次のページが存在しない場合は省略する
+	NextPageToken string `json:"nextPageToken,omitempty"`
+	// TotalSize は検索条件に一致するHogeの件数。件数が多い場合は概算となる
+	TotalSize int `json:"totalSize"`
+}
+
+// SetupHogeV2 はV2のHogeのAPIのハンドリングを行う
+//
+// ルートはSetupHogeと同じで、Get, List, Insert, Update, Searchのレスポンスの形式のみ異なる
+// Protocol Buffersのレスポンス、エクスポート、変更の監視、インポート、Job、WebhookのペイロードはV1と同じ形式とする
+func SetupHogeV2(rg *gin.RouterGroup) {
+	setupHoge(rg, &HogeAPI{basePath: rg.BasePath(), view: v2View{}})
+}
+
+// newHogeV2 はHogeをV2のレスポンスに変換する
+func newHogeV2(hoge *model.Hoge) *HogeV2 {
+	return &HogeV2{
+		ID:         hoge.ID,
+		Value:      hoge.Value,
+		CreateTime: hoge.CreatedAt,
+		UpdateTime: hoge.UpdatedAt,
+	}
+}
+
+// toModel はV2のレスポンスをHogeに戻す。Protocol Buffersのメッセージへの変換に利用する
+func (x *HogeV2) toModel() *model.Hoge {
+	return &model.Hoge{
+		ID:        x.ID,
+		Value:     x.Value,
+		CreatedAt: x.CreateTime,
+		UpdatedAt: x.UpdateTime,
+	}
+}
+
+// toModel はV2のレスポンスをHogeListRespに戻す
+func (x *HogeListRespV2) toModel() *model.HogeListResp {
+	return &model.HogeListResp{
+		List:        hogeModels(x.Items),
+		Cursor:      x.NextPageToken,
+		PrevCursor:  x.PrevPageToken,
+		Total:       x.TotalSize,
+		TotalCapped: x.TotalSizeCapped,
+	}
+}
+
+// toModel はV2のレスポンスをHogeSearchRespに戻す
+func (x *HogeSearchRespV2) toModel() *model.HogeSearchResp {
+	return &model.HogeSearchResp{
+		List:   hogeModels(x.Items),
+		Cursor: x.NextPageToken,
+		Total:  x.TotalSize,
+	}
+}
+
+func newHogesV2(list []*model.Hoge) []*HogeV2 {
+	items := make([]*HogeV2, len(list))
+	for i, hoge := range list {
+		items[i] = newHogeV2(hoge)
+	}
+
+	return items
+}
+
+func hogeModels(items []*HogeV2) []*model.Hoge {
+	list := make([]*model.Hoge, len(items))
+	for i, x := range items {
+		list[i] = x.toModel()
+	}
+
+	return list
+}
+
+// v2View はHogeV2の形式で返すV2のレスポンスの形式
+type v2View struct{}
+
+func (v2View) hoge(hoge *model.Hoge) interface{} {
+	return newHogeV2(hoge)
+}
+
+func (v2View) list(resp *model.HogeListResp) interface{} {
+	return &HogeListRespV2{
+		Items:           newHogesV2(resp.List),
+		NextPageToken:   resp.Cursor,
+		PrevPageToken:   resp.PrevCursor,
+		TotalSize:       resp.Total,
+		TotalSizeCapped: resp.TotalCapped,
+	}
+}
+
+func (v2View) search(resp *model.HogeSearchResp) interface{} {
+	return &HogeSearchRespV2{
+		Items:         newHogesV2(resp.List),
+		NextPageToken: resp.Cursor,
+		TotalSize:     resp.Total,
+	}
+}
+
+func (v2View) hogeType() interface{} {
+	return HogeV2{}
+}
+
+func (v2View) listMask(m fieldMask) fieldMask {
+	return fieldMask{"items": m, "nextPageToken": nil, "prevPageToken": nil, "totalSize": nil, "totalSizeCapped": nil}
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importJobType は非同期で実行するインポートのJobの種類
+const importJobType = "hoge.import"
+
+func init() {
+	jobs.Register(importJobType, runImportJob)
+}
+
+// importParams は非同期で実行するインポートのJobのパラメータ
+type importParams struct {
+	Format   string               `json:"format"`
+	Conflict model.ConflictPolicy `json:"conflict"`
+	DryRun   bool                 `json:"dryRun"`
+}
+
+// importCheckpoint はリトライした場合に再開するための非同期のインポートの状態
+type importCheckpoint struct {
+	// Offset は保存済みの行数
+	Offset  int `json:"offset"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// runImportJob はJobに保存したファイルをインポートし、バッチ毎に進捗を保存する
+//
+// リトライした場合は保存済みの行を読み飛ばし、件数を引き継いで再開する。再開前の行の結果はresultsに含めない
+// conflict=failの場合は全ての行を確認するまで保存しないため、最初の行から実行し直す
+func runImportJob(g ds.Client, job *model.Job) (interface{}, error) {
+	p := &importParams{}
+	if err := job.Bind(p); err != nil {
+		return nil, err
+	}
+
+	resumable := p.Conflict != model.ConflictFail
+
+	cp := &importCheckpoint{}
+	if resumable {
+		if err := job.BindCheckpoint(cp); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := (&model.JobStore{}).LoadPayload(g, job); err != nil {
+		return nil, err
+	}
+
+	r, err := newImportReader(p.Format, bytes.NewReader(job.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	store := &model.HogeStore{}
+
+	report, err := store.Import(g, r, model.ImportOptions{
+		Conflict: p.Conflict,
+		DryRun:   p.DryRun,
+		Offset:   cp.Offset,
+		Progress: func(done int, report *model.ImportReport) error {
+			if !resumable {
+				return jobs.Progress(g, job, done, 0)
+			}
+
+			return jobs.Checkpoint(g, job, done, 0, &importCheckpoint{
+				Offset:  done,
+				Created: cp.Created + report.Created,
+				Updated: cp.Updated + report.Updated,
+				Skipped: cp.Skipped + report.Skipped,
+				Failed:  cp.Failed + report.Failed,
+			})
+		},
+		Publish: outbox.PublishEvents,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Created += cp.Created
+	report.Updated += cp.Updated
+	report.Skipped += cp.Skipped
+	report.Failed += cp.Failed
+
+	return report, nil
+}
+
+// GetImport は非同期で実行するインポートの状態を取得する
+// @Description 非同期で実行するインポートのJobを取得する。/jobs/{id}と同じ内容を返す。
+// @Description 非同期のインポートの状態を/imports/{id}で取得していたクライアントとの互換性のために残す。新しいクライアントは202のLocationヘッダーのURLを利用する。
+// @Tags Hoge
+// @Summary Hoge 一括登録の状態取得
+// @Accept  json
+// @Produce  json
+// @Param  id path string true "Job.ID"
+// @Success 200 {object} model.Job
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /imports/{id} [get]
+func (api *HogeAPI) GetImport(c *gin.Context) {
+	g := ds.FromRequest(c.Request)
+
+	store := &model.JobStore{}
+
+	var job *model.Job
+	if err := model.Retry(g.Context(), "job.get", func() error {
+		var err error
+		job, err = store.Get(g, c.Param("id"))
+		return err
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if job.Type != importJobType {
+		respondError(c, ds.ErrNoSuchEntity)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// importFileError はインポートするファイルの形式が不正な場合のエラー
+type importFileError struct {
+	err error
+}
+
+func (e *importFileError) Error() string {
+	return "invalid import file: " + e.err.Error()
+}
+
+// importMediaTypes はContent-Typeのメディアタイプに対応するインポートするファイルの形式
+var importMediaTypes = map[string]string{
+	"application/x-ndjson": formatNDJSON,
+	"application/ndjson":   formatNDJSON,
+	"text/csv":             formatCSV,
+}
+
+// negotiateImportFormat はformatパラメータ、またはContent-Typeヘッダーからインポートするファイルの形式を決定する
+//
+// formatパラメータが不正な場合は400、Content-Typeが対応しない形式の場合は415を返し、falseを返す
+func negotiateImportFormat(c *gin.Context) (string, bool) {
+	if f := c.Query("format"); f != "" {
+		if _, ok := exportContentTypes[f]; !ok {
+			c.String(http.StatusBadRequest, "format must be ndjson or csv")
+			return "", false
+		}
+
+		return f, true
+	}
+
+	mt, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err == nil {
+		if f, ok := importMediaTypes[mt]; ok {
+			return f, true
+		}
+	}
+
+	c.String(http.StatusUnsupportedMediaType, "supported media types are application/x-ndjson and text/csv")
+	return "", false
+}
+
+// newImportReader はformatの形式でrを読み込むmodel.ImportReaderを生成する
+//
+// CSVはヘッダーを読み込み、id列が存在しない場合はエラーを返す
+func newImportReader(format string, r io.Reader) (model.ImportReader, error) {
+	if format == formatCSV {
+		return newCSVImportReader(r)
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return &ndjsonImportReader{s: s}, nil
+}
+
+// importRecord はインポートするファイルの1行
+//
+// 書き出したファイルをそのまま読み込めるよう、それ以外のフィールドは無視する
+type importRecord struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+}
+
+type ndjsonImportReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func (r *ndjsonImportReader) Read() (*model.ImportRow, error) {
+	for r.s.Scan() {
+		r.line++
+
+		b := r.s.Bytes()
+		if len(b) == 0 {
+			continue
+		}
+
+		v := &importRecord{}
+		if err := json.Unmarshal(b, v); err != nil {
+			return &model.ImportRow{Line: r.line, Err: err}, nil
+		}
+
+		return &model.ImportRow{Line: r.line, Hoge: &model.Hoge{ID: v.ID, Value: v.Value}}, nil
+	}
+
+	if err := r.s.Err(); err != nil {
+		return nil, &importFileError{err: err}
+	}
+
+	return nil, io.EOF
+}
+
+type csvImportReader struct {
+	r     *csv.Reader
+	line  int
+	id    int
+	value int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, &importFileError{err: errors.New("header is required")}
+	}
+	if err != nil {
+		return nil, &importFileError{err: err}
+	}
+
+	ir := &csvImportReader{r: cr, line: 1, id: -1, value: -1}
+	for i, name := range header {
+		switch name {
+		case "id":
+			ir.id = i
+		case "value":
+			ir.value = i
+		}
+	}
+
+	if ir.id == -1 {
+		return nil, &importFileError{err: errors.New("id column is required")}
+	}
+
+	return ir, nil
+}
+
+func (r *csvImportReader) Read() (*model.ImportRow, error) {
+	record, err := r.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	r.line++
+
+	if err != nil {
+		if _, ok := err.(*csv.ParseError); ok {
+			return &model.ImportRow{Line: r.line, Err: err}, nil
+		}
+
+		return nil, &importFileError{err: err}
+	}
+
+	hoge := &model.Hoge{}
+	if r.id < len(record) {
+		hoge.ID = record[r.id]
+	}
+	if r.value != -1 {
+		if r.value >= len(record) {
+			return &model.ImportRow{Line: r.line, Err: fmt.Errorf("value column is missing")}, nil
+		}
+
+		hoge.Value = record[r.value]
+	}
+
+	return &model.ImportRow{Line: r.line, Hoge: hoge}, nil
+}
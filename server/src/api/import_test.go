@@ -0,0 +1,202 @@
+package api_test
+
+import (
+	"encoding/json"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHogeAPI_Import_Format(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	g := dstest.NewClient()
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, g.Store().Factory())
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+	handler := middleware.CustomMethods(r)
+
+	request := func(query, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/hoge:import"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		return w
+	}
+
+	value := func(t *testing.T, id string) string {
+		hoge := &model.Hoge{ID: id}
+		if err := g.Get(hoge); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return hoge.Value
+	}
+
+	report := func(t *testing.T, w *httptest.ResponseRecorder) *model.ImportReport {
+		v := &model.ImportReport{}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return v
+	}
+
+	t.Run("NDJSONを読み込めること", func(t *testing.T) {
+		w := request("", "application/x-ndjson", "{\"id\":\"hoge0\",\"value\":\"a\"}\n\n{\"id\":\"hoge1\"}\n{invalid\n")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		v := report(t, w)
+		AssertEquals(t, "report.Created", v.Created, 2)
+		AssertEquals(t, "report.Failed", v.Failed, 1)
+		AssertEquals(t, "report.Results[2].Line", v.Results[2].Line, 4)
+		AssertEquals(t, "hoge0.Value", value(t, "hoge0"), "a")
+	})
+
+	t.Run("書き出したCSVを読み込めること", func(t *testing.T) {
+		w := request("?conflict=overwrite", "text/csv; charset=utf-8", "id,value,createdAt,updatedAt,cursor\nhoge0,\"b,c\",,,\n,d,,,\n")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		v := report(t, w)
+		AssertEquals(t, "report.Updated", v.Updated, 1)
+		AssertEquals(t, "report.Results[1].Line", v.Results[1].Line, 3)
+		AssertEquals(t, "report.Results[1].Action", v.Results[1].Action, model.ImportInvalid)
+		AssertEquals(t, "hoge0.Value", value(t, "hoge0"), "b,c")
+	})
+
+	t.Run("conflict=failで既存のHogeが存在する場合、409エラーとなること", func(t *testing.T) {
+		w := request("?format=ndjson&conflict=fail", "", "{\"id\":\"hoge1\"}\n")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusConflict, w.Body.Bytes())
+		AssertEquals(t, "report.Aborted", report(t, w).Aborted, true)
+	})
+
+	t.Run("CSVにid列が存在しない場合、400エラーとなること", func(t *testing.T) {
+		w := request("", "text/csv", "value\nhoge\n")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+	})
+
+	t.Run("パラメータが不正な場合、400エラーとなること", func(t *testing.T) {
+		for _, query := range []string{"?conflict=replace", "?dryRun=maybe", "?format=xml"} {
+			w := request(query, "text/csv", "id\n")
+
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+		}
+	})
+
+	t.Run("対応しないContent-Typeの場合、415エラーとなること", func(t *testing.T) {
+		w := request("", "application/json", "[]")
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusUnsupportedMediaType, w.Body.Bytes())
+	})
+}
+
+func TestHogeAPI_Import_Resume(t *testing.T) {
+	defer func(q jobs.Queue) { jobs.DefaultQueue = q }(jobs.DefaultQueue)
+	jobs.DefaultQueue = &jobs.Local{}
+
+	g := dstest.NewClient()
+
+	payload := "{\"id\":\"hoge0\"}\n{\"id\":\"hoge1\"}\n{\"id\":\"hoge0\"}\n"
+	job, err := model.NewJob("hoge.import", map[string]string{"format": "ndjson", "conflict": "skip"}, []byte(payload))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 1行目を保存した後に失敗した状態
+	job.Status = model.JobRunning
+	job.Attempts = 1
+	job.Checkpoint = []byte(`{"offset":1,"created":1}`)
+	if err := job.Save(g); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := jobs.Run(g, job.ID); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	v, err := (&model.JobStore{}).Get(g, job.ID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	AssertEquals(t, "job.Status", v.Status, model.JobSucceeded)
+
+	report := &model.ImportReport{}
+	if err := json.Unmarshal(v.Result, report); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	t.Run("保存済みの行を読み飛ばし、件数を引き継ぐこと", func(t *testing.T) {
+		AssertEquals(t, "report.Offset", report.Offset, 1)
+		AssertEquals(t, "report.Created", report.Created, 2)
+		AssertEquals(t, "report.Results[0].Line", report.Results[0].Line, 2)
+
+		if err := g.Get(&model.Hoge{ID: "hoge0"}); err != ds.ErrNoSuchEntity {
+			t.Errorf("hoge0: unexpected, err: `%v`", err)
+		}
+		if err := g.Get(&model.Hoge{ID: "hoge1"}); err != nil {
+			t.Errorf("hoge1: unexpected, err: `%v`", err)
+		}
+	})
+
+	t.Run("読み飛ばした行とIDが重複する行が不正となること", func(t *testing.T) {
+		AssertEquals(t, "report.Failed", report.Failed, 1)
+		AssertEquals(t, "report.Results[1].Action", report.Results[1].Action, model.ImportInvalid)
+	})
+}
+
+func TestHogeAPI_Import_GetImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	g := dstest.NewClient()
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, g.Store().Factory())
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+
+	request := func(t *testing.T, typ string) *httptest.ResponseRecorder {
+		job, err := model.NewJob(typ, nil, nil)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := job.Save(g); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/imports/"+job.ID, nil))
+
+		return w
+	}
+
+	t.Run("インポートのJobが取得できること", func(t *testing.T) {
+		w := request(t, "hoge.import")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+	})
+
+	t.Run("インポート以外のJobの場合、404エラーとなること", func(t *testing.T) {
+		w := request(t, "search.reindex")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusNotFound, w.Body.Bytes())
+	})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobAPI はJobのAPIを管理する
+type JobAPI struct{}
+
+// SetupJob はJobのAPIのハンドリングを行う
+func SetupJob(rg *gin.RouterGroup) {
+	api := &JobAPI{}
+
+	rg.GET("/jobs/:id", api.Get)
+}
+
+// SetupJobTasks はタスクキューからJobを実行するハンドリングを行う
+//
+// rgは"/tasks"に登録する
+func SetupJobTasks(rg *gin.RouterGroup) {
+	api := &JobAPI{}
+
+	rg.POST(strings.TrimPrefix(jobs.TaskPath, rg.BasePath()), api.Run)
+}
+
+// Get はJobの状態を取得する
+// @Description 非同期で実行する処理の状態を取得する。完了した場合は結果を含める
+// @Tags Job
+// @Summary Job 状態取得
+// @Accept  json
+// @Produce  json
+// @Param  id path string true "Job.ID"
+// @Success 200 {object} model.Job
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /jobs/{id} [get]
+func (api *JobAPI) Get(c *gin.Context) {
+	g := ds.FromRequest(c.Request)
+
+	store := &model.JobStore{}
+
+	var job *model.Job
+	if err := model.Retry(g.Context(), "job.get", func() error {
+		var err error
+		job, err = store.Get(g, c.Param("id"))
+		return err
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// Run はタスクキューからJobを実行する
+//
+// 2xx以外を返した場合はタスクキューがリトライするため、存在しないJobは200を返す
+func (api *JobAPI) Run(c *gin.Context) {
+	g := ds.FromRequest(c.Request)
+
+	id := c.PostForm("id")
+
+	err := jobs.Run(g, id)
+	if err == ds.ErrNoSuchEntity || err == model.ErrIDRequired {
+		log.Warningf(c.Request.Context(), "job %q not found: %v", id, err)
+		c.Status(http.StatusOK)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
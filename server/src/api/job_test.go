@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"encoding/json"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mjibson/goon"
+	"google.golang.org/appengine/aetest"
+)
+
+func init() {
+	jobs.Register("test.echo", func(g ds.Client, job *model.Job) (interface{}, error) {
+		p := map[string]string{}
+		if err := job.Bind(&p); err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	})
+}
+
+func TestJobAPI(t *testing.T) {
+	inst, err := aetest.NewInstance(&aetest.Options{AppID: "unittest", StronglyConsistentDatastore: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	adminHelper := NewAdminTestHelper(inst)
+	g := ds.NewGoon(goon.FromContext(adminHelper.ctx))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.SetupJob(r.Group("/api"))
+	tasks := r.Group("/tasks")
+	tasks.Use(middleware.TaskQueue())
+	api.SetupJobTasks(tasks)
+
+	request := func(t *testing.T, method, path, queue string, form url.Values) *httptest.ResponseRecorder {
+		req, err := inst.NewRequest(method, path, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if queue != "" {
+			req.Header.Set("X-AppEngine-QueueName", queue)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	getJob := func(t *testing.T, id string) *model.Job {
+		w := request(t, "GET", "/api/jobs/"+id, "", nil)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		job := &model.Job{}
+		if err := json.Unmarshal(w.Body.Bytes(), job); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return job
+	}
+
+	job, err := model.NewJob("test.echo", map[string]string{"value": "hogehoge"}, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := job.Save(g); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	t.Run("タスクキュー以外からのリクエストの場合、403エラーとなること", func(t *testing.T) {
+		w := request(t, "POST", jobs.TaskPath, "", url.Values{"id": {job.ID}})
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusForbidden, w.Body.Bytes())
+		AssertEquals(t, "job.Status", getJob(t, job.ID).Status, model.JobPending)
+	})
+
+	t.Run("タスクキューから実行したJobの結果を取得できること", func(t *testing.T) {
+		w := request(t, "POST", jobs.TaskPath, "default", url.Values{"id": {job.ID}})
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		v := getJob(t, job.ID)
+		AssertEquals(t, "job.Status", v.Status, model.JobSucceeded)
+		AssertEquals(t, "job.Attempts", v.Attempts, 1)
+		AssertEquals(t, "job.Result", string(v.Result), `{"value":"hogehoge"}`)
+	})
+
+	t.Run("完了したJobのタスクが重複して実行された場合、再度実行されないこと", func(t *testing.T) {
+		w := request(t, "POST", jobs.TaskPath, "default", url.Values{"id": {job.ID}})
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "job.Attempts", getJob(t, job.ID).Attempts, 1)
+	})
+
+	t.Run("存在しないJobの場合、タスクは200、状態の取得は404となること", func(t *testing.T) {
+		w := request(t, "POST", jobs.TaskPath, "default", url.Values{"id": {"unknown"}})
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		w = request(t, "GET", "/api/jobs/unknown", "", nil)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusNotFound, w.Body.Bytes())
+	})
+}
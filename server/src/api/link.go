@@ -0,0 +1,38 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// link はRFC 8288のLinkヘッダーの1要素
+type link struct {
+	rel    string
+	cursor string
+}
+
+// setLinkHeader はリクエストのURLのcursorを置き換えたURLをLinkヘッダーに設定する
+//
+// URLはプロキシを経由しても解決できるよう、スキームとホストを含まない相対参照とする
+// middleware.Deprecationsが設定した廃止予定のLinkを残すため、既存のLinkヘッダーに追加する
+func setLinkHeader(c *gin.Context, links ...link) {
+	vs := make([]string, 0, len(links))
+	for _, l := range links {
+		q := c.Request.URL.Query()
+		q.Set("cursor", l.cursor)
+		if l.cursor == "" {
+			q.Del("cursor")
+		}
+
+		u := *c.Request.URL
+		u.RawQuery = q.Encode()
+
+		vs = append(vs, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), l.rel))
+	}
+
+	if len(vs) > 0 {
+		c.Writer.Header().Add("Link", strings.Join(vs, ", "))
+	}
+}
@@ -0,0 +1,143 @@
+//go:build !appengine
+// +build !appengine
+
+package api
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"sync"
+	"time"
+)
+
+// maxHogeLoaderBatch はhogeLoaderが1回のGetMultiで取得する件数の上限
+const maxHogeLoaderBatch = 100
+
+// hogeLoader はリクエスト内のHogeの取得をまとめ、GetMultiで一括で取得する
+//
+// waitの間に要求されたIDを1回のGetMultiで取得し、取得した結果はリクエストの間キャッシュする
+type hogeLoader struct {
+	g    ds.Client
+	wait time.Duration
+
+	mu      sync.Mutex
+	results map[string]*hogeLoadResult
+	batch   *hogeLoadBatch
+}
+
+// hogeLoadResult は1件の取得結果。doneを閉じた後にhogeとerrを参照する
+type hogeLoadResult struct {
+	done chan struct{}
+	hoge *model.Hoge
+	err  error
+}
+
+// hogeLoadBatch は1回のGetMultiで取得するIDと結果
+type hogeLoadBatch struct {
+	once    sync.Once
+	ids     []string
+	results []*hogeLoadResult
+}
+
+func newHogeLoader(g ds.Client, wait time.Duration) *hogeLoader {
+	return &hogeLoader{
+		g:       g,
+		wait:    wait,
+		results: map[string]*hogeLoadResult{},
+	}
+}
+
+// Load はIDのHogeを取得する。存在しない場合はds.ErrNoSuchEntityを返す
+func (l *hogeLoader) Load(id string) (*model.Hoge, error) {
+	l.mu.Lock()
+
+	r, ok := l.results[id]
+	if !ok {
+		r = &hogeLoadResult{done: make(chan struct{})}
+		l.results[id] = r
+
+		b := l.batch
+		if b == nil {
+			b = &hogeLoadBatch{}
+			l.batch = b
+			time.AfterFunc(l.wait, func() { l.dispatch(b) })
+		}
+
+		b.ids = append(b.ids, id)
+		b.results = append(b.results, r)
+
+		if len(b.ids) >= maxHogeLoaderBatch {
+			go l.dispatch(b)
+		}
+	}
+
+	l.mu.Unlock()
+
+	<-r.done
+	return r.hoge, r.err
+}
+
+// Prime は取得済みのhogeをキャッシュする。既に要求されたIDの場合は何もしない
+func (l *hogeLoader) Prime(hoge *model.Hoge) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.results[hoge.ID]; ok {
+		return
+	}
+
+	r := &hogeLoadResult{done: make(chan struct{}), hoge: hoge}
+	close(r.done)
+	l.results[hoge.ID] = r
+}
+
+// Clear はIDのキャッシュを削除する。変更した場合に、以降のLoadで取得し直す
+func (l *hogeLoader) Clear(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.results, id)
+}
+
+// dispatch はbのIDをGetMultiで取得し、結果を通知する
+//
+// waitの経過と件数の上限の両方から呼び出されるため、1回のみ実行する
+func (l *hogeLoader) dispatch(b *hogeLoadBatch) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+
+		list := make([]*model.Hoge, len(b.ids))
+		for i, id := range b.ids {
+			list[i] = &model.Hoge{ID: id}
+		}
+
+		var merr ds.MultiError
+		err := model.Retry(l.g.Context(), "hoge.load", func() error {
+			err := l.g.GetMulti(list)
+			if m, ok := err.(ds.MultiError); ok {
+				merr = m
+				return nil
+			}
+
+			merr = nil
+			return err
+		})
+
+		for i, r := range b.results {
+			switch {
+			case err != nil:
+				r.err = err
+			case merr != nil && merr[i] != nil:
+				r.err = merr[i]
+			default:
+				r.hoge = list[i]
+			}
+
+			close(r.done)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/search"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search はHogeを全文検索する
+// @Description Hoge.Valueの単語やHoge.IDでHogeを検索し、ID順に返す。
+// @Description qは空白区切りの項を全て満たすHogeを検索する。各項は単語(word)、フレーズ("some words")、接頭辞(pre*)、またはフィールドを指定した項(id:hoge1, id:hoge*, value:word)とする。
+// @Description インデックスは変更のコミット後に非同期で更新するため、直後の検索結果には反映されない場合がある。
+// @Description インデックスが設定されていない環境(第1世代のApp Engine以外)では501を返す。
+// @Tags Hoge
+// @Summary Hoge 検索
+// @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/x-protobuf
+// @Param  q query string true "search query"
+// @Param  cursor query string false "cursor returned by the previous page"
+// @Param  limit query int false "page size (1-100, default 10). values above 100 are treated as 100"
+// @Success 200 {object} model.HogeSearchResp
+// @Header 200 {string} Link "RFC 8288 link to the next page (rel=next)"
+// @Failure 400 {string} string
+// @Failure 406 {string} string
+// @Failure 500 {string} string
+// @Failure 501 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /hoge:search [get]
+func (api *HogeAPI) Search(c *gin.Context) {
+	format, ok := negotiateFormat(c, hogeFormats...)
+	if !ok {
+		return
+	}
+
+	if search.DefaultIndex == nil {
+		c.String(http.StatusNotImplemented, search.ErrNoIndex.Error())
+		return
+	}
+
+	q, err := search.ParseQuery(c.Query("q"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit := model.DefaultHogeListLimit
+	if c.Query("limit") != "" {
+		if limit, err = strconv.Atoi(c.Query("limit")); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if limit <= 0 {
+			c.String(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", model.MaxHogeListLimit))
+			return
+		}
+		if limit > model.MaxHogeListLimit {
+			limit = model.MaxHogeListLimit
+		}
+	}
+
+	g := ds.FromRequest(c.Request)
+
+	res, err := search.DefaultIndex.Search(g.Context(), q, c.Query("cursor"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	list := make([]*model.Hoge, len(res.IDs))
+	for i, id := range res.IDs {
+		list[i] = &model.Hoge{ID: id}
+	}
+
+	var merr ds.MultiError
+	if len(list) > 0 {
+		if err := model.Retry(g.Context(), "hoge.search", func() error {
+			err := g.GetMulti(list)
+			if m, ok := err.(ds.MultiError); ok {
+				merr = m
+				return nil
+			}
+
+			merr = nil
+			return err
+
+		}); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	// インデックスに反映する前に削除したHogeは除く
+	resp := &model.HogeSearchResp{List: []*model.Hoge{}, Cursor: res.Cursor, Total: res.Total}
+	for i, hoge := range list {
+		if merr != nil && merr[i] != nil {
+			if merr[i] != ds.ErrNoSuchEntity {
+				respondError(c, merr[i])
+				return
+			}
+
+			continue
+		}
+
+		resp.List = append(resp.List, hoge)
+	}
+
+	if resp.Cursor != "" {
+		setLinkHeader(c, link{rel: "next", cursor: resp.Cursor})
+	}
+
+	respond(c, http.StatusOK, format, api.view.search(resp), nil)
+}
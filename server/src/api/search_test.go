@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"encoding/json"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"gaego-gin/server/src/search"
+	"gaego-gin/server/src/search/searchtest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestHogeSearchAPI(t *testing.T) {
+	inst, err := aetest.NewInstance(&aetest.Options{AppID: "unittest", StronglyConsistentDatastore: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	adminHelper := NewAdminTestHelper(inst)
+	defer adminHelper.ClearEntity(t, model.Hoge{})
+
+	defer func(sinks []outbox.Sink, index search.Index) {
+		outbox.Sinks = sinks
+		search.DefaultIndex = index
+	}(outbox.Sinks, search.DefaultIndex)
+	outbox.Sinks = []outbox.Sink{&search.Sink{}}
+	search.DefaultIndex = searchtest.NewMemory()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.SetupHoge(r.Group("/api"))
+
+	request := func(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+		req, err := inst.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	searchIDs := func(t *testing.T, q string) (string, *model.HogeSearchResp) {
+		w := request(t, "GET", "/api/hoge:search?q="+url.QueryEscape(q), "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		resp := &model.HogeSearchResp{}
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		ids := make([]string, len(resp.List))
+		for i, hoge := range resp.List {
+			ids[i] = hoge.ID
+		}
+
+		return strings.Join(ids, ","), resp
+	}
+
+	t.Run("作成したHogeが検索できること", func(t *testing.T) {
+		for _, body := range []string{
+			`{"id":"hoge1","value":"quick brown fox"}`,
+			`{"id":"hoge2","value":"lazy brown dog"}`,
+			`{"id":"hoge3","value":"quick red fox"}`,
+		} {
+			w := request(t, "POST", "/api/hoge", body)
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		}
+
+		ids, resp := searchIDs(t, "quick fox")
+		AssertEquals(t, "IDs", ids, "hoge1,hoge3")
+		AssertEquals(t, "Total", resp.Total, 2)
+
+		ids, _ = searchIDs(t, `"brown dog"`)
+		AssertEquals(t, "IDs", ids, "hoge2")
+
+		ids, _ = searchIDs(t, "id:hoge* bro*")
+		AssertEquals(t, "IDs", ids, "hoge1,hoge2")
+	})
+
+	t.Run("更新と削除が検索結果に反映されること", func(t *testing.T) {
+		w := request(t, "PUT", "/api/hoge/hoge1", `{"id":"hoge1","value":"slow green turtle"}`)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		w = request(t, "DELETE", "/api/hoge/hoge3", "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		ids, _ := searchIDs(t, "fox")
+		AssertEquals(t, "fox", ids, "")
+
+		ids, _ = searchIDs(t, "turtle")
+		AssertEquals(t, "turtle", ids, "hoge1")
+	})
+
+	t.Run("カーソルで次のページが取得でき、Linkヘッダーが設定されること", func(t *testing.T) {
+		w := request(t, "GET", "/api/hoge:search?q=id:hoge*&limit=1", "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		resp := &model.HogeSearchResp{}
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "first", resp.List[0].ID, "hoge1")
+		AssertEquals(t, "Link", strings.Contains(w.Header().Get("Link"), `rel="next"`), true)
+
+		w = request(t, "GET", "/api/hoge:search?q=id:hoge*&limit=1&cursor="+url.QueryEscape(resp.Cursor), "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		resp = &model.HogeSearchResp{}
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "second", resp.List[0].ID, "hoge2")
+		AssertEquals(t, "Cursor", resp.Cursor, "")
+	})
+
+	for _, query := range []string{"q=", "q=unknown:x", "q=%22open", "q=fox&limit=0", "q=fox&cursor=!!"} {
+		t.Run(query+" の場合、400となること", func(t *testing.T) {
+			w := request(t, "GET", "/api/hoge:search?"+query, "")
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+		})
+	}
+
+	t.Run("インデックスが設定されていない場合、501エラーとなること", func(t *testing.T) {
+		defer func(index search.Index) { search.DefaultIndex = index }(search.DefaultIndex)
+		search.DefaultIndex = nil
+
+		w := request(t, "GET", "/api/hoge:search?q=fox", "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusNotImplemented, w.Body.Bytes())
+	})
+}
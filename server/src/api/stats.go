@@ -0,0 +1,49 @@
+package api
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsAPI は集計のAPIを管理する
+type StatsAPI struct{}
+
+// SetupStats は集計のAPIのハンドリングを行う
+func SetupStats(rg *gin.RouterGroup) {
+	api := &StatsAPI{}
+
+	rg.GET("/stats", api.Get)
+}
+
+// Get はHogeの件数の集計を取得する
+// @Description cronのrecount-hoges(24時間毎)で数え直したHogeの件数を返す。countedAt以降の作成、削除は含まない。一度も数えていない場合はtotalが0、countedAtがゼロ値となる。
+// @Tags Stats
+// @Summary 集計取得
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.HogeStats
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /stats [get]
+func (api *StatsAPI) Get(c *gin.Context) {
+	g := ds.FromRequest(c.Request)
+
+	store := &model.HogeStatsStore{}
+
+	var stats *model.HogeStats
+	if err := model.Retry(g.Context(), "stats.get", func() error {
+		var err error
+		stats, err = store.Get(g)
+		return err
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,72 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStatsAPI_Get(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := dstest.NewStore()
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, func(r *http.Request) ds.Client {
+			return store.Client(r.Context())
+		})
+		c.Next()
+	})
+	api.SetupStats(r.Group("/api"))
+
+	get := func(t *testing.T) *model.HogeStats {
+		req := httptest.NewRequest("GET", "/api/stats", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		stats := &model.HogeStats{}
+		if err := json.Unmarshal(w.Body.Bytes(), stats); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return stats
+	}
+
+	t.Run("数え直していない場合、totalが0となること", func(t *testing.T) {
+		stats := get(t)
+
+		AssertEquals(t, "total", stats.Total, 0)
+		AssertEquals(t, "countedAt", stats.CountedAt.IsZero(), true)
+	})
+
+	t.Run("数え直した件数が取得できること", func(t *testing.T) {
+		g := store.Client(context.Background())
+		for i := 0; i < 3; i++ {
+			if err := g.Put(&model.Hoge{ID: fmt.Sprintf("hoge%d", i)}); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		statsStore := &model.HogeStatsStore{}
+		if _, err := statsStore.Recount(g, func(done int) error { return nil }); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		stats := get(t)
+
+		AssertEquals(t, "total", stats.Total, 3)
+		AssertEquals(t, "countedAt", stats.CountedAt.IsZero(), false)
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"gaego-gin/server/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version はAPIのバージョン
+type Version string
+
+const (
+	// V1 は最初のバージョン。バージョンを指定しない"/api"もV1として扱う
+	V1 Version = "v1"
+	// V2 はHogeの日時のフィールド名と一覧のレスポンスの形式を変更したバージョン
+	V2 Version = "v2"
+)
+
+// Versions は提供するAPIのバージョン
+var Versions = []Version{V1, V2}
+
+// SetupVersion はバージョンvのAPIのハンドリングを行う
+//
+// Hogeはバージョン毎のハンドラとレスポンスの形式を登録し、Job、集計、Webhookは全てのバージョンで共通とする
+func SetupVersion(rg *gin.RouterGroup, v Version) {
+	switch v {
+	case V2:
+		SetupHogeV2(rg)
+	default:
+		SetupHoge(rg)
+	}
+
+	SetupJob(rg)
+	SetupStats(rg)
+	SetupWebhook(rg)
+}
+
+// hogeView はAPIのバージョン毎のHogeのレスポンスの形式
+type hogeView interface {
+	// hoge は1件のHogeのレスポンスを返す
+	hoge(hoge *model.Hoge) interface{}
+	// list は一覧取得のレスポンスを返す
+	list(resp *model.HogeListResp) interface{}
+	// search は検索のレスポンスを返す
+	search(resp *model.HogeSearchResp) interface{}
+	// hogeType はfieldsを検証するHogeのレスポンスの型の値を返す
+	hogeType() interface{}
+	// listMask は一覧の各Hogeに適用するmを、ページングのフィールドを常に含む一覧のレスポンスのfieldMaskとする
+	listMask(m fieldMask) fieldMask
+}
+
+// v1View はmodelの構造体をそのまま返すV1のレスポンスの形式
+type v1View struct{}
+
+func (v1View) hoge(hoge *model.Hoge) interface{} {
+	return hoge
+}
+
+func (v1View) list(resp *model.HogeListResp) interface{} {
+	return resp
+}
+
+func (v1View) search(resp *model.HogeSearchResp) interface{} {
+	return resp
+}
+
+func (v1View) hogeType() interface{} {
+	return model.Hoge{}
+}
+
+func (v1View) listMask(m fieldMask) fieldMask {
+	return fieldMask{"list": m, "cursor": nil, "prevCursor": nil, "total": nil, "totalCapped": nil}
+}
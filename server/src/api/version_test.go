@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"encoding/json"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/hogepb"
+	"gaego-gin/server/src/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHogeAPI_Version(t *testing.T) {
+	factory := dstest.NewStore().Factory()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, factory)
+		c.Next()
+	})
+	for _, v := range api.Versions {
+		api.SetupVersion(r.Group("/api/"+string(v)), v)
+	}
+	handler := middleware.CustomMethods(r)
+
+	request := func(t *testing.T, method, path, body, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		return w
+	}
+
+	object := func(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal(w.Body.Bytes(), &obj); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return obj
+	}
+
+	t.Run("V2で作成したHogeをV2の形式で返すこと", func(t *testing.T) {
+		for _, id := range []string{"hoge1", "hoge2"} {
+			obj := object(t, request(t, "POST", "/api/v2/hoge", `{"id":"`+id+`","value":"value-`+id+`"}`, ""))
+
+			AssertEquals(t, "id", obj["id"], id)
+			AssertEquals(t, "createTime", obj["createTime"] != nil, true)
+			AssertEquals(t, "updateTime", obj["updateTime"] != nil, true)
+			AssertEquals(t, "createdAt", obj["createdAt"], nil)
+		}
+	})
+
+	t.Run("同じHogeをV1とV2の形式で取得できること", func(t *testing.T) {
+		v1 := object(t, request(t, "GET", "/api/v1/hoge/hoge1", "", ""))
+		v2 := object(t, request(t, "GET", "/api/v2/hoge/hoge1", "", ""))
+
+		AssertEquals(t, "len(v1)", len(v1), 4)
+		AssertEquals(t, "len(v2)", len(v2), 4)
+		AssertEquals(t, "value", v2["value"], v1["value"])
+		AssertEquals(t, "createTime", v2["createTime"], v1["createdAt"])
+		AssertEquals(t, "updateTime", v2["updateTime"], v1["updatedAt"])
+	})
+
+	t.Run("V2の一覧取得はitemsとnextPageTokenを返すこと", func(t *testing.T) {
+		w := request(t, "GET", "/api/v2/hoge?limit=1", "", "")
+		obj := object(t, w)
+
+		items := obj["items"].([]interface{})
+		AssertEquals(t, "len(items)", len(items), 1)
+		AssertEquals(t, "items[0].id", items[0].(map[string]interface{})["id"], "hoge1")
+		AssertEquals(t, "nextPageToken", obj["nextPageToken"] != "", true)
+		AssertEquals(t, "prevPageToken", obj["prevPageToken"], nil)
+		AssertEquals(t, "list", obj["list"], nil)
+		AssertEquals(t, "Link", strings.Contains(w.Header().Get("Link"), `rel="next"`), true)
+	})
+
+	t.Run("V2のfieldsはV2のフィールド名で指定すること", func(t *testing.T) {
+		obj := object(t, request(t, "GET", "/api/v2/hoge?limit=1&fields=id,createTime", "", ""))
+
+		item := obj["items"].([]interface{})[0].(map[string]interface{})
+		AssertEquals(t, "len(item)", len(item), 2)
+		AssertEquals(t, "createTime", item["createTime"] != nil, true)
+		AssertEquals(t, "nextPageToken", obj["nextPageToken"] != nil, true)
+
+		w := request(t, "GET", "/api/v2/hoge/hoge1?fields=createdAt", "", "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+	})
+
+	t.Run("V2でもProtocol Buffersは同じメッセージで返すこと", func(t *testing.T) {
+		v2 := object(t, request(t, "GET", "/api/v2/hoge/hoge2", "", ""))
+
+		w := request(t, "GET", "/api/v2/hoge/hoge2", "", "application/x-protobuf")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		x := &hogepb.Hoge{}
+		if err := proto.Unmarshal(w.Body.Bytes(), x); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		hoge := x.Model()
+		AssertEquals(t, "id", hoge.ID, "hoge2")
+		AssertEquals(t, "value", hoge.Value, "value-hoge2")
+
+		b, err := json.Marshal(hoge.CreatedAt)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "createdAt", strings.Trim(string(b), `"`), v2["createTime"])
+	})
+
+	t.Run("V2で更新したHogeをV2の形式で返すこと", func(t *testing.T) {
+		obj := object(t, request(t, "PUT", "/api/v2/hoge/hoge2", `{"id":"hoge2","value":"updated"}`, ""))
+
+		AssertEquals(t, "value", obj["value"], "updated")
+		AssertEquals(t, "updateTime", obj["updateTime"] != nil, true)
+		AssertEquals(t, "updatedAt", obj["updatedAt"], nil)
+	})
+}
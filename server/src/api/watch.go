@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// WatchPollInterval は変更履歴を確認する間隔
+	WatchPollInterval = time.Second
+	// WatchHeartbeatInterval はプロキシに切断されないようにコメントを送信する間隔
+	WatchHeartbeatInterval = 15 * time.Second
+)
+
+// watchRetry は再接続までの待機時間としてクライアントに通知するミリ秒
+const watchRetry = 3000
+
+// watchReset は指定した位置の変更履歴が削除されているため、現在の位置から送信することを表すイベントの種類
+const watchReset = "reset"
+
+// Watch はHogeの変更をServer-Sent Eventsで逐次送信する
+// @Description Hogeの変更をtext/event-streamで逐次送信する。idは変更履歴の位置、eventは変更の種類(hoge.created, hoge.updated, hoge.deleted)、dataはWebhookと同じイベントのJSONとなる。
+// @Description Last-Event-IDヘッダー、またはlastEventIdパラメータを指定した場合は、その位置より後の変更から送信する。指定しない場合は接続した後の変更のみ送信する。
+// @Description 変更履歴は24時間保持する。指定した位置の変更履歴が削除されている場合は、event: resetで現在の位置を送信し、その後の変更から送信する。クライアントは一覧を取得し直す。
+// @Description インスタンス間の時刻のずれを考慮し、変更は5秒程度遅れて送信する。接続を維持するため、15秒毎にコメントを送信する。
+// @Description 第1世代のApp Engineはレスポンスをバッファするため、スタンドアロンでのみ提供する。
+// @Tags Hoge
+// @Summary Hoge 変更の監視
+// @Produce  text/event-stream
+// @Param  Last-Event-ID header string false "position of the last received event"
+// @Param  lastEventId query string false "same as Last-Event-ID, for clients that cannot set headers"
+// @Param  prefix query string false "only send changes of Hoge whose id starts with this prefix"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /hoge:watch [get]
+func (api *HogeAPI) Watch(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+
+	prefix := c.Query("prefix")
+
+	g := ds.FromRequest(c.Request)
+
+	store := &model.HogeChangeStore{}
+
+	last, reset := lastEventID, false
+	if last != "" {
+		var err error
+		if reset, err = store.Expired(last); err != nil {
+			c.String(http.StatusBadRequest, "Last-Event-ID must be an id of a received event")
+			return
+		}
+	}
+	if last == "" || reset {
+		last = store.Latest()
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// nginxなどのプロキシにバッファさせない
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", watchRetry)
+	if reset {
+		// 指定した位置からの変更履歴は削除されている可能性があるため、現在の位置から送信することを通知する
+		fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: {}\n\n", last, watchReset)
+	}
+	c.Writer.Flush()
+
+	poll := time.NewTicker(WatchPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(WatchHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	done := c.Request.Context().Done()
+
+	for {
+		var changes []*model.HogeChange
+		if err := model.Retry(g.Context(), "hoge.watch", func() error {
+			var err error
+			changes, err = store.List(g, last)
+			return err
+
+		}); err != nil {
+			// 送信を始めた後はステータスコードを変更できないため、切断してLast-Event-IDによる再接続に任せる
+			log.Warningf(c.Request.Context(), "watch aborted: %v", err)
+			return
+		}
+
+		for _, change := range changes {
+			last = change.Position
+
+			if !strings.HasPrefix(change.HogeID, prefix) {
+				continue
+			}
+
+			fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", change.Position, change.Type, change.Event)
+		}
+
+		if len(changes) > 0 {
+			c.Writer.Flush()
+		}
+
+		// 取得の上限に達した場合は続きをすぐに取得する
+		if len(changes) >= model.MaxHogeChanges {
+			select {
+			case <-done:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case <-poll.C:
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !appengine
+// +build !appengine
+
+package api
+
+import (
+	"gaego-gin/server/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupWatch はHogeの変更の監視のAPIのハンドリングを行う
+func setupWatch(rg *gin.RouterGroup, api *HogeAPI) {
+	rg.GET(middleware.CustomMethodPath("/hoge", "watch"), middleware.Streaming(), api.Watch)
+}
@@ -0,0 +1,15 @@
+//go:build appengine
+// +build appengine
+
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// 第1世代のApp Engineはレスポンスをバッファし、ハンドラが終了するまで送信しないため、
+// Server-Sent Eventsで逐次送信するGET /hoge:watchは登録しない
+
+// setupWatch は何もしない
+func setupWatch(rg *gin.RouterGroup, api *HogeAPI) {
+}
@@ -0,0 +1,138 @@
+package api_test
+
+import (
+	"context"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHogeAPI_Watch(t *testing.T) {
+	defer func(poll, heartbeat, lag time.Duration) {
+		api.WatchPollInterval = poll
+		api.WatchHeartbeatInterval = heartbeat
+		model.HogeChangeReadLag = lag
+	}(api.WatchPollInterval, api.WatchHeartbeatInterval, model.HogeChangeReadLag)
+	api.WatchPollInterval = 10 * time.Millisecond
+	api.WatchHeartbeatInterval = 50 * time.Millisecond
+	model.HogeChangeReadLag = 0
+
+	gin.SetMode(gin.TestMode)
+
+	g := dstest.NewClient()
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, g.Store().Factory())
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+	handler := middleware.CustomMethods(r)
+
+	appendChange := func(t *testing.T, typ model.EventType, id string) string {
+		event, err := model.NewHogeEvent(typ, &model.Hoge{ID: id, Value: "hogehoge"})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		store := &model.HogeChangeStore{}
+		change, err := store.Append(g, event)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return change.Position
+	}
+
+	// watch はdの間接続し、受信した内容を返す
+	watch := func(query, lastEventID string, d time.Duration) *httptest.ResponseRecorder {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+
+		req := httptest.NewRequest("GET", "/api/hoge:watch"+query, nil).WithContext(ctx)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		return w
+	}
+
+	p1 := appendChange(t, model.HogeCreated, "hoge-1")
+	p2 := appendChange(t, model.HogeCreated, "fuga")
+	p3 := appendChange(t, model.HogeUpdated, "hoge-1")
+
+	t.Run("Last-Event-IDより後の変更が送信されること", func(t *testing.T) {
+		w := watch("", p1, 30*time.Millisecond)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+		AssertEquals(t, "Content-Type", w.Header().Get("Content-Type"), "text/event-stream")
+
+		body := w.Body.String()
+		AssertEquals(t, "p1", strings.Contains(body, "id: "+p1+"\n"), false)
+		AssertEquals(t, "p2", strings.Contains(body, "id: "+p2+"\nevent: hoge.created\ndata: {"), true)
+		AssertEquals(t, "p3", strings.Contains(body, "id: "+p3+"\nevent: hoge.updated\ndata: {"), true)
+		AssertEquals(t, "value", strings.Contains(body, `"value":"hogehoge"`), true)
+		AssertEquals(t, "order", strings.Index(body, "id: "+p2+"\n") < strings.Index(body, "id: "+p3+"\n"), true)
+	})
+
+	t.Run("prefixを指定した場合、IDが一致する変更のみ送信されること", func(t *testing.T) {
+		// p1と同じ時刻の全ての変更より前の位置
+		w := watch("?prefix=hoge-", p1[:strings.Index(p1, "-")+1], 30*time.Millisecond)
+
+		body := w.Body.String()
+		AssertEquals(t, "p1", strings.Contains(body, "id: "+p1+"\n"), true)
+		AssertEquals(t, "p2", strings.Contains(body, "id: "+p2+"\n"), false)
+		AssertEquals(t, "p3", strings.Contains(body, "id: "+p3+"\n"), true)
+	})
+
+	t.Run("Last-Event-IDを指定しない場合、接続後の変更のみ送信されること", func(t *testing.T) {
+		done := make(chan string)
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			done <- appendChange(t, model.HogeDeleted, "hoge-1")
+		}()
+
+		w := watch("", "", 100*time.Millisecond)
+		p4 := <-done
+
+		body := w.Body.String()
+		AssertEquals(t, "p3", strings.Contains(body, "id: "+p3+"\n"), false)
+		AssertEquals(t, "p4", strings.Contains(body, "id: "+p4+"\nevent: hoge.deleted\n"), true)
+	})
+
+	t.Run("Last-Event-IDの変更履歴が削除されている場合、resetで現在の位置が送信されること", func(t *testing.T) {
+		w := watch("", "00000000000000000001-expired", 30*time.Millisecond)
+
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		body := w.Body.String()
+		AssertEquals(t, "reset", strings.Contains(body, "\nevent: reset\ndata: {}\n\n"), true)
+		AssertEquals(t, "p3", strings.Contains(body, "id: "+p3+"\n"), false)
+	})
+
+	t.Run("一定時間毎にheartbeatのコメントが送信されること", func(t *testing.T) {
+		w := watch("", "", 120*time.Millisecond)
+
+		AssertEquals(t, "heartbeat", strings.Contains(w.Body.String(), ": heartbeat\n\n"), true)
+	})
+
+	t.Run("Last-Event-IDが不正な場合、400エラーとなること", func(t *testing.T) {
+		for _, v := range []string{"abc", "1"} {
+			w := watch("", v, time.Second)
+
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+		}
+	})
+}
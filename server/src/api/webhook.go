@@ -0,0 +1,228 @@
+package api
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookAPI はWebhookのAPIを管理する
+type WebhookAPI struct{}
+
+// SetupWebhook はWebhookのAPIのハンドリングを行う
+func SetupWebhook(rg *gin.RouterGroup) {
+	api := &WebhookAPI{}
+
+	rg.GET("/webhooks", api.List)
+	rg.GET("/webhooks/:id", api.Get)
+	rg.GET("/webhooks/:id/dead-letters", api.ListDeadLetters)
+	rg.POST("/webhooks", api.Create)
+	rg.PUT("/webhooks/:id", api.Update)
+	rg.DELETE("/webhooks/:id", api.Delete)
+}
+
+// List はWebhookの一覧を取得する
+// @Description Webhookの一覧を取得する。secretは含めない
+// @Tags Webhook
+// @Summary Webhook 一覧取得
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} model.Webhook
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /webhooks [get]
+func (api *WebhookAPI) List(c *gin.Context) {
+	g := ds.FromRequest(c.Request)
+
+	store := &model.WebhookStore{}
+
+	var list []*model.Webhook
+	if err := model.Retry(g.Context(), "webhook.list", func() error {
+		var err error
+		list, err = store.List(g)
+		return err
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	for _, w := range list {
+		w.Secret = ""
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// Get はWebhookを1件取得する
+// @Description Webhookを1件取得する。secretは含めない
+// @Tags Webhook
+// @Summary Webhook 1件取得
+// @Accept  json
+// @Produce  json
+// @Param  id path string true "Webhook.ID"
+// @Success 200 {object} model.Webhook
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /webhooks/{id} [get]
+func (api *WebhookAPI) Get(c *gin.Context) {
+	g := ds.FromRequest(c.Request)
+
+	store := &model.WebhookStore{}
+
+	var w *model.Webhook
+	if err := model.Retry(g.Context(), "webhook.get", func() error {
+		var err error
+		w, err = store.Get(g, c.Param("id"))
+		return err
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	w.Secret = ""
+
+	c.JSON(http.StatusOK, w)
+}
+
+// ListDeadLetters はWebhookに通知できなかったイベントを取得する
+// @Description リトライの上限に達しても通知できなかったイベントを新しい順に100件まで取得する
+// @Tags Webhook
+// @Summary Webhook 未配信イベント取得
+// @Accept  json
+// @Produce  json
+// @Param  id path string true "Webhook.ID"
+// @Success 200 {array} model.WebhookDeadLetter
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /webhooks/{id}/dead-letters [get]
+func (api *WebhookAPI) ListDeadLetters(c *gin.Context) {
+	g := ds.FromRequest(c.Request)
+
+	store := &model.WebhookStore{}
+
+	var list []*model.WebhookDeadLetter
+	if err := model.Retry(g.Context(), "webhook.deadletters", func() error {
+		var err error
+		list, err = store.ListDeadLetters(g, c.Param("id"))
+		return err
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// Create はWebhookを新規作成する
+// @Description Webhookを新規作成する。secretを省略した場合は生成し、レスポンスに含める
+// @Description 通知はX-Hoge-Signatureヘッダーにsecretを鍵としたリクエストボディのHMAC-SHA256を"sha256=<hex>"の形式で含める
+// @Description urlはhttpsとし、ループバックや内部ネットワークのアドレスは指定できない
+// @Tags Webhook
+// @Summary Webhook 新規作成
+// @Accept  json
+// @Produce  json
+// @Param  webhook body model.Webhook true "新規作成するWebhook"
+// @Success 200 {object} model.Webhook
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /webhooks [post]
+func (api *WebhookAPI) Create(c *gin.Context) {
+	w := &model.Webhook{}
+	if err := decode(c, mimeJSON, func() error { return c.BindJSON(w) }); err != nil {
+		return
+	}
+
+	g := ds.FromRequest(c.Request)
+
+	store := &model.WebhookStore{}
+
+	if err := store.Create(g, w); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, w)
+}
+
+// Update はWebhookを更新する
+// @Description Webhookを更新する。secretを省略した場合は既存の値を引き継ぎ、レスポンスに含めない
+// @Tags Webhook
+// @Summary Webhook 更新
+// @Accept  json
+// @Produce  json
+// @Param  id path string true "Webhook.ID"
+// @Param  webhook body model.Webhook true "更新するWebhook"
+// @Success 200 {object} model.Webhook
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /webhooks/{id} [put]
+func (api *WebhookAPI) Update(c *gin.Context) {
+	w := &model.Webhook{}
+	if err := decode(c, mimeJSON, func() error { return c.BindJSON(w) }); err != nil {
+		return
+	}
+
+	w.ID = c.Param("id")
+	rotated := w.Secret != ""
+
+	g := ds.FromRequest(c.Request)
+
+	store := &model.WebhookStore{}
+
+	if err := model.RunInTransaction(g, "webhook.update", func(tg ds.Client) error {
+		return store.Update(tg, w)
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if !rotated {
+		w.Secret = ""
+	}
+
+	c.JSON(http.StatusOK, w)
+}
+
+// Delete はWebhookを削除する
+// @Description Webhookを削除する。配信待ちのイベントは送信しない
+// @Tags Webhook
+// @Summary Webhook 削除
+// @Accept  json
+// @Produce  json
+// @Param  id path string true "Webhook.ID"
+// @Success 200 {null} null
+// @Failure 500 {string} string
+// @Failure 503 {string} string
+// @Failure 504 {string} string
+// @Router /webhooks/{id} [delete]
+func (api *WebhookAPI) Delete(c *gin.Context) {
+	g := ds.FromRequest(c.Request)
+
+	store := &model.WebhookStore{}
+
+	if err := model.Retry(g.Context(), "webhook.delete", func() error {
+		return store.Delete(g, c.Param("id"))
+
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, nil)
+}
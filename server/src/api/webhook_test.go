@@ -0,0 +1,171 @@
+package api_test
+
+import (
+	"encoding/json"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"gaego-gin/server/src/webhook"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mjibson/goon"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestWebhookAPI(t *testing.T) {
+	inst, err := aetest.NewInstance(&aetest.Options{AppID: "unittest", StronglyConsistentDatastore: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	adminHelper := NewAdminTestHelper(inst)
+	defer adminHelper.ClearEntity(t, model.Webhook{})
+
+	defer func(sinks []outbox.Sink) { outbox.Sinks = sinks }(outbox.Sinks)
+	outbox.Sinks = []outbox.Sink{&webhook.Sink{}}
+
+	// 受信側のhttptest.Serverはループバックのアドレスのhttpで待ち受ける
+	defer func(v bool) { model.AllowInsecureWebhooks = v }(model.AllowInsecureWebhooks)
+	model.AllowInsecureWebhooks = true
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.SetupHoge(r.Group("/api"))
+	api.SetupWebhook(r.Group("/api"))
+
+	request := func(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+		req, err := inst.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	bind := func(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		received []*http.Request
+		bodies   [][]byte
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := ioutil.ReadAll(req.Body)
+		received = append(received, req)
+		bodies = append(bodies, body)
+	}))
+	defer receiver.Close()
+
+	created := &model.Webhook{}
+
+	t.Run("Webhookが作成でき、secretが返ること", func(t *testing.T) {
+		w := request(t, "POST", "/api/webhooks", `{"url":"`+receiver.URL+`","events":["hoge.created"]}`)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		bind(t, w, created)
+		AssertEquals(t, "ID", created.ID != "", true)
+		AssertEquals(t, "Secret", created.Secret != "", true)
+	})
+
+	t.Run("URLが不正な場合、400エラーとなること", func(t *testing.T) {
+		w := request(t, "POST", "/api/webhooks", `{"url":"example.com"}`)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+
+		w = request(t, "POST", "/api/webhooks", `{"url":"https://example.com","events":["hoge.moved"]}`)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+	})
+
+	t.Run("AllowInsecureWebhooksでない場合、httpと内部ネットワークのURLは400エラーとなること", func(t *testing.T) {
+		model.AllowInsecureWebhooks = false
+		defer func() { model.AllowInsecureWebhooks = true }()
+
+		for _, u := range []string{"http://example.com", "https://localhost", "https://10.0.0.1", "https://169.254.169.254"} {
+			w := request(t, "POST", "/api/webhooks", `{"url":"`+u+`"}`)
+			AssertHTTPStatusCodeEquals(t, w.Code, http.StatusBadRequest, w.Body.Bytes())
+		}
+	})
+
+	t.Run("取得したWebhookにsecretが含まれないこと", func(t *testing.T) {
+		w := request(t, "GET", "/api/webhooks/"+created.ID, "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		v := &model.Webhook{}
+		bind(t, w, v)
+		AssertEquals(t, "URL", v.URL, receiver.URL)
+		AssertEquals(t, "Secret", v.Secret, "")
+
+		w = request(t, "GET", "/api/webhooks", "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		list := []*model.Webhook{}
+		bind(t, w, &list)
+		AssertEquals(t, "len(list)", len(list), 1)
+		AssertEquals(t, "list[0].Secret", list[0].Secret, "")
+	})
+
+	t.Run("Hogeを作成した場合、署名したイベントが通知されること", func(t *testing.T) {
+		defer adminHelper.ClearEntity(t, model.Hoge{})
+
+		w := request(t, "POST", "/api/hoge", `{"id":"hoge","value":"hogehoge"}`)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		w = request(t, "DELETE", "/api/hoge/hoge", "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		AssertEquals(t, "len(received)", len(received), 1)
+		AssertEquals(t, "Event", received[0].Header.Get(webhook.EventHeader), string(model.HogeCreated))
+		AssertEquals(t, "Verify", webhook.Verify(created.Secret, bodies[0], received[0].Header.Get(webhook.SignatureHeader)), true)
+
+		event := &model.HogeEvent{}
+		if err := json.Unmarshal(bodies[0], event); err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "event.Hoge.Value", event.Hoge.Value, "hogehoge")
+	})
+
+	t.Run("secretを省略して更新した場合、既存のsecretが引き継がれること", func(t *testing.T) {
+		w := request(t, "PUT", "/api/webhooks/"+created.ID, `{"url":"`+receiver.URL+`/v2"}`)
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		v := &model.Webhook{}
+		bind(t, w, v)
+		AssertEquals(t, "URL", v.URL, receiver.URL+"/v2")
+		AssertEquals(t, "Secret", v.Secret, "")
+
+		store := &model.WebhookStore{}
+		stored, err := store.Get(ds.NewGoon(goon.FromContext(adminHelper.ctx)), created.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "stored.Secret", stored.Secret, created.Secret)
+	})
+
+	t.Run("Webhookが削除できること", func(t *testing.T) {
+		w := request(t, "DELETE", "/api/webhooks/"+created.ID, "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusOK, w.Body.Bytes())
+
+		w = request(t, "GET", "/api/webhooks/"+created.ID, "")
+		AssertHTTPStatusCodeEquals(t, w.Code, http.StatusNotFound, w.Body.Bytes())
+	})
+}
@@ -0,0 +1,24 @@
+//go:build appengine
+// +build appengine
+
+package app
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/search"
+	"net/http"
+)
+
+// 第1世代のApp Engine(go1.9)ではgoapp serve/deployがinitでハンドラを登録する
+func init() {
+	cfg, err := LoadConfig(ds.AppEngine)
+	if err != nil {
+		panic(err)
+	}
+
+	cfg.Jobs = &jobs.TaskQueue{}
+	cfg.Search = &search.AppEngine{}
+
+	http.Handle("/", NewRouter(cfg))
+}
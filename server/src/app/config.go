@@ -0,0 +1,305 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"gaego-gin/server/src/search"
+	"gaego-gin/server/src/webhook"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/appengine"
+)
+
+// Config はルーターの設定
+type Config struct {
+	// Datastore はリクエスト毎のDatastoreのClientを生成する
+	Datastore ds.Factory
+	// RequestTimeout は1リクエストあたりの処理時間の上限。0の場合は上限なし
+	RequestTimeout time.Duration
+	// Debug がtrueの場合、5xxのレスポンスに元のエラーを含め、httpや内部ネットワークのWebhookを許可する
+	Debug bool
+	// Retry はトランザクションの競合や一時的なエラーのリトライ方針
+	Retry *model.RetryPolicy
+	// PageTokens は一覧取得のページトークンの暗号化に利用する。nilの場合はmodel.DefaultPageTokenCodecを利用する
+	PageTokens *model.PageTokenCodec
+	// Jobs はJobの実行を登録するQueue。nilの場合はDatastoreのClientでプロセス内で実行する
+	Jobs jobs.Queue
+	// OutboxSinks はHogeのドメインイベントの配信先
+	OutboxSinks []outbox.Sink
+	// Search はHogeの全文検索のインデックス。nilの場合は全文検索を提供せず、/hoge:searchは501を返す
+	Search search.Index
+	// Deprecations はAPIのバージョン毎のルートの廃止予定。値のキーはmiddleware.Deprecationsのrulesと同じ形式とする
+	Deprecations map[api.Version]map[string]middleware.Deprecation
+	// MetricsToken は"/metrics"のBearerトークン。空の場合、App Engine以外では開発用のみ"/metrics"を登録する
+	MetricsToken string
+	// CronToken は第1世代のApp Engine以外での"/cron"のBearerトークン。空の場合、App Engine以外では"/cron"を登録しない
+	CronToken string
+	// CompressMinSize は圧縮するレスポンスの最小サイズ。0の場合はmiddleware.DefaultCompressMinSize、負の場合は圧縮しない
+	CompressMinSize int
+}
+
+// LoadConfig は環境変数から設定を読み込む
+//
+//	REQUEST_TIMEOUT: 1リクエストあたりの処理時間の上限(デフォルト: 30s)
+//	COMPRESS_MIN_SIZE: 圧縮するレスポンスの最小バイト数。負の場合は圧縮しない(デフォルト: 1024)
+//	API_V1_DEPRECATED_AT, API_V2_DEPRECATED_AT: バージョンの全てのルートを廃止予定とした日時(RFC 3339)
+//	API_V1_SUNSET, API_V2_SUNSET: バージョンの提供を終了する日時(RFC 3339)
+//	API_V1_DEPRECATION_LINK, API_V2_DEPRECATION_LINK: バージョンの廃止の詳細を説明するURL
+//	API_V1_DEPRECATIONS, API_V2_DEPRECATIONS: ルート毎の廃止予定。middleware.Deprecationsのrulesと同じキーに、deprecatedAt, sunset, linkを指定したJSONのオブジェクト
+//	LOG_LEVEL: パッケージ毎のログの重要度。"info,api=debug"の形式で指定する(デフォルト: info)
+//	DEBUG: デバッグモードを有効にするか(デフォルト: goapp serveなど開発用サーバーの場合のみtrue)
+//	RETRY_MAX_ATTEMPTS: 最初の試行を含む最大の試行回数(デフォルト: 5)
+//	RETRY_INITIAL_BACKOFF: 1回目のリトライまでの待機時間の上限(デフォルト: 50ms)
+//	RETRY_MAX_BACKOFF: リトライまでの待機時間の上限(デフォルト: 2s)
+//	PAGE_TOKEN_SECRET: ページトークンの暗号化に利用する鍵。複数インスタンスで共通の値を指定する。DEBUGが無効な場合は必須(デフォルト: プロセス毎にランダム)
+//	PAGE_TOKEN_TTL: ページトークンの有効期限。0の場合は無期限(デフォルト: 24h)
+//	OUTBOX_SINKS: ドメインイベントの配信先。webhook, search, log, http, pubsubをカンマ区切りで指定する(デフォルト: webhook,search)
+//	OUTBOX_HTTP_URL: httpの配信先のURL
+//	PUBSUB_EMULATOR_HOST: pubsubの配信先とするPub/Subエミュレーターの"host:port"
+//	PUBSUB_PROJECT_ID: pubsubの配信先のプロジェクトID(デフォルト: GOOGLE_CLOUD_PROJECT)
+//	OUTBOX_PUBSUB_TOPIC: pubsubの配信先のトピック(デフォルト: hoge-events)
+//	METRICS_TOKEN: "/metrics"のBearerトークン。App Engine以外で指定しない場合、DEBUGが無効なら"/metrics"を登録しない
+//	CRON_TOKEN: "/cron"のBearerトークン。App Engine以外で指定しない場合は"/cron"を登録しない
+func LoadConfig(factory ds.Factory) (*Config, error) {
+	retry := *model.DefaultRetryPolicy
+
+	cfg := &Config{
+		Datastore:      factory,
+		RequestTimeout: 30 * time.Second,
+		Debug:          appengine.IsDevAppServer(),
+		Retry:          &retry,
+	}
+
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.RequestTimeout = d
+	}
+
+	if v := os.Getenv("COMPRESS_MIN_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.CompressMinSize = n
+	}
+
+	var err error
+	if cfg.Deprecations, err = loadDeprecations(); err != nil {
+		return nil, err
+	}
+
+	if v := os.Getenv("DEBUG"); v != "" {
+		debug, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Debug = debug
+	}
+
+	if v := os.Getenv("RETRY_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Retry.MaxAttempts = n
+	}
+
+	if v := os.Getenv("RETRY_INITIAL_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Retry.InitialBackoff = d
+	}
+
+	if v := os.Getenv("RETRY_MAX_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Retry.MaxBackoff = d
+	}
+
+	ttl := 24 * time.Hour
+	if v := os.Getenv("PAGE_TOKEN_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+
+		ttl = d
+	}
+
+	// プロセス毎のランダムな鍵では他のインスタンスが発行したページトークンを検証できないため、開発用以外では鍵を必須とする
+	secret := os.Getenv("PAGE_TOKEN_SECRET")
+	if secret == "" && !cfg.Debug {
+		return nil, errors.New("PAGE_TOKEN_SECRET is required unless DEBUG is enabled")
+	}
+
+	cfg.PageTokens = model.NewPageTokenCodec([]byte(secret), ttl)
+
+	cfg.MetricsToken = os.Getenv("METRICS_TOKEN")
+	cfg.CronToken = os.Getenv("CRON_TOKEN")
+
+	sinks := os.Getenv("OUTBOX_SINKS")
+	if sinks == "" {
+		sinks = "webhook,search"
+	}
+
+	if cfg.OutboxSinks, err = newOutboxSinks(sinks); err != nil {
+		return nil, err
+	}
+
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := logger.ConfigureLevels(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// deprecationRule はAPI_V1_DEPRECATIONSなどで指定するルートの廃止予定
+type deprecationRule struct {
+	DeprecatedAt time.Time `json:"deprecatedAt"`
+	Sunset       time.Time `json:"sunset"`
+	Link         string    `json:"link"`
+}
+
+// loadDeprecations は環境変数からバージョン毎の廃止予定を読み込む
+//
+// API_V1_DEPRECATED_ATなどはバージョンの全てのルートを表す"*"の廃止予定とし、API_V1_DEPRECATIONSのルート毎の廃止予定を優先する
+func loadDeprecations() (map[api.Version]map[string]middleware.Deprecation, error) {
+	deprecations := map[api.Version]map[string]middleware.Deprecation{}
+
+	for _, v := range api.Versions {
+		prefix := "API_" + strings.ToUpper(string(v)) + "_"
+
+		rules := map[string]middleware.Deprecation{}
+
+		d := middleware.Deprecation{Link: os.Getenv(prefix + "DEPRECATION_LINK")}
+		for name, t := range map[string]*time.Time{"DEPRECATED_AT": &d.At, "SUNSET": &d.Sunset} {
+			s := os.Getenv(prefix + name)
+			if s == "" {
+				continue
+			}
+
+			var err error
+			if *t, err = time.Parse(time.RFC3339, s); err != nil {
+				return nil, fmt.Errorf("%s%s: %v", prefix, name, err)
+			}
+		}
+
+		if d != (middleware.Deprecation{}) {
+			rules["*"] = d
+		}
+
+		if s := os.Getenv(prefix + "DEPRECATIONS"); s != "" {
+			var routes map[string]deprecationRule
+			if err := json.Unmarshal([]byte(s), &routes); err != nil {
+				return nil, fmt.Errorf("%sDEPRECATIONS: %v", prefix, err)
+			}
+
+			for key, r := range routes {
+				if !validDeprecationKey(key) {
+					return nil, fmt.Errorf("%sDEPRECATIONS: invalid route %q", prefix, key)
+				}
+
+				rules[key] = middleware.Deprecation{At: r.DeprecatedAt, Sunset: r.Sunset, Link: r.Link}
+			}
+		}
+
+		if len(rules) > 0 {
+			deprecations[v] = rules
+		}
+	}
+
+	return deprecations, nil
+}
+
+// validDeprecationKey はkeyが"GET /hoge/:id", "/hoge/:id", "*"のいずれかの形式であるかを返す
+func validDeprecationKey(key string) bool {
+	if key == "*" {
+		return true
+	}
+
+	if i := strings.Index(key, " "); i >= 0 {
+		method := key[:i]
+		if method == "" || strings.ToUpper(method) != method {
+			return false
+		}
+
+		key = key[i+1:]
+	}
+
+	return strings.HasPrefix(key, "/") && !strings.Contains(key, " ")
+}
+
+// newOutboxSinks はカンマ区切りの名前から配信先を生成する
+func newOutboxSinks(names string) ([]outbox.Sink, error) {
+	var sinks []outbox.Sink
+
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "webhook":
+			sinks = append(sinks, &webhook.Sink{})
+
+		case "search":
+			sinks = append(sinks, &search.Sink{})
+
+		case "log":
+			sinks = append(sinks, &outbox.LogSink{})
+
+		case "http":
+			u := os.Getenv("OUTBOX_HTTP_URL")
+			if u == "" {
+				return nil, errors.New("OUTBOX_HTTP_URL is required for the http outbox sink")
+			}
+
+			sinks = append(sinks, &outbox.HTTPSink{URL: u})
+
+		case "pubsub":
+			s := &outbox.PubSubSink{
+				Host:    os.Getenv("PUBSUB_EMULATOR_HOST"),
+				Project: os.Getenv("PUBSUB_PROJECT_ID"),
+				Topic:   os.Getenv("OUTBOX_PUBSUB_TOPIC"),
+			}
+			if s.Project == "" {
+				s.Project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+			}
+			if s.Topic == "" {
+				s.Topic = "hoge-events"
+			}
+			if s.Host == "" || s.Project == "" {
+				return nil, errors.New("PUBSUB_EMULATOR_HOST and PUBSUB_PROJECT_ID are required for the pubsub outbox sink")
+			}
+
+			sinks = append(sinks, s)
+
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+
+	return sinks, nil
+}
@@ -0,0 +1,25 @@
+//go:build !appengine
+// +build !appengine
+
+package app
+
+import (
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initCron はcronから実行するメンテナンスを"/cron"に登録する
+//
+// X-Appengine-Cronヘッダーは第1世代のApp Engine以外では外部から偽装できるため、CronTokenのBearerトークンを必須とする
+// CronTokenを指定しない場合は"/cron"を登録しない。Cloud Schedulerなどからトークンを付与して実行する
+func initCron(r *gin.Engine, cfg *Config) {
+	if cfg.CronToken == "" {
+		return
+	}
+
+	rg := r.Group("/cron")
+	rg.Use(middleware.BearerToken(cfg.CronToken))
+	api.SetupCron(rg)
+}
@@ -0,0 +1,21 @@
+//go:build appengine
+// +build appengine
+
+package app
+
+import (
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initCron はcronから実行するメンテナンスを"/cron"に登録する
+//
+// App Engineは外部からのリクエストのX-Appengine-Cronヘッダーを取り除くため、middleware.Cronで検証できる
+// App EngineのcronはAuthorizationヘッダーを付与できないため、CronTokenは利用しない
+func initCron(r *gin.Engine, cfg *Config) {
+	rg := r.Group("/cron")
+	rg.Use(middleware.Cron())
+	api.SetupCron(rg)
+}
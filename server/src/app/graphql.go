@@ -0,0 +1,19 @@
+//go:build !appengine
+// +build !appengine
+
+package app
+
+import (
+	"gaego-gin/server/src/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initGraphQL はバージョンを指定しない"/api"のグループにGraphQLのAPIを登録する
+func initGraphQL(rg *gin.RouterGroup) {
+	api.SetupGraphQL(rg)
+}
+
+func initGraphiQL(r *gin.Engine) {
+	r.GET("/graphiql", api.GraphiQL("/api/graphql"))
+}
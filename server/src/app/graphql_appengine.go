@@ -0,0 +1,14 @@
+//go:build appengine
+// +build appengine
+
+package app
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// 第1世代のApp Engine(go1.9)ではgraphql-goをビルドできないため、GraphQLのAPIとGraphiQLを登録しない
+
+func initGraphQL(rg *gin.RouterGroup) {}
+
+func initGraphiQL(r *gin.Engine) {}
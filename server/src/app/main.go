@@ -3,6 +3,16 @@ package app
 import (
 	"gaego-gin/server/src/api"
 	_ "gaego-gin/server/src/docs" // nolint
+	docsv2 "gaego-gin/server/src/docs/v2"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/metrics"
+	"gaego-gin/server/src/middleware"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"gaego-gin/server/src/search"
+	"gaego-gin/server/src/tracing"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -18,21 +28,105 @@ import (
 
 // @host localhost:8080
 // @BasePath /api
-func init() {
+
+// NewRouter はAPIとSwaggerのルーティングを設定したhttp.Handlerを返す
+//
+// "/api/hoge:export"のようなカスタムメソッドをginのルーターの前で振り分けるため、*gin.Engineではなくhttp.Handlerを返す
+// 戻り値の*gin.Engineにルートを追加していた呼び出し側は、http.ServeMuxなどで戻り値と振り分ける
+func NewRouter(cfg *Config) http.Handler {
+	api.SetDebug(cfg.Debug)
+	model.AllowInsecureWebhooks = cfg.Debug
+	if cfg.Retry != nil {
+		model.DefaultRetryPolicy = cfg.Retry
+	}
+	if cfg.PageTokens != nil {
+		model.DefaultPageTokenCodec = cfg.PageTokens
+	}
+
+	factory := cfg.InstrumentedDatastore()
+	if cfg.Jobs != nil {
+		jobs.DefaultQueue = cfg.Jobs
+	} else {
+		jobs.DefaultQueue = jobs.NewLocal(factory)
+	}
+	if cfg.OutboxSinks != nil {
+		outbox.Sinks = cfg.OutboxSinks
+	}
+	if cfg.Search != nil {
+		search.DefaultIndex = cfg.Search
+	}
+
 	r := gin.New()
+	r.Use(tracing.Middleware(r))
+	r.Use(logger.Middleware(r))
+	r.Use(metrics.Middleware(r))
+	r.Use(api.Recovery())
+	r.Use(bindDatastore(factory))
 
-	initAPI(r)
+	initAPI(r, cfg)
+	initTasks(r)
+	initCron(r, cfg)
 	initSwagger(r)
+	initGraphiQL(r)
+	initMetrics(r, cfg)
+
+	return middleware.CustomMethods(r)
+}
+
+// InstrumentedDatastore はDatastoreの操作のメトリクスとspanを記録するClientを生成するFactoryを返す
+func (cfg *Config) InstrumentedDatastore() ds.Factory {
+	return metrics.InstrumentFactory(tracing.InstrumentFactory(cfg.Datastore))
+}
 
-	http.Handle("/", r)
+func bindDatastore(factory ds.Factory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, factory)
+		c.Next()
+	}
 }
 
-func initAPI(r *gin.Engine) {
-	rg := r.Group("/api")
-	api.SetupHoge(rg)
+func initAPI(r *gin.Engine, cfg *Config) {
+	routes := middleware.NewRouteResolver(r)
+
+	// バージョンを指定しない"/api"はV1として扱う
+	rg := apiGroup(r, routes, cfg, "/api", api.V1)
+	api.SetupVersion(rg, api.V1)
+	initGraphQL(rg)
+
+	for _, v := range api.Versions {
+		api.SetupVersion(apiGroup(r, routes, cfg, "/api/"+string(v), v), v)
+	}
 }
 
+// apiGroup はバージョンvのAPIのグループを生成し、廃止予定のヘッダー、圧縮、処理時間の上限を設定する
+func apiGroup(r *gin.Engine, routes *middleware.RouteResolver, cfg *Config, path string, v api.Version) *gin.RouterGroup {
+	rg := r.Group(path)
+	rg.Use(middleware.Deprecations(routes, path, cfg.Deprecations[v]))
+	if minSize := cfg.CompressMinSize; minSize >= 0 {
+		if minSize == 0 {
+			minSize = middleware.DefaultCompressMinSize
+		}
+		rg.Use(middleware.Compress(minSize))
+	}
+	rg.Use(middleware.Timeout(cfg.RequestTimeout))
+
+	return rg
+}
+
+// initSwagger はバージョン毎のSwaggerを登録する
+//
+// "/swagger/v2/"はV2、"/swagger/v1/"と従来の"/swagger/"はV1のドキュメントを表示する
+// gin-swaggerはswagに登録したV1のドキュメントのみ返すため、V2のdoc.jsonはdocs/v2から返す
 func initSwagger(r *gin.Engine) {
+	h := ginSwagger.WrapHandler(swaggerFiles.Handler)
+
 	rg := r.Group("/swagger")
-	rg.GET("/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	rg.GET("/*any", func(c *gin.Context) {
+		if c.Param("any") == "/"+string(api.V2)+"/doc.json" {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(docsv2.ReadDoc()))
+			return
+		}
+
+		h(c)
+	})
 }
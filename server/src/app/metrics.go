@@ -0,0 +1,26 @@
+//go:build !appengine
+// +build !appengine
+
+package app
+
+import (
+	"gaego-gin/server/src/metrics"
+	"gaego-gin/server/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initMetrics はPrometheusのメトリクスを"/metrics"に登録する
+//
+// 第2世代のApp Engineとスタンドアロンはapp.yamlのlogin: adminで制限できないため、MetricsTokenのBearerトークンを必須とする
+// MetricsTokenを指定しない場合は、開発用以外では"/metrics"を登録しない
+func initMetrics(r *gin.Engine, cfg *Config) {
+	h := gin.WrapH(metrics.Handler())
+
+	switch {
+	case cfg.MetricsToken != "":
+		r.GET("/metrics", middleware.BearerToken(cfg.MetricsToken), h)
+	case cfg.Debug:
+		r.GET("/metrics", h)
+	}
+}
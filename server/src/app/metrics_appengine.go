@@ -0,0 +1,25 @@
+//go:build appengine
+// +build appengine
+
+package app
+
+import (
+	"gaego-gin/server/src/metrics"
+	"gaego-gin/server/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initMetrics はPrometheusのメトリクスを"/metrics"に登録する
+//
+// 第1世代のApp Engineではapp.yamlのlogin: adminで管理者のみに制限する。MetricsTokenを指定した場合はBearerトークンも必須とする
+func initMetrics(r *gin.Engine, cfg *Config) {
+	h := gin.WrapH(metrics.Handler())
+
+	if cfg.MetricsToken != "" {
+		r.GET("/metrics", middleware.BearerToken(cfg.MetricsToken), h)
+		return
+	}
+
+	r.GET("/metrics", h)
+}
@@ -0,0 +1,13 @@
+//go:build !appengine
+// +build !appengine
+
+package app
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// App Engine以外ではJobをjobs.Localがプロセス内で実行するため、"/tasks"を登録しない
+// X-AppEngine-QueueNameヘッダーは外部から偽装できるため、登録するとJobを任意に実行できてしまう
+
+func initTasks(r *gin.Engine) {}
@@ -0,0 +1,20 @@
+//go:build appengine
+// +build appengine
+
+package app
+
+import (
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initTasks はタスクキューから実行するJobのハンドリングを"/tasks"に登録する
+//
+// App Engineは外部からのリクエストのX-AppEngine-QueueNameヘッダーを取り除くため、middleware.TaskQueueで検証できる
+func initTasks(r *gin.Engine) {
+	rg := r.Group("/tasks")
+	rg.Use(middleware.TaskQueue())
+	api.SetupJobTasks(rg)
+}
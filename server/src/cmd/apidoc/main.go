@@ -0,0 +1,256 @@
+// Command apidoc はswagで生成したV1のSwaggerのドキュメントから、V2のドキュメントをdocs/v2に書き出す
+//
+// V2はV1と同じハンドラを利用するため、swagのコメントはV1の形式で記述し、V2で形式の異なるレスポンスのみ置き換える
+//
+//	swag init -g app/main.go && go run ./cmd/apidoc
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"gaego-gin/server/src/api"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	v1Doc = "docs/swagger/swagger.json"
+	v2Dir = "docs/v2"
+)
+
+// v2Responses はV2でレスポンスの形式が異なる操作("パス メソッド")と、置き換えるレスポンスの型
+var v2Responses = map[string]interface{}{
+	"/hoge get":        api.HogeListRespV2{},
+	"/hoge post":       api.HogeV2{},
+	"/hoge/{id} get":   api.HogeV2{},
+	"/hoge/{id} put":   api.HogeV2{},
+	"/hoge:search get": api.HogeSearchRespV2{},
+}
+
+// v1OnlyPaths はV2に登録しないパス
+var v1OnlyPaths = []string{"/graphql", "/imports/{id}"}
+
+// v2Description はV2のドキュメントの説明に追記する、V2の対象外としてV1と同じ形式とするAPI
+const v2Description = "V2で形式が異なるのはHogeの取得、一覧取得、作成、更新、検索のJSONとMessagePackのレスポンスのみとする。" +
+	"Protocol Buffersのレスポンス、エクスポート、変更の監視、インポート、Job、集計、WebhookのペイロードはV1と同じ形式とし、GraphQLは/api/graphqlのみに登録する。"
+
+var refPattern = regexp.MustCompile(`"#/definitions/([^"]+)"`)
+
+func main() {
+	b, err := ioutil.ReadFile(v1Doc)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	doc := &spec.Swagger{}
+	if err := json.Unmarshal(b, doc); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := convertV2(doc); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := write(doc); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// convertV2 はV1のドキュメントをV2に変換する
+func convertV2(doc *spec.Swagger) error {
+	doc.BasePath = "/api/" + string(api.V2)
+	doc.Info.Version = "2.0"
+	doc.Info.Description = strings.TrimSpace(doc.Info.Description + "\n\n" + v2Description)
+
+	for _, p := range v1OnlyPaths {
+		delete(doc.Paths.Paths, p)
+	}
+
+	for key, v := range v2Responses {
+		fields := strings.Fields(key)
+
+		item, ok := doc.Paths.Paths[fields[0]]
+		if !ok {
+			return fmt.Errorf("path %s is not found", fields[0])
+		}
+
+		var op *spec.Operation
+		switch fields[1] {
+		case "get":
+			op = item.Get
+		case "post":
+			op = item.Post
+		case "put":
+			op = item.Put
+		}
+		if op == nil || op.Responses == nil {
+			return fmt.Errorf("operation %s is not found", key)
+		}
+
+		res, ok := op.Responses.StatusCodeResponses[200]
+		if !ok || res.Schema == nil {
+			return fmt.Errorf("response of %s is not found", key)
+		}
+
+		t := reflect.TypeOf(v)
+		res.Schema.Ref = spec.MustCreateRef("#/definitions/" + definitionName(t))
+		op.Responses.StatusCodeResponses[200] = res
+
+		addDefinition(doc, t)
+	}
+
+	return pruneDefinitions(doc)
+}
+
+// definitionName はswagと同じく"パッケージ名.型名"の定義名を返す
+func definitionName(t reflect.Type) string {
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// addDefinition は構造体tとそのフィールドの構造体の定義を追加する
+func addDefinition(doc *spec.Swagger, t reflect.Type) {
+	name := definitionName(t)
+	if _, ok := doc.Definitions[name]; ok {
+		return
+	}
+
+	s := spec.Schema{}
+	s.Type = spec.StringOrArray{"object"}
+	s.Properties = map[string]spec.Schema{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag == "-" || f.PkgPath != "" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+
+		s.Properties[tag] = propertySchema(doc, f.Type)
+	}
+
+	doc.Definitions[name] = s
+}
+
+// propertySchema はswagと同じ形式のフィールドのスキーマを返す
+func propertySchema(doc *spec.Swagger, t reflect.Type) spec.Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	s := spec.Schema{}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		s.Type = spec.StringOrArray{"string"}
+	case t.Kind() == reflect.Struct:
+		addDefinition(doc, t)
+		s.Ref = spec.MustCreateRef("#/definitions/" + definitionName(t))
+	case t.Kind() == reflect.Slice:
+		items := propertySchema(doc, t.Elem())
+		s.Type = spec.StringOrArray{"array"}
+		s.Items = &spec.SchemaOrArray{Schema: &items}
+	case t.Kind() == reflect.String:
+		s.Type = spec.StringOrArray{"string"}
+	case t.Kind() == reflect.Bool:
+		s.Type = spec.StringOrArray{"boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s.Type = spec.StringOrArray{"integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s.Type = spec.StringOrArray{"number"}
+	default:
+		s.Type = spec.StringOrArray{"object"}
+	}
+
+	return s
+}
+
+// pruneDefinitions はパスから参照されない定義を削除する
+func pruneDefinitions(doc *spec.Swagger) error {
+	b, err := json.Marshal(doc.Paths)
+	if err != nil {
+		return err
+	}
+
+	used := map[string]bool{}
+	queue := refPattern.FindAllSubmatch(b, -1)
+	for len(queue) > 0 {
+		name := string(queue[0][1])
+		queue = queue[1:]
+
+		if used[name] {
+			continue
+		}
+		used[name] = true
+
+		b, err := json.Marshal(doc.Definitions[name])
+		if err != nil {
+			return err
+		}
+		queue = append(queue, refPattern.FindAllSubmatch(b, -1)...)
+	}
+
+	for name := range doc.Definitions {
+		if !used[name] {
+			delete(doc.Definitions, name)
+		}
+	}
+
+	return nil
+}
+
+// write はdocをv2Dirのswagger.json、swagger.yaml、docs.goに書き出す
+func write(doc *spec.Swagger) error {
+	b, err := json.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	var obj interface{}
+	if err := yaml.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	y, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(v2Dir, "swagger"), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(v2Dir, "swagger", "swagger.json"), b, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(v2Dir, "swagger", "swagger.yaml"), y, 0644); err != nil {
+		return err
+	}
+
+	src := fmt.Sprintf(`// GENERATED BY cmd/apidoc; DO NOT EDIT
+
+// Package v2 はV2のAPIのSwaggerのドキュメント
+//
+// swagはドキュメントを1つのみ登録できるため、登録せずにReadDocで参照する
+package v2
+
+var doc = %s
+
+// ReadDoc はV2のAPIのドキュメントを返す
+func ReadDoc() string {
+	return doc
+}
+`, "`"+string(b)+"`")
+
+	return ioutil.WriteFile(filepath.Join(v2Dir, "docs.go"), []byte(src), 0644)
+}
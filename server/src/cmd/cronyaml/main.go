@@ -0,0 +1,16 @@
+// Command cronyaml はapi/cron.goのメンテナンスの一覧からApp Engineのcron.yamlを標準出力に書き出す
+//
+//	go generate ./api/
+package main
+
+import (
+	"gaego-gin/server/src/api"
+	"log"
+	"os"
+)
+
+func main() {
+	if err := api.WriteCronYAML(os.Stdout, "/cron"); err != nil {
+		log.Fatal(err)
+	}
+}
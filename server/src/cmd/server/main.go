@@ -0,0 +1,163 @@
+//go:build !appengine
+// +build !appengine
+
+// Command server はApp Engine(go1.2x)、Cloud Run、ローカル環境で動作するAPIサーバー
+//
+// 環境変数
+//
+//	PORT: 待ち受けるポート番号(デフォルト: 8080)
+//	GRPC_PORT: gRPCのHogeServiceを待ち受けるポート番号。指定しない場合は待ち受けない
+//	DATASTORE_PROJECT_ID: DatastoreのプロジェクトID(未設定の場合はGOOGLE_CLOUD_PROJECTを利用する)
+//	DATASTORE_EMULATOR_HOST: 設定されている場合はDatastoreエミュレータに接続する
+//	REQUEST_TIMEOUT: 1リクエストあたりの処理時間の上限(デフォルト: 30s)
+//	SHUTDOWN_TIMEOUT: SIGTERM受信後に処理中のリクエストの完了を待つ時間(デフォルト: 10s)
+//	METRICS_TOKEN: "/metrics"のBearerトークン。指定しない場合、DEBUGが無効なら"/metrics"を登録しない
+//	CRON_TOKEN: "/cron"のBearerトークン。指定しない場合は"/cron"を登録しない
+//	OTEL_TRACES_EXPORTER: spanのエクスポーター(stdout, otlp, none。デフォルト: none)
+//	OTEL_EXPORTER_OTLP_ENDPOINT: otlpの場合の送信先
+package main
+
+import (
+	"context"
+	"gaego-gin/server/src/app"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/rpc"
+	"gaego-gin/server/src/tracing"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	ctx := context.Background()
+
+	client, err := datastore.NewClient(ctx, projectID())
+	if err != nil {
+		log.Fatalf("failed to create datastore client: %v", err)
+	}
+	defer client.Close()
+
+	cfg, err := app.LoadConfig(ds.Cloud(client))
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	timeout, err := shutdownTimeout()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	exporter, err := tracing.NewExporter(ctx, os.Getenv("OTEL_TRACES_EXPORTER"))
+	if err != nil {
+		log.Fatalf("failed to create trace exporter: %v", err)
+	}
+	shutdownTracing := tracing.Setup(exporter)
+
+	srv := &http.Server{
+		Addr:    ":" + port(),
+		Handler: app.NewRouter(cfg),
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		log.Printf("listening on %s", srv.Addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	// gRPCはginのルーターと同じDatastoreのClientで、GRPC_PORTを指定した場合のみ別のポートで待ち受ける
+	// TLSは終端しないため、信頼できるネットワークまたはTLSを終端するプロキシの背後でのみ有効にする
+	var grpcSrv *grpc.Server
+	if p := os.Getenv("GRPC_PORT"); p != "" {
+		grpcSrv = rpc.NewServer(cfg.InstrumentedDatastore(), cfg.RequestTimeout)
+
+		lis, err := net.Listen("tcp", ":"+p)
+		if err != nil {
+			log.Fatalf("failed to listen grpc: %v", err)
+		}
+
+		go func() {
+			log.Printf("listening grpc on %s", lis.Addr())
+			errCh <- grpcSrv.Serve(lis)
+		}()
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt)
+
+	select {
+	case err := <-errCh:
+		if err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+		return
+	case sig := <-sigCh:
+		log.Printf("received %s, shutting down", sig)
+	}
+
+	// 新規の接続の受け付けを停止し、処理中のリクエストの完了を待つ
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("failed to shutdown gracefully: %v", err)
+	}
+	if grpcSrv != nil {
+		shutdownGRPC(ctx, grpcSrv)
+	}
+	if err := jobs.Shutdown(ctx); err != nil {
+		log.Printf("failed to wait for running jobs: %v", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("failed to flush spans: %v", err)
+	}
+}
+
+func port() string {
+	if p := os.Getenv("PORT"); p != "" {
+		return p
+	}
+
+	return "8080"
+}
+
+// shutdownGRPC は処理中の呼び出しの完了を待ち、ctxの期限を過ぎた場合は接続を閉じる
+//
+// Watchは切断されるまで終わらないため、期限まで待つ
+func shutdownGRPC(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("failed to shutdown grpc gracefully: %v", ctx.Err())
+		s.Stop()
+	}
+}
+
+func shutdownTimeout() (time.Duration, error) {
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		return time.ParseDuration(v)
+	}
+
+	return 10 * time.Second, nil
+}
+
+func projectID() string {
+	if id := os.Getenv("DATASTORE_PROJECT_ID"); id != "" {
+		return id
+	}
+
+	return os.Getenv("GOOGLE_CLOUD_PROJECT")
+}
@@ -1,6 +1,6 @@
 // GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
 // This file was generated by swaggo/swag at
-// 2018-08-16 18:12:59.725753475 +0900 JST m=+0.059275799
+// 2026-10-19 15:42:09.228169313 +0900 JST m=+0.098305168
 
 package docs
 
@@ -22,6 +22,47 @@ var doc = `{
     "host": "localhost:8080",
     "basePath": "/api",
     "paths": {
+        "/graphql": {
+            "post": {
+                "description": "GraphQLのクエリとミューテーションを実行する。スキーマは/graphiqlのGraphiQLで参照できる。\nエラーはerrorsに含め、extensionsのstatusとcodeにHTTPのAPIと同じステータスコードとその名前を返す。\n1つのリクエストに含まれるhogeクエリは、まとめてDatastoreから取得する。\nDatastoreを参照するフィールドのコスト(hogeとミューテーションは1、hogesは取得件数、totalCountは100)の合計が200を超えた場合、超えたフィールドはstatus 400のエラーとなる。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GraphQL"
+                ],
+                "summary": "GraphQL",
+                "parameters": [
+                    {
+                        "description": "query, operationName and variables",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.GraphQLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/hoge": {
             "get": {
                 "description": "Hogeの一覧を取得する",
@@ -29,7 +70,9 @@ var doc = `{
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "tags": [
                     "Hoge"
@@ -38,15 +81,39 @@ var doc = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "start cursor",
+                        "description": "page token returned as cursor or prevCursor",
                         "name": "cursor",
                         "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "query limit",
+                        "type": "integer",
+                        "description": "page size (1-100, default 10). values above 100 are treated as 100",
                         "name": "limit",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort order (id, -id, createdAt, -createdAt, updatedAt, -updatedAt)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter by value",
+                        "name": "value",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include the total number of matching entities, capped at 1000 (totalCapped is true when capped)",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated fields of each Hoge in list to include (e.g. id,value). cursor, prevCursor, total and totalCapped are always included",
+                        "name": "fields",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -55,6 +122,12 @@ var doc = `{
                         "schema": {
                             "type": "object",
                             "$ref": "#/definitions/model.HogeListResp"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "RFC 8288 links to the next and previous pages (rel=next, rel=prev)"
+                            }
                         }
                     },
                     "400": {
@@ -63,21 +136,43 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
             "post": {
                 "description": "Hogeを新規作成する",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "tags": [
                     "Hoge"
@@ -109,11 +204,41 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
@@ -125,7 +250,9 @@ var doc = `{
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "tags": [
                     "Hoge"
@@ -138,6 +265,12 @@ var doc = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated fields to include (e.g. id,value). nested fields are selected with a/b or a(b,c)",
+                        "name": "fields",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -154,21 +287,43 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
             "put": {
                 "description": "Hogeを更新する",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "tags": [
                     "Hoge"
@@ -206,11 +361,41 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
@@ -220,7 +405,9 @@ var doc = `{
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "tags": [
                     "Hoge"
@@ -248,45 +435,1060 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.Hoge": {
-            "type": "object",
-            "properties": {
-                "createdAt": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "updatedAt": {
-                    "type": "string"
-                },
-                "value": {
-                    "type": "string"
+        },
+        "/hoge:export": {
+            "get": {
+                "description": "条件に一致するHogeを全件、NDJSONまたはCSVで逐次書き出す。\n各バッチの最後の行には再開用のcursorを含めるため、切断された場合は最後に受信したcursorを指定して再開できる。\n書き出しの結果はX-Export-Statusトレーラー(completeまたはaborted)で返す。途中でエラーとなった場合、HTTP/1.1では接続を切断する。",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/csv"
+                ],
+                "tags": [
+                    "Hoge"
+                ],
+                "summary": "Hoge 全件書き出し",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "output format (ndjson or csv). defaults to the Accept header, then ndjson",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "cursor to resume from",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort order (id, -id, createdAt, -createdAt, updatedAt, -updatedAt)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter by value",
+                        "name": "value",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
-        "model.HogeListResp": {
-            "type": "object",
-            "properties": {
-                "cursor": {
-                    "type": "string"
-                },
-                "list": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.Hoge"
+        "/hoge:import": {
+            "post": {
+                "description": "NDJSONまたはCSVで指定したHogeを一括で登録する。各行はHogeの新規作成と同じ規則で検証する。\nasync=trueの場合はJobとして実行し、202と共に状態を取得するURL(/jobs/{id})をLocationヘッダーに返す。",
+                "consumes": [
+                    "application/x-ndjson",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Hoge"
+                ],
+                "summary": "Hoge 一括登録",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "input format (ndjson or csv). defaults to the Content-Type header",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "what to do when a Hoge with the same id exists (skip, overwrite or fail). defaults to skip",
+                        "name": "conflict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "report what would change without saving",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "run the import as a background job",
+                        "name": "async",
+                        "in": "query"
                     }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.ImportReport"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.ImportReport"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/hoge:search": {
+            "get": {
+                "description": "Hoge.Valueの単語やHoge.IDでHogeを検索し、ID順に返す。\nqは空白区切りの項を全て満たすHogeを検索する。各項は単語(word)、フレーズ(\"some words\")、接頭辞(pre*)、またはフィールドを指定した項(id:hoge1, id:hoge*, value:word)とする。\nインデックスは変更のコミット後に非同期で更新するため、直後の検索結果には反映されない場合がある。\nインデックスが設定されていない環境(第1世代のApp Engine以外)では501を返す。",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "Hoge"
+                ],
+                "summary": "Hoge 検索",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "cursor returned by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size (1-100, default 10). values above 100 are treated as 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.HogeSearchResp"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "RFC 8288 link to the next page (rel=next)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/hoge:watch": {
+            "get": {
+                "description": "Hogeの変更をtext/event-streamで逐次送信する。idは変更履歴の位置、eventは変更の種類(hoge.created, hoge.updated, hoge.deleted)、dataはWebhookと同じイベントのJSONとなる。\nLast-Event-IDヘッダー、またはlastEventIdパラメータを指定した場合は、その位置より後の変更から送信する。指定しない場合は接続した後の変更のみ送信する。\n変更履歴は24時間保持する。指定した位置の変更履歴が削除されている場合は、event: resetで現在の位置を送信し、その後の変更から送信する。クライアントは一覧を取得し直す。\nインスタンス間の時刻のずれを考慮し、変更は5秒程度遅れて送信する。接続を維持するため、15秒毎にコメントを送信する。\n第1世代のApp Engineはレスポンスをバッファするため、スタンドアロンでのみ提供する。",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Hoge"
+                ],
+                "summary": "Hoge 変更の監視",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "position of the last received event",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "same as Last-Event-ID, for clients that cannot set headers",
+                        "name": "lastEventId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only send changes of Hoge whose id starts with this prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/{id}": {
+            "get": {
+                "description": "非同期で実行するインポートのJobを取得する。/jobs/{id}と同じ内容を返す。\n非同期のインポートの状態を/imports/{id}で取得していたクライアントとの互換性のために残す。新しいクライアントは202のLocationヘッダーのURLを利用する。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hoge"
+                ],
+                "summary": "Hoge 一括登録の状態取得",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job.ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "非同期で実行する処理の状態を取得する。完了した場合は結果を含める",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Job"
+                ],
+                "summary": "Job 状態取得",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job.ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "description": "cronのrecount-hoges(24時間毎)で数え直したHogeの件数を返す。countedAt以降の作成、削除は含まない。一度も数えていない場合はtotalが0、countedAtがゼロ値となる。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stats"
+                ],
+                "summary": "集計取得",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.HogeStats"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Webhookの一覧を取得する。secretは含めない",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Webhook 一覧取得",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Webhook"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Webhookを新規作成する。secretを省略した場合は生成し、レスポンスに含める\n通知はX-Hoge-Signatureヘッダーにsecretを鍵としたリクエストボディのHMAC-SHA256を\"sha256=<hex>\"の形式で含める\nurlはhttpsとし、ループバックや内部ネットワークのアドレスは指定できない",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Webhook 新規作成",
+                "parameters": [
+                    {
+                        "description": "新規作成するWebhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "description": "Webhookを1件取得する。secretは含めない",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Webhook 1件取得",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook.ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Webhookを更新する。secretを省略した場合は既存の値を引き継ぎ、レスポンスに含めない",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Webhook 更新",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook.ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "更新するWebhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Webhookを削除する。配信待ちのイベントは送信しない",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Webhook 削除",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook.ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "null"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/dead-letters": {
+            "get": {
+                "description": "リトライの上限に達しても通知できなかったイベントを新しい順に100件まで取得する",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Webhook 未配信イベント取得",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook.ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.WebhookDeadLetter"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.GraphQLRequest": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object"
+                }
+            }
+        },
+        "model.Hoge": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.HogeListResp": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Hoge"
+                    }
+                },
+                "prevCursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "totalCapped": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.HogeSearchResp": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Hoge"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.HogeStats": {
+            "type": "object",
+            "properties": {
+                "countedAt": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ImportReport": {
+            "type": "object",
+            "properties": {
+                "aborted": {
+                    "type": "boolean"
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ImportResult"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ImportResult": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Job": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object"
+                },
+                "progress": {
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Webhook": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookDeadLetter": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "object"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "statusCode": {
+                    "type": "integer"
+                },
+                "webhookId": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,307 @@
+//go:build !appengine
+// +build !appengine
+
+package ds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"cloud.google.com/go/datastore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Cloud はCloud Datastoreのclientを利用するFactoryを生成する
+//
+// DATASTORE_EMULATOR_HOSTが設定されている場合、clientはエミュレータに接続する
+func Cloud(client *datastore.Client) Factory {
+	return func(r *http.Request) Client {
+		return NewCloud(r.Context(), client)
+	}
+}
+
+// NewCloud はCloud Datastoreのclientを利用するClientを生成する
+func NewCloud(ctx context.Context, client *datastore.Client) Client {
+	return &cloudClient{ctx: ctx, client: client}
+}
+
+type cloudClient struct {
+	ctx    context.Context
+	client *datastore.Client
+	tx     *datastore.Transaction
+}
+
+func (c *cloudClient) Context() context.Context {
+	return c.ctx
+}
+
+// WithContext はctxで操作するClientを返す。トランザクション内の操作はトランザクションのcontextを利用する
+func (c *cloudClient) WithContext(ctx context.Context) Client {
+	return &cloudClient{ctx: ctx, client: c.client, tx: c.tx}
+}
+
+func (c *cloudClient) Kind(src interface{}) string {
+	return kindOf(src)
+}
+
+func (c *cloudClient) Get(dst interface{}) error {
+	key, err := keyOf(dst)
+	if err != nil {
+		return err
+	}
+
+	if c.tx != nil {
+		return cloudError(c.tx.Get(key, dst))
+	}
+
+	return cloudError(c.client.Get(c.ctx, key, dst))
+}
+
+func (c *cloudClient) GetMulti(dst interface{}) error {
+	keys, err := keysOf(dst)
+	if err != nil {
+		return err
+	}
+
+	if c.tx != nil {
+		return cloudError(c.tx.GetMulti(keys, dst))
+	}
+
+	return cloudError(c.client.GetMulti(c.ctx, keys, dst))
+}
+
+func (c *cloudClient) Put(src interface{}) error {
+	key, err := keyOf(src)
+	if err != nil {
+		return err
+	}
+
+	if c.tx != nil {
+		_, err = c.tx.Put(key, src)
+		return cloudError(err)
+	}
+
+	_, err = c.client.Put(c.ctx, key, src)
+	return cloudError(err)
+}
+
+func (c *cloudClient) PutMulti(src interface{}) error {
+	keys, err := keysOf(src)
+	if err != nil {
+		return err
+	}
+
+	if c.tx != nil {
+		_, err = c.tx.PutMulti(keys, src)
+		return cloudError(err)
+	}
+
+	_, err = c.client.PutMulti(c.ctx, keys, src)
+	return cloudError(err)
+}
+
+func (c *cloudClient) Delete(src interface{}) error {
+	key, err := keyOf(src)
+	if err != nil {
+		return err
+	}
+
+	if c.tx != nil {
+		return cloudError(c.tx.Delete(key))
+	}
+
+	return cloudError(c.client.Delete(c.ctx, key))
+}
+
+func (c *cloudClient) DeleteMulti(src interface{}) error {
+	keys, err := keysOf(src)
+	if err != nil {
+		return err
+	}
+
+	if c.tx != nil {
+		return cloudError(c.tx.DeleteMulti(keys))
+	}
+
+	return cloudError(c.client.DeleteMulti(c.ctx, keys))
+}
+
+func (c *cloudClient) Run(q *Query) Iterator {
+	dq := datastore.NewQuery(q.kind)
+	if q.keysOnly {
+		dq = dq.KeysOnly()
+	}
+	for _, f := range q.filters {
+		dq = dq.Filter(f.field+" "+f.op, f.value)
+	}
+	for _, o := range q.orders {
+		dq = dq.Order(o)
+	}
+	if q.limit != -1 {
+		dq = dq.Limit(q.limit)
+	}
+	if q.start != "" {
+		start, err := datastore.DecodeCursor(q.start)
+		if err != nil {
+			return &errIterator{err: ErrInvalidCursor}
+		}
+
+		dq = dq.Start(start)
+	}
+	if q.end != "" {
+		end, err := datastore.DecodeCursor(q.end)
+		if err != nil {
+			return &errIterator{err: ErrInvalidCursor}
+		}
+
+		dq = dq.End(end)
+	}
+	if c.tx != nil {
+		dq = dq.Transaction(c.tx)
+	}
+
+	return &cloudIterator{it: c.client.Run(c.ctx, dq)}
+}
+
+// RunInTransaction はトランザクション内でfを1回のみ実行する
+//
+// 競合した場合のリトライはmodel.RetryPolicyで行うため、クライアントライブラリではリトライしない
+func (c *cloudClient) RunInTransaction(f func(tg Client) error) error {
+	_, err := c.client.RunInTransaction(c.ctx, func(tx *datastore.Transaction) error {
+		return f(&cloudClient{ctx: c.ctx, client: c.client, tx: tx})
+	}, datastore.MaxAttempts(1))
+
+	return cloudError(err)
+}
+
+type cloudIterator struct {
+	it *datastore.Iterator
+}
+
+func (it *cloudIterator) Next(dst interface{}) (string, error) {
+	key, err := it.it.Next(dst)
+	if err != nil {
+		return "", cloudError(err)
+	}
+
+	if dst != nil {
+		if err := setID(dst, key); err != nil {
+			return "", err
+		}
+	}
+
+	return key.Name, nil
+}
+
+func (it *cloudIterator) Cursor() (string, error) {
+	cur, err := it.it.Cursor()
+	if err != nil {
+		return "", cloudError(err)
+	}
+
+	return cur.String(), nil
+}
+
+// cloudError はcloud.google.com/go/datastoreのエラーをdsのエラーに変換する
+func cloudError(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case datastore.ErrNoSuchEntity:
+		return ErrNoSuchEntity
+	case datastore.ErrConcurrentTransaction:
+		return ErrConcurrentTransaction
+	case iterator.Done:
+		return Done
+	}
+
+	if merr, ok := err.(datastore.MultiError); ok {
+		errs := make(MultiError, len(merr))
+		for i, err := range merr {
+			errs[i] = cloudError(err)
+		}
+
+		return errs
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return &TransientError{Err: err}
+	}
+
+	return err
+}
+
+/* goonと互換のあるentityのキー解決 */
+
+var errNoIDField = errors.New("ds: entity has no `goon:\"id\"` field")
+
+func structValue(src interface{}) reflect.Value {
+	v := reflect.ValueOf(src)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	return v
+}
+
+func kindOf(src interface{}) string {
+	return structValue(src).Type().Name()
+}
+
+func idField(v reflect.Value) (reflect.Value, error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("goon") == "id" {
+			return v.Field(i), nil
+		}
+	}
+
+	return reflect.Value{}, errNoIDField
+}
+
+func keyOf(src interface{}) (*datastore.Key, error) {
+	v := structValue(src)
+
+	f, err := idField(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Kind() != reflect.String || f.String() == "" {
+		return nil, errors.New("ds: id is required")
+	}
+
+	return datastore.NameKey(v.Type().Name(), f.String(), nil), nil
+}
+
+func keysOf(src interface{}) ([]*datastore.Key, error) {
+	v := reflect.Indirect(reflect.ValueOf(src))
+
+	keys := make([]*datastore.Key, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		key, err := keyOf(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		keys[i] = key
+	}
+
+	return keys, nil
+}
+
+func setID(dst interface{}, key *datastore.Key) error {
+	f, err := idField(structValue(dst))
+	if err != nil {
+		return err
+	}
+
+	f.SetString(key.Name)
+
+	return nil
+}
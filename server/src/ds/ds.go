@@ -0,0 +1,123 @@
+package ds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrNoSuchEntity はentityが存在しない場合のエラー
+	ErrNoSuchEntity = errors.New("ds: no such entity")
+	// ErrConcurrentTransaction はトランザクションが競合した場合のエラー
+	ErrConcurrentTransaction = errors.New("ds: concurrent transaction")
+	// ErrInvalidCursor はCursorの形式が不正な場合のエラー
+	ErrInvalidCursor = errors.New("ds: invalid cursor")
+	// Done はIteratorの終端を表す
+	Done = errors.New("ds: query has no more results")
+)
+
+// TransientError はリトライにより成功する可能性のある一時的なエラー
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+// IsTransient はerrが一時的なエラーか判定する
+func IsTransient(err error) bool {
+	_, ok := err.(*TransientError)
+	return ok
+}
+
+// MultiError はGetMultiなどの複数件操作におけるentity毎のエラー
+type MultiError []error
+
+func (m MultiError) Error() string {
+	for _, err := range m {
+		if err != nil {
+			return err.Error()
+		}
+	}
+
+	return "(0 errors)"
+}
+
+// Client はDatastoreの操作を抽象化する
+//
+// entityはgoonと同様に`goon:"id"`タグを付けたstring型のフィールドでIDを表す
+type Client interface {
+	// Context はClientに紐づくcontextを返す
+	Context() context.Context
+	// Kind はentityのKind名を返す
+	Kind(src interface{}) string
+	// Get はentityを1件取得する
+	Get(dst interface{}) error
+	// GetMulti は[]*Tで指定されたentityをまとめて取得する
+	GetMulti(dst interface{}) error
+	// Put はentityを保存する
+	Put(src interface{}) error
+	// PutMulti は[]*Tで指定されたentityをまとめて保存する
+	PutMulti(src interface{}) error
+	// Delete はentityを削除する
+	Delete(src interface{}) error
+	// DeleteMulti は[]*Tで指定されたentityをまとめて削除する
+	DeleteMulti(src interface{}) error
+	// Run はクエリを実行する
+	Run(q *Query) Iterator
+	// RunInTransaction はトランザクション内でfを実行する。競合した場合もリトライしない
+	RunInTransaction(f func(tg Client) error) error
+}
+
+// ContextClient はcontextを差し替えられるClient
+type ContextClient interface {
+	Client
+	// WithContext はctxで操作するClientを返す
+	WithContext(ctx context.Context) Client
+}
+
+// WithContext はctxで操作するClientを返す
+//
+// トレーシングのspanなど、操作毎のcontextをDatastoreのRPCに引き継ぐために利用する
+// contextを差し替えられないClientの場合はcをそのまま返す
+func WithContext(c Client, ctx context.Context) Client {
+	if cc, ok := c.(ContextClient); ok {
+		return cc.WithContext(ctx)
+	}
+
+	return c
+}
+
+// Iterator はクエリの実行結果を順に返す
+type Iterator interface {
+	// Next は次のentityをdstに読み込み、そのIDを返す
+	// 結果が存在しない場合はDoneを返す
+	Next(dst interface{}) (string, error)
+	// Cursor は現在位置のCursorを返す
+	Cursor() (string, error)
+}
+
+// Factory はリクエスト毎のClientを生成する
+type Factory func(r *http.Request) Client
+
+type factoryKey struct{}
+
+// WithFactory はFactoryを紐づけたリクエストを返す
+func WithFactory(r *http.Request, f Factory) *http.Request {
+	ctx := context.WithValue(r.Context(), factoryKey{}, f)
+	return r.WithContext(ctx)
+}
+
+// FromRequest はリクエストに紐づくFactoryでClientを生成する
+//
+// Clientはその時点のリクエストのcontextを引き継ぐ
+// Factoryが紐づいていない場合はApp EngineのDatastoreを利用する
+func FromRequest(r *http.Request) Client {
+	if f, ok := r.Context().Value(factoryKey{}).(Factory); ok {
+		return f(r)
+	}
+
+	return AppEngine(r)
+}
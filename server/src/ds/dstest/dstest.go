@@ -0,0 +1,618 @@
+// Package dstest はテスト用にentityをメモリに保持するds.Clientを提供する
+//
+// entityはKindとIDで保持し、クエリの条件、ソート順、件数の上限、Cursorを解釈する
+// Datastoreと同様に、noindexのプロパティは条件とソート順に一致せず、トランザクション内の読み込みはコミット前の書き込みを含まない
+// datastore:"-"のフィールドはgoonのIDを除いて保存しない
+package dstest
+
+import (
+	"context"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errNoIDField    = errors.New("dstest: no field tagged with goon:\"id\"")
+	errIDRequired   = errors.New("dstest: id is required")
+	errNestedTx     = errors.New("dstest: nested transactions are not supported")
+	errInvalidOrder = errors.New("dstest: invalid order")
+)
+
+// Store はentityをKindとIDでメモリに保持する。複数のClientで共有できる
+type Store struct {
+	mu       sync.Mutex
+	entities map[string]map[string]reflect.Value
+}
+
+// NewStore は空のStoreを生成する
+func NewStore() *Store {
+	return &Store{entities: map[string]map[string]reflect.Value{}}
+}
+
+// Client はctxでStoreを操作するClientを返す
+func (s *Store) Client(ctx context.Context) *Client {
+	return &Client{store: s, ctx: ctx}
+}
+
+// Factory はリクエストのcontextでStoreを操作するClientを生成するFactoryを返す
+func (s *Store) Factory() ds.Factory {
+	return func(r *http.Request) ds.Client {
+		return s.Client(r.Context())
+	}
+}
+
+// Len はKindのentityの件数を返す
+func (s *Store) Len(kind string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entities[kind])
+}
+
+// NewClient は新しいStoreを操作するClientを生成する
+func NewClient() *Client {
+	return NewStore().Client(context.Background())
+}
+
+// Client はStoreを操作するds.Client
+type Client struct {
+	store *Store
+	ctx   context.Context
+	tx    *transaction
+}
+
+// transaction はコミットまで保留する書き込み。値がゼロ値のreflect.Valueの場合は削除を表す
+type transaction struct {
+	writes map[string]map[string]reflect.Value
+}
+
+// Store はClientが操作するStoreを返す
+func (c *Client) Store() *Store {
+	return c.store
+}
+
+// Context はClientに紐づくcontextを返す
+func (c *Client) Context() context.Context {
+	return c.ctx
+}
+
+// WithContext はctxで同じStoreを操作するClientを返す
+func (c *Client) WithContext(ctx context.Context) ds.Client {
+	return &Client{store: c.store, ctx: ctx, tx: c.tx}
+}
+
+// Kind はentityの型名を返す
+func (c *Client) Kind(src interface{}) string {
+	return structValue(src).Type().Name()
+}
+
+// Get はentityを1件取得する
+func (c *Client) Get(dst interface{}) error {
+	v := structValue(dst)
+
+	id, err := idOf(v)
+	if err != nil {
+		return err
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	stored, ok := c.store.entities[v.Type().Name()][id]
+	if !ok {
+		return ds.ErrNoSuchEntity
+	}
+
+	v.Set(clone(stored))
+	return nil
+}
+
+// GetMulti は[]*Tで指定されたentityをまとめて取得する
+func (c *Client) GetMulti(dst interface{}) error {
+	return multi(dst, c.Get)
+}
+
+// Put はentityを保存する。トランザクション内の場合はコミットまで保留する
+func (c *Client) Put(src interface{}) error {
+	v := structValue(src)
+
+	id, err := idOf(v)
+	if err != nil {
+		return err
+	}
+
+	c.write(v.Type().Name(), id, persisted(v))
+	return nil
+}
+
+// PutMulti は[]*Tで指定されたentityをまとめて保存する
+func (c *Client) PutMulti(src interface{}) error {
+	return multi(src, c.Put)
+}
+
+// Delete はentityを削除する。存在しない場合もエラーとしない
+func (c *Client) Delete(src interface{}) error {
+	v := structValue(src)
+
+	id, err := idOf(v)
+	if err != nil {
+		return err
+	}
+
+	c.write(v.Type().Name(), id, reflect.Value{})
+	return nil
+}
+
+// DeleteMulti は[]*Tで指定されたentityをまとめて削除する
+func (c *Client) DeleteMulti(src interface{}) error {
+	return multi(src, c.Delete)
+}
+
+// write はvを保存する。vがゼロ値の場合は削除する
+func (c *Client) write(kind, id string, v reflect.Value) {
+	if c.tx != nil {
+		if c.tx.writes[kind] == nil {
+			c.tx.writes[kind] = map[string]reflect.Value{}
+		}
+		c.tx.writes[kind][id] = v
+		return
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	c.store.apply(kind, id, v)
+}
+
+func (s *Store) apply(kind, id string, v reflect.Value) {
+	if !v.IsValid() {
+		delete(s.entities[kind], id)
+		return
+	}
+
+	if s.entities[kind] == nil {
+		s.entities[kind] = map[string]reflect.Value{}
+	}
+	s.entities[kind][id] = v
+}
+
+// RunInTransaction はfを実行し、fがnilを返した場合のみ書き込みをコミットする
+func (c *Client) RunInTransaction(f func(tg ds.Client) error) error {
+	if c.tx != nil {
+		return errNestedTx
+	}
+
+	tx := &transaction{writes: map[string]map[string]reflect.Value{}}
+	if err := f(&Client{store: c.store, ctx: c.ctx, tx: tx}); err != nil {
+		return err
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	for kind, writes := range tx.writes {
+		for id, v := range writes {
+			c.store.apply(kind, id, v)
+		}
+	}
+
+	return nil
+}
+
+// Run はクエリを実行する。結果は実行した時点の内容とする
+//
+// Cursorは結果の先頭からの件数を10進数で表す。Datastoreと異なり、Cursorより前のentityを削除すると位置がずれる
+func (c *Client) Run(q *ds.Query) ds.Iterator {
+	spec := q.Spec()
+
+	start, end := 0, -1
+	if spec.Start != "" {
+		n, err := strconv.Atoi(spec.Start)
+		if err != nil || n < 0 {
+			return &iterator{err: ds.ErrInvalidCursor}
+		}
+		start = n
+	}
+	if spec.End != "" {
+		n, err := strconv.Atoi(spec.End)
+		if err != nil || n < 0 {
+			return &iterator{err: ds.ErrInvalidCursor}
+		}
+		end = n
+	}
+
+	c.store.mu.Lock()
+	var results []result
+	for id, v := range c.store.entities[spec.Kind] {
+		if match(v, spec.Filters) && indexed(v, spec.Orders) {
+			results = append(results, result{id: id, v: v})
+		}
+	}
+	c.store.mu.Unlock()
+
+	orders := spec.Orders
+	if len(orders) == 0 {
+		// Datastoreと同様に、不等号の条件のプロパティを先にソートする
+		for _, f := range spec.Filters {
+			if f.Op != "=" {
+				orders = []string{f.Field}
+				break
+			}
+		}
+	}
+
+	var sortErr error
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, o := range orders {
+			field, desc := strings.TrimPrefix(o, "-"), strings.HasPrefix(o, "-")
+			if field == "__key__" {
+				if results[i].id != results[j].id {
+					return (results[i].id < results[j].id) != desc
+				}
+				continue
+			}
+
+			a, _ := property(results[i].v, field)
+			b, _ := property(results[j].v, field)
+			n, ok := compare(a, b)
+			if !ok {
+				sortErr = errInvalidOrder
+				return false
+			}
+			if n != 0 {
+				return (n < 0) != desc
+			}
+		}
+
+		return results[i].id < results[j].id
+	})
+	if sortErr != nil {
+		return &iterator{err: sortErr}
+	}
+
+	if end >= 0 && end < len(results) {
+		results = results[:end]
+	}
+	if start > len(results) {
+		start = len(results)
+	}
+
+	it := &iterator{results: results, pos: start, keysOnly: spec.KeysOnly, limit: -1}
+	if spec.Limit >= 0 {
+		it.limit = start + spec.Limit
+	}
+
+	return it
+}
+
+type result struct {
+	id string
+	v  reflect.Value
+}
+
+// iterator はクエリの結果を順に返すds.Iterator
+type iterator struct {
+	results  []result
+	pos      int
+	limit    int
+	keysOnly bool
+	err      error
+}
+
+func (it *iterator) Next(dst interface{}) (string, error) {
+	if it.err != nil {
+		return "", it.err
+	}
+	if it.pos >= len(it.results) || (it.limit >= 0 && it.pos >= it.limit) {
+		return "", ds.Done
+	}
+
+	r := it.results[it.pos]
+	it.pos++
+
+	if dst != nil && !it.keysOnly {
+		v := structValue(dst)
+		v.Set(clone(r.v))
+		if f, err := idField(v); err == nil {
+			f.SetString(r.id)
+		}
+	}
+
+	return r.id, nil
+}
+
+func (it *iterator) Cursor() (string, error) {
+	if it.err != nil {
+		return "", it.err
+	}
+
+	return strconv.Itoa(it.pos), nil
+}
+
+// match はvが全ての条件に一致するか判定する
+func match(v reflect.Value, filters []ds.FilterSpec) bool {
+	for _, f := range filters {
+		p, ok := property(v, f.Field)
+		if !ok {
+			return false
+		}
+
+		// 複数の値を持つプロパティは、いずれかの値が一致する場合に一致とする
+		values := []reflect.Value{p}
+		if p.Kind() == reflect.Slice && p.Type().Elem().Kind() != reflect.Uint8 {
+			values = values[:0]
+			for i := 0; i < p.Len(); i++ {
+				values = append(values, p.Index(i))
+			}
+		}
+
+		matched := false
+		for _, x := range values {
+			n, ok := compare(x, reflect.ValueOf(f.Value))
+			if !ok {
+				continue
+			}
+
+			switch f.Op {
+			case "=":
+				matched = n == 0
+			case "<":
+				matched = n < 0
+			case "<=":
+				matched = n <= 0
+			case ">":
+				matched = n > 0
+			case ">=":
+				matched = n >= 0
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// indexed はvがソート順の全てのプロパティのインデックスを持つか判定する
+func indexed(v reflect.Value, orders []string) bool {
+	for _, o := range orders {
+		field := strings.TrimPrefix(o, "-")
+		if field == "__key__" {
+			continue
+		}
+
+		if _, ok := property(v, field); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// property はdatastoreタグを考慮した名前のプロパティを返す。存在しない場合とnoindexの場合はfalseを返す
+func property(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		opts := strings.Split(f.Tag.Get("datastore"), ",")
+		if opts[0] == "-" {
+			continue
+		}
+
+		fieldName := opts[0]
+		if fieldName == "" {
+			fieldName = f.Name
+		}
+		if fieldName != name {
+			continue
+		}
+
+		for _, opt := range opts[1:] {
+			if opt == "noindex" {
+				return reflect.Value{}, false
+			}
+		}
+
+		return v.Field(i), true
+	}
+
+	return reflect.Value{}, false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compare はaとbを比較し、aが小さい場合は負、等しい場合は0、大きい場合は正を返す。比較できない場合はfalseを返す
+func compare(a, b reflect.Value) (int, bool) {
+	if !a.IsValid() || !b.IsValid() {
+		return 0, false
+	}
+
+	if a.Type() == timeType || b.Type() == timeType {
+		if a.Type() != timeType || b.Type() != timeType {
+			return 0, false
+		}
+
+		x, y := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case x.Before(y):
+			return -1, true
+		case x.After(y):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		if b.Kind() != reflect.String {
+			return 0, false
+		}
+		return strings.Compare(a.String(), b.String()), true
+
+	case reflect.Bool:
+		if b.Kind() != reflect.Bool {
+			return 0, false
+		}
+		x, y := a.Bool(), b.Bool()
+		switch {
+		case x == y:
+			return 0, true
+		case !x:
+			return -1, true
+		}
+		return 1, true
+	}
+
+	x, ok := number(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := number(b)
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
+
+// multi は[]*Tの要素毎にfを実行し、いずれかが失敗した場合はds.MultiErrorを返す
+func multi(src interface{}, f func(interface{}) error) error {
+	v := reflect.Indirect(reflect.ValueOf(src))
+
+	var merr ds.MultiError
+	for i := 0; i < v.Len(); i++ {
+		if err := f(v.Index(i).Interface()); err != nil {
+			if merr == nil {
+				merr = make(ds.MultiError, v.Len())
+			}
+			merr[i] = err
+		}
+	}
+
+	if merr != nil {
+		return merr
+	}
+	return nil
+}
+
+func structValue(src interface{}) reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(src))
+}
+
+func idField(v reflect.Value) (reflect.Value, error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("goon") == "id" {
+			return v.Field(i), nil
+		}
+	}
+
+	return reflect.Value{}, errNoIDField
+}
+
+func idOf(v reflect.Value) (string, error) {
+	f, err := idField(v)
+	if err != nil {
+		return "", err
+	}
+
+	if f.Kind() != reflect.String || f.String() == "" {
+		return "", errIDRequired
+	}
+
+	return f.String(), nil
+}
+
+// persisted はvの複製のうち、datastore:"-"のフィールドをgoonのIDを除いてゼロ値にしたものを返す
+func persisted(v reflect.Value) reflect.Value {
+	x := clone(v)
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("datastore") != "-" || f.Tag.Get("goon") == "id" {
+			continue
+		}
+
+		x.Field(i).Set(reflect.Zero(f.Type))
+	}
+
+	return x
+}
+
+// clone はvの複製を返す。スライスとマップは保存した値と共有しないように複製する
+func clone(v reflect.Value) reflect.Value {
+	x := reflect.New(v.Type()).Elem()
+	x.Set(v)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" && x.Field(i).CanSet() {
+				x.Field(i).Set(clone(v.Field(i)))
+			}
+		}
+
+	case reflect.Slice:
+		if v.IsNil() {
+			break
+		}
+		x.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			x.Index(i).Set(clone(v.Index(i)))
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			break
+		}
+		x.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		for _, k := range v.MapKeys() {
+			x.SetMapIndex(k, clone(v.MapIndex(k)))
+		}
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			break
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(clone(v.Elem()))
+		x.Set(p)
+	}
+
+	return x
+}
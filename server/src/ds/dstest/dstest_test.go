@@ -0,0 +1,169 @@
+package dstest_test
+
+import (
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"strings"
+	"testing"
+)
+
+type entity struct {
+	ID    string `datastore:"-" goon:"id"`
+	Value int
+	Tags  []string
+	Note  string `datastore:",noindex"`
+	Cache string `datastore:"-"`
+}
+
+func newClient(t *testing.T) *dstest.Client {
+	g := dstest.NewClient()
+	for _, e := range []*entity{
+		{ID: "e1", Value: 3, Tags: []string{"a"}, Note: "x"},
+		{ID: "e2", Value: 1, Tags: []string{"a", "b"}, Note: "x"},
+		{ID: "e3", Value: 2, Tags: []string{"b"}, Note: "x"},
+		{ID: "e4", Value: 2},
+	} {
+		if err := g.Put(e); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	return g
+}
+
+// ids はクエリの結果のIDをカンマ区切りで返す
+func ids(t *testing.T, g ds.Client, q *ds.Query) string {
+	it := g.Run(q)
+
+	var list []string
+	for {
+		e := &entity{}
+		id, err := it.Next(e)
+		if err == ds.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if e.ID != id {
+			t.Errorf("ID: unexpected, actual: `%s`, expected: `%s`", e.ID, id)
+		}
+
+		list = append(list, id)
+	}
+
+	return strings.Join(list, ",")
+}
+
+func TestClient_Run(t *testing.T) {
+	g := newClient(t)
+	q := ds.NewQuery("entity")
+
+	for _, tc := range []struct {
+		title    string
+		q        *ds.Query
+		expected string
+	}{
+		{"条件がない場合、IDの順に返すこと", q, "e1,e2,e3,e4"},
+		{"等号の条件に一致するentityを返すこと", q.Filter("Value", "=", 2), "e3,e4"},
+		{"複数の値のいずれかが一致するentityを返すこと", q.Filter("Tags", "=", "b"), "e2,e3"},
+		{"不等号の条件のプロパティの順に返すこと", q.Filter("Value", ">", 1), "e3,e4,e1"},
+		{"降順とキーの順を指定できること", q.Order("-Value").Order("-__key__"), "e1,e4,e3,e2"},
+		{"noindexのプロパティは条件に一致しないこと", q.Filter("Note", "=", "x"), ""},
+		{"件数の上限を指定できること", q.Limit(2), "e1,e2"},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			AssertEquals(t, "IDs", ids(t, g, tc.q), tc.expected)
+		})
+	}
+
+	t.Run("Cursorから続きを取得できること", func(t *testing.T) {
+		it := g.Run(q.Limit(3))
+		for i := 0; i < 3; i++ {
+			if _, err := it.Next(nil); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		cursor, err := it.Cursor()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		AssertEquals(t, "IDs", ids(t, g, q.Start(cursor)), "e4")
+	})
+
+	t.Run("不正なCursorの場合、ErrInvalidCursorとなること", func(t *testing.T) {
+		if _, err := g.Run(q.Start("invalid")).Next(nil); err != ds.ErrInvalidCursor {
+			t.Errorf("err: unexpected, actual: `%v`", err)
+		}
+	})
+}
+
+func TestClient_Put(t *testing.T) {
+	t.Run("datastore:\"-\"のフィールドは保存されないこと", func(t *testing.T) {
+		g := dstest.NewClient()
+		if err := g.Put(&entity{ID: "e1", Value: 1, Cache: "x"}); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		e := &entity{ID: "e1"}
+		if err := g.Get(e); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		AssertEquals(t, "Value", e.Value, 1)
+		AssertEquals(t, "Cache", e.Cache, "")
+	})
+}
+
+func TestClient_RunInTransaction(t *testing.T) {
+	t.Run("エラーを返した場合、書き込みが破棄されること", func(t *testing.T) {
+		g := newClient(t)
+
+		errAbort := errors.New("abort")
+		err := g.RunInTransaction(func(tg ds.Client) error {
+			if err := tg.Put(&entity{ID: "e5"}); err != nil {
+				return err
+			}
+			if err := tg.Delete(&entity{ID: "e1"}); err != nil {
+				return err
+			}
+
+			return errAbort
+		})
+
+		if err != errAbort {
+			t.Fatalf("err: unexpected, actual: `%v`", err)
+		}
+		AssertEquals(t, "IDs", ids(t, g, ds.NewQuery("entity")), "e1,e2,e3,e4")
+	})
+
+	t.Run("コミットするまで書き込みが読み込めないこと", func(t *testing.T) {
+		g := newClient(t)
+
+		err := g.RunInTransaction(func(tg ds.Client) error {
+			if err := tg.Put(&entity{ID: "e5"}); err != nil {
+				return err
+			}
+
+			if err := tg.Get(&entity{ID: "e5"}); err != ds.ErrNoSuchEntity {
+				t.Errorf("err: unexpected, actual: `%v`", err)
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		AssertEquals(t, "Len", g.Store().Len("entity"), 5)
+	})
+}
+
+// AssertEquals は実値と期待値が同値か判定する
+func AssertEquals(t *testing.T, title string, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("%s: unexpected, actual: `%v`, expected: `%v`", title, actual, expected)
+	}
+}
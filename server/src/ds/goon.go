@@ -0,0 +1,186 @@
+package ds
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/mjibson/goon"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+)
+
+// AppEngine はApp EngineのDatastoreをgoon経由で利用するClientを生成する
+func AppEngine(r *http.Request) Client {
+	ctx := appengine.NewContext(r)
+	return NewGoon(goon.FromContext(ctx))
+}
+
+// NewGoon はgoonを利用するClientを生成する
+func NewGoon(g *goon.Goon) Client {
+	return &goonClient{g: g}
+}
+
+type goonClient struct {
+	g *goon.Goon
+}
+
+func (c *goonClient) Context() context.Context {
+	return c.g.Context
+}
+
+func (c *goonClient) Kind(src interface{}) string {
+	return c.g.Kind(src)
+}
+
+func (c *goonClient) Get(dst interface{}) error {
+	return goonError(c.g.Get(dst))
+}
+
+func (c *goonClient) GetMulti(dst interface{}) error {
+	return goonError(c.g.GetMulti(dst))
+}
+
+func (c *goonClient) Put(src interface{}) error {
+	_, err := c.g.Put(src)
+	return goonError(err)
+}
+
+func (c *goonClient) PutMulti(src interface{}) error {
+	_, err := c.g.PutMulti(src)
+	return goonError(err)
+}
+
+func (c *goonClient) Delete(src interface{}) error {
+	key, err := c.g.KeyError(src)
+	if err != nil {
+		return err
+	}
+
+	return goonError(c.g.Delete(key))
+}
+
+func (c *goonClient) DeleteMulti(src interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(src))
+
+	keys := make([]*datastore.Key, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		key, err := c.g.KeyError(v.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+
+		keys[i] = key
+	}
+
+	return goonError(c.g.DeleteMulti(keys))
+}
+
+func (c *goonClient) Run(q *Query) Iterator {
+	dq := datastore.NewQuery(q.kind)
+	if q.keysOnly {
+		dq = dq.KeysOnly()
+	}
+	for _, f := range q.filters {
+		dq = dq.Filter(f.field+" "+f.op, f.value)
+	}
+	for _, o := range q.orders {
+		dq = dq.Order(o)
+	}
+	if q.limit != -1 {
+		dq = dq.Limit(q.limit)
+	}
+	if q.start != "" {
+		start, err := datastore.DecodeCursor(q.start)
+		if err != nil {
+			return &errIterator{err: ErrInvalidCursor}
+		}
+
+		dq = dq.Start(start)
+	}
+	if q.end != "" {
+		end, err := datastore.DecodeCursor(q.end)
+		if err != nil {
+			return &errIterator{err: ErrInvalidCursor}
+		}
+
+		dq = dq.End(end)
+	}
+
+	return &goonIterator{it: c.g.Run(dq)}
+}
+
+// RunInTransaction はクロスグループのトランザクション内でfを1回のみ実行する
+//
+// 競合した場合のリトライはmodel.RetryPolicyで行うため、App Engineのdatastoreパッケージではリトライしない
+func (c *goonClient) RunInTransaction(f func(tg Client) error) error {
+	err := c.g.RunInTransaction(func(tg *goon.Goon) error {
+		return f(NewGoon(tg))
+
+	}, &datastore.TransactionOptions{XG: true, Attempts: 1})
+
+	return goonError(err)
+}
+
+type goonIterator struct {
+	it *goon.Iterator
+}
+
+func (it *goonIterator) Next(dst interface{}) (string, error) {
+	key, err := it.it.Next(dst)
+	if err != nil {
+		return "", goonError(err)
+	}
+
+	return key.StringID(), nil
+}
+
+func (it *goonIterator) Cursor() (string, error) {
+	cur, err := it.it.Cursor()
+	if err != nil {
+		return "", goonError(err)
+	}
+
+	return cur.String(), nil
+}
+
+type errIterator struct {
+	err error
+}
+
+func (it *errIterator) Next(dst interface{}) (string, error) {
+	return "", it.err
+}
+
+func (it *errIterator) Cursor() (string, error) {
+	return "", it.err
+}
+
+// goonError はappengine/datastoreのエラーをdsのエラーに変換する
+func goonError(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case datastore.ErrNoSuchEntity:
+		return ErrNoSuchEntity
+	case datastore.ErrConcurrentTransaction:
+		return ErrConcurrentTransaction
+	case datastore.Done:
+		return Done
+	}
+
+	if merr, ok := err.(appengine.MultiError); ok {
+		errs := make(MultiError, len(merr))
+		for i, err := range merr {
+			errs[i] = goonError(err)
+		}
+
+		return errs
+	}
+
+	if appengine.IsTimeoutError(err) {
+		return &TransientError{Err: err}
+	}
+
+	return err
+}
@@ -0,0 +1,129 @@
+package ds
+
+// Query はDatastoreのクエリを表す
+//
+// 各メソッドはレシーバを変更せず、条件を追加したQueryを返す
+type Query struct {
+	kind     string
+	keysOnly bool
+	filters  []filter
+	orders   []string
+	limit    int
+	start    string
+	end      string
+}
+
+type filter struct {
+	field string
+	op    string
+	value interface{}
+}
+
+// NewQuery はKindを対象とするQueryを生成する
+func NewQuery(kind string) *Query {
+	return &Query{
+		kind:  kind,
+		limit: -1,
+	}
+}
+
+func (q *Query) clone() *Query {
+	x := *q
+	x.filters = append([]filter(nil), q.filters...)
+	x.orders = append([]string(nil), q.orders...)
+
+	return &x
+}
+
+// KeysOnly はキーのみを取得するQueryを返す
+func (q *Query) KeysOnly() *Query {
+	q = q.clone()
+	q.keysOnly = true
+
+	return q
+}
+
+// Filter は"field op"形式の条件を追加したQueryを返す
+// opには"=", "<", "<=", ">", ">="を指定できる
+func (q *Query) Filter(field, op string, value interface{}) *Query {
+	q = q.clone()
+	q.filters = append(q.filters, filter{field: field, op: op, value: value})
+
+	return q
+}
+
+// Order はソート順を追加したQueryを返す
+// 降順の場合はfieldの先頭に"-"を付ける
+func (q *Query) Order(field string) *Query {
+	q = q.clone()
+	q.orders = append(q.orders, field)
+
+	return q
+}
+
+// Limit は取得件数の上限を設定したQueryを返す
+// -1の場合は上限なし
+func (q *Query) Limit(limit int) *Query {
+	q = q.clone()
+	q.limit = limit
+
+	return q
+}
+
+// Start は開始位置のCursorを設定したQueryを返す
+func (q *Query) Start(cursor string) *Query {
+	q = q.clone()
+	q.start = cursor
+
+	return q
+}
+
+// End は終了位置のCursorを設定したQueryを返す
+func (q *Query) End(cursor string) *Query {
+	q = q.clone()
+	q.end = cursor
+
+	return q
+}
+
+// Kind はクエリ対象のKind名を返す
+func (q *Query) Kind() string {
+	return q.kind
+}
+
+// FilterSpec はQueryの"field op value"形式の条件
+type FilterSpec struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+// QuerySpec はQueryの内容。dsパッケージ外のClientの実装がクエリを解釈するために利用する
+type QuerySpec struct {
+	Kind     string
+	KeysOnly bool
+	Filters  []FilterSpec
+	// Orders はOrderで追加した順のソート順。降順の場合は先頭に"-"を付ける
+	Orders []string
+	// Limit は取得件数の上限。-1の場合は上限なし
+	Limit int
+	Start string
+	End   string
+}
+
+// Spec はQueryの内容を返す
+func (q *Query) Spec() QuerySpec {
+	spec := QuerySpec{
+		Kind:     q.kind,
+		KeysOnly: q.keysOnly,
+		Orders:   append([]string(nil), q.orders...),
+		Limit:    q.limit,
+		Start:    q.start,
+		End:      q.end,
+	}
+	for _, f := range q.filters {
+		spec.Filters = append(spec.Filters, FilterSpec{Field: f.field, Op: f.op, Value: f.value})
+	}
+
+	return spec
+}
@@ -0,0 +1,121 @@
+// Package hogepb はHogeのProtocol Buffersのメッセージ、gRPCのHogeServiceと、modelとの変換を定義する
+//
+// hoge.pb.goとhoge_grpc.pb.goはhoge.protoから生成する。hoge.protoを変更した場合はgo generateを実行する
+package hogepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative hoge.proto
+
+import (
+	"gaego-gin/server/src/model"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewHoge はmodel.HogeをHogeに変換する
+func NewHoge(hoge *model.Hoge) *Hoge {
+	return &Hoge{
+		Id:        hoge.ID,
+		Value:     hoge.Value,
+		CreatedAt: newTimestamp(hoge.CreatedAt),
+		UpdatedAt: newTimestamp(hoge.UpdatedAt),
+	}
+}
+
+// Model はmodel.Hogeに変換する
+func (x *Hoge) Model() *model.Hoge {
+	return &model.Hoge{
+		ID:        x.GetId(),
+		Value:     x.GetValue(),
+		CreatedAt: modelTime(x.GetCreatedAt()),
+		UpdatedAt: modelTime(x.GetUpdatedAt()),
+	}
+}
+
+// NewHogeListResp はmodel.HogeListRespをHogeListRespに変換する
+func NewHogeListResp(resp *model.HogeListResp) *HogeListResp {
+	x := &HogeListResp{
+		List:        newHoges(resp.List),
+		Cursor:      resp.Cursor,
+		PrevCursor:  resp.PrevCursor,
+		TotalCapped: resp.TotalCapped,
+	}
+
+	if resp.Total != nil {
+		total := int32(*resp.Total)
+		x.Total = &total
+	}
+
+	return x
+}
+
+// Model はmodel.HogeListRespに変換する
+func (x *HogeListResp) Model() *model.HogeListResp {
+	resp := &model.HogeListResp{
+		List:        modelHoges(x.GetList()),
+		Cursor:      x.GetCursor(),
+		PrevCursor:  x.GetPrevCursor(),
+		TotalCapped: x.GetTotalCapped(),
+	}
+
+	if x.Total != nil {
+		total := int(x.GetTotal())
+		resp.Total = &total
+	}
+
+	return resp
+}
+
+// NewHogeSearchResp はmodel.HogeSearchRespをHogeSearchRespに変換する
+func NewHogeSearchResp(resp *model.HogeSearchResp) *HogeSearchResp {
+	return &HogeSearchResp{
+		List:   newHoges(resp.List),
+		Cursor: resp.Cursor,
+		Total:  int32(resp.Total),
+	}
+}
+
+// Model はmodel.HogeSearchRespに変換する
+func (x *HogeSearchResp) Model() *model.HogeSearchResp {
+	return &model.HogeSearchResp{
+		List:   modelHoges(x.GetList()),
+		Cursor: x.GetCursor(),
+		Total:  int(x.GetTotal()),
+	}
+}
+
+func newHoges(list []*model.Hoge) []*Hoge {
+	xs := make([]*Hoge, len(list))
+	for i, hoge := range list {
+		xs[i] = NewHoge(hoge)
+	}
+
+	return xs
+}
+
+func modelHoges(xs []*Hoge) []*model.Hoge {
+	list := make([]*model.Hoge, len(xs))
+	for i, x := range xs {
+		list[i] = x.Model()
+	}
+
+	return list
+}
+
+// newTimestamp はtをTimestampに変換する。ゼロ値の場合はnilとする
+func newTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+// modelTime はTimestampをtime.Timeに変換する。nilの場合はゼロ値とする
+func modelTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
@@ -0,0 +1,774 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: hoge.proto
+
+package hogepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Hoge はサンプル用の構造体。model.Hogeに対応する
+type Hoge struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Hoge) Reset() {
+	*x = Hoge{}
+	mi := &file_hoge_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hoge) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hoge) ProtoMessage() {}
+
+func (x *Hoge) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hoge.ProtoReflect.Descriptor instead.
+func (*Hoge) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Hoge) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Hoge) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Hoge) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Hoge) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// HogeListResp はHoge一覧取得のレスポンス。model.HogeListRespに対応する
+type HogeListResp struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	List  []*Hoge                `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
+	// cursor は次のページのページトークン。次のページが存在しない場合は空
+	Cursor string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// prev_cursor は前のページのページトークン。前のページが存在しない場合は空
+	PrevCursor string `protobuf:"bytes,3,opt,name=prev_cursor,json=prevCursor,proto3" json:"prev_cursor,omitempty"`
+	// total は条件に一致するHogeの総数。count=trueの場合のみ設定する
+	Total *int32 `protobuf:"varint,4,opt,name=total,proto3,oneof" json:"total,omitempty"`
+	// total_capped がtrueの場合は、総数が上限を超えるためtotalを上限で打ち切ったことを表す
+	TotalCapped   bool `protobuf:"varint,5,opt,name=total_capped,json=totalCapped,proto3" json:"total_capped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HogeListResp) Reset() {
+	*x = HogeListResp{}
+	mi := &file_hoge_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HogeListResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HogeListResp) ProtoMessage() {}
+
+func (x *HogeListResp) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HogeListResp.ProtoReflect.Descriptor instead.
+func (*HogeListResp) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *HogeListResp) GetList() []*Hoge {
+	if x != nil {
+		return x.List
+	}
+	return nil
+}
+
+func (x *HogeListResp) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *HogeListResp) GetPrevCursor() string {
+	if x != nil {
+		return x.PrevCursor
+	}
+	return ""
+}
+
+func (x *HogeListResp) GetTotal() int32 {
+	if x != nil && x.Total != nil {
+		return *x.Total
+	}
+	return 0
+}
+
+func (x *HogeListResp) GetTotalCapped() bool {
+	if x != nil {
+		return x.TotalCapped
+	}
+	return false
+}
+
+// HogeSearchResp はHoge検索のレスポンス。model.HogeSearchRespに対応する
+type HogeSearchResp struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	List  []*Hoge                `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
+	// cursor は次のページのカーソル。次のページが存在しない場合は空
+	Cursor string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// total は検索条件に一致するHogeの件数。件数が多い場合は概算となる
+	Total         int32 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HogeSearchResp) Reset() {
+	*x = HogeSearchResp{}
+	mi := &file_hoge_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HogeSearchResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HogeSearchResp) ProtoMessage() {}
+
+func (x *HogeSearchResp) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HogeSearchResp.ProtoReflect.Descriptor instead.
+func (*HogeSearchResp) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HogeSearchResp) GetList() []*Hoge {
+	if x != nil {
+		return x.List
+	}
+	return nil
+}
+
+func (x *HogeSearchResp) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *HogeSearchResp) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// GetHogeRequest はGetのリクエスト
+type GetHogeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHogeRequest) Reset() {
+	*x = GetHogeRequest{}
+	mi := &file_hoge_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHogeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHogeRequest) ProtoMessage() {}
+
+func (x *GetHogeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHogeRequest.ProtoReflect.Descriptor instead.
+func (*GetHogeRequest) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetHogeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// ListHogeRequest はListのリクエスト。GET /api/hogeのクエリパラメータに対応する
+type ListHogeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// cursor は前回の一覧取得で返されたcursorまたはprev_cursor
+	Cursor string `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// limit は取得件数(1-100)。0の場合は10件とする
+	Limit int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// sort はソート順(id, -id, createdAt, -createdAt, updatedAt, -updatedAt)
+	Sort string `protobuf:"bytes,3,opt,name=sort,proto3" json:"sort,omitempty"`
+	// value が空でない場合は、valueが一致するHogeのみを対象とする
+	Value string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// count がtrueの場合は、条件に一致するHogeの総数を返す
+	Count         bool `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHogeRequest) Reset() {
+	*x = ListHogeRequest{}
+	mi := &file_hoge_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHogeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHogeRequest) ProtoMessage() {}
+
+func (x *ListHogeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHogeRequest.ProtoReflect.Descriptor instead.
+func (*ListHogeRequest) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListHogeRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListHogeRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListHogeRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListHogeRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *ListHogeRequest) GetCount() bool {
+	if x != nil {
+		return x.Count
+	}
+	return false
+}
+
+// CreateHogeRequest はCreateのリクエスト
+type CreateHogeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hoge          *Hoge                  `protobuf:"bytes,1,opt,name=hoge,proto3" json:"hoge,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateHogeRequest) Reset() {
+	*x = CreateHogeRequest{}
+	mi := &file_hoge_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateHogeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateHogeRequest) ProtoMessage() {}
+
+func (x *CreateHogeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateHogeRequest.ProtoReflect.Descriptor instead.
+func (*CreateHogeRequest) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateHogeRequest) GetHoge() *Hoge {
+	if x != nil {
+		return x.Hoge
+	}
+	return nil
+}
+
+// UpdateHogeRequest はUpdateのリクエスト
+type UpdateHogeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hoge          *Hoge                  `protobuf:"bytes,1,opt,name=hoge,proto3" json:"hoge,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateHogeRequest) Reset() {
+	*x = UpdateHogeRequest{}
+	mi := &file_hoge_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateHogeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateHogeRequest) ProtoMessage() {}
+
+func (x *UpdateHogeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateHogeRequest.ProtoReflect.Descriptor instead.
+func (*UpdateHogeRequest) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateHogeRequest) GetHoge() *Hoge {
+	if x != nil {
+		return x.Hoge
+	}
+	return nil
+}
+
+// DeleteHogeRequest はDeleteのリクエスト
+type DeleteHogeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteHogeRequest) Reset() {
+	*x = DeleteHogeRequest{}
+	mi := &file_hoge_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteHogeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteHogeRequest) ProtoMessage() {}
+
+func (x *DeleteHogeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteHogeRequest.ProtoReflect.Descriptor instead.
+func (*DeleteHogeRequest) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteHogeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// WatchHogeRequest はWatchのリクエスト
+type WatchHogeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// last_event_id を指定した場合は、その位置より後の変更から送信する。指定しない場合は呼び出した後の変更のみ送信する
+	LastEventId string `protobuf:"bytes,3,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	// prefix を指定した場合は、IDがprefixで始まるHogeの変更のみ送信する
+	Prefix        string `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchHogeRequest) Reset() {
+	*x = WatchHogeRequest{}
+	mi := &file_hoge_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchHogeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchHogeRequest) ProtoMessage() {}
+
+func (x *WatchHogeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchHogeRequest.ProtoReflect.Descriptor instead.
+func (*WatchHogeRequest) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchHogeRequest) GetLastEventId() string {
+	if x != nil {
+		return x.LastEventId
+	}
+	return ""
+}
+
+func (x *WatchHogeRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+// HogeChange はHogeの変更。model.HogeChangeに対応する
+type HogeChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// position は変更履歴の位置。再接続する場合はlast_event_idに指定する
+	Position string `protobuf:"bytes,6,opt,name=position,proto3" json:"position,omitempty"`
+	// type は変更の種類(hoge.created, hoge.updated, hoge.deleted)
+	// 指定した位置の変更履歴が削除されている場合は、現在の位置を表すresetとなる。hogeは含めない
+	Type string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	// event_id はイベントの識別子。Webhookのイベントのidと同じ値となる
+	EventId string `protobuf:"bytes,3,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// hoge は変更後のHoge。削除の場合は削除したHoge
+	Hoge          *Hoge                  `protobuf:"bytes,4,opt,name=hoge,proto3" json:"hoge,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HogeChange) Reset() {
+	*x = HogeChange{}
+	mi := &file_hoge_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HogeChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HogeChange) ProtoMessage() {}
+
+func (x *HogeChange) ProtoReflect() protoreflect.Message {
+	mi := &file_hoge_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HogeChange.ProtoReflect.Descriptor instead.
+func (*HogeChange) Descriptor() ([]byte, []int) {
+	return file_hoge_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *HogeChange) GetPosition() string {
+	if x != nil {
+		return x.Position
+	}
+	return ""
+}
+
+func (x *HogeChange) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *HogeChange) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *HogeChange) GetHoge() *Hoge {
+	if x != nil {
+		return x.Hoge
+	}
+	return nil
+}
+
+func (x *HogeChange) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+var File_hoge_proto protoreflect.FileDescriptor
+
+const file_hoge_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"hoge.proto\x12\ahoge.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa2\x01\n" +
+	"\x04Hoge\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x129\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xb2\x01\n" +
+	"\fHogeListResp\x12!\n" +
+	"\x04list\x18\x01 \x03(\v2\r.hoge.v1.HogeR\x04list\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x1f\n" +
+	"\vprev_cursor\x18\x03 \x01(\tR\n" +
+	"prevCursor\x12\x19\n" +
+	"\x05total\x18\x04 \x01(\x05H\x00R\x05total\x88\x01\x01\x12!\n" +
+	"\ftotal_capped\x18\x05 \x01(\bR\vtotalCappedB\b\n" +
+	"\x06_total\"a\n" +
+	"\x0eHogeSearchResp\x12!\n" +
+	"\x04list\x18\x01 \x03(\v2\r.hoge.v1.HogeR\x04list\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x05R\x05total\" \n" +
+	"\x0eGetHogeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x7f\n" +
+	"\x0fListHogeRequest\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x12\n" +
+	"\x04sort\x18\x03 \x01(\tR\x04sort\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\bR\x05count\"6\n" +
+	"\x11CreateHogeRequest\x12!\n" +
+	"\x04hoge\x18\x01 \x01(\v2\r.hoge.v1.HogeR\x04hoge\"6\n" +
+	"\x11UpdateHogeRequest\x12!\n" +
+	"\x04hoge\x18\x01 \x01(\v2\r.hoge.v1.HogeR\x04hoge\"#\n" +
+	"\x11DeleteHogeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"T\n" +
+	"\x10WatchHogeRequest\x12\"\n" +
+	"\rlast_event_id\x18\x03 \x01(\tR\vlastEventId\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefixJ\x04\b\x01\x10\x02\"\xc2\x01\n" +
+	"\n" +
+	"HogeChange\x12\x1a\n" +
+	"\bposition\x18\x06 \x01(\tR\bposition\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x19\n" +
+	"\bevent_id\x18\x03 \x01(\tR\aeventId\x12!\n" +
+	"\x04hoge\x18\x04 \x01(\v2\r.hoge.v1.HogeR\x04hoge\x12;\n" +
+	"\voccurred_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAtJ\x04\b\x01\x10\x02R\x03seq2\xd8\x02\n" +
+	"\vHogeService\x12-\n" +
+	"\x03Get\x12\x17.hoge.v1.GetHogeRequest\x1a\r.hoge.v1.Hoge\x127\n" +
+	"\x04List\x12\x18.hoge.v1.ListHogeRequest\x1a\x15.hoge.v1.HogeListResp\x123\n" +
+	"\x06Create\x12\x1a.hoge.v1.CreateHogeRequest\x1a\r.hoge.v1.Hoge\x123\n" +
+	"\x06Update\x12\x1a.hoge.v1.UpdateHogeRequest\x1a\r.hoge.v1.Hoge\x12<\n" +
+	"\x06Delete\x12\x1a.hoge.v1.DeleteHogeRequest\x1a\x16.google.protobuf.Empty\x129\n" +
+	"\x05Watch\x12\x19.hoge.v1.WatchHogeRequest\x1a\x13.hoge.v1.HogeChange0\x01B\x1dZ\x1bgaego-gin/server/src/hogepbb\x06proto3"
+
+var (
+	file_hoge_proto_rawDescOnce sync.Once
+	file_hoge_proto_rawDescData []byte
+)
+
+func file_hoge_proto_rawDescGZIP() []byte {
+	file_hoge_proto_rawDescOnce.Do(func() {
+		file_hoge_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_hoge_proto_rawDesc), len(file_hoge_proto_rawDesc)))
+	})
+	return file_hoge_proto_rawDescData
+}
+
+var file_hoge_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_hoge_proto_goTypes = []any{
+	(*Hoge)(nil),                  // 0: hoge.v1.Hoge
+	(*HogeListResp)(nil),          // 1: hoge.v1.HogeListResp
+	(*HogeSearchResp)(nil),        // 2: hoge.v1.HogeSearchResp
+	(*GetHogeRequest)(nil),        // 3: hoge.v1.GetHogeRequest
+	(*ListHogeRequest)(nil),       // 4: hoge.v1.ListHogeRequest
+	(*CreateHogeRequest)(nil),     // 5: hoge.v1.CreateHogeRequest
+	(*UpdateHogeRequest)(nil),     // 6: hoge.v1.UpdateHogeRequest
+	(*DeleteHogeRequest)(nil),     // 7: hoge.v1.DeleteHogeRequest
+	(*WatchHogeRequest)(nil),      // 8: hoge.v1.WatchHogeRequest
+	(*HogeChange)(nil),            // 9: hoge.v1.HogeChange
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 11: google.protobuf.Empty
+}
+var file_hoge_proto_depIdxs = []int32{
+	10, // 0: hoge.v1.Hoge.created_at:type_name -> google.protobuf.Timestamp
+	10, // 1: hoge.v1.Hoge.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: hoge.v1.HogeListResp.list:type_name -> hoge.v1.Hoge
+	0,  // 3: hoge.v1.HogeSearchResp.list:type_name -> hoge.v1.Hoge
+	0,  // 4: hoge.v1.CreateHogeRequest.hoge:type_name -> hoge.v1.Hoge
+	0,  // 5: hoge.v1.UpdateHogeRequest.hoge:type_name -> hoge.v1.Hoge
+	0,  // 6: hoge.v1.HogeChange.hoge:type_name -> hoge.v1.Hoge
+	10, // 7: hoge.v1.HogeChange.occurred_at:type_name -> google.protobuf.Timestamp
+	3,  // 8: hoge.v1.HogeService.Get:input_type -> hoge.v1.GetHogeRequest
+	4,  // 9: hoge.v1.HogeService.List:input_type -> hoge.v1.ListHogeRequest
+	5,  // 10: hoge.v1.HogeService.Create:input_type -> hoge.v1.CreateHogeRequest
+	6,  // 11: hoge.v1.HogeService.Update:input_type -> hoge.v1.UpdateHogeRequest
+	7,  // 12: hoge.v1.HogeService.Delete:input_type -> hoge.v1.DeleteHogeRequest
+	8,  // 13: hoge.v1.HogeService.Watch:input_type -> hoge.v1.WatchHogeRequest
+	0,  // 14: hoge.v1.HogeService.Get:output_type -> hoge.v1.Hoge
+	1,  // 15: hoge.v1.HogeService.List:output_type -> hoge.v1.HogeListResp
+	0,  // 16: hoge.v1.HogeService.Create:output_type -> hoge.v1.Hoge
+	0,  // 17: hoge.v1.HogeService.Update:output_type -> hoge.v1.Hoge
+	11, // 18: hoge.v1.HogeService.Delete:output_type -> google.protobuf.Empty
+	9,  // 19: hoge.v1.HogeService.Watch:output_type -> hoge.v1.HogeChange
+	14, // [14:20] is the sub-list for method output_type
+	8,  // [8:14] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_hoge_proto_init() }
+func file_hoge_proto_init() {
+	if File_hoge_proto != nil {
+		return
+	}
+	file_hoge_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_hoge_proto_rawDesc), len(file_hoge_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_hoge_proto_goTypes,
+		DependencyIndexes: file_hoge_proto_depIdxs,
+		MessageInfos:      file_hoge_proto_msgTypes,
+	}.Build()
+	File_hoge_proto = out.File
+	file_hoge_proto_goTypes = nil
+	file_hoge_proto_depIdxs = nil
+}
@@ -0,0 +1,332 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: hoge.proto
+
+package hogepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	HogeService_Get_FullMethodName    = "/hoge.v1.HogeService/Get"
+	HogeService_List_FullMethodName   = "/hoge.v1.HogeService/List"
+	HogeService_Create_FullMethodName = "/hoge.v1.HogeService/Create"
+	HogeService_Update_FullMethodName = "/hoge.v1.HogeService/Update"
+	HogeService_Delete_FullMethodName = "/hoge.v1.HogeService/Delete"
+	HogeService_Watch_FullMethodName  = "/hoge.v1.HogeService/Watch"
+)
+
+// HogeServiceClient is the client API for HogeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// HogeService はHogeのAPIと同じ操作を提供する
+type HogeServiceClient interface {
+	// Get はHogeを1件取得する
+	Get(ctx context.Context, in *GetHogeRequest, opts ...grpc.CallOption) (*Hoge, error)
+	// List はHogeの一覧を取得する
+	List(ctx context.Context, in *ListHogeRequest, opts ...grpc.CallOption) (*HogeListResp, error)
+	// Create はHogeを新規作成する
+	Create(ctx context.Context, in *CreateHogeRequest, opts ...grpc.CallOption) (*Hoge, error)
+	// Update はHogeを更新する
+	Update(ctx context.Context, in *UpdateHogeRequest, opts ...grpc.CallOption) (*Hoge, error)
+	// Delete はHogeを削除する
+	Delete(ctx context.Context, in *DeleteHogeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Watch はHogeの変更を逐次送信する
+	Watch(ctx context.Context, in *WatchHogeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[HogeChange], error)
+}
+
+type hogeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHogeServiceClient(cc grpc.ClientConnInterface) HogeServiceClient {
+	return &hogeServiceClient{cc}
+}
+
+func (c *hogeServiceClient) Get(ctx context.Context, in *GetHogeRequest, opts ...grpc.CallOption) (*Hoge, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Hoge)
+	err := c.cc.Invoke(ctx, HogeService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hogeServiceClient) List(ctx context.Context, in *ListHogeRequest, opts ...grpc.CallOption) (*HogeListResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HogeListResp)
+	err := c.cc.Invoke(ctx, HogeService_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hogeServiceClient) Create(ctx context.Context, in *CreateHogeRequest, opts ...grpc.CallOption) (*Hoge, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Hoge)
+	err := c.cc.Invoke(ctx, HogeService_Create_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hogeServiceClient) Update(ctx context.Context, in *UpdateHogeRequest, opts ...grpc.CallOption) (*Hoge, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Hoge)
+	err := c.cc.Invoke(ctx, HogeService_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hogeServiceClient) Delete(ctx context.Context, in *DeleteHogeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, HogeService_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hogeServiceClient) Watch(ctx context.Context, in *WatchHogeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[HogeChange], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &HogeService_ServiceDesc.Streams[0], HogeService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchHogeRequest, HogeChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HogeService_WatchClient = grpc.ServerStreamingClient[HogeChange]
+
+// HogeServiceServer is the server API for HogeService service.
+// All implementations must embed UnimplementedHogeServiceServer
+// for forward compatibility.
+//
+// HogeService はHogeのAPIと同じ操作を提供する
+type HogeServiceServer interface {
+	// Get はHogeを1件取得する
+	Get(context.Context, *GetHogeRequest) (*Hoge, error)
+	// List はHogeの一覧を取得する
+	List(context.Context, *ListHogeRequest) (*HogeListResp, error)
+	// Create はHogeを新規作成する
+	Create(context.Context, *CreateHogeRequest) (*Hoge, error)
+	// Update はHogeを更新する
+	Update(context.Context, *UpdateHogeRequest) (*Hoge, error)
+	// Delete はHogeを削除する
+	Delete(context.Context, *DeleteHogeRequest) (*emptypb.Empty, error)
+	// Watch はHogeの変更を逐次送信する
+	Watch(*WatchHogeRequest, grpc.ServerStreamingServer[HogeChange]) error
+	mustEmbedUnimplementedHogeServiceServer()
+}
+
+// UnimplementedHogeServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHogeServiceServer struct{}
+
+func (UnimplementedHogeServiceServer) Get(context.Context, *GetHogeRequest) (*Hoge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedHogeServiceServer) List(context.Context, *ListHogeRequest) (*HogeListResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedHogeServiceServer) Create(context.Context, *CreateHogeRequest) (*Hoge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedHogeServiceServer) Update(context.Context, *UpdateHogeRequest) (*Hoge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedHogeServiceServer) Delete(context.Context, *DeleteHogeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedHogeServiceServer) Watch(*WatchHogeRequest, grpc.ServerStreamingServer[HogeChange]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedHogeServiceServer) mustEmbedUnimplementedHogeServiceServer() {}
+func (UnimplementedHogeServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeHogeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HogeServiceServer will
+// result in compilation errors.
+type UnsafeHogeServiceServer interface {
+	mustEmbedUnimplementedHogeServiceServer()
+}
+
+func RegisterHogeServiceServer(s grpc.ServiceRegistrar, srv HogeServiceServer) {
+	// If the following call pancis, it indicates UnimplementedHogeServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HogeService_ServiceDesc, srv)
+}
+
+func _HogeService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHogeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HogeServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HogeService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HogeServiceServer).Get(ctx, req.(*GetHogeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HogeService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHogeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HogeServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HogeService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HogeServiceServer).List(ctx, req.(*ListHogeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HogeService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateHogeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HogeServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HogeService_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HogeServiceServer).Create(ctx, req.(*CreateHogeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HogeService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateHogeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HogeServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HogeService_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HogeServiceServer).Update(ctx, req.(*UpdateHogeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HogeService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteHogeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HogeServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HogeService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HogeServiceServer).Delete(ctx, req.(*DeleteHogeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HogeService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchHogeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HogeServiceServer).Watch(m, &grpc.GenericServerStream[WatchHogeRequest, HogeChange]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HogeService_WatchServer = grpc.ServerStreamingServer[HogeChange]
+
+// HogeService_ServiceDesc is the grpc.ServiceDesc for HogeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HogeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "hoge.v1.HogeService",
+	HandlerType: (*HogeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _HogeService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _HogeService_List_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _HogeService_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _HogeService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _HogeService_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _HogeService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "hoge.proto",
+}
@@ -0,0 +1,222 @@
+// Package jobs はリクエストの外で実行する処理(Job)の登録と実行を行う
+//
+// Jobの状態はmodel.JobとしてDatastoreに保存し、Queueが実行を登録する
+// 第1世代のApp EngineではTaskQueue、それ以外ではLocalを利用する
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/model"
+	"sync"
+)
+
+var log = logger.New("jobs")
+
+// TaskPath はタスクキューからJobを実行するパス
+const TaskPath = "/tasks/jobs/run"
+
+// MaxAttempts はJobを失敗とするまでの最大の試行回数
+var MaxAttempts = 5
+
+// ErrUnknownType は登録されていない種類のJobを実行した場合のエラー
+var ErrUnknownType = errors.New("jobs: unknown job type")
+
+// Func はJobの種類毎の処理
+//
+// 戻り値はJSONに変換してJob.Resultに保存する。進捗はProgressで保存する
+// 一時的なエラー(model.IsRetryableなど)やRetryableErrorを返した場合は、MaxAttemptsに達するまでリトライする
+type Func func(g ds.Client, job *model.Job) (interface{}, error)
+
+// RetryableError はJobをリトライするエラー
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+// LastAttempt はjobの実行中の試行が最後の試行か判定する
+func LastAttempt(job *model.Job) bool {
+	return job.Attempts >= MaxAttempts
+}
+
+var (
+	funcsMu sync.RWMutex
+	funcs   = map[string]Func{}
+)
+
+// Register はJobの種類に対応する処理を登録する。同じ種類を複数回登録した場合はpanicする
+func Register(typ string, f Func) {
+	funcsMu.Lock()
+	defer funcsMu.Unlock()
+
+	if _, ok := funcs[typ]; ok {
+		panic(fmt.Sprintf("jobs: %q is already registered", typ))
+	}
+
+	funcs[typ] = f
+}
+
+func lookup(typ string) (Func, bool) {
+	funcsMu.RLock()
+	defer funcsMu.RUnlock()
+
+	f, ok := funcs[typ]
+	return f, ok
+}
+
+// Queue はJobを保存し、実行を登録する
+type Queue interface {
+	// Enqueue はjobを保存し、実行を登録する
+	// gがmodel.RunInTransactionのトランザクション内のClientの場合、実行はコミットした場合のみ登録する
+	Enqueue(g ds.Client, job *model.Job) error
+}
+
+// DefaultQueue はEnqueueが利用するQueue
+//
+// 既定では呼び出し元のClientで同期的に実行する
+var DefaultQueue Queue = &Local{}
+
+// Enqueue はDefaultQueueにjobを登録する
+func Enqueue(g ds.Client, job *model.Job) error {
+	return DefaultQueue.Enqueue(g, job)
+}
+
+// Shutdown はDefaultQueueがプロセス内で実行中のJobの完了を、ctxが終了するまで待つ
+func Shutdown(ctx context.Context) error {
+	if l, ok := DefaultQueue.(*Local); ok {
+		return l.Shutdown(ctx)
+	}
+
+	return nil
+}
+
+// Progress はJobの進捗を保存する
+func Progress(g ds.Client, job *model.Job, done, total int) error {
+	job.Progress = done
+	job.Total = total
+
+	return save(g, job)
+}
+
+// Checkpoint はJobの進捗と、リトライした場合に途中から再開するための状態を保存する
+//
+// 保存した状態はリトライした場合にmodel.Job.BindCheckpointで読み込む
+func Checkpoint(g ds.Client, job *model.Job, done, total int, state interface{}) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	job.Checkpoint = b
+
+	return Progress(g, job, done, total)
+}
+
+// Run はIDのJobを実行し、結果を保存する
+//
+// 完了済みのJobは実行しない。一時的なエラーで失敗した場合はエラーを返し、呼び出し元にリトライさせる
+// MaxAttemptsに達した場合やそれ以外のエラーの場合はJobを失敗として保存し、nilを返す
+func Run(g ds.Client, id string) error {
+	store := &model.JobStore{}
+
+	var job *model.Job
+	if err := model.Retry(g.Context(), "job.get", func() error {
+		var err error
+		job, err = store.Get(g, id)
+		return err
+
+	}); err != nil {
+		return err
+	}
+
+	if job.Status.Finished() {
+		// タスクが重複して実行された場合は何もしない
+		return nil
+	}
+
+	f, ok := lookup(job.Type)
+	if !ok {
+		return finish(g, job, nil, ErrUnknownType)
+	}
+
+	job.Status = model.JobRunning
+	job.Attempts++
+	if err := save(g, job); err != nil {
+		return err
+	}
+
+	result, err := f(g, job)
+	if err != nil && isRetryable(err) && !LastAttempt(job) {
+		log.Warningf(g.Context(), "job %s (%s) attempt %d failed: %v", job.ID, job.Type, job.Attempts, err)
+
+		job.Error = err.Error()
+		if serr := save(g, job); serr != nil {
+			log.Errorf(g.Context(), "failed to save job %s: %v", job.ID, serr)
+		}
+
+		return err
+	}
+
+	return finish(g, job, result, err)
+}
+
+// finish はJobを完了として保存する
+func finish(g ds.Client, job *model.Job, result interface{}, err error) error {
+	if err != nil {
+		job.Status = model.JobFailed
+		job.Error = err.Error()
+	} else {
+		b, merr := json.Marshal(result)
+		if merr != nil {
+			return merr
+		}
+
+		job.Status = model.JobSucceeded
+		job.Result = b
+		job.Error = ""
+		job.Checkpoint = nil
+	}
+
+	if err := save(g, job); err != nil {
+		return err
+	}
+
+	if job.Status == model.JobSucceeded {
+		// 削除に失敗したPayloadはPurgeFinishedで削除する
+		if err := (&model.JobStore{}).DeletePayload(g, job); err != nil {
+			log.Warningf(g.Context(), "failed to delete payload of job %s: %v", job.ID, err)
+		}
+	}
+
+	log.Infof(g.Context(), "job %s (%s) %s", job.ID, job.Type, job.Status)
+
+	return nil
+}
+
+func save(g ds.Client, job *model.Job) error {
+	return model.Retry(g.Context(), "job.save", func() error {
+		return job.Save(g)
+	})
+}
+
+// isRetryable はリトライにより成功する可能性のあるエラーか判定する
+func isRetryable(err error) bool {
+	switch err.(type) {
+	case *model.RetryError, *RetryableError:
+		return true
+	}
+
+	switch err {
+	case context.DeadlineExceeded, context.Canceled:
+		return true
+	}
+
+	return model.IsRetryable(err)
+}
@@ -0,0 +1,168 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// savedJob は保存されたJobを取得する
+func savedJob(t *testing.T, g ds.Client, id string) *model.Job {
+	job := &model.Job{ID: id}
+	if err := g.Get(job); err != nil {
+		t.Fatalf("job %s is not saved: %s", id, err.Error())
+	}
+
+	return job
+}
+
+// failures は指定した回数だけerrを返し、その後は成功する処理を登録する
+func failures(typ string, n int, err error) {
+	calls := 0
+	jobs.Register(typ, func(g ds.Client, job *model.Job) (interface{}, error) {
+		calls++
+		if calls <= n {
+			return nil, err
+		}
+
+		if err := jobs.Progress(g, job, 1, 1); err != nil {
+			return nil, err
+		}
+
+		return map[string]int{"calls": calls}, nil
+	})
+}
+
+func newJob(t *testing.T, typ string) *model.Job {
+	job, err := model.NewJob(typ, nil, []byte("payload"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return job
+}
+
+func TestLocal_Enqueue(t *testing.T) {
+	defer func(n int) { jobs.MaxAttempts = n }(jobs.MaxAttempts)
+	jobs.MaxAttempts = 3
+
+	failures("test.ok", 0, nil)
+	failures("test.transient", 1, ds.ErrConcurrentTransaction)
+	failures("test.permanent", 1, errors.New("permanent"))
+	failures("test.exhausted", 3, &ds.TransientError{Err: errors.New("unavailable")})
+
+	for _, tc := range []struct {
+		title    string
+		typ      string
+		status   model.JobStatus
+		attempts int
+		result   string
+		err      string
+	}{
+		{"成功した場合、結果が保存されること", "test.ok", model.JobSucceeded, 1, `{"calls":1}`, ""},
+		{"一時的なエラーの場合、リトライされること", "test.transient", model.JobSucceeded, 2, `{"calls":2}`, ""},
+		{"一時的なエラー以外の場合、リトライせずに失敗となること", "test.permanent", model.JobFailed, 1, "", "permanent"},
+		{"MaxAttemptsに達した場合、失敗となること", "test.exhausted", model.JobFailed, 3, "", "unavailable"},
+		{"登録されていない種類の場合、失敗となること", "test.unknown", model.JobFailed, 0, "", jobs.ErrUnknownType.Error()},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			g := dstest.NewClient()
+			job := newJob(t, tc.typ)
+
+			l := &jobs.Local{}
+			if err := l.Enqueue(g, job); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			v, err := (&model.JobStore{}).Get(g, job.ID)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if v.Status != tc.status {
+				t.Errorf("job.Status: unexpected, actual: `%s`, expected: `%s`", v.Status, tc.status)
+			}
+			if v.Attempts != tc.attempts {
+				t.Errorf("job.Attempts: unexpected, actual: `%d`, expected: `%d`", v.Attempts, tc.attempts)
+			}
+			if string(v.Result) != tc.result {
+				t.Errorf("job.Result: unexpected, actual: `%s`, expected: `%s`", v.Result, tc.result)
+			}
+			if v.Error != tc.err {
+				t.Errorf("job.Error: unexpected, actual: `%s`, expected: `%s`", v.Error, tc.err)
+			}
+			if err := (&model.JobStore{}).LoadPayload(g, v); (err == nil) != (tc.status == model.JobFailed) {
+				t.Errorf("payload: unexpected, err: `%v`", err)
+			}
+		})
+	}
+
+	t.Run("Factoryを指定した場合、非同期で実行されShutdownで完了を待つこと", func(t *testing.T) {
+		g := dstest.NewClient()
+		job := newJob(t, "test.ok")
+
+		l := jobs.NewLocal(func(r *http.Request) ds.Client {
+			return g
+		})
+		if err := l.Enqueue(g, job); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := l.Shutdown(ctx); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if v := savedJob(t, g, job.ID); v.Status != model.JobSucceeded || v.Progress != 1 {
+			t.Errorf("job: unexpected, actual: `%s`, progress: `%d`", v.Status, v.Progress)
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("完了したJobは再度実行されないこと", func(t *testing.T) {
+		g := dstest.NewClient()
+		job := newJob(t, "test.ok")
+		job.Status = model.JobFailed
+		if err := job.Save(g); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if err := jobs.Run(g, job.ID); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if v := savedJob(t, g, job.ID); v.Status != model.JobFailed || v.Attempts != 0 {
+			t.Errorf("job: unexpected, actual: `%s`, attempts: `%d`", v.Status, v.Attempts)
+		}
+	})
+
+	t.Run("存在しないJobの場合、ErrNoSuchEntityを返すこと", func(t *testing.T) {
+		if err := jobs.Run(dstest.NewClient(), "unknown"); err != ds.ErrNoSuchEntity {
+			t.Errorf("err: unexpected, actual: `%v`", err)
+		}
+	})
+}
+
+func TestRegister(t *testing.T) {
+	jobs.Register("test.duplicated", func(g ds.Client, job *model.Job) (interface{}, error) {
+		return nil, nil
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register: expected panic")
+		}
+	}()
+
+	jobs.Register("test.duplicated", func(g ds.Client, job *model.Job) (interface{}, error) {
+		return nil, nil
+	})
+}
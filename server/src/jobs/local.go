@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"net/http"
+	"sync"
+)
+
+// Local はJobをプロセス内で実行するQueue。テストとローカル環境で利用する
+//
+// プロセスが終了した場合、実行中のJobはrunningのまま残る
+type Local struct {
+	// Factory は非同期で実行する場合のClientを生成する
+	// nilの場合はEnqueueを呼び出したClientで同期的に実行する
+	Factory ds.Factory
+
+	wg sync.WaitGroup
+}
+
+// NewLocal はfactoryのClientでJobを非同期に実行するLocalを生成する
+func NewLocal(factory ds.Factory) *Local {
+	return &Local{Factory: factory}
+}
+
+// Enqueue はjobを保存して実行する
+//
+// Factoryが設定されている場合はgoroutineで実行し、完了を待たずに返す
+// gがトランザクション内のClientの場合は、コミット後に実行する
+func (l *Local) Enqueue(g ds.Client, job *model.Job) error {
+	if err := save(g, job); err != nil {
+		return err
+	}
+
+	// コミット後に実行した場合はエラーを返せないため、ログに出力する
+	inTx := model.InTransaction(g)
+
+	var err error
+	model.AfterCommit(g, func(g ds.Client) {
+		err = l.start(g, job.ID)
+		if err != nil && inTx {
+			log.Errorf(g.Context(), "job %s gave up: %v", job.ID, err)
+		}
+	})
+
+	return err
+}
+
+// start はFactoryが設定されている場合はgoroutineで、それ以外はgで同期的にJobを実行する
+func (l *Local) start(g ds.Client, id string) error {
+	if l.Factory == nil {
+		return l.run(g, id)
+	}
+
+	// リクエストの終了後も実行を続けるため、リクエストのcontextを引き継がない
+	r, err := http.NewRequest(http.MethodPost, TaskPath, nil)
+	if err != nil {
+		return err
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+
+		jg := l.Factory(r)
+		if err := l.run(jg, id); err != nil {
+			log.Errorf(jg.Context(), "job %s gave up: %v", id, err)
+		}
+	}()
+
+	return nil
+}
+
+// run はタスクキューと同様に、Runがエラーを返す間MaxAttemptsまでリトライする
+func (l *Local) run(g ds.Client, id string) error {
+	policy := *model.DefaultRetryPolicy
+	policy.MaxAttempts = MaxAttempts
+	policy.Retryable = func(err error) bool {
+		return err != ds.ErrNoSuchEntity
+	}
+
+	return policy.Do(g.Context(), "job.run", func() error {
+		return Run(g, id)
+	})
+}
+
+// Shutdown は実行中のJobの完了を、ctxが終了するまで待つ
+func (l *Local) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,44 @@
+//go:build appengine
+// +build appengine
+
+package jobs
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"net/url"
+
+	"google.golang.org/appengine/taskqueue"
+)
+
+// TaskQueue はApp Engineのタスクキューで実行を登録するQueue
+//
+// タスクはTaskPathにPOSTし、タスクキューが2xx以外のレスポンスをリトライする
+type TaskQueue struct {
+	// Name はタスクを登録するキューの名前。空の場合はdefaultキューを利用する
+	Name string
+}
+
+// Enqueue はjobの保存とタスクの登録を同じトランザクションで行う
+//
+// gがトランザクション内のClientの場合は、そのトランザクションで行う
+func (q *TaskQueue) Enqueue(g ds.Client, job *model.Job) error {
+	if model.InTransaction(g) {
+		return q.add(g, job)
+	}
+
+	return model.RunInTransaction(g, "job.enqueue", func(tg ds.Client) error {
+		return q.add(tg, job)
+	})
+}
+
+func (q *TaskQueue) add(tg ds.Client, job *model.Job) error {
+	if err := job.Save(tg); err != nil {
+		return err
+	}
+
+	t := taskqueue.NewPOSTTask(TaskPath, url.Values{"id": {job.ID}})
+	_, err := taskqueue.Add(tg.Context(), t, q.Name)
+
+	return err
+}
@@ -0,0 +1,59 @@
+//go:build appengine
+// +build appengine
+
+package logger
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"google.golang.org/appengine"
+	aelog "google.golang.org/appengine/log"
+)
+
+// 第1世代のApp Engine(go1.9)ではgoogle.golang.org/appengine/logでリクエストログに出力する
+func init() {
+	prepareContext = func(r *http.Request) context.Context {
+		return appengine.WithContext(r.Context(), r)
+	}
+	SetSink(&appEngineSink{})
+}
+
+// appEngineSink はリクエストログに重要度毎のアプリケーションログとして出力する
+// リクエスト外のログは標準のlogパッケージで出力する
+type appEngineSink struct{}
+
+func (s *appEngineSink) Write(ctx context.Context, e *Entry) {
+	msg := "[" + e.Package + "] " + e.Message
+	if e.RequestID != "" {
+		msg = msg + " requestId=" + e.RequestID
+	}
+	if e.Route != "" {
+		msg = msg + " route=" + e.Route
+	}
+	if e.Tenant != "" {
+		msg = msg + " tenant=" + e.Tenant
+	}
+	for k, v := range e.Labels {
+		msg = msg + " " + k + "=" + v
+	}
+
+	if fieldsFromContext(ctx) == nil {
+		log.Printf("%s: %s", e.Severity, msg)
+		return
+	}
+
+	switch e.Severity {
+	case Debug:
+		aelog.Debugf(ctx, "%s", msg)
+	case Info:
+		aelog.Infof(ctx, "%s", msg)
+	case Warning:
+		aelog.Warningf(ctx, "%s", msg)
+	case Error:
+		aelog.Errorf(ctx, "%s", msg)
+	default:
+		aelog.Criticalf(ctx, "%s", msg)
+	}
+}
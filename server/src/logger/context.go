@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"gaego-gin/server/src/middleware"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fields はリクエスト毎にログへ付与する項目
+type Fields struct {
+	RequestID string
+	TraceID   string
+	Route     string
+	Tenant    string
+}
+
+type fieldsKey struct{}
+
+// WithFields はログに付与する項目を設定したcontextを返す
+func WithFields(ctx context.Context, f *Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+func fieldsFromContext(ctx context.Context) *Fields {
+	if ctx == nil {
+		return nil
+	}
+
+	f, _ := ctx.Value(fieldsKey{}).(*Fields)
+	return f
+}
+
+// RequestID はcontextに設定されたリクエストIDを返す
+func RequestID(ctx context.Context) string {
+	if f := fieldsFromContext(ctx); f != nil {
+		return f.RequestID
+	}
+
+	return ""
+}
+
+// prepareContext はリクエストのcontextをログの出力先が利用できるcontextに変換する
+var prepareContext = func(r *http.Request) context.Context {
+	return r.Context()
+}
+
+const (
+	requestIDHeader = "X-Request-Id"
+	tenantHeader    = "X-Tenant-Id"
+)
+
+// Middleware はリクエストID、トレースID、ルート、テナントをログに付与する
+//
+// リクエストIDはX-Request-Idヘッダー、App EngineのリクエストログIDの順に利用し、
+// どちらも存在しない場合は生成する。リクエストIDはレスポンスのヘッダーにも設定する
+func Middleware(r *gin.Engine) gin.HandlerFunc {
+	routes := middleware.NewRouteResolver(r)
+
+	return func(c *gin.Context) {
+		ctx := prepareContext(c.Request)
+
+		f := &Fields{
+			RequestID: requestID(c.Request),
+			TraceID:   traceID(ctx, c.Request),
+			Route:     routes.Template(c),
+			Tenant:    c.GetHeader(tenantHeader),
+		}
+		c.Header(requestIDHeader, f.RequestID)
+
+		c.Request = c.Request.WithContext(WithFields(ctx, f))
+		c.Next()
+	}
+}
+
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); id != "" {
+		return id
+	}
+	if id := r.Header.Get("X-Appengine-Request-Log-Id"); id != "" {
+		return id
+	}
+
+	return newRequestID()
+}
+
+// newRequestID はランダムなリクエストIDを生成する
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// traceID はOpenTelemetryのspan、X-Cloud-Trace-Contextヘッダーの順にトレースIDを取得する
+func traceID(ctx context.Context, r *http.Request) string {
+	if id := spanTraceID(ctx); id != "" {
+		return id
+	}
+
+	// X-Cloud-Trace-Context: TRACE_ID/SPAN_ID;o=TRACE_TRUE
+	h := r.Header.Get("X-Cloud-Trace-Context")
+	if i := strings.Index(h, "/"); i != -1 {
+		return h[:i]
+	}
+
+	return h
+}
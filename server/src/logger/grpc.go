@@ -0,0 +1,64 @@
+//go:build !appengine
+// +build !appengine
+
+package logger
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor はMiddlewareと同様に、リクエストID、トレースID、メソッド、テナントをgRPCの呼び出しのログに付与する
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRPCFields(ctx, info.FullMethod), req)
+	}
+}
+
+// StreamServerInterceptor はUnaryServerInterceptorと同じ項目をストリームのログに付与する
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: withRPCFields(ss.Context(), info.FullMethod)})
+	}
+}
+
+// withRPCFields はメタデータのx-request-idとx-tenant-idからログの項目を設定したcontextを返す
+//
+// リクエストIDはメタデータにない場合は生成し、レスポンスのヘッダーにも設定する
+func withRPCFields(ctx context.Context, method string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	f := &Fields{
+		RequestID: firstValue(md, requestIDHeader),
+		TraceID:   spanTraceID(ctx),
+		Route:     method,
+		Tenant:    firstValue(md, tenantHeader),
+	}
+	if f.RequestID == "" {
+		f.RequestID = newRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, f.RequestID)) // nolint: errcheck
+
+	return WithFields(ctx, f)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if v := md.Get(strings.ToLower(key)); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
+
+// serverStream はContextがctxを返すgrpc.ServerStream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
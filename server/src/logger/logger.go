@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Severity はログの重要度
+type Severity int
+
+// ログの重要度。名前はCloud Loggingのseverityに合わせる
+const (
+	Debug Severity = iota
+	Info
+	Warning
+	Error
+	Critical
+)
+
+var severityNames = []string{"DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL"}
+
+func (s Severity) String() string {
+	if s < Debug || Critical < s {
+		return "DEFAULT"
+	}
+
+	return severityNames[s]
+}
+
+// ParseSeverity は名前に対応するSeverityを返す
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(n, name) {
+			return Severity(i), nil
+		}
+	}
+
+	return Debug, fmt.Errorf("logger: unknown severity %q", name)
+}
+
+// Entry は1件のログ
+type Entry struct {
+	Severity  Severity
+	Time      time.Time
+	Package   string
+	Message   string
+	RequestID string
+	TraceID   string
+	Route     string
+	Tenant    string
+	Labels    map[string]string
+}
+
+// Sink はログの出力先
+type Sink interface {
+	Write(ctx context.Context, e *Entry)
+}
+
+var (
+	mu            sync.RWMutex
+	sink          Sink = NewJSONSink(os.Stdout)
+	defaultLevel       = Info
+	packageLevels      = map[string]Severity{}
+)
+
+// SetSink はログの出力先を設定する
+func SetSink(s Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	sink = s
+}
+
+// SetLevel はパッケージ毎の出力する最低の重要度を設定する
+// pkgが空文字の場合は、個別に設定されていないパッケージの重要度を設定する
+func SetLevel(pkg string, s Severity) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pkg == "" {
+		defaultLevel = s
+		return
+	}
+
+	packageLevels[pkg] = s
+}
+
+// ConfigureLevels は"info,api=debug,model=warning"形式の設定でSetLevelを行う
+func ConfigureLevels(spec string) error {
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		pkg, name := "", part
+		if i := strings.Index(part, "="); i != -1 {
+			pkg, name = part[:i], part[i+1:]
+		}
+
+		s, err := ParseSeverity(name)
+		if err != nil {
+			return err
+		}
+
+		SetLevel(pkg, s)
+	}
+
+	return nil
+}
+
+// Logger はパッケージ毎のロガー
+type Logger struct {
+	pkg    string
+	labels map[string]string
+}
+
+// New はpkgのロガーを生成する
+func New(pkg string) *Logger {
+	return &Logger{pkg: pkg}
+}
+
+// With はkeyとvalueのラベルを付与するロガーを返す
+func (l *Logger) With(key, value string) *Logger {
+	labels := make(map[string]string, len(l.labels)+1)
+	for k, v := range l.labels {
+		labels[k] = v
+	}
+	labels[key] = value
+
+	return &Logger{pkg: l.pkg, labels: labels}
+}
+
+// Enabled はsの重要度のログを出力するか判定する
+func (l *Logger) Enabled(s Severity) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	level, ok := packageLevels[l.pkg]
+	if !ok {
+		level = defaultLevel
+	}
+
+	return level <= s
+}
+
+func (l *Logger) logf(ctx context.Context, s Severity, format string, args ...interface{}) {
+	if !l.Enabled(s) {
+		return
+	}
+
+	e := &Entry{
+		Severity: s,
+		Time:     time.Now(),
+		Package:  l.pkg,
+		Message:  fmt.Sprintf(format, args...),
+		Labels:   l.labels,
+	}
+	if f := fieldsFromContext(ctx); f != nil {
+		e.RequestID = f.RequestID
+		e.TraceID = f.TraceID
+		e.Route = f.Route
+		e.Tenant = f.Tenant
+	}
+
+	mu.RLock()
+	s0 := sink
+	mu.RUnlock()
+
+	s0.Write(ctx, e)
+}
+
+// Debugf はDEBUGのログを出力する
+func (l *Logger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	l.logf(ctx, Debug, format, args...)
+}
+
+// Infof はINFOのログを出力する
+func (l *Logger) Infof(ctx context.Context, format string, args ...interface{}) {
+	l.logf(ctx, Info, format, args...)
+}
+
+// Warningf はWARNINGのログを出力する
+func (l *Logger) Warningf(ctx context.Context, format string, args ...interface{}) {
+	l.logf(ctx, Warning, format, args...)
+}
+
+// Errorf はERRORのログを出力する
+func (l *Logger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.logf(ctx, Error, format, args...)
+}
+
+// Criticalf はCRITICALのログを出力する
+func (l *Logger) Criticalf(ctx context.Context, format string, args ...interface{}) {
+	l.logf(ctx, Critical, format, args...)
+}
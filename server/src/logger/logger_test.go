@@ -0,0 +1,163 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/metrics"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConfigureLevels(t *testing.T) {
+	defer logger.ConfigureLevels("info,api=info,model=info")
+
+	if err := logger.ConfigureLevels("warning,api=debug"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	cases := []struct {
+		pkg      string
+		severity logger.Severity
+		expected bool
+	}{
+		{"api", logger.Debug, true},
+		{"model", logger.Info, false},
+		{"model", logger.Warning, true},
+	}
+	for _, c := range cases {
+		if actual := logger.New(c.pkg).Enabled(c.severity); actual != c.expected {
+			t.Errorf("%s.Enabled(%s): unexpected, actual: `%v`, expected: `%v`", c.pkg, c.severity, actual, c.expected)
+		}
+	}
+
+	if err := logger.ConfigureLevels("api=verbose"); err == nil {
+		t.Error("unknown severity: expected error")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetSink(logger.NewJSONSink(buf))
+	defer logger.SetSink(logger.NewJSONSink(os.Stdout))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(logger.Middleware(r))
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, func(r *http.Request) ds.Client {
+			return &failingClient{ctx: r.Context()}
+		})
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+
+	t.Run("5xxの場合、元のエラーをログに出力し、クライアントには返さないこと", func(t *testing.T) {
+		buf.Reset()
+
+		req := httptest.NewRequest("GET", "/api/hoge/hoge", nil)
+		req.Header.Set("X-Request-Id", "req-1")
+		req.Header.Set("X-Tenant-Id", "tenant-1")
+		req.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", w.Code, http.StatusInternalServerError)
+		}
+		if strings.Contains(w.Body.String(), "connection reset") {
+			t.Errorf("body: internal error is exposed: `%s`", w.Body.String())
+		}
+		if actual := w.Header().Get("X-Request-Id"); actual != "req-1" {
+			t.Errorf("X-Request-Id: unexpected, actual: `%s`, expected: `req-1`", actual)
+		}
+
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: `%s`", err.Error(), buf.String())
+		}
+
+		expected := map[string]string{
+			"severity":  "ERROR",
+			"package":   "api",
+			"requestId": "req-1",
+			"traceId":   "105445aa7843bc8bf206b12000100000",
+			"route":     "/api/hoge/:id",
+			"tenant":    "tenant-1",
+		}
+		for k, v := range expected {
+			if entry[k] != v {
+				t.Errorf("%s: unexpected, actual: `%s`, expected: `%s`", k, entry[k], v)
+			}
+		}
+		if !strings.Contains(entry["message"].(string), "connection reset") {
+			t.Errorf("message: underlying error is not logged: `%s`", entry["message"])
+		}
+	})
+
+	t.Run("X-Request-Idが指定されていない場合、リクエストIDを生成すること", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/hoge/hoge", nil))
+
+		if w.Header().Get("X-Request-Id") == "" {
+			t.Error("X-Request-Id: expected to be generated")
+		}
+	})
+}
+
+// failingClient は全ての操作が失敗するClient
+type failingClient struct {
+	ds.Client
+	ctx context.Context
+}
+
+func (c *failingClient) Context() context.Context {
+	return c.ctx
+}
+
+func (c *failingClient) Get(dst interface{}) error {
+	return errors.New("rpc error: connection reset by peer")
+}
+
+// errWriter は常にエラーを返すio.Writer
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("closed")
+}
+
+func TestJSONSink_WriteError(t *testing.T) {
+	before := logWriteErrors(t)
+
+	logger.NewJSONSink(errWriter{}).Write(context.Background(), &logger.Entry{Severity: logger.Error, Message: "message"})
+
+	if actual := logWriteErrors(t); actual != before+1 {
+		t.Errorf("log_write_errors_total: unexpected, actual: `%v`, expected: `%v`", actual, before+1)
+	}
+}
+
+func logWriteErrors(t *testing.T) float64 {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, f := range families {
+		if f.GetName() == "log_write_errors_total" {
+			return f.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+
+	t.Fatal("log_write_errors_total is not registered")
+	return 0
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"gaego-gin/server/src/metrics"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// jsonEntry はCloud Loggingの構造化ログの形式
+type jsonEntry struct {
+	Severity  string            `json:"severity"`
+	Time      string            `json:"time"`
+	Message   string            `json:"message"`
+	Package   string            `json:"package,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+	TraceID   string            `json:"traceId,omitempty"`
+	Trace     string            `json:"logging.googleapis.com/trace,omitempty"`
+	Route     string            `json:"route,omitempty"`
+	Tenant    string            `json:"tenant,omitempty"`
+	Labels    map[string]string `json:"logging.googleapis.com/labels,omitempty"`
+}
+
+// JSONSink は1行1件のJSONでログを出力する
+type JSONSink struct {
+	mu        sync.Mutex
+	w         io.Writer
+	projectID string
+}
+
+// NewJSONSink はwに出力するJSONSinkを生成する
+//
+// GOOGLE_CLOUD_PROJECTが設定されている場合は、Cloud Traceと関連付けるためのtraceを出力する
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{
+		w:         w,
+		projectID: os.Getenv("GOOGLE_CLOUD_PROJECT"),
+	}
+}
+
+// Write はeを1行のJSONで出力する
+//
+// 変換や書き込みに失敗した場合はlog_write_errors_totalに記録する
+func (s *JSONSink) Write(ctx context.Context, e *Entry) {
+	v := &jsonEntry{
+		Severity:  e.Severity.String(),
+		Time:      e.Time.Format(time.RFC3339Nano),
+		Message:   e.Message,
+		Package:   e.Package,
+		RequestID: e.RequestID,
+		TraceID:   e.TraceID,
+		Route:     e.Route,
+		Tenant:    e.Tenant,
+		Labels:    e.Labels,
+	}
+	if s.projectID != "" && e.TraceID != "" {
+		v.Trace = "projects/" + s.projectID + "/traces/" + e.TraceID
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		metrics.RecordLogWriteError()
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write(append(b, '\n')); err != nil {
+		metrics.RecordLogWriteError()
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !appengine
+// +build !appengine
+
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanTraceID はctxのOpenTelemetryのspanのトレースIDを返す。spanがない場合は空文字
+func spanTraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+
+	return ""
+}
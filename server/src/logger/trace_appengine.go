@@ -0,0 +1,15 @@
+//go:build appengine
+// +build appengine
+
+package logger
+
+import (
+	"context"
+)
+
+// spanTraceID は空文字を返す
+//
+// 第1世代のApp Engine(go1.9)ではOpenTelemetryのspanを記録しないため、X-Cloud-Trace-ContextのトレースIDを利用する
+func spanTraceID(ctx context.Context) string {
+	return ""
+}
@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"context"
+	"gaego-gin/server/src/ds"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	datastoreOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "datastore_operations_total",
+		Help: "Number of Datastore operations by kind, operation and result.",
+	}, []string{"kind", "operation", "result"})
+
+	datastoreDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "datastore_operation_duration_seconds",
+		Help:    "Latency of Datastore operations by kind and operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"kind", "operation"})
+)
+
+func init() {
+	Registry.MustRegister(datastoreOperations, datastoreDuration)
+}
+
+// InstrumentFactory はDatastoreの操作の件数とレイテンシを計測するClientを生成するFactoryを返す
+func InstrumentFactory(f ds.Factory) ds.Factory {
+	return func(r *http.Request) ds.Client {
+		return InstrumentClient(f(r))
+	}
+}
+
+// InstrumentClient はDatastoreの操作の件数とレイテンシを計測するClientを返す
+func InstrumentClient(c ds.Client) ds.Client {
+	return &client{c: c}
+}
+
+type client struct {
+	c ds.Client
+}
+
+// observe はopの実行結果を記録する
+func observe(kind, op string, start time.Time, err error) {
+	result := "ok"
+	switch err {
+	case nil, ds.Done:
+	case ds.ErrNoSuchEntity:
+		result = "not_found"
+	case ds.ErrConcurrentTransaction:
+		result = "conflict"
+	default:
+		result = "error"
+	}
+
+	datastoreOperations.WithLabelValues(kind, op, result).Inc()
+	datastoreDuration.WithLabelValues(kind, op).Observe(time.Since(start).Seconds())
+}
+
+// multiKind は[]*Tの要素のKind名を返す
+func (c *client) multiKind(src interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(src))
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		return ""
+	}
+
+	return c.c.Kind(v.Index(0).Interface())
+}
+
+func (c *client) Context() context.Context {
+	return c.c.Context()
+}
+
+func (c *client) WithContext(ctx context.Context) ds.Client {
+	return &client{c: ds.WithContext(c.c, ctx)}
+}
+
+func (c *client) Kind(src interface{}) string {
+	return c.c.Kind(src)
+}
+
+func (c *client) Get(dst interface{}) error {
+	start := time.Now()
+	err := c.c.Get(dst)
+	observe(c.c.Kind(dst), "get", start, err)
+
+	return err
+}
+
+func (c *client) GetMulti(dst interface{}) error {
+	start := time.Now()
+	err := c.c.GetMulti(dst)
+	observe(c.multiKind(dst), "get_multi", start, err)
+
+	return err
+}
+
+func (c *client) Put(src interface{}) error {
+	start := time.Now()
+	err := c.c.Put(src)
+	observe(c.c.Kind(src), "put", start, err)
+
+	return err
+}
+
+func (c *client) PutMulti(src interface{}) error {
+	start := time.Now()
+	err := c.c.PutMulti(src)
+	observe(c.multiKind(src), "put_multi", start, err)
+
+	return err
+}
+
+func (c *client) Delete(src interface{}) error {
+	start := time.Now()
+	err := c.c.Delete(src)
+	observe(c.c.Kind(src), "delete", start, err)
+
+	return err
+}
+
+func (c *client) DeleteMulti(src interface{}) error {
+	start := time.Now()
+	err := c.c.DeleteMulti(src)
+	observe(c.multiKind(src), "delete_multi", start, err)
+
+	return err
+}
+
+func (c *client) Run(q *ds.Query) ds.Iterator {
+	return &iterator{it: c.c.Run(q), kind: q.Kind(), start: time.Now()}
+}
+
+func (c *client) RunInTransaction(f func(tg ds.Client) error) error {
+	start := time.Now()
+
+	err := c.c.RunInTransaction(func(tg ds.Client) error {
+		return f(InstrumentClient(tg))
+	})
+	observe("", "transaction", start, err)
+
+	return err
+}
+
+// iterator はクエリの実行開始から最初の結果を取得するまでを計測する
+//
+// 呼び出し側が結果を最後まで読まずに終了することがあるため、最初の結果で記録する
+type iterator struct {
+	it    ds.Iterator
+	kind  string
+	start time.Time
+	done  bool
+}
+
+func (it *iterator) Next(dst interface{}) (string, error) {
+	id, err := it.it.Next(dst)
+	if !it.done {
+		it.done = true
+		observe(it.kind, "query", it.start, err)
+	}
+
+	return id, err
+}
+
+func (it *iterator) Cursor() (string, error) {
+	return it.it.Cursor()
+}
+
+var (
+	retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "datastore_retries_total",
+		Help: "Number of retries by the retry policy by operation and reason.",
+	}, []string{"operation", "reason"})
+
+	retriesExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "datastore_retries_exhausted_total",
+		Help: "Number of operations that failed after reaching the maximum attempts by operation and reason.",
+	}, []string{"operation", "reason"})
+)
+
+func init() {
+	Registry.MustRegister(retries, retriesExhausted)
+}
+
+// RecordRetry はopのリトライを記録する
+func RecordRetry(op, reason string) {
+	retries.WithLabelValues(op, reason).Inc()
+}
+
+// RecordRetryExhausted はopがリトライの上限に達したことを記録する
+func RecordRetryExhausted(op, reason string) {
+	retriesExhausted.WithLabelValues(op, reason).Inc()
+}
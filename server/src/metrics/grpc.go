@@ -0,0 +1,61 @@
+//go:build !appengine
+// +build !appengine
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Number of gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Latency of gRPC calls by method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+)
+
+func init() {
+	Registry.MustRegister(grpcHandled, grpcDuration)
+}
+
+// UnaryServerInterceptor はMiddlewareと同様に、gRPCの呼び出しの件数とレイテンシを計測する
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+		observeRPC(info.FullMethod, start, err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor はストリームの件数と、開始から終了までの時間を計測する
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		err := handler(srv, ss)
+		observeRPC(info.FullMethod, start, err)
+
+		return err
+	}
+}
+
+func observeRPC(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+
+	grpcHandled.WithLabelValues(method, code).Inc()
+	grpcDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"gaego-gin/server/src/middleware"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	Registry.MustRegister(httpRequests, httpDuration)
+}
+
+// Middleware はHTTPリクエストの件数とレイテンシを計測する
+//
+// ラベルには実際のパスではなく"/api/hoge/:id"のようなルートのテンプレートを用いる
+func Middleware(r *gin.Engine) gin.HandlerFunc {
+	routes := middleware.NewRouteResolver(r)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := routes.Template(c)
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
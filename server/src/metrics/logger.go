@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var logWriteErrors = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "log_write_errors_total",
+	Help: "Number of log entries that could not be written.",
+})
+
+func init() {
+	Registry.MustRegister(logWriteErrors)
+}
+
+// RecordLogWriteError はログを出力できなかったことを記録する
+//
+// ログの出力先のエラーはログに出力できないため、メトリクスで検知する
+func RecordLogWriteError() {
+	logWriteErrors.Inc()
+}
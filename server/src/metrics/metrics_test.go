@@ -0,0 +1,66 @@
+package metrics_test
+
+import (
+	"context"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/metrics"
+	"gaego-gin/server/src/model"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetrics(t *testing.T) {
+	store := dstest.NewStore()
+	for _, id := range []string{"hoge1", "hoge2"} {
+		if err := store.Client(context.Background()).Put(&model.Hoge{ID: id}); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(metrics.Middleware(r))
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, metrics.InstrumentFactory(func(r *http.Request) ds.Client {
+			return store.Client(r.Context())
+		}))
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	for _, path := range []string{"/api/hoge/hoge1", "/api/hoge/hoge2", "/api/hoge/missing", "/unknown"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := ioutil.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []string{
+		`http_requests_total{method="GET",route="/api/hoge/:id",status="200"} 2`,
+		`http_requests_total{method="GET",route="/api/hoge/:id",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/api/hoge/:id",status="200"} 2`,
+		`datastore_operations_total{kind="Hoge",operation="get",result="ok"} 2`,
+		`datastore_operations_total{kind="Hoge",operation="get",result="not_found"} 1`,
+		`datastore_operation_duration_seconds_count{kind="Hoge",operation="get"} 3`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(body), e) {
+			t.Errorf("metrics: `%s` is not found", e)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bearerPrefix はAuthorizationヘッダーのBearerトークンの接頭辞
+const bearerPrefix = "Bearer "
+
+// BearerToken はAuthorization: Bearerヘッダーにtokenを指定したリクエストのみを受け付ける
+//
+// トークンが一致しない場合はWWW-Authenticateヘッダーと共に401を返す。比較にかかる時間からトークンを推測できないように、定数時間で比較する
+func BearerToken(token string) gin.HandlerFunc {
+	expected := []byte(token)
+
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		if !strings.HasPrefix(auth, bearerPrefix) ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, bearerPrefix)), expected) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Next()
+	}
+}
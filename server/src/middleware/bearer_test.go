@@ -0,0 +1,46 @@
+package middleware_test
+
+import (
+	"gaego-gin/server/src/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics", middleware.BearerToken("secret"), func(c *gin.Context) {
+		c.String(http.StatusOK, "metrics")
+	})
+
+	for _, tc := range []struct {
+		title string
+		auth  string
+		code  int
+	}{
+		{"トークンが一致する場合は実行されること", "Bearer secret", http.StatusOK},
+		{"Authorizationヘッダーがない場合、401エラーとなること", "", http.StatusUnauthorized},
+		{"トークンが一致しない場合、401エラーとなること", "Bearer secret2", http.StatusUnauthorized},
+		{"Bearer以外の形式の場合、401エラーとなること", "Basic secret", http.StatusUnauthorized},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.code {
+				t.Errorf("code: unexpected, actual: `%d`, expected: `%d`", w.Code, tc.code)
+			}
+			if tc.code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate: unexpected, actual: `%s`", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultCompressMinSize は圧縮するレスポンスの最小サイズのデフォルト値
+const DefaultCompressMinSize = 1024
+
+// compressEncodings は対応するContent-Encoding。q値が同じ場合は先頭を優先する
+var compressEncodings = []string{"br", "gzip"}
+
+// Compress はAccept-Encodingに応じてレスポンスをbrotliまたはgzipで圧縮する
+//
+// レスポンスをminSizeまでバッファし、minSize未満で終了した場合は圧縮せずに返す
+// Streamingを指定したルート、Content-Encodingを設定済みのレスポンス、ボディのないレスポンスは対象外とする
+// 圧縮の有無がAccept-Encodingにより変わるため、対象のレスポンスにはVary: Accept-Encodingを付与する
+func Compress(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			c:              c,
+			encoding:       negotiateEncoding(c.GetHeader("Accept-Encoding")),
+			minSize:        minSize,
+		}
+
+		c.Writer = w
+		defer func() { c.Writer = w.ResponseWriter }()
+
+		c.Next()
+		w.finish()
+	}
+}
+
+// negotiateEncoding はAccept-Encodingから利用するContent-Encodingを選択する。圧縮しない場合は空文字列を返す
+func negotiateEncoding(header string) string {
+	q := map[string]float64{}
+
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+
+		v := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if f, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					v = f
+				}
+			}
+		}
+
+		q[name] = v
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range compressEncodings {
+		v, ok := q[enc]
+		if !ok {
+			v, ok = q["*"]
+		}
+
+		if ok && v > bestQ {
+			best, bestQ = enc, v
+		}
+	}
+
+	return best
+}
+
+// compressWriter はminSizeまでレスポンスをバッファし、超えた時点で圧縮を開始するResponseWriter
+type compressWriter struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	encoding string
+	minSize  int
+
+	buf     []byte
+	pending bool
+	decided bool
+	enc     io.WriteCloser
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			w.passthrough()
+		} else {
+			w.buf = append(w.buf, b...)
+			if len(w.buf) < w.minSize {
+				return len(b), nil
+			}
+
+			return len(b), w.start()
+		}
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow は圧縮の有無を決定するまでヘッダーの書き出しを遅らせる
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided || IsStreaming(w.c) {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+
+	w.pending = true
+}
+
+// Written はバッファした書き込みも含めてレスポンスを書き込んだか判定する
+func (w *compressWriter) Written() bool {
+	return w.pending || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush はバッファしたレスポンスを書き出す。圧縮中の場合は圧縮済みのデータを書き出す
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.compressible() && len(w.buf) >= w.minSize {
+			w.start() // nolint: errcheck
+		} else {
+			w.passthrough()
+		}
+	}
+
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush() // nolint: errcheck
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+// compressible はレスポンスが圧縮の対象か判定する
+func (w *compressWriter) compressible() bool {
+	if IsStreaming(w.c) || w.c.Request.Method == http.MethodHead {
+		return false
+	}
+
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+
+	return w.Header().Get("Content-Encoding") == ""
+}
+
+// start はヘッダーを設定して圧縮を開始し、バッファしたレスポンスを書き出す
+func (w *compressWriter) start() error {
+	w.decided = true
+	w.vary()
+
+	if w.encoding == "" {
+		return w.writeBuffer(w.ResponseWriter)
+	}
+
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+
+	switch w.encoding {
+	case "br":
+		w.enc = brotli.NewWriterLevel(w.ResponseWriter, brotli.DefaultCompression)
+	default:
+		w.enc = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	return w.writeBuffer(w.enc)
+}
+
+// passthrough は圧縮せずにバッファしたレスポンスを書き出す
+func (w *compressWriter) passthrough() {
+	w.decided = true
+	if !IsStreaming(w.c) {
+		w.vary()
+	}
+
+	if w.pending {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+
+	w.writeBuffer(w.ResponseWriter) // nolint: errcheck
+}
+
+func (w *compressWriter) writeBuffer(dst io.Writer) error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	buf := w.buf
+	w.buf = nil
+
+	_, err := dst.Write(buf)
+	return err
+}
+
+// vary はVaryにAccept-Encodingを追加する
+func (w *compressWriter) vary() {
+	h := w.Header()
+	for _, v := range h["Vary"] {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" || strings.EqualFold(name, "Accept-Encoding") {
+				return
+			}
+		}
+	}
+
+	h.Add("Vary", "Accept-Encoding")
+}
+
+// finish はハンドラの終了後に、minSize未満のレスポンスを書き出し、圧縮を終える
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.passthrough()
+	}
+
+	if w.enc != nil {
+		w.enc.Close() // nolint: errcheck
+	}
+}
@@ -0,0 +1,161 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"gaego-gin/server/src/middleware"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"id":"hoge","value":"value"},`, 100)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	rg := r.Group("/api")
+	rg.Use(middleware.Compress(1024))
+	rg.Use(middleware.Timeout(time.Second))
+	rg.GET("/large", func(c *gin.Context) {
+		c.String(http.StatusOK, large)
+	})
+	rg.GET("/small", func(c *gin.Context) {
+		c.String(http.StatusOK, "small")
+	})
+	rg.GET("/chunked", func(c *gin.Context) {
+		for i := 0; i < 100; i++ {
+			c.Writer.WriteString(large[:30]) // nolint: errcheck
+		}
+	})
+	rg.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "identity")
+		c.String(http.StatusOK, large)
+	})
+	rg.DELETE("/empty", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	rg.GET("/stream", middleware.Streaming(), func(c *gin.Context) {
+		c.String(http.StatusOK, large)
+		c.Writer.Flush()
+	})
+
+	request := func(method, path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		var rd io.Reader
+		switch enc := w.Header().Get("Content-Encoding"); enc {
+		case "gzip":
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			rd = zr
+		case "br":
+			rd = brotli.NewReader(w.Body)
+		default:
+			t.Fatalf("unexpected Content-Encoding: `%s`", enc)
+		}
+
+		b, err := ioutil.ReadAll(rd)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return string(b)
+	}
+
+	t.Run("Accept-Encodingに応じてgzipまたはbrotliで圧縮されること", func(t *testing.T) {
+		for _, c := range []struct {
+			acceptEncoding string
+			expected       string
+		}{
+			{"gzip", "gzip"},
+			{"br", "br"},
+			{"gzip, deflate, br", "br"},
+			{"br;q=0.5, gzip", "gzip"},
+			{"*", "br"},
+			{"br;q=0, *;q=0.1", "gzip"},
+		} {
+			for _, path := range []string{"/api/large", "/api/chunked"} {
+				w := request("GET", path, c.acceptEncoding)
+				if w.Code != http.StatusOK {
+					t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", w.Code, http.StatusOK)
+				}
+
+				AssertEquals(t, c.acceptEncoding+" Content-Encoding", w.Header().Get("Content-Encoding"), c.expected)
+				AssertEquals(t, c.acceptEncoding+" Vary", w.Header().Get("Vary"), "Accept-Encoding")
+				AssertEquals(t, c.acceptEncoding+" Content-Length", w.Header().Get("Content-Length"), "")
+				AssertEquals(t, c.acceptEncoding+" body", decode(t, w) == large, true)
+				AssertEquals(t, c.acceptEncoding+" size", w.Body.Len() < len(large), true)
+			}
+		}
+	})
+
+	t.Run("圧縮に対応していない場合は圧縮されず、Varyが付与されること", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "identity", "deflate", "gzip;q=0, br;q=0"} {
+			w := request("GET", "/api/large", acceptEncoding)
+
+			AssertEquals(t, acceptEncoding+" Content-Encoding", w.Header().Get("Content-Encoding"), "")
+			AssertEquals(t, acceptEncoding+" Vary", w.Header().Get("Vary"), "Accept-Encoding")
+			AssertEquals(t, acceptEncoding+" body", w.Body.String() == large, true)
+		}
+	})
+
+	t.Run("最小サイズ未満のレスポンスは圧縮されないこと", func(t *testing.T) {
+		w := request("GET", "/api/small", "gzip, br")
+
+		AssertEquals(t, "Content-Encoding", w.Header().Get("Content-Encoding"), "")
+		AssertEquals(t, "Vary", w.Header().Get("Vary"), "Accept-Encoding")
+		AssertEquals(t, "body", w.Body.String(), "small")
+	})
+
+	t.Run("Content-Encodingを設定済みのレスポンスは圧縮されないこと", func(t *testing.T) {
+		w := request("GET", "/api/encoded", "gzip")
+
+		AssertEquals(t, "Content-Encoding", w.Header().Get("Content-Encoding"), "identity")
+		AssertEquals(t, "body", w.Body.String() == large, true)
+	})
+
+	t.Run("ボディのないレスポンスはステータスコードのみ返すこと", func(t *testing.T) {
+		w := request("DELETE", "/api/empty", "gzip")
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", w.Code, http.StatusNoContent)
+		}
+
+		AssertEquals(t, "Content-Encoding", w.Header().Get("Content-Encoding"), "")
+		AssertEquals(t, "len(body)", w.Body.Len(), 0)
+	})
+
+	t.Run("Streamingを指定したルートは圧縮されず、逐次書き出されること", func(t *testing.T) {
+		w := request("GET", "/api/stream", "gzip, br")
+
+		AssertEquals(t, "Content-Encoding", w.Header().Get("Content-Encoding"), "")
+		AssertEquals(t, "Vary", w.Header().Get("Vary"), "")
+		AssertEquals(t, "Flushed", w.Flushed, true)
+		AssertEquals(t, "body", w.Body.String() == large, true)
+	})
+}
+
+// AssertEquals は実値と期待値が同値か判定する
+func AssertEquals(t *testing.T, title string, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("%s: unexpected, actual: `%v`, expected: `%v`", title, actual, expected)
+	}
+}
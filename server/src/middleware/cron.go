@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cronHeader はApp Engineのcronがリクエストに付与するヘッダー
+const cronHeader = "X-Appengine-Cron"
+
+// Cron はApp Engineのcronからのリクエストのみを受け付ける
+//
+// App Engineは外部からのリクエストのX-AppEngine-*ヘッダーを取り除くため、
+// X-Appengine-Cron: trueのリクエストをcronからのリクエストとみなし、それ以外は403を返す
+func Cron() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(cronHeader) != "true" {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
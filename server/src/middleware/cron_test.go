@@ -0,0 +1,43 @@
+package middleware_test
+
+import (
+	"gaego-gin/server/src/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCron(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Cron())
+	r.GET("/cron/run", func(c *gin.Context) {
+		c.String(http.StatusOK, "run")
+	})
+
+	for _, tc := range []struct {
+		title string
+		cron  string
+		code  int
+	}{
+		{"cronからのリクエストは実行されること", "true", http.StatusOK},
+		{"X-Appengine-Cronヘッダーがない場合、403エラーとなること", "", http.StatusForbidden},
+		{"X-Appengine-Cronヘッダーがtrue以外の場合、403エラーとなること", "false", http.StatusForbidden},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/cron/run", nil)
+			if tc.cron != "" {
+				req.Header.Set("X-Appengine-Cron", tc.cron)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.code {
+				t.Errorf("code: unexpected, actual: `%d`, expected: `%d`", w.Code, tc.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomMethodPath はカスタムメソッド("/hoge:export")をginに登録するためのパスを返す
+//
+// ginのルーターは"/hoge/:id"と同じ階層に"/hoge:export"を登録できないため、"/hoge.export"として登録し、
+// CustomMethodsで振り分ける
+func CustomMethodPath(path, verb string) string {
+	return path + "." + verb
+}
+
+// CustomMethods は"/api/hoge:export"のようなカスタムメソッドのリクエストを、
+// CustomMethodPathで登録したルートに振り分けるhttp.Handlerを返す
+//
+// 対応するルートが登録されていない場合はパスを変更しないため、":"を含むIDはそのまま扱われる
+func CustomMethods(engine *gin.Engine) http.Handler {
+	var once sync.Once
+	var routes map[string]bool
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ルートは生成後に追加されるため、最初のリクエストで読み込む
+		once.Do(func() {
+			routes = map[string]bool{}
+			for _, route := range engine.Routes() {
+				routes[route.Method+" "+route.Path] = true
+			}
+		})
+
+		p := r.URL.Path
+		if i := strings.LastIndex(p, ":"); i > strings.LastIndex(p, "/") {
+			if path := CustomMethodPath(p[:i], p[i+1:]); routes[r.Method+" "+path] {
+				u := *r.URL
+				u.Path, u.RawPath = path, ""
+
+				rr := *r
+				rr.URL = &u
+				r = &rr
+			}
+		}
+
+		engine.ServeHTTP(w, r)
+	})
+}
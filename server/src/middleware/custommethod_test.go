@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"gaego-gin/server/src/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCustomMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/hoge/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, "get "+c.Param("id"))
+	})
+	r.GET(middleware.CustomMethodPath("/api/hoge", "export"), func(c *gin.Context) {
+		c.String(http.StatusOK, "export")
+	})
+
+	handler := middleware.CustomMethods(r)
+
+	for _, tc := range []struct {
+		title  string
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{"カスタムメソッドが登録されたルートに振り分けられること", "GET", "/api/hoge:export", http.StatusOK, "export"},
+		{"登録されていないカスタムメソッドは404エラーとなること", "GET", "/api/hoge:import", http.StatusNotFound, "404 page not found"},
+		{"メソッドが異なる場合は振り分けられないこと", "POST", "/api/hoge:export", http.StatusNotFound, "404 page not found"},
+		{"IDに含まれる\":\"は変更されないこと", "GET", "/api/hoge/a:export", http.StatusOK, "get a:export"},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if w.Code != tc.code {
+				t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", w.Code, tc.code)
+			}
+			if w.Body.String() != tc.body {
+				t.Errorf("body: unexpected, actual: `%s`, expected: `%s`", w.Body.String(), tc.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation はルートの廃止予定
+type Deprecation struct {
+	// At は廃止予定とした日時。ゼロ値の場合はDeprecationヘッダーを付与しない
+	At time.Time
+	// Sunset は提供を終了する日時。ゼロ値の場合はSunsetヘッダーを付与しない
+	Sunset time.Time
+	// Link は移行先や廃止の詳細を説明するURL。空の場合はLinkヘッダーを付与しない
+	Link string
+}
+
+// Deprecations はrulesに一致するルートのレスポンスに、廃止予定を示すヘッダーを付与する
+//
+// rulesのキーは"GET /hoge/:id"のようなメソッドとbasePathからの相対パスのテンプレート、"/hoge/:id"のようなテンプレートのみ、
+// またはbasePathの全てのルートを表す"*"とし、この順に優先する。"/hoge:export"のようなカスタムメソッドも指定できる
+//
+//	Deprecation: @1767225600 (RFC 9745)
+//	Sunset: Wed, 01 Jul 2026 00:00:00 GMT (RFC 8594)
+//	Link: <https://example.com/migration>; rel="deprecation"; type="text/html"
+func Deprecations(routes *RouteResolver, basePath string, rules map[string]Deprecation) gin.HandlerFunc {
+	// ルートのテンプレートはCustomMethodPathで登録したパスとなるため、キーを合わせる
+	normalized := make(map[string]Deprecation, len(rules))
+	for key, d := range rules {
+		method, route := "", key
+		if i := strings.Index(key, " "); i >= 0 {
+			method, route = key[:i+1], key[i+1:]
+		}
+
+		if i := strings.LastIndex(route, ":"); i > strings.LastIndex(route, "/")+1 {
+			route = CustomMethodPath(route[:i], route[i+1:])
+		}
+
+		normalized[method+route] = d
+	}
+
+	return func(c *gin.Context) {
+		if len(normalized) == 0 {
+			c.Next()
+			return
+		}
+
+		route := strings.TrimPrefix(routes.Template(c), strings.TrimSuffix(basePath, "/"))
+
+		d, ok := normalized[c.Request.Method+" "+route]
+		if !ok {
+			d, ok = normalized[route]
+		}
+		if !ok {
+			d, ok = normalized["*"]
+		}
+
+		if ok {
+			d.setHeaders(c.Writer.Header())
+		}
+
+		c.Next()
+	}
+}
+
+// setHeaders は廃止予定のヘッダーをhに設定する
+func (d Deprecation) setHeaders(h http.Header) {
+	if !d.At.IsZero() {
+		h.Set("Deprecation", fmt.Sprintf("@%d", d.At.Unix()))
+	}
+
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	if d.Link != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"; type="text/html"`, d.Link))
+	}
+}
@@ -0,0 +1,115 @@
+package middleware_test
+
+import (
+	"gaego-gin/server/src/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeprecations(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes := middleware.NewRouteResolver(r)
+
+	ok := func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	}
+
+	v1 := r.Group("/api/v1")
+	v1.Use(middleware.Deprecations(routes, "/api/v1", map[string]middleware.Deprecation{
+		"*":                {At: at, Link: "https://example.com/v1"},
+		"/hoge/:id":        {At: at, Sunset: sunset},
+		"DELETE /hoge/:id": {At: at, Sunset: sunset, Link: "https://example.com/v2"},
+		"GET /hoge:export": {Sunset: sunset},
+	}))
+	v1.GET("/hoge", func(c *gin.Context) {
+		c.Writer.Header().Add("Link", `</api/v1/hoge?cursor=next>; rel="next"`)
+		c.String(http.StatusOK, "ok")
+	})
+	v1.GET("/hoge/:id", ok)
+	v1.DELETE("/hoge/:id", ok)
+	v1.GET(middleware.CustomMethodPath("/hoge", "export"), ok)
+
+	v2 := r.Group("/api/v2")
+	v2.Use(middleware.Deprecations(routes, "/api/v2", nil))
+	v2.GET("/hoge/:id", ok)
+
+	handler := middleware.CustomMethods(r)
+
+	for _, tc := range []struct {
+		title       string
+		method      string
+		path        string
+		deprecation string
+		sunset      string
+		links       []string
+	}{
+		{
+			title:       "ルートを指定しない場合はグループ全体の設定を適用し、ハンドラのLinkヘッダーを残すこと",
+			method:      "GET",
+			path:        "/api/v1/hoge",
+			deprecation: "@1767225600",
+			links: []string{
+				`<https://example.com/v1>; rel="deprecation"; type="text/html"`,
+				`</api/v1/hoge?cursor=next>; rel="next"`,
+			},
+		},
+		{
+			title:       "パスを指定した設定を適用すること",
+			method:      "GET",
+			path:        "/api/v1/hoge/hoge1",
+			deprecation: "@1767225600",
+			sunset:      "Wed, 01 Jul 2026 00:00:00 GMT",
+		},
+		{
+			title:       "メソッドとパスを指定した設定を優先し、Linkヘッダーを付与すること",
+			method:      "DELETE",
+			path:        "/api/v1/hoge/hoge1",
+			deprecation: "@1767225600",
+			sunset:      "Wed, 01 Jul 2026 00:00:00 GMT",
+			links:       []string{`<https://example.com/v2>; rel="deprecation"; type="text/html"`},
+		},
+		{
+			title:  "カスタムメソッドを指定できること",
+			method: "GET",
+			path:   "/api/v1/hoge:export",
+			sunset: "Wed, 01 Jul 2026 00:00:00 GMT",
+		},
+		{
+			title:  "設定のないグループにはヘッダーを付与しないこと",
+			method: "GET",
+			path:   "/api/v2/hoge/hoge1",
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", w.Code, http.StatusOK)
+			}
+
+			AssertEquals(t, "Deprecation", w.Header().Get("Deprecation"), tc.deprecation)
+			AssertEquals(t, "Sunset", w.Header().Get("Sunset"), tc.sunset)
+
+			if tc.links == nil {
+				return
+			}
+
+			links := w.Header()["Link"]
+			if len(links) != len(tc.links) {
+				t.Fatalf("Link: unexpected, actual: `%v`, expected: `%v`", links, tc.links)
+			}
+			for i := range links {
+				AssertEquals(t, "Link", links[i], tc.links[i])
+			}
+		})
+	}
+}
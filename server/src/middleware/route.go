@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnmatchedRoute はどのルートにも一致しなかったリクエストのルートのテンプレート
+const UnmatchedRoute = "unmatched"
+
+// RouteResolver はリクエストが一致したルートのテンプレートを解決する
+type RouteResolver struct {
+	engine *gin.Engine
+	once   sync.Once
+	routes map[string][]string
+}
+
+// NewRouteResolver はengineに登録されたルートを対象とするRouteResolverを生成する
+func NewRouteResolver(engine *gin.Engine) *RouteResolver {
+	return &RouteResolver{engine: engine}
+}
+
+// Template はリクエストが一致した"/api/hoge/:id"のようなルートのテンプレートを返す
+//
+// どのルートにも一致しない場合はUnmatchedRouteを返す
+func (s *RouteResolver) Template(c *gin.Context) string {
+	// ルートはミドルウェアの登録後に追加されるため、最初のリクエストで読み込む
+	s.once.Do(func() {
+		s.routes = map[string][]string{}
+		for _, route := range s.engine.Routes() {
+			s.routes[route.Method] = append(s.routes[route.Method], route.Path)
+		}
+	})
+
+	for _, route := range s.routes[c.Request.Method] {
+		if matchRoute(route, c.Request.URL.Path, c.Params) {
+			return route
+		}
+	}
+
+	return UnmatchedRoute
+}
+
+// matchRoute はpathとパラメータの値がルートのテンプレートに一致するか判定する
+func matchRoute(route, path string, params gin.Params) bool {
+	rs := strings.Split(route, "/")
+	ps := strings.Split(path, "/")
+
+	for i, r := range rs {
+		// catch-allのパラメータは先頭の"/"を含む残りのパスが値となる
+		if strings.HasPrefix(r, "*") {
+			if i > len(ps) {
+				return false
+			}
+
+			return params.ByName(r[1:]) == "/"+strings.Join(ps[i:], "/")
+		}
+
+		if i >= len(ps) {
+			return false
+		}
+
+		if strings.HasPrefix(r, ":") {
+			if params.ByName(r[1:]) != ps[i] {
+				return false
+			}
+			continue
+		}
+
+		if r != ps[i] {
+			return false
+		}
+	}
+
+	return len(rs) == len(ps)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	streamingKey     = "middleware.streaming"
+	parentContextKey = "middleware.parentContext"
+)
+
+// Streaming はレスポンスを逐次書き出すルートであることを示す
+//
+// Timeoutによる処理時間の上限を解除し、クライアントが切断するまで処理を続ける
+// 圧縮などレスポンス全体をバッファするミドルウェアはIsStreamingで対象外とする
+func Streaming() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(streamingKey, true)
+
+		if v, ok := c.Get(parentContextKey); ok {
+			c.Request = c.Request.WithContext(v.(context.Context))
+		}
+
+		c.Next()
+	}
+}
+
+// IsStreaming はリクエストがStreamingを指定したルートに一致したか判定する
+func IsStreaming(c *gin.Context) bool {
+	return c.GetBool(streamingKey)
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queueNameHeader はタスクキューがタスクのリクエストに付与するヘッダー
+const queueNameHeader = "X-AppEngine-QueueName"
+
+// TaskQueue はタスクキューからのリクエストのみを受け付ける
+//
+// App Engineは外部からのリクエストのX-AppEngine-*ヘッダーを取り除くため、
+// X-AppEngine-QueueNameヘッダーが存在するリクエストをタスクキューからのリクエストとみなし、それ以外は403を返す
+func TaskQueue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(queueNameHeader) == "" {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
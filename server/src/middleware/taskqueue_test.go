@@ -0,0 +1,42 @@
+package middleware_test
+
+import (
+	"gaego-gin/server/src/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTaskQueue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.TaskQueue())
+	r.POST("/tasks/run", func(c *gin.Context) {
+		c.String(http.StatusOK, "run")
+	})
+
+	for _, tc := range []struct {
+		title string
+		queue string
+		code  int
+	}{
+		{"タスクキューからのリクエストは実行されること", "default", http.StatusOK},
+		{"X-AppEngine-QueueNameヘッダーがない場合、403エラーとなること", "", http.StatusForbidden},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/tasks/run", nil)
+			if tc.queue != "" {
+				req.Header.Set("X-AppEngine-QueueName", tc.queue)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.code {
+				t.Errorf("code: unexpected, actual: `%d`, expected: `%d`", w.Code, tc.code)
+			}
+		})
+	}
+}
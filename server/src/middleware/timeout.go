@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout はリクエストのcontextに処理時間の上限を設定する
+//
+// contextはDatastoreの操作にも伝播し、上限を超えた操作はキャンセルされる
+// ハンドラがレスポンスを返さずに上限を超えた場合は504を返す
+// Streamingを指定したルートには上限を設定しない
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		c.Set(parentContextKey, c.Request.Context())
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if c.Writer.Written() {
+			return
+		}
+
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			c.String(http.StatusGatewayTimeout, ctx.Err().Error())
+		case context.Canceled:
+			c.String(http.StatusServiceUnavailable, ctx.Err().Error())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package middleware_test
+
+import (
+	"context"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeout(t *testing.T) {
+	t.Run("Datastoreの操作が上限を超えた場合、キャンセルされ504エラーとなること", func(t *testing.T) {
+		store := &slowClient{}
+
+		code := request(t, 50*time.Millisecond, store, "/api/hoge/hoge")
+
+		if code != http.StatusGatewayTimeout {
+			t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", code, http.StatusGatewayTimeout)
+		}
+		if store.err != context.DeadlineExceeded {
+			t.Errorf("store call: unexpected, actual: `%v`, expected: `%v`", store.err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("上限内に完了した場合、ハンドラのレスポンスが返ること", func(t *testing.T) {
+		store := &slowClient{delay: time.Millisecond}
+
+		code := request(t, time.Second, store, "/api/hoge/hoge")
+
+		if code != http.StatusOK {
+			t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", code, http.StatusOK)
+		}
+		if store.err != nil {
+			t.Errorf("store call: unexpected, actual: `%v`, expected: `<nil>`", store.err)
+		}
+	})
+
+	t.Run("ハンドラがレスポンスを返さずに上限を超えた場合、504エラーとなること", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middleware.Timeout(10 * time.Millisecond))
+		r.GET("/slow", func(c *gin.Context) {
+			<-c.Request.Context().Done()
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+
+		if w.Code != http.StatusGatewayTimeout {
+			t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", w.Code, http.StatusGatewayTimeout)
+		}
+	})
+}
+
+func TestStreaming(t *testing.T) {
+	t.Run("Streamingを指定したルートには上限が設定されないこと", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middleware.Timeout(10 * time.Millisecond))
+		r.GET("/stream", middleware.Streaming(), func(c *gin.Context) {
+			if _, ok := c.Request.Context().Deadline(); ok {
+				t.Error("deadline: unexpected")
+			}
+			if !middleware.IsStreaming(c) {
+				t.Error("IsStreaming: expected true")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			c.String(http.StatusOK, "ok")
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`", w.Code, http.StatusOK)
+		}
+	})
+}
+
+func request(t *testing.T, timeout time.Duration, store *slowClient, path string) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, store.factory)
+		c.Next()
+	})
+
+	rg := r.Group("/api")
+	rg.Use(middleware.Timeout(timeout))
+	api.SetupHoge(rg)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+	return w.Code
+}
+
+// slowClient はGetがdelayだけ待機するClient
+// delayが0の場合はcontextが終了するまで待機する
+type slowClient struct {
+	ds.Client
+	ctx   context.Context
+	delay time.Duration
+	err   error
+}
+
+func (c *slowClient) factory(r *http.Request) ds.Client {
+	c.ctx = r.Context()
+	return c
+}
+
+func (c *slowClient) Context() context.Context {
+	return c.ctx
+}
+
+func (c *slowClient) Get(dst interface{}) error {
+	var after <-chan time.Time
+	if c.delay > 0 {
+		after = time.After(c.delay)
+	}
+
+	select {
+	case <-after:
+		return nil
+	case <-c.ctx.Done():
+		c.err = c.ctx.Err()
+		return c.err
+	}
+}
@@ -0,0 +1,254 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HogeChangeRetention は変更履歴を保持する期間
+const HogeChangeRetention = 24 * time.Hour
+
+// HogeChangeCompactAfter は変更履歴をHogeChangeStore.Compactで圧縮するまでの期間
+const HogeChangeCompactAfter = time.Hour
+
+// MaxHogeChanges はHogeChangeStore.Listで取得する件数の上限
+const MaxHogeChanges = 100
+
+// HogeChangeReadLag はHogeChangeStore.Listで読み込む変更履歴を現在時刻から遅らせる時間
+//
+// 変更履歴の位置は変更したインスタンスの時刻で決まるため、インスタンス間の時刻のずれやコミットの遅延により、
+// 読み込んだ位置より前の変更が後からコミットされることがある。この時間より前の変更のみ読み込むことで読み飛ばしを防ぐ
+var HogeChangeReadLag = 5 * time.Second
+
+// ErrInvalidHogeChangePosition は変更履歴の位置の形式が不正な場合のエラー
+var ErrInvalidHogeChangePosition = errors.New("invalid change position")
+
+// HogeChangeStore はHogeの変更履歴を操作するメソッドをまとめる
+type HogeChangeStore struct{}
+
+// HogeChange はHogeの変更履歴
+type HogeChange struct {
+	// ID はPositionと同じ文字列
+	ID string `json:"-" datastore:"-" goon:"id"`
+	// Position は変更履歴の位置。CreatedAtとイベントのIDから求め、文字列の順が変更の順となる
+	Position  string          `json:"position"`
+	Type      EventType       `json:"type" datastore:",noindex"`
+	HogeID    string          `json:"hogeId" datastore:",noindex"`
+	Event     json.RawMessage `json:"event" datastore:",noindex"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// hogeChangePosition はtのUnix時間(ナノ秒)を20桁で0埋めした文字列とeventIDを"-"で連結した位置を返す
+//
+// 同じ時刻の変更はイベントのIDの順とする。eventIDが空の場合は、その時刻の全ての変更より前の位置となる
+func hogeChangePosition(t time.Time, eventID string) string {
+	return fmt.Sprintf("%020d-%s", t.UnixNano(), eventID)
+}
+
+// parseHogeChangePosition は変更履歴の位置の時刻を返す
+func parseHogeChangePosition(position string) (time.Time, error) {
+	i := strings.Index(position, "-")
+	if i != 20 {
+		return time.Time{}, ErrInvalidHogeChangePosition
+	}
+
+	n, err := strconv.ParseInt(position[:i], 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}, ErrInvalidHogeChangePosition
+	}
+
+	return time.Unix(0, n), nil
+}
+
+// Append はeventを変更履歴として保存する
+//
+// gはRunInTransactionのトランザクション内のClientとし、変更と共にコミットする
+// 位置はイベントの時刻で決まるため、変更履歴同士は競合しない
+func (store *HogeChangeStore) Append(g ds.Client, event *HogeEvent) (*HogeChange, error) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	position := hogeChangePosition(event.OccurredAt, event.ID)
+
+	change := &HogeChange{
+		ID:        position,
+		Position:  position,
+		Type:      event.Type,
+		HogeID:    event.Hoge.ID,
+		Event:     b,
+		CreatedAt: event.OccurredAt,
+	}
+	if err := g.Put(change); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// Latest は現在読み込める最後の位置を返す。この位置から読み込むと、これ以降の変更のみ取得する
+func (store *HogeChangeStore) Latest() string {
+	return hogeChangePosition(time.Now().Add(-HogeChangeReadLag), "")
+}
+
+// Expired はpositionより後の変更履歴が削除されている可能性があるか判定する
+//
+// positionの形式が不正な場合はErrInvalidHogeChangePositionを返す
+func (store *HogeChangeStore) Expired(position string) (bool, error) {
+	t, err := parseHogeChangePosition(position)
+	if err != nil {
+		return false, err
+	}
+
+	return t.Before(time.Now().Add(-HogeChangeRetention)), nil
+}
+
+// List はafterより後の変更履歴を位置の順に最大MaxHogeChanges件取得する
+//
+// HogeChangeReadLagより前にコミットした変更履歴のみ取得する
+// 取得した件数がMaxHogeChanges未満の場合は、読み込める最後の位置まで取得している
+func (store *HogeChangeStore) List(g ds.Client, after string) ([]*HogeChange, error) {
+	q := ds.NewQuery(g.Kind(HogeChange{})).
+		Filter("Position", ">", after).
+		Filter("Position", "<=", store.Latest()).
+		Order("Position").
+		Limit(MaxHogeChanges)
+
+	it := g.Run(q)
+
+	list := []*HogeChange{}
+	for {
+		change := &HogeChange{}
+		id, err := it.Next(change)
+		if err == ds.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		change.ID = id
+		list = append(list, change)
+	}
+
+	return list, nil
+}
+
+// Purge はbeforeより前の変更履歴を削除し、削除した件数を返す
+func (store *HogeChangeStore) Purge(g ds.Client, before time.Time) (int, error) {
+	q := ds.NewQuery(g.Kind(HogeChange{})).Filter("CreatedAt", "<", before).Limit(purgeBatchSize)
+
+	purged := 0
+	cursor := ""
+
+	for {
+		if cursor != "" {
+			q = q.Start(cursor)
+		}
+
+		it := g.Run(q)
+
+		var list []*HogeChange
+		for {
+			change := &HogeChange{}
+			id, err := it.Next(change)
+			if err == ds.Done {
+				break
+			}
+			if err != nil {
+				return purged, err
+			}
+
+			change.ID = id
+			list = append(list, change)
+		}
+
+		if len(list) == 0 {
+			return purged, nil
+		}
+
+		if err := Retry(g.Context(), "hogechange.purge", func() error {
+			return g.DeleteMulti(list)
+		}); err != nil {
+			return purged, err
+		}
+
+		purged += len(list)
+
+		if len(list) < purgeBatchSize {
+			return purged, nil
+		}
+
+		next, err := it.Cursor()
+		if err != nil {
+			return purged, err
+		}
+
+		cursor = next
+	}
+}
+
+// Compact はbeforeより前の変更履歴のうち、同じHogeのより新しい変更履歴がbeforeより前に存在するものを削除し、削除した件数を返す
+//
+// 圧縮した範囲から読み込むと途中の変更は読み飛ばすが、各Hogeのその範囲の最後の変更は読み込めるため、最終的な状態は変わらない
+// 範囲内のHogeのIDを保持するため、メモリの使用量はHogeの件数に比例する。何度実行しても結果は変わらない
+func (store *HogeChangeStore) Compact(g ds.Client, before time.Time) (int, error) {
+	// 新しい順に読み込み、既に読み込んだHogeの変更履歴を削除する
+	seen := map[string]bool{}
+
+	compacted := 0
+	last := hogeChangePosition(before, "")
+
+	for {
+		// 削除しながら読み進めるため、Cursorではなく最後に読み込んだ位置から続きを取得する
+		q := ds.NewQuery(g.Kind(HogeChange{})).
+			Filter("Position", "<", last).
+			Order("-Position").
+			Limit(purgeBatchSize)
+
+		it := g.Run(q)
+
+		n := 0
+		var list []*HogeChange
+		for {
+			change := &HogeChange{}
+			id, err := it.Next(change)
+			if err == ds.Done {
+				break
+			}
+			if err != nil {
+				return compacted, err
+			}
+
+			n++
+			last = change.Position
+
+			if seen[change.HogeID] {
+				list = append(list, &HogeChange{ID: id})
+				continue
+			}
+
+			seen[change.HogeID] = true
+		}
+
+		if len(list) > 0 {
+			if err := Retry(g.Context(), "hogechange.compact", func() error {
+				return g.DeleteMulti(list)
+			}); err != nil {
+				return compacted, err
+			}
+
+			compacted += len(list)
+		}
+
+		if n < purgeBatchSize {
+			return compacted, nil
+		}
+	}
+}
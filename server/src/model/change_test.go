@@ -0,0 +1,192 @@
+package model_test
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// appendChange はidのHogeの変更をgに保存する
+func appendChange(t *testing.T, g ds.Client, typ model.EventType, id string) *model.HogeChange {
+	event, err := model.NewHogeEvent(typ, &model.Hoge{ID: id})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	store := &model.HogeChangeStore{}
+	change, err := store.Append(g, event)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return change
+}
+
+func TestHogeChangeStore(t *testing.T) {
+	defer func(lag time.Duration) { model.HogeChangeReadLag = lag }(model.HogeChangeReadLag)
+	model.HogeChangeReadLag = 0
+
+	store := &model.HogeChangeStore{}
+
+	t.Run("変更履歴が存在しない場合、空の一覧となること", func(t *testing.T) {
+		list, err := store.List(dstest.NewClient(), "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(list) != 0 {
+			t.Errorf("List: unexpected, actual: `%d`", len(list))
+		}
+	})
+
+	t.Run("位置の順に、指定した位置より後の変更履歴が取得できること", func(t *testing.T) {
+		g := dstest.NewClient()
+
+		var changes []*model.HogeChange
+		for _, id := range []string{"hoge0", "hoge1", "hoge2"} {
+			changes = append(changes, appendChange(t, g, model.HogeUpdated, id))
+		}
+
+		list, err := store.List(g, changes[0].Position)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(list) != 2 {
+			t.Fatalf("List: unexpected, actual: `%d`", len(list))
+		}
+		if list[0].HogeID != "hoge1" || list[1].HogeID != "hoge2" {
+			t.Errorf("List: unexpected, actual: `%s`, `%s`", list[0].HogeID, list[1].HogeID)
+		}
+		if list[0].ID != changes[1].Position {
+			t.Errorf("ID: unexpected, actual: `%s`, expected: `%s`", list[0].ID, changes[1].Position)
+		}
+	})
+
+	t.Run("HogeChangeReadLagより後の変更履歴は取得されないこと", func(t *testing.T) {
+		defer func(lag time.Duration) { model.HogeChangeReadLag = lag }(model.HogeChangeReadLag)
+		model.HogeChangeReadLag = time.Hour
+
+		g := dstest.NewClient()
+		appendChange(t, g, model.HogeCreated, "hoge")
+
+		list, err := store.List(g, "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(list) != 0 {
+			t.Errorf("List: unexpected, actual: `%d`", len(list))
+		}
+	})
+
+	t.Run("MaxHogeChanges件ずつ、続きから取得できること", func(t *testing.T) {
+		g := dstest.NewClient()
+
+		last := store.Latest()
+		for i := 0; i < model.MaxHogeChanges+1; i++ {
+			appendChange(t, g, model.HogeCreated, "hoge")
+		}
+
+		list, err := store.List(g, last)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(list) != model.MaxHogeChanges {
+			t.Fatalf("List: unexpected, actual: `%d`", len(list))
+		}
+
+		list, err = store.List(g, list[len(list)-1].Position)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(list) != 1 {
+			t.Fatalf("List: unexpected, actual: `%d`", len(list))
+		}
+	})
+
+	t.Run("保持期間より前の位置の場合、Expiredがtrueとなること", func(t *testing.T) {
+		for _, tc := range []struct {
+			position string
+			expired  bool
+		}{
+			{"00000000000000000001-hoge", true},
+			{store.Latest(), false},
+		} {
+			expired, err := store.Expired(tc.position)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if expired != tc.expired {
+				t.Errorf("Expired(%s): unexpected, actual: `%t`, expected: `%t`", tc.position, expired, tc.expired)
+			}
+		}
+
+		for _, position := range []string{"", "1", "abcdefghijklmnopqrst-hoge"} {
+			if _, err := store.Expired(position); err != model.ErrInvalidHogeChangePosition {
+				t.Errorf("Expired(%s): unexpected, actual: `%v`", position, err)
+			}
+		}
+	})
+}
+
+func TestHogeChangeStore_Compact(t *testing.T) {
+	defer func(lag time.Duration) { model.HogeChangeReadLag = lag }(model.HogeChangeReadLag)
+	model.HogeChangeReadLag = 0
+
+	g := dstest.NewClient()
+	store := &model.HogeChangeStore{}
+
+	old := time.Now().Add(-model.HogeChangeCompactAfter - time.Hour)
+	appendAt := func(typ model.EventType, id string, at time.Time) *model.HogeChange {
+		event, err := model.NewHogeEvent(typ, &model.Hoge{ID: id})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		event.OccurredAt = at
+
+		change, err := store.Append(g, event)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return change
+	}
+
+	appendAt(model.HogeCreated, "hoge1", old)
+	appendAt(model.HogeUpdated, "hoge1", old.Add(1*time.Millisecond))
+	last1 := appendAt(model.HogeUpdated, "hoge1", old.Add(2*time.Millisecond))
+	last2 := appendAt(model.HogeCreated, "hoge2", old.Add(3*time.Millisecond))
+
+	// 1回のクエリの件数を超える変更履歴
+	var last3 *model.HogeChange
+	for i := 0; i < 150; i++ {
+		last3 = appendAt(model.HogeUpdated, "hoge3", old.Add(time.Duration(10+i)*time.Millisecond))
+	}
+
+	recent := appendAt(model.HogeUpdated, "hoge1", time.Now().Add(-time.Minute))
+
+	for _, expected := range []int{151, 0} {
+		compacted, err := store.Compact(g, time.Now().Add(-model.HogeChangeCompactAfter))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if compacted != expected {
+			t.Errorf("compacted: unexpected, actual: `%d`, expected: `%d`", compacted, expected)
+		}
+	}
+
+	list, err := store.List(g, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var positions []string
+	for _, change := range list {
+		positions = append(positions, change.Position)
+	}
+	expected := []string{last1.Position, last2.Position, last3.Position, recent.Position}
+	if strings.Join(positions, ",") != strings.Join(expected, ",") {
+		t.Errorf("positions: unexpected, actual: `%v`, expected: `%v`", positions, expected)
+	}
+}
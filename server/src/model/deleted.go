@@ -0,0 +1,91 @@
+package model
+
+import (
+	"gaego-gin/server/src/ds"
+	"time"
+)
+
+// DeletedHogeRetention は削除したHogeを保持する期間
+const DeletedHogeRetention = 30 * 24 * time.Hour
+
+// DeletedHoge は削除したHoge。DeletedHogeRetentionを経過するまで復旧用に保持する
+//
+// IDは削除したHogeのIDとし、同じIDのHogeを再度削除した場合は上書きする
+type DeletedHoge struct {
+	ID        string    `json:"id" datastore:"-" goon:"id"`
+	Value     string    `json:"value" datastore:",noindex"`
+	CreatedAt time.Time `json:"createdAt" datastore:",noindex"`
+	UpdatedAt time.Time `json:"updatedAt" datastore:",noindex"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// DeletedHogeStore は削除したHogeを操作するメソッドをまとめる
+type DeletedHogeStore struct{}
+
+// Get は削除したHogeを1件取得する
+func (store *DeletedHogeStore) Get(g ds.Client, id string) (*DeletedHoge, error) {
+	if id == "" {
+		return nil, ErrIDRequired
+	}
+
+	deleted := &DeletedHoge{
+		ID: id,
+	}
+	if err := g.Get(deleted); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// Purge はbeforeより前に削除したHogeを削除し、削除した件数を返す
+func (store *DeletedHogeStore) Purge(g ds.Client, before time.Time) (int, error) {
+	q := ds.NewQuery(g.Kind(DeletedHoge{})).Filter("DeletedAt", "<", before).KeysOnly().Limit(purgeBatchSize)
+
+	purged := 0
+	cursor := ""
+
+	for {
+		if cursor != "" {
+			q = q.Start(cursor)
+		}
+
+		it := g.Run(q)
+
+		var list []*DeletedHoge
+		for {
+			id, err := it.Next(nil)
+			if err == ds.Done {
+				break
+			}
+			if err != nil {
+				return purged, err
+			}
+
+			list = append(list, &DeletedHoge{ID: id})
+		}
+
+		if len(list) == 0 {
+			return purged, nil
+		}
+
+		if err := Retry(g.Context(), "deletedhoge.purge", func() error {
+			return g.DeleteMulti(list)
+		}); err != nil {
+			return purged, err
+		}
+
+		purged += len(list)
+
+		if len(list) < purgeBatchSize {
+			return purged, nil
+		}
+
+		next, err := it.Cursor()
+		if err != nil {
+			return purged, err
+		}
+
+		cursor = next
+	}
+}
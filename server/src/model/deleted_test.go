@@ -0,0 +1,92 @@
+package model_test
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"testing"
+	"time"
+)
+
+func TestHogeStore_Delete(t *testing.T) {
+	store := &model.HogeStore{}
+	deletedStore := &model.DeletedHogeStore{}
+
+	t.Run("削除したHogeがDeletedHogeとして保持されること", func(t *testing.T) {
+		createdAt := time.Now().Add(-time.Hour)
+		g := newHogeClient(t, &model.Hoge{ID: "hoge", Value: "hogehoge", CreatedAt: createdAt, UpdatedAt: createdAt})
+
+		hoge, err := store.Delete(g, "hoge")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if hoge == nil || hoge.Value != "hogehoge" {
+			t.Errorf("hoge: unexpected, actual: `%+v`", hoge)
+		}
+
+		if _, err := store.Get(g, "hoge"); err != ds.ErrNoSuchEntity {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, ds.ErrNoSuchEntity)
+		}
+
+		deleted, err := deletedStore.Get(g, "hoge")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if deleted.Value != "hogehoge" {
+			t.Errorf("Value: unexpected, actual: `%s`, expected: `%s`", deleted.Value, "hogehoge")
+		}
+		if !deleted.CreatedAt.Equal(createdAt) {
+			t.Errorf("CreatedAt: unexpected, actual: `%v`, expected: `%v`", deleted.CreatedAt, createdAt)
+		}
+		if deleted.DeletedAt.IsZero() {
+			t.Errorf("DeletedAt: unexpected, actual: `%v`", deleted.DeletedAt)
+		}
+	})
+
+	t.Run("存在しないHogeの場合、エラーとならずnilを返し、DeletedHogeも保存されないこと", func(t *testing.T) {
+		g := dstest.NewClient()
+
+		hoge, err := store.Delete(g, "hoge")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if hoge != nil {
+			t.Errorf("hoge: unexpected, actual: `%+v`", hoge)
+		}
+
+		if _, err := deletedStore.Get(g, "hoge"); err != ds.ErrNoSuchEntity {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, ds.ErrNoSuchEntity)
+		}
+	})
+}
+
+func TestDeletedHogeStore_Purge(t *testing.T) {
+	g := dstest.NewClient()
+
+	old := time.Now().Add(-model.DeletedHogeRetention - time.Hour)
+	for _, deleted := range []*model.DeletedHoge{
+		{ID: "old1", DeletedAt: old},
+		{ID: "old2", DeletedAt: old},
+		{ID: "recent", DeletedAt: time.Now()},
+	} {
+		if err := g.Put(deleted); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	store := &model.DeletedHogeStore{}
+
+	for _, expected := range []int{2, 0} {
+		purged, err := store.Purge(g, time.Now().Add(-model.DeletedHogeRetention))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if purged != expected {
+			t.Errorf("purged: unexpected, actual: `%d`, expected: `%d`", purged, expected)
+		}
+	}
+
+	if _, err := store.Get(g, "recent"); err != nil {
+		t.Errorf("recent: unexpected, actual: `%v`", err)
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"context"
+	"gaego-gin/server/src/ds"
+)
+
+// ErrorKind はHTTPのAPIとgRPCで共通のエラーの分類
+//
+// HTTPのステータスコードとgRPCのステータスコードはこの分類から決める
+type ErrorKind int
+
+const (
+	// ErrorInternal は他のいずれにも該当しないエラー
+	ErrorInternal ErrorKind = iota
+	// ErrorInvalidArgument は引数やリクエストが不正な場合のエラー
+	ErrorInvalidArgument
+	// ErrorNotFound は対象が存在しない場合のエラー
+	ErrorNotFound
+	// ErrorAlreadyExists は作成する対象が既に存在する場合のエラー
+	ErrorAlreadyExists
+	// ErrorAborted はトランザクションが競合した場合のエラー
+	ErrorAborted
+	// ErrorUnavailable は一時的なエラー。リトライの上限に達した場合を含む
+	ErrorUnavailable
+	// ErrorDeadlineExceeded は処理時間の上限を超えた場合のエラー
+	ErrorDeadlineExceeded
+	// ErrorCanceled は呼び出し元がキャンセルした場合のエラー
+	ErrorCanceled
+)
+
+// ClassifyError はmodelとdsのエラーの分類を返す
+//
+// リトライの上限に達した場合はトランザクションの競合をErrorAborted、それ以外をErrorUnavailableとする
+func ClassifyError(err error) ErrorKind {
+	if rerr, ok := err.(*RetryError); ok {
+		if rerr.Err == ds.ErrConcurrentTransaction {
+			return ErrorAborted
+		}
+
+		return ErrorUnavailable
+	}
+
+	if ds.IsTransient(err) {
+		return ErrorUnavailable
+	}
+
+	switch err {
+	case ds.ErrNoSuchEntity:
+		return ErrorNotFound
+	case ErrAlreadyExists:
+		return ErrorAlreadyExists
+	case ds.ErrConcurrentTransaction:
+		return ErrorAborted
+	case ErrIDRequired, ErrInvalidSort, ErrInvalidLimit, ErrInvalidConflictPolicy,
+		ErrInvalidWebhookURL, ErrInsecureWebhookURL, ErrWebhookAddressNotAllowed, ErrInvalidEventType,
+		ErrInvalidPageToken, ErrPageTokenMismatch, ErrPageTokenExpired, ds.ErrInvalidCursor:
+		return ErrorInvalidArgument
+	case context.DeadlineExceeded:
+		return ErrorDeadlineExceeded
+	case context.Canceled:
+		return ErrorCanceled
+	}
+
+	return ErrorInternal
+}
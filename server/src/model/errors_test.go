@@ -0,0 +1,34 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		err      error
+		expected model.ErrorKind
+	}{
+		{"存在しない場合はErrorNotFoundとなること", ds.ErrNoSuchEntity, model.ErrorNotFound},
+		{"既に存在する場合はErrorAlreadyExistsとなること", model.ErrAlreadyExists, model.ErrorAlreadyExists},
+		{"トランザクションの競合はErrorAbortedとなること", ds.ErrConcurrentTransaction, model.ErrorAborted},
+		{"競合でリトライの上限に達した場合はErrorAbortedとなること", &model.RetryError{Attempts: 5, Err: ds.ErrConcurrentTransaction}, model.ErrorAborted},
+		{"一時的なエラーでリトライの上限に達した場合はErrorUnavailableとなること", &model.RetryError{Attempts: 5, Err: &ds.TransientError{Err: errors.New("unavailable")}}, model.ErrorUnavailable},
+		{"一時的なエラーはErrorUnavailableとなること", &ds.TransientError{Err: errors.New("unavailable")}, model.ErrorUnavailable},
+		{"不正なページトークンはErrorInvalidArgumentとなること", model.ErrInvalidPageToken, model.ErrorInvalidArgument},
+		{"タイムアウトはErrorDeadlineExceededとなること", context.DeadlineExceeded, model.ErrorDeadlineExceeded},
+		{"キャンセルはErrorCanceledとなること", context.Canceled, model.ErrorCanceled},
+		{"それ以外のエラーはErrorInternalとなること", errors.New("unknown"), model.ErrorInternal},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			if actual := model.ClassifyError(tc.err); actual != tc.expected {
+				t.Errorf("kind: unexpected, actual: `%d`, expected: `%d`", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// EventType はHogeの変更の種類
+type EventType string
+
+const (
+	// HogeCreated はHogeを新規作成した
+	HogeCreated EventType = "hoge.created"
+	// HogeUpdated はHogeを更新した
+	HogeUpdated EventType = "hoge.updated"
+	// HogeDeleted はHogeを削除した
+	HogeDeleted EventType = "hoge.deleted"
+)
+
+// EventTypes は全てのEventType
+var EventTypes = []EventType{HogeCreated, HogeUpdated, HogeDeleted}
+
+// Valid はEventTypeが定義済みか判定する
+func (t EventType) Valid() bool {
+	for _, v := range EventTypes {
+		if t == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HogeEvent はHogeの変更を通知するイベント
+type HogeEvent struct {
+	// ID はイベントの識別子。受信側で重複を取り除くために利用する
+	ID         string    `json:"id"`
+	Type       EventType `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	// Hoge は変更後のHoge。削除の場合は削除したHoge
+	Hoge *Hoge `json:"hoge"`
+}
+
+// NewHogeEvent はhogeの変更を通知するHogeEventを生成する
+func NewHogeEvent(typ EventType, hoge *Hoge) (*HogeEvent, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &HogeEvent{
+		ID:         id,
+		Type:       typ,
+		OccurredAt: time.Now(),
+		Hoge:       hoge,
+	}, nil
+}
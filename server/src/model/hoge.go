@@ -2,10 +2,21 @@ package model
 
 import (
 	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/logger"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+)
+
+var log = logger.New("model")
 
-	"github.com/mjibson/goon"
-	"google.golang.org/appengine/datastore"
+var (
+	// ErrAlreadyExists は同じIDのentityが既に存在する場合のエラー
+	ErrAlreadyExists = errors.New("already exist")
+	// ErrIDRequired はIDが指定されていない場合のエラー
+	ErrIDRequired = errors.New("id is required")
 )
 
 // HogeStore はHogeを操作するメソッドをまとめる
@@ -19,73 +30,67 @@ type Hoge struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
-// Load はPropertyLoadSaverのインターフェースを実装する
-func (src *Hoge) Load(p []datastore.Property) error {
-	if err := datastore.LoadStruct(src, p); err != nil {
-		return err
+// Validate はHogeを保存できるか検証する
+func (src *Hoge) Validate() error {
+	if src.ID == "" {
+		return ErrIDRequired
 	}
 
 	return nil
 }
 
-// Save はPropertyLoadSaverのインターフェースを実装する
-func (src *Hoge) Save() ([]datastore.Property, error) {
-	src.UpdatedAt = time.Now()
-
-	p, err := datastore.SaveStruct(src)
-	if err != nil {
-		return nil, err
-	}
-
-	return p, nil
-}
-
 // Insert はHogeを新規登録する
-func (src *Hoge) Insert(g *goon.Goon) error {
-	if src.ID == "" {
-		return errors.New("id is required")
+func (src *Hoge) Insert(g ds.Client) error {
+	if err := src.Validate(); err != nil {
+		return err
 	}
 
 	old := &Hoge{
 		ID: src.ID,
 	}
 	if err := g.Get(old); err != nil {
-		if err == datastore.ErrNoSuchEntity {
+		if err == ds.ErrNoSuchEntity {
 			// OK!
 		} else {
 			return err
 		}
 	} else {
-		return errors.New("already exist")
+		log.Debugf(g.Context(), "hoge %q already exists", src.ID)
+		return ErrAlreadyExists
 	}
 
 	return src.put(g, nil)
 }
 
 // Update はHogeを更新する
-func (src *Hoge) Update(g *goon.Goon) error {
-	if src.ID == "" {
-		return errors.New("id is required")
+func (src *Hoge) Update(g ds.Client) error {
+	if err := src.Validate(); err != nil {
+		return err
 	}
 
 	old := &Hoge{
 		ID: src.ID,
 	}
 	if err := g.Get(old); err != nil {
+		if err == ds.ErrNoSuchEntity {
+			log.Debugf(g.Context(), "hoge %q does not exist", src.ID)
+		}
+
 		return err
 	}
 
 	return src.put(g, old)
 }
 
-func (src *Hoge) put(g *goon.Goon, old *Hoge) error {
+func (src *Hoge) put(g ds.Client, old *Hoge) error {
 	src.CreatedAt = time.Now()
+	src.UpdatedAt = src.CreatedAt
 
 	if old != nil {
 		src.CreatedAt = old.CreatedAt
 	}
 
-	if _, err := g.Put(src); err != nil {
+	if err := g.Put(src); err != nil {
 		return err
 	}
 
@@ -93,9 +98,9 @@ func (src *Hoge) put(g *goon.Goon, old *Hoge) error {
 }
 
 // Get はHogeを1件取得する
-func (store *HogeStore) Get(g *goon.Goon, id string) (*Hoge, error) {
+func (store *HogeStore) Get(g ds.Client, id string) (*Hoge, error) {
 	if id == "" {
-		return nil, errors.New("id is required")
+		return nil, ErrIDRequired
 	}
 
 	hoge := &Hoge{
@@ -110,92 +115,353 @@ func (store *HogeStore) Get(g *goon.Goon, id string) (*Hoge, error) {
 
 // HogeListResp はHoge一覧取得のレスポンス
 type HogeListResp struct {
-	List   []*Hoge `json:"list"`
-	Cursor string  `json:"cursor"`
+	List []*Hoge `json:"list"`
+	// Cursor は次のページのページトークン。次のページが存在しない場合は空
+	Cursor string `json:"cursor"`
+	// PrevCursor は前のページのページトークン。前のページが存在しない場合は空
+	PrevCursor string `json:"prevCursor"`
+	// Total は条件に一致するHogeの総数。HogeQuery.Countがtrueの場合のみ設定する
+	Total *int `json:"total,omitempty"`
+	// TotalCapped がtrueの場合は、総数がMaxHogeCountを超えるためTotalをMaxHogeCountで打ち切ったことを表す
+	TotalCapped bool `json:"totalCapped,omitempty"`
+}
+
+// HogeSearchResp はHoge検索のレスポンス
+type HogeSearchResp struct {
+	List []*Hoge `json:"list"`
+	// Cursor は次のページのカーソル。次のページが存在しない場合は空
+	Cursor string `json:"cursor"`
+	// Total は検索条件に一致するHogeの件数。件数が多い場合は概算となる
+	Total int `json:"total"`
+}
+
+var (
+	// ErrInvalidSort はソート順の指定が不正な場合のエラー
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrInvalidLimit は取得件数の指定が不正な場合のエラー
+	ErrInvalidLimit = errors.New("invalid limit")
+)
+
+const (
+	// DefaultHogeListLimit は取得件数を省略した場合のHoge一覧の取得件数
+	DefaultHogeListLimit = 10
+	// MaxHogeListLimit はHoge一覧の1ページあたりの取得件数の上限
+	MaxHogeListLimit = 100
+	// MaxHogeCount はHoge一覧の総数を数える件数の上限
+	MaxHogeCount = 1000
+)
+
+// hogeSortProperties はHoge一覧のソート順に指定できるJSONのフィールド名とDatastoreのプロパティ名
+var hogeSortProperties = map[string]string{
+	"id":        "__key__",
+	"createdAt": "CreatedAt",
+	"updatedAt": "UpdatedAt",
+}
+
+// HogeQuery はHoge一覧取得の条件
+type HogeQuery struct {
+	// Cursor は前回の一覧取得で返されたページトークン
+	Cursor string
+	// Limit は取得件数。0の場合はDefaultHogeListLimit件で、MaxHogeListLimitを超える場合はMaxHogeListLimit件に切り詰める
+	Limit int
+	// Sort はソート順。"id", "createdAt", "updatedAt"のいずれかで、降順の場合は先頭に"-"を付ける
+	Sort string
+	// Value が空でない場合は、Valueが一致するHogeのみを対象とする
+	Value string
+	// Count がtrueの場合は、条件に一致するHogeの総数をHogeListResp.Totalに設定する。総数はMaxHogeCountで打ち切る
+	Count bool
+}
+
+// normalize は既定値を補完したHogeQueryを返す
+func (hq HogeQuery) normalize() (*HogeQuery, error) {
+	switch {
+	case hq.Limit < 0:
+		return nil, ErrInvalidLimit
+	case hq.Limit == 0:
+		hq.Limit = DefaultHogeListLimit
+	case hq.Limit > MaxHogeListLimit:
+		hq.Limit = MaxHogeListLimit
+	}
+
+	if hq.Sort == "" {
+		hq.Sort = "id"
+	}
+	if _, ok := hogeSortProperties[strings.TrimPrefix(hq.Sort, "-")]; !ok {
+		return nil, ErrInvalidSort
+	}
+
+	return &hq, nil
+}
+
+// signature はページトークンに紐づけるクエリの条件
+func (hq *HogeQuery) signature() string {
+	vs := url.Values{}
+	vs.Set("limit", strconv.Itoa(hq.Limit))
+	vs.Set("sort", hq.Sort)
+	vs.Set("value", hq.Value)
+
+	return vs.Encode()
+}
+
+// query はLimitとCursorを除いた条件でHogeを取得するクエリを返す
+//
+// reverseがtrueの場合は逆順に並べる。前後どちらに読み進めても同じ順序となるよう、
+// ID以外でソートする場合は同じ値のHogeをIDで並べる
+func (hq *HogeQuery) query(g ds.Client, reverse bool) *ds.Query {
+	q := ds.NewQuery(g.Kind(Hoge{}))
+
+	if hq.Value != "" {
+		q = q.Filter("Value", "=", hq.Value)
+	}
+
+	desc := strings.HasPrefix(hq.Sort, "-")
+	orders := []string{hogeSortProperties[strings.TrimPrefix(hq.Sort, "-")]}
+	if orders[0] != "__key__" {
+		orders = append(orders, "__key__")
+	}
+
+	for i, o := range orders {
+		// 2番目以降のIDは常に昇順
+		if (i == 0 && desc) != reverse {
+			o = "-" + o
+		}
+
+		q = q.Order(o)
+	}
+
+	return q
 }
 
 // List はHogeの一覧を取得する
-func (store *HogeStore) List(g *goon.Goon, cursor string, limit int) (*HogeListResp, error) {
-	q := datastore.NewQuery(g.Kind(Hoge{})).KeysOnly()
+//
+// 前のページは、ページトークンのCursorから逆順のクエリを実行して取得する
+func (store *HogeStore) List(g ds.Client, hq HogeQuery) (*HogeListResp, error) {
+	cond, err := hq.normalize()
+	if err != nil {
+		return nil, err
+	}
 
-	if limit == 0 {
-		limit = 10
+	var start string
+	backward := false
+	if cond.Cursor != "" {
+		start, backward, err = DefaultPageTokenCodec.Decode(cond.Cursor, cond.signature())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	list, cur, hasMore, err := store.page(g, cond, start, backward)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := g.GetMulti(list); err != nil {
+		return nil, err
 	}
-	if limit != -1 {
-		// 次の1件が存在するかを確認するため、1件多く取得する
-		q = q.Limit(limit + 1)
+
+	resp := &HogeListResp{
+		List: list,
 	}
 
-	if cursor != "" {
-		start, err := datastore.DecodeCursor(cursor)
+	if backward {
+		// 逆順に取得したため、元の順序に戻す
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+
+		// 次のページは開始位置のCursorから読み進める
+		resp.Cursor = DefaultPageTokenCodec.Encode(start, cond.signature(), false)
+		if hasMore {
+			resp.PrevCursor = DefaultPageTokenCodec.Encode(cur, cond.signature(), true)
+		}
+	} else {
+		if hasMore {
+			resp.Cursor = DefaultPageTokenCodec.Encode(cur, cond.signature(), false)
+		}
+		// 前のページは開始位置のCursorから逆順に読み進める
+		if start != "" {
+			resp.PrevCursor = DefaultPageTokenCodec.Encode(start, cond.signature(), true)
+		}
+	}
+
+	if cond.Count {
+		total, capped, err := store.count(g, cond)
 		if err != nil {
 			return nil, err
 		}
 
+		resp.Total = &total
+		resp.TotalCapped = capped
+	}
+
+	return resp, nil
+}
+
+// page はstartからLimit件のキーを取得し、Limit件目の位置のCursorと続きが存在するかを返す
+func (store *HogeStore) page(g ds.Client, cond *HogeQuery, start string, reverse bool) (list []*Hoge, cur string, hasMore bool, err error) {
+	limit := cond.Limit
+
+	// 次の1件が存在するかを確認するため、1件多く取得する
+	q := cond.query(g, reverse).KeysOnly().Limit(limit + 1)
+	if start != "" {
 		q = q.Start(start)
 	}
 
 	it := g.Run(q)
 
-	count := 0
-	hasNext := false
-	var cur datastore.Cursor
-	list := make([]*Hoge, 0, limit)
+	list = make([]*Hoge, 0, limit)
 
 	for {
-		key, err := it.Next(nil)
+		id, err := it.Next(nil)
 		if err != nil {
-			if err == datastore.Done {
+			if err == ds.Done {
 				break
 			}
 
-			return nil, err
+			return nil, "", false, err
 		}
 
-		count++
-		if limit != -1 && limit < count {
-			hasNext = true
+		if len(list) == limit {
+			hasMore = true
 			break
 		}
 
-		list = append(list, &Hoge{ID: key.StringID()})
+		list = append(list, &Hoge{ID: id})
 
 		// limitで指定した件数に到達したところでCursorを保存
-		if limit == count {
+		if len(list) == limit {
 			cur, err = it.Cursor()
 			if err != nil {
-				return nil, err
+				return nil, "", false, err
 			}
 		}
 	}
 
-	if err := g.GetMulti(list); err != nil {
-		return nil, err
+	return list, cur, hasMore, nil
+}
+
+// count はCursorとLimitを除いた条件に一致するHogeの件数をキーのみのクエリで数える
+//
+// 読み込むキーはMaxHogeCount件までとし、それを超える場合はMaxHogeCountとcapped=trueを返す
+func (store *HogeStore) count(g ds.Client, cond *HogeQuery) (total int, capped bool, err error) {
+	// 上限を超えるかを確認するため、1件多く取得する
+	it := g.Run(cond.query(g, false).KeysOnly().Limit(MaxHogeCount + 1))
+
+	for {
+		if _, err := it.Next(nil); err != nil {
+			if err == ds.Done {
+				return total, false, nil
+			}
+
+			return 0, false, err
+		}
+
+		if total == MaxHogeCount {
+			return MaxHogeCount, true, nil
+		}
+
+		total++
 	}
+}
 
-	resp := &HogeListResp{
-		List: list,
+// Export はSortとValueの条件に一致する全てのHogeを順にfに渡す
+//
+// MaxHogeListLimit件ずつCursorで読み進めるため、件数に関わらずメモリの使用量は一定となる
+// 各バッチの最後のHogeには続きから再開するためのページトークンをcursorとして渡し、それ以外は空とする
+// hq.Cursorにページトークンを指定した場合はその位置から再開する。hq.Limitは無視する
+// fがエラーを返した場合はその時点で中断し、そのエラーを返す
+func (store *HogeStore) Export(g ds.Client, hq HogeQuery, f func(hoge *Hoge, cursor string) error) error {
+	hq.Limit = MaxHogeListLimit
+
+	cond, err := hq.normalize()
+	if err != nil {
+		return err
 	}
 
-	if hasNext {
-		resp.Cursor = cur.String()
+	var start string
+	if cond.Cursor != "" {
+		var backward bool
+		start, backward, err = DefaultPageTokenCodec.Decode(cond.Cursor, cond.signature())
+		if err != nil {
+			return err
+		}
+		if backward {
+			return ErrInvalidPageToken
+		}
 	}
 
-	return resp, nil
+	for {
+		var list []*Hoge
+		var cur string
+		var hasMore bool
+
+		// 書き出し済みのHogeは取り消せないため、バッチ毎にリトライする
+		if err := Retry(g.Context(), "hoge.export", func() error {
+			var err error
+			list, cur, hasMore, err = store.page(g, cond, start, false)
+			if err != nil {
+				return err
+			}
+
+			return g.GetMulti(list)
+
+		}); err != nil {
+			return err
+		}
+
+		for i, hoge := range list {
+			cursor := ""
+			if hasMore && i == len(list)-1 {
+				cursor = DefaultPageTokenCodec.Encode(cur, cond.signature(), false)
+			}
+
+			if err := f(hoge, cursor); err != nil {
+				return err
+			}
+		}
+
+		if !hasMore {
+			return nil
+		}
+
+		start = cur
+	}
 }
 
-// Delete はHogeを削除する
-func (store *HogeStore) Delete(g *goon.Goon, id string) error {
+// Delete はHogeを削除し、削除したHogeを返す。存在しないHogeの場合は何もせずnilを返す
+//
+// 削除したHogeはDeletedHogeとしてDeletedHogeRetentionの間保持する
+// gはRunInTransactionのトランザクション内のClientとし、DeletedHogeの保存と削除を共にコミットする
+func (store *HogeStore) Delete(g ds.Client, id string) (*Hoge, error) {
 	if id == "" {
-		return errors.New("id is required")
+		return nil, ErrIDRequired
 	}
 
 	hoge := &Hoge{
 		ID: id,
 	}
-	if err := g.Delete(g.Key(hoge)); err != nil {
-		return err
+	if err := g.Get(hoge); err != nil {
+		if err == ds.ErrNoSuchEntity {
+			return nil, nil
+		}
+
+		return nil, err
 	}
 
-	return nil
+	deleted := &DeletedHoge{
+		ID:        hoge.ID,
+		Value:     hoge.Value,
+		CreatedAt: hoge.CreatedAt,
+		UpdatedAt: hoge.UpdatedAt,
+		DeletedAt: time.Now(),
+	}
+	if err := g.Put(deleted); err != nil {
+		return nil, err
+	}
+
+	if err := g.Delete(hoge); err != nil {
+		return nil, err
+	}
+
+	return hoge, nil
 }
@@ -0,0 +1,205 @@
+package model_test
+
+import (
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"testing"
+)
+
+// newHogesClient はIDが"hoge000"から連番のHogeをn件保存したClientと、そのHogeをIDの順に返す
+func newHogesClient(t *testing.T, n int) (*dstest.Client, []*model.Hoge) {
+	var list []*model.Hoge
+	for i := 0; i < n; i++ {
+		list = append(list, &model.Hoge{ID: fmt.Sprintf("hoge%03d", i), Value: "hogehoge"})
+	}
+
+	return newHogeClient(t, list...), list
+}
+
+func TestHogeStore_List(t *testing.T) {
+	store := &model.HogeStore{}
+
+	t.Run("limitが負の場合、ErrInvalidLimitとなること", func(t *testing.T) {
+		_, err := store.List(dstest.NewClient(), model.HogeQuery{Limit: -1})
+		if err != model.ErrInvalidLimit {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, model.ErrInvalidLimit)
+		}
+	})
+
+	t.Run("sortが不正な場合、ErrInvalidSortとなること", func(t *testing.T) {
+		_, err := store.List(dstest.NewClient(), model.HogeQuery{Sort: "value"})
+		if err != model.ErrInvalidSort {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, model.ErrInvalidSort)
+		}
+	})
+}
+
+func TestHogeStore_IDRequired(t *testing.T) {
+	store := &model.HogeStore{}
+
+	t.Run("Getでidが空の場合、ErrIDRequiredとなること", func(t *testing.T) {
+		if _, err := store.Get(dstest.NewClient(), ""); err != model.ErrIDRequired {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, model.ErrIDRequired)
+		}
+	})
+
+	t.Run("Deleteでidが空の場合、ErrIDRequiredとなること", func(t *testing.T) {
+		if _, err := store.Delete(dstest.NewClient(), ""); err != model.ErrIDRequired {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, model.ErrIDRequired)
+		}
+	})
+}
+
+func TestHogeStore_List_Count(t *testing.T) {
+	store := &model.HogeStore{}
+
+	g, list := newHogesClient(t, 15)
+
+	t.Run("Countがtrueの場合、取得件数に関わらず総数が設定されること", func(t *testing.T) {
+		resp, err := store.List(g, model.HogeQuery{Count: true})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(resp.List) != model.DefaultHogeListLimit {
+			t.Errorf("len(resp.List): unexpected, actual: `%d`, expected: `%d`", len(resp.List), model.DefaultHogeListLimit)
+		}
+		if resp.Total == nil || *resp.Total != len(list) {
+			t.Errorf("resp.Total: unexpected, actual: `%v`, expected: `%d`", resp.Total, len(list))
+		}
+		if resp.TotalCapped {
+			t.Errorf("resp.TotalCapped: unexpected, actual: `%t`, expected: `%t`", resp.TotalCapped, false)
+		}
+	})
+
+	t.Run("総数がMaxHogeCountを超える場合、MaxHogeCountで打ち切られること", func(t *testing.T) {
+		g, _ := newHogesClient(t, model.MaxHogeCount+1)
+
+		resp, err := store.List(g, model.HogeQuery{Count: true})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if resp.Total == nil || *resp.Total != model.MaxHogeCount {
+			t.Errorf("resp.Total: unexpected, actual: `%v`, expected: `%d`", resp.Total, model.MaxHogeCount)
+		}
+		if !resp.TotalCapped {
+			t.Errorf("resp.TotalCapped: unexpected, actual: `%t`, expected: `%t`", resp.TotalCapped, true)
+		}
+	})
+
+	t.Run("総数がMaxHogeCountちょうどの場合、打ち切られないこと", func(t *testing.T) {
+		g, _ := newHogesClient(t, model.MaxHogeCount)
+
+		resp, err := store.List(g, model.HogeQuery{Count: true})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if resp.Total == nil || *resp.Total != model.MaxHogeCount {
+			t.Errorf("resp.Total: unexpected, actual: `%v`, expected: `%d`", resp.Total, model.MaxHogeCount)
+		}
+		if resp.TotalCapped {
+			t.Errorf("resp.TotalCapped: unexpected, actual: `%t`, expected: `%t`", resp.TotalCapped, false)
+		}
+	})
+}
+
+func TestHogeStore_Export(t *testing.T) {
+	store := &model.HogeStore{}
+
+	newClient := func(t *testing.T) *dstest.Client {
+		g, _ := newHogesClient(t, model.MaxHogeListLimit*2+1)
+		return g
+	}
+
+	t.Run("上限を超える件数でも全件を読み込めること", func(t *testing.T) {
+		g, list := newHogesClient(t, model.MaxHogeListLimit*2+1)
+
+		var ids []string
+		var cursors []string
+		err := store.Export(g, model.HogeQuery{}, func(hoge *model.Hoge, cursor string) error {
+			ids = append(ids, hoge.ID)
+			if cursor != "" {
+				cursors = append(cursors, cursor)
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(ids) != len(list) {
+			t.Fatalf("len(ids): unexpected, actual: `%d`, expected: `%d`", len(ids), len(list))
+		}
+		for i, id := range ids {
+			if id != list[i].ID {
+				t.Errorf("ids[%d]: unexpected, actual: `%s`, expected: `%s`", i, id, list[i].ID)
+			}
+		}
+		// 最後のバッチ以外の末尾でCursorが渡される
+		if len(cursors) != 2 {
+			t.Errorf("len(cursors): unexpected, actual: `%d`, expected: `%d`", len(cursors), 2)
+		}
+	})
+
+	t.Run("Cursorを指定した場合、その位置から再開すること", func(t *testing.T) {
+		g, list := newHogesClient(t, model.MaxHogeListLimit*2+1)
+
+		var cursor string
+		err := store.Export(g, model.HogeQuery{}, func(hoge *model.Hoge, c string) error {
+			if c != "" {
+				cursor = c
+				return errors.New("disconnected")
+			}
+			return nil
+		})
+		if err == nil {
+			t.Fatal("err: expected error")
+		}
+
+		var first string
+		err = store.Export(g, model.HogeQuery{Cursor: cursor}, func(hoge *model.Hoge, c string) error {
+			if first == "" {
+				first = hoge.ID
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if expected := list[model.MaxHogeListLimit].ID; first != expected {
+			t.Errorf("first: unexpected, actual: `%s`, expected: `%s`", first, expected)
+		}
+	})
+
+	t.Run("不正なCursorを指定した場合、エラーとなること", func(t *testing.T) {
+		err := store.Export(newClient(t), model.HogeQuery{Cursor: "invalid"}, func(hoge *model.Hoge, cursor string) error {
+			return nil
+		})
+
+		if err != model.ErrInvalidPageToken {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, model.ErrInvalidPageToken)
+		}
+	})
+
+	t.Run("fがエラーを返した場合、中断してそのエラーを返すこと", func(t *testing.T) {
+		stop := errors.New("stop")
+
+		count := 0
+		err := store.Export(newClient(t), model.HogeQuery{}, func(hoge *model.Hoge, cursor string) error {
+			count++
+			return stop
+		})
+
+		if err != stop {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, stop)
+		}
+		if count != 1 {
+			t.Errorf("count: unexpected, actual: `%d`, expected: `%d`", count, 1)
+		}
+	})
+}
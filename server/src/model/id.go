@@ -0,0 +1,16 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newID はentityのIDに利用するランダムな文字列を生成する
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
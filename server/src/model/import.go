@@ -0,0 +1,502 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"io"
+	"sort"
+	"time"
+)
+
+// ConflictPolicy はインポートするHogeと同じIDのHogeが既に存在する場合の扱い
+type ConflictPolicy string
+
+const (
+	// ConflictSkip は既存のHogeを変更せずに読み飛ばす
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite は既存のHogeを上書きする。CreatedAtは既存の値を引き継ぐ
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail はその行でインポートを中断する。全ての行の既存の有無を確認するまで保存しないため、中断した場合は1件も保存しない
+	// 確認した後に同じIDのHogeが作成された場合は、保存する時点でErrAlreadyExistsを返す
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// ErrInvalidConflictPolicy はConflictPolicyの指定が不正な場合のエラー
+var ErrInvalidConflictPolicy = errors.New("conflict must be skip, overwrite or fail")
+
+// ParseConflictPolicy は文字列をConflictPolicyに変換する。空の場合はConflictSkipとする
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return p, nil
+	}
+
+	return "", ErrInvalidConflictPolicy
+}
+
+// ImportAction は1行毎のインポートの結果
+type ImportAction string
+
+const (
+	// ImportCreated は新規作成した(DryRunの場合は新規作成する)
+	ImportCreated ImportAction = "created"
+	// ImportUpdated は上書きした(DryRunの場合は上書きする)
+	ImportUpdated ImportAction = "updated"
+	// ImportSkipped は既存のHogeが存在するため読み飛ばした
+	ImportSkipped ImportAction = "skipped"
+	// ImportInvalid は行の形式が不正、または検証に失敗した
+	ImportInvalid ImportAction = "invalid"
+	// ImportConflict は既存のHogeが存在するため中断した
+	ImportConflict ImportAction = "conflict"
+)
+
+// ImportBatchSize はインポートでまとめて取得、保存するHogeの件数
+const ImportBatchSize = 500
+
+// importPublishBatchSize はImportOptions.Publishを指定した場合に、1つのトランザクションで保存するHogeの件数
+//
+// 1件毎にHoge、変更履歴、配信待ちのイベントを保存するため、1回のコミットで保存できる500件に収まるようにする
+const importPublishBatchSize = 100
+
+// ImportOptions はインポートの設定
+type ImportOptions struct {
+	// Conflict は同じIDのHogeが既に存在する場合の扱い
+	Conflict ConflictPolicy
+	// DryRun がtrueの場合は保存せず、結果のみを返す
+	DryRun bool
+	// Offset は先頭から読み飛ばす行数。リトライした場合に、前回までに保存した行を除くために利用する
+	//
+	// 読み飛ばした行は結果に含めないが、ファイル内のIDの重複は判定する
+	// ConflictFailの場合は全ての行を確認するまで保存しないため、0とする
+	Offset int
+	// Progress はバッチを処理する毎に、Offsetを含めた処理済みの行数とOffsetより後の行の結果で呼び出す
+	//
+	// ConflictFail以外の場合、処理済みの行は保存済みとなる。エラーを返した場合はインポートを中断する
+	Progress func(done int, report *ImportReport) error
+	// Publish は保存するHogeの変更のイベントを、保存と同じトランザクション内のgで通知する
+	//
+	// nilの場合は通知しない
+	Publish func(g ds.Client, events []*HogeEvent) error
+}
+
+// ImportRow はインポートするファイルから読み込んだ1行
+type ImportRow struct {
+	// Line は行番号。CSVのヘッダーは1行目とする
+	Line int
+	// Hoge は読み込んだHoge。Errが設定されている場合はnil
+	Hoge *Hoge
+	// Err は行の形式が不正な場合のエラー
+	Err error
+}
+
+// ImportReader はインポートするファイルを1行ずつ読み込む
+type ImportReader interface {
+	// Read は次の行を返す。終端に達した場合はio.EOFを返す
+	Read() (*ImportRow, error)
+}
+
+// ImportResult は1行毎のインポートの結果
+type ImportResult struct {
+	Line   int          `json:"line"`
+	ID     string       `json:"id,omitempty"`
+	Action ImportAction `json:"action"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ImportReport はインポートの結果
+type ImportReport struct {
+	DryRun bool `json:"dryRun"`
+	// Aborted はConflictFailにより中断したかを表す。中断した行より後の行は結果に含まない
+	Aborted bool `json:"aborted"`
+	// Offset はリトライにより再開した場合に、結果に含めない先頭の行数
+	Offset  int             `json:"offset,omitempty"`
+	Created int             `json:"created"`
+	Updated int             `json:"updated"`
+	Skipped int             `json:"skipped"`
+	Failed  int             `json:"failed"`
+	Results []*ImportResult `json:"results"`
+}
+
+func (report *ImportReport) add(result *ImportResult) {
+	switch result.Action {
+	case ImportCreated:
+		report.Created++
+	case ImportUpdated:
+		report.Updated++
+	case ImportSkipped:
+		report.Skipped++
+	default:
+		report.Failed++
+	}
+
+	report.Results = append(report.Results, result)
+}
+
+// importEntry は保存を待つ行
+type importEntry struct {
+	hoge   *Hoge
+	result *ImportResult
+}
+
+// Import はrから読み込んだHogeをImportBatchSize件ずつ保存する
+//
+// 各行はInsertと同じ規則で検証し、不正な行やファイル内でIDが重複する行は保存せずに結果に含める
+// rが行の形式以外のエラーを返した場合は、その時点で中断してエラーを返す
+func (store *HogeStore) Import(g ds.Client, r ImportReader, opts ImportOptions) (*ImportReport, error) {
+	report := &ImportReport{
+		DryRun:  opts.DryRun,
+		Offset:  opts.Offset,
+		Results: []*ImportResult{},
+	}
+
+	seen := map[string]int{}
+	batch := make([]*importEntry, 0, ImportBatchSize)
+
+	// ConflictFailの場合に、全ての行を確認するまで保存を待つ行
+	var pending []*importEntry
+
+	for read := 0; !report.Aborted; {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if read++; read <= opts.Offset {
+			if row.Err == nil {
+				seen[row.Hoge.ID] = row.Line
+			}
+			continue
+		}
+
+		result := &ImportResult{Line: row.Line}
+
+		if row.Err == nil {
+			result.ID = row.Hoge.ID
+			row.Err = row.Hoge.Validate()
+		}
+		if row.Err == nil {
+			if line, ok := seen[row.Hoge.ID]; ok {
+				row.Err = fmt.Errorf("id %q is duplicated with line %d", row.Hoge.ID, line)
+			}
+		}
+		if row.Err != nil {
+			result.Action = ImportInvalid
+			result.Error = row.Err.Error()
+			report.add(result)
+			continue
+		}
+
+		seen[row.Hoge.ID] = row.Line
+		batch = append(batch, &importEntry{hoge: row.Hoge, result: result})
+
+		if len(batch) == ImportBatchSize {
+			if err := store.importBatch(g, batch, opts, report, &pending); err != nil {
+				return nil, err
+			}
+
+			batch = batch[:0]
+		}
+	}
+
+	if !report.Aborted {
+		if err := store.importBatch(g, batch, opts, report, &pending); err != nil {
+			return nil, err
+		}
+	}
+
+	if !report.Aborted {
+		if err := store.saveEntries(g, pending, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// 不正な行は読み込んだ時点で結果に含めるため、行番号順に並べ直す
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Line < report.Results[j].Line
+	})
+
+	return report, nil
+}
+
+// importBatch はbatchの既存の有無を確認して保存し、進捗を通知する
+//
+// ConflictFailの場合は保存せずにpendingに追加する
+func (store *HogeStore) importBatch(g ds.Client, batch []*importEntry, opts ImportOptions, report *ImportReport, pending *[]*importEntry) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if opts.DryRun || opts.Conflict == ConflictFail {
+		if err := store.checkBatch(g, batch, opts, report); err != nil {
+			return err
+		}
+	} else if err := store.saveBatch(g, batch, opts, report); err != nil {
+		return err
+	}
+
+	if opts.Conflict == ConflictFail {
+		// batchは次のバッチで再利用するため、保存する行を別のスライスに取り出す
+		for _, e := range batch {
+			if e.result.Action == ImportCreated {
+				*pending = append(*pending, e)
+			}
+		}
+	}
+
+	if opts.Progress != nil {
+		return opts.Progress(opts.Offset+len(report.Results), report)
+	}
+
+	return nil
+}
+
+// checkBatch はbatchのHogeの既存の有無を確認し、ConflictPolicyに従って各行の結果を決める
+//
+// 保存はしないため、トランザクションを利用しない
+func (store *HogeStore) checkBatch(g ds.Client, batch []*importEntry, opts ImportOptions, report *ImportReport) error {
+	var olds []*Hoge
+	var exists []bool
+	if err := Retry(g.Context(), "hoge.import", func() error {
+		var err error
+		olds, exists, err = store.lookupBatch(g, batch)
+		return err
+
+	}); err != nil {
+		return err
+	}
+
+	aborted := decideBatch(batch, olds, exists, opts.Conflict)
+
+	for i, e := range batch {
+		report.add(e.result)
+
+		if i == aborted {
+			report.Aborted = true
+			// 中断した行より前に読み込んだ不正な行のみを残す
+			store.dropResultsAfter(report, e.result.Line)
+			break
+		}
+	}
+
+	return nil
+}
+
+// saveBatch はbatchをimportTxSize件毎のトランザクションで既存の有無を確認して保存する
+//
+// 確認と保存を同じトランザクションで行うため、確認した後に作成、更新されたHogeを読み飛ばさずに上書きしたり、
+// 作成されたHogeを新規作成として上書きしたりしない
+func (store *HogeStore) saveBatch(g ds.Client, batch []*importEntry, opts ImportOptions, report *ImportReport) error {
+	size := importTxSize(opts)
+
+	for len(batch) > 0 {
+		n := size
+		if n > len(batch) {
+			n = len(batch)
+		}
+
+		chunk := batch[:n]
+		if err := RunInTransaction(g, "hoge.import", func(tg ds.Client) error {
+			olds, exists, err := store.lookupBatch(tg, chunk)
+			if err != nil {
+				return err
+			}
+
+			decideBatch(chunk, olds, exists, opts.Conflict)
+
+			var entries []*importEntry
+			for _, e := range chunk {
+				if e.result.Action == ImportCreated || e.result.Action == ImportUpdated {
+					entries = append(entries, e)
+				}
+			}
+
+			return store.writeEntries(tg, entries, opts.Publish)
+
+		}); err != nil {
+			return err
+		}
+
+		for _, e := range chunk {
+			report.add(e.result)
+		}
+
+		batch = batch[n:]
+	}
+
+	return nil
+}
+
+// saveEntries はConflictFailで既存のHogeが存在しないことを確認したentriesをimportTxSize件毎のトランザクションで保存する
+//
+// 確認した後に同じIDのHogeが作成されていた場合は、そのトランザクションを保存せずにErrAlreadyExistsを返す
+// 前のトランザクションで保存したHogeは残るため、同時に同じIDのHogeを作成した場合は全ての行を保存しないことは保証しない
+func (store *HogeStore) saveEntries(g ds.Client, entries []*importEntry, opts ImportOptions) error {
+	if opts.DryRun {
+		return nil
+	}
+
+	size := importTxSize(opts)
+
+	for len(entries) > 0 {
+		n := size
+		if n > len(entries) {
+			n = len(entries)
+		}
+
+		chunk := entries[:n]
+		if err := RunInTransaction(g, "hoge.import", func(tg ds.Client) error {
+			_, exists, err := store.lookupBatch(tg, chunk)
+			if err != nil {
+				return err
+			}
+
+			for i, ok := range exists {
+				if ok {
+					log.Debugf(g.Context(), "hoge %q was created during the import", chunk[i].hoge.ID)
+					return ErrAlreadyExists
+				}
+			}
+
+			return store.writeEntries(tg, chunk, opts.Publish)
+
+		}); err != nil {
+			return err
+		}
+
+		entries = entries[n:]
+	}
+
+	return nil
+}
+
+// importTxSize はインポートで1つのトランザクションで確認、保存するHogeの件数を返す
+func importTxSize(opts ImportOptions) int {
+	if opts.Publish != nil {
+		return importPublishBatchSize
+	}
+
+	return ImportBatchSize
+}
+
+// lookupBatch はbatchのHogeを取得し、既存のHogeと存在するかを返す
+func (store *HogeStore) lookupBatch(g ds.Client, batch []*importEntry) ([]*Hoge, []bool, error) {
+	olds := make([]*Hoge, len(batch))
+	for i, e := range batch {
+		olds[i] = &Hoge{ID: e.hoge.ID}
+	}
+
+	exists := make([]bool, len(batch))
+
+	err := g.GetMulti(olds)
+	if err == nil {
+		for i := range exists {
+			exists[i] = true
+		}
+		return olds, exists, nil
+	}
+
+	merr, ok := err.(ds.MultiError)
+	if !ok {
+		return nil, nil, err
+	}
+
+	for i, err := range merr {
+		switch err {
+		case nil:
+			exists[i] = true
+		case ds.ErrNoSuchEntity:
+			exists[i] = false
+		default:
+			return nil, nil, err
+		}
+	}
+
+	return olds, exists, nil
+}
+
+// decideBatch はbatchの既存の有無からConflictPolicyに従って各行の結果を決める
+//
+// ConflictFailで中断する行の位置を返す。中断しない場合は-1を返す
+func decideBatch(batch []*importEntry, olds []*Hoge, exists []bool, conflict ConflictPolicy) int {
+	now := time.Now()
+
+	for i, e := range batch {
+		e.hoge.CreatedAt = now
+		e.hoge.UpdatedAt = now
+		e.result.Error = ""
+
+		if !exists[i] {
+			e.result.Action = ImportCreated
+			continue
+		}
+
+		switch conflict {
+		case ConflictOverwrite:
+			e.hoge.CreatedAt = olds[i].CreatedAt
+			e.result.Action = ImportUpdated
+		case ConflictFail:
+			e.result.Action = ImportConflict
+			e.result.Error = ErrAlreadyExists.Error()
+			return i
+		default:
+			e.result.Action = ImportSkipped
+		}
+	}
+
+	return -1
+}
+
+// writeEntries はentriesのHogeを保存し、publishが設定されている場合は同じgでイベントを通知する
+func (store *HogeStore) writeEntries(tg ds.Client, entries []*importEntry, publish func(g ds.Client, events []*HogeEvent) error) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	list := make([]*Hoge, len(entries))
+	for i, e := range entries {
+		list[i] = e.hoge
+	}
+
+	if err := tg.PutMulti(list); err != nil {
+		return err
+	}
+
+	if publish == nil {
+		return nil
+	}
+
+	events := make([]*HogeEvent, len(entries))
+	for i, e := range entries {
+		typ := HogeCreated
+		if e.result.Action == ImportUpdated {
+			typ = HogeUpdated
+		}
+
+		var err error
+		if events[i], err = NewHogeEvent(typ, e.hoge); err != nil {
+			return err
+		}
+	}
+
+	return publish(tg, events)
+}
+
+// dropResultsAfter は行番号がlineより大きい結果を取り除き、集計し直す
+func (store *HogeStore) dropResultsAfter(report *ImportReport, line int) {
+	results := report.Results
+
+	report.Created, report.Updated, report.Skipped, report.Failed = 0, 0, 0, 0
+	report.Results = make([]*ImportResult, 0, len(results))
+
+	for _, result := range results {
+		if result.Line <= line {
+			report.add(result)
+		}
+	}
+}
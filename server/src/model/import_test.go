@@ -0,0 +1,364 @@
+package model_test
+
+import (
+	"errors"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// txCounter はトランザクションの開始回数を数えるClient
+//
+// beforeTxが設定されている場合は、トランザクションを開始する前に実行する
+type txCounter struct {
+	*dstest.Client
+	txs      int
+	beforeTx func()
+}
+
+func (c *txCounter) RunInTransaction(f func(tg ds.Client) error) error {
+	c.txs++
+	if c.beforeTx != nil {
+		c.beforeTx()
+	}
+
+	return c.Client.RunInTransaction(f)
+}
+
+// newHogeClient はhogesを保存したClientを生成する
+func newHogeClient(t *testing.T, hoges ...*model.Hoge) *dstest.Client {
+	g := dstest.NewClient()
+	for _, hoge := range hoges {
+		if err := g.Put(hoge); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	return g
+}
+
+// rowsReader はImportRowを順に返すmodel.ImportReader
+type rowsReader struct {
+	rows []*model.ImportRow
+	// onEOF は最初に終端に達した時に実行する
+	onEOF func()
+}
+
+func newRowsReader(ids ...string) *rowsReader {
+	r := &rowsReader{}
+	for i, id := range ids {
+		r.rows = append(r.rows, &model.ImportRow{Line: i + 1, Hoge: &model.Hoge{ID: id, Value: "new"}})
+	}
+
+	return r
+}
+
+func (r *rowsReader) Read() (*model.ImportRow, error) {
+	if len(r.rows) == 0 {
+		if r.onEOF != nil {
+			r.onEOF()
+			r.onEOF = nil
+		}
+		return nil, io.EOF
+	}
+
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+
+	return row, nil
+}
+
+// actions は結果の"行番号:Action"を連結した文字列を返す
+func actions(report *model.ImportReport) string {
+	var vs []string
+	for _, result := range report.Results {
+		vs = append(vs, fmt.Sprintf("%d:%s", result.Line, result.Action))
+	}
+
+	return strings.Join(vs, ",")
+}
+
+func TestHogeStore_Import(t *testing.T) {
+	store := &model.HogeStore{}
+
+	createdAt := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	existing := func(t *testing.T) *dstest.Client {
+		return newHogeClient(t, &model.Hoge{ID: "hoge1", Value: "old", CreatedAt: createdAt})
+	}
+
+	for _, tc := range []struct {
+		title    string
+		opts     model.ImportOptions
+		actions  string
+		value    string
+		aborted  bool
+		created  int
+		updated  int
+		skipped  int
+		failed   int
+		imported bool
+	}{
+		{"skipの場合、既存のHogeは変更されないこと", model.ImportOptions{Conflict: model.ConflictSkip}, "1:created,2:skipped,3:created", "old", false, 2, 0, 1, 0, true},
+		{"overwriteの場合、既存のHogeが上書きされること", model.ImportOptions{Conflict: model.ConflictOverwrite}, "1:created,2:updated,3:created", "new", false, 2, 1, 0, 0, true},
+		{"failの場合、既存のHogeが存在する行で中断すること", model.ImportOptions{Conflict: model.ConflictFail}, "1:created,2:conflict", "old", true, 1, 0, 0, 1, false},
+		{"dryRunの場合、保存されないこと", model.ImportOptions{Conflict: model.ConflictOverwrite, DryRun: true}, "1:created,2:updated,3:created", "old", false, 2, 1, 0, 0, false},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			g := existing(t)
+
+			report, err := store.Import(g, newRowsReader("hoge0", "hoge1", "hoge2"), tc.opts)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if v := actions(report); v != tc.actions {
+				t.Errorf("actions: unexpected, actual: `%s`, expected: `%s`", v, tc.actions)
+			}
+			if report.Aborted != tc.aborted {
+				t.Errorf("report.Aborted: unexpected, actual: `%v`, expected: `%v`", report.Aborted, tc.aborted)
+			}
+			if report.DryRun != tc.opts.DryRun {
+				t.Errorf("report.DryRun: unexpected, actual: `%v`, expected: `%v`", report.DryRun, tc.opts.DryRun)
+			}
+			counts := []int{report.Created, report.Updated, report.Skipped, report.Failed}
+			if fmt.Sprint(counts) != fmt.Sprint([]int{tc.created, tc.updated, tc.skipped, tc.failed}) {
+				t.Errorf("counts: unexpected, actual: `%v`, expected: `%v`", counts, []int{tc.created, tc.updated, tc.skipped, tc.failed})
+			}
+
+			hoge1 := &model.Hoge{ID: "hoge1"}
+			if err := g.Get(hoge1); err != nil || hoge1.Value != tc.value || !hoge1.CreatedAt.Equal(createdAt) {
+				t.Errorf("hoge1: unexpected, actual: `%v`, err: `%v`", hoge1, err)
+			}
+			if err := g.Get(&model.Hoge{ID: "hoge2"}); (err == nil) != tc.imported {
+				t.Errorf("hoge2 imported: unexpected, actual: `%v`, expected: `%v`", err == nil, tc.imported)
+			}
+		})
+	}
+
+	t.Run("不正な行と重複したIDの行は保存されず、結果に含まれること", func(t *testing.T) {
+		g := dstest.NewClient()
+		r := newRowsReader("hoge0", "", "hoge0", "hoge1")
+		r.rows = append(r.rows, &model.ImportRow{Line: 5, Err: errors.New("invalid json")})
+
+		report, err := store.Import(g, r, model.ImportOptions{Conflict: model.ConflictSkip})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if v, expected := actions(report), "1:created,2:invalid,3:invalid,4:created,5:invalid"; v != expected {
+			t.Errorf("actions: unexpected, actual: `%s`, expected: `%s`", v, expected)
+		}
+		if v, expected := report.Results[1].Error, model.ErrIDRequired.Error(); v != expected {
+			t.Errorf("report.Results[1].Error: unexpected, actual: `%s`, expected: `%s`", v, expected)
+		}
+		if n := g.Store().Len("Hoge"); n != 2 {
+			t.Errorf("len(Hoge): unexpected, actual: `%d`, expected: `%d`", n, 2)
+		}
+	})
+
+	t.Run("failで中断した場合、中断した行より後の不正な行は結果に含まれないこと", func(t *testing.T) {
+		g := existing(t)
+		r := newRowsReader("hoge0", "hoge1", "", "hoge2")
+
+		report, err := store.Import(g, r, model.ImportOptions{Conflict: model.ConflictFail})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if v, expected := actions(report), "1:created,2:conflict"; v != expected {
+			t.Errorf("actions: unexpected, actual: `%s`, expected: `%s`", v, expected)
+		}
+		if report.Failed != 1 {
+			t.Errorf("report.Failed: unexpected, actual: `%d`, expected: `%d`", report.Failed, 1)
+		}
+	})
+
+	t.Run("ImportBatchSize件ずつ保存されること", func(t *testing.T) {
+		g := &txCounter{Client: dstest.NewClient()}
+
+		var ids []string
+		for i := 0; i < model.ImportBatchSize+1; i++ {
+			ids = append(ids, fmt.Sprintf("hoge%d", i))
+		}
+
+		report, err := store.Import(g, newRowsReader(ids...), model.ImportOptions{})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if report.Created != len(ids) {
+			t.Errorf("report.Created: unexpected, actual: `%d`, expected: `%d`", report.Created, len(ids))
+		}
+		if g.txs != 2 {
+			t.Errorf("txs: unexpected, actual: `%d`, expected: `%d`", g.txs, 2)
+		}
+	})
+
+	t.Run("skipの場合、既存の有無の確認と保存を同じトランザクションで行うこと", func(t *testing.T) {
+		g := &txCounter{Client: dstest.NewClient()}
+		// 確認の前に他のリクエストで作成された場合
+		g.beforeTx = func() {
+			if err := g.Client.Put(&model.Hoge{ID: "hoge0", Value: "other", CreatedAt: createdAt}); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		report, err := store.Import(g, newRowsReader("hoge0"), model.ImportOptions{Conflict: model.ConflictSkip})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if v := actions(report); v != "1:skipped" {
+			t.Errorf("actions: unexpected, actual: `%s`, expected: `%s`", v, "1:skipped")
+		}
+
+		hoge := &model.Hoge{ID: "hoge0"}
+		if err := g.Get(hoge); err != nil || hoge.Value != "other" {
+			t.Errorf("hoge0: unexpected, actual: `%v`, err: `%v`", hoge, err)
+		}
+	})
+
+	t.Run("failで中断した場合、前のバッチのHogeも保存されないこと", func(t *testing.T) {
+		var ids []string
+		for i := 0; i < model.ImportBatchSize; i++ {
+			ids = append(ids, fmt.Sprintf("new%d", i))
+		}
+		ids = append(ids, "hoge1")
+
+		g := existing(t)
+
+		report, err := store.Import(g, newRowsReader(ids...), model.ImportOptions{Conflict: model.ConflictFail})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !report.Aborted {
+			t.Error("report.Aborted: unexpected, actual: `false`")
+		}
+		if n := g.Store().Len("Hoge"); n != 1 {
+			t.Errorf("Len: unexpected, actual: `%d`, expected: `%d`", n, 1)
+		}
+	})
+
+	t.Run("failで中断しなかった場合、全てのHogeが保存されること", func(t *testing.T) {
+		var ids []string
+		for i := 0; i < model.ImportBatchSize+1; i++ {
+			ids = append(ids, fmt.Sprintf("new%d", i))
+		}
+
+		g := existing(t)
+
+		report, err := store.Import(g, newRowsReader(ids...), model.ImportOptions{Conflict: model.ConflictFail})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if report.Aborted || report.Created != len(ids) {
+			t.Errorf("report: unexpected, aborted: `%v`, created: `%d`", report.Aborted, report.Created)
+		}
+		if n := g.Store().Len("Hoge"); n != len(ids)+1 {
+			t.Errorf("Len: unexpected, actual: `%d`, expected: `%d`", n, len(ids)+1)
+		}
+	})
+
+	t.Run("failで確認した後に同じIDのHogeが作成された場合、ErrAlreadyExistsとなり、そのトランザクションのHogeは保存されないこと", func(t *testing.T) {
+		var ids []string
+		for i := 0; i < model.ImportBatchSize; i++ {
+			ids = append(ids, fmt.Sprintf("new%d", i))
+		}
+
+		g := dstest.NewClient()
+
+		r := newRowsReader(ids...)
+		// ImportBatchSize件の確認が終わった後に作成する
+		r.onEOF = func() {
+			if err := g.Put(&model.Hoge{ID: "new0", Value: "other", CreatedAt: createdAt}); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		if _, err := store.Import(g, r, model.ImportOptions{Conflict: model.ConflictFail}); err != model.ErrAlreadyExists {
+			t.Fatalf("err: unexpected, actual: `%v`, expected: `%v`", err, model.ErrAlreadyExists)
+		}
+
+		if n := g.Store().Len("Hoge"); n != 1 {
+			t.Errorf("Len: unexpected, actual: `%d`, expected: `%d`", n, 1)
+		}
+	})
+
+	t.Run("Publishを指定した場合、保存したHogeのイベントが保存と同じトランザクションで通知されること", func(t *testing.T) {
+		g := existing(t)
+
+		var ids []string
+		for i := 0; i < model.ImportBatchSize+1; i++ {
+			ids = append(ids, fmt.Sprintf("hoge%d", i))
+		}
+
+		calls := 0
+		types := map[string]model.EventType{}
+		opts := model.ImportOptions{Conflict: model.ConflictOverwrite, Publish: func(tg ds.Client, events []*model.HogeEvent) error {
+			calls++
+			if !model.InTransaction(tg) {
+				t.Error("InTransaction: expected true")
+			}
+
+			for _, event := range events {
+				types[event.Hoge.ID] = event.Type
+			}
+			return nil
+		}}
+
+		if _, err := store.Import(g, newRowsReader(ids...), opts); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		// ImportBatchSize件のバッチは100件毎のトランザクションに分ける
+		if calls != model.ImportBatchSize/100+1 {
+			t.Errorf("calls: unexpected, actual: `%d`, expected: `%d`", calls, model.ImportBatchSize/100+1)
+		}
+		if len(types) != len(ids) {
+			t.Errorf("len(events): unexpected, actual: `%d`, expected: `%d`", len(types), len(ids))
+		}
+		if types["hoge0"] != model.HogeCreated || types["hoge1"] != model.HogeUpdated {
+			t.Errorf("types: unexpected, actual: `%s`, `%s`", types["hoge0"], types["hoge1"])
+		}
+	})
+
+	t.Run("Publishが失敗した場合、Hogeは保存されないこと", func(t *testing.T) {
+		g := dstest.NewClient()
+
+		errPublish := errors.New("publish")
+		_, err := store.Import(g, newRowsReader("hoge0"), model.ImportOptions{Publish: func(tg ds.Client, events []*model.HogeEvent) error {
+			return errPublish
+		}})
+
+		if err != errPublish {
+			t.Fatalf("err: unexpected, actual: `%v`", err)
+		}
+		if n := g.Store().Len("Hoge"); n != 0 {
+			t.Errorf("len(Hoge): unexpected, actual: `%d`, expected: `%d`", n, 0)
+		}
+	})
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected model.ConflictPolicy
+		err      error
+	}{
+		{"", model.ConflictSkip, nil},
+		{"overwrite", model.ConflictOverwrite, nil},
+		{"replace", "", model.ErrInvalidConflictPolicy},
+	} {
+		p, err := model.ParseConflictPolicy(tc.in)
+		if p != tc.expected || err != tc.err {
+			t.Errorf("ParseConflictPolicy(%q): unexpected, actual: `%s, %v`, expected: `%s, %v`", tc.in, p, err, tc.expected, tc.err)
+		}
+	}
+}
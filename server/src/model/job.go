@@ -0,0 +1,352 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"time"
+)
+
+// JobStatus はJobの状態
+type JobStatus string
+
+const (
+	// JobPending は実行を待っている
+	JobPending JobStatus = "pending"
+	// JobRunning は実行中。一時的なエラーで失敗した場合はリトライを待っている
+	JobRunning JobStatus = "running"
+	// JobSucceeded は完了した
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed はエラーにより完了できなかった
+	JobFailed JobStatus = "failed"
+)
+
+// Finished は完了したか判定する
+func (s JobStatus) Finished() bool {
+	return s == JobSucceeded || s == JobFailed
+}
+
+// MaxJobPayloadSize はJobに保存する入力の上限。Jobと同じトランザクションで保存できる上限に余裕を持たせる
+const MaxJobPayloadSize = 8 * 1024 * 1024
+
+// jobChunkSize はJobChunk 1件に保存する上限。Datastoreのentityの上限に余裕を持たせる
+const jobChunkSize = 900 * 1024
+
+// Job はリクエストの外で実行する処理とその状態
+type Job struct {
+	ID string `json:"id" datastore:"-" goon:"id"`
+	// Type は処理の種類
+	Type   string    `json:"type"`
+	Status JobStatus `json:"status"`
+	// Params は処理のパラメータ
+	Params json.RawMessage `json:"params,omitempty" datastore:",noindex"`
+	// Payload は処理の入力。JobChunkに分割して保存し、JobStore.LoadPayloadで読み込む。完了した場合は削除する
+	Payload []byte `json:"-" datastore:"-"`
+	// PayloadChunks はPayloadを保存したJobChunkの件数
+	PayloadChunks int `json:"-" datastore:",noindex"`
+	// Progress は処理済みの件数
+	Progress int `json:"progress"`
+	// Total は処理する件数。不明な場合は0
+	Total int `json:"total,omitempty"`
+	// Checkpoint はリトライした場合に途中から再開するための状態
+	Checkpoint json.RawMessage `json:"-" datastore:",noindex"`
+	// Result は完了した場合の結果。JobChunkに分割して保存し、JobStore.Getで読み込む
+	Result json.RawMessage `json:"result,omitempty" datastore:"-"`
+	// ResultChunks はResultを保存したJobChunkの件数
+	ResultChunks int    `json:"-" datastore:",noindex"`
+	Error        string `json:"error,omitempty" datastore:",noindex"`
+	// Attempts は実行した回数
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NewJob は実行を待つJobを生成する
+func NewJob(typ string, params interface{}, payload []byte) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:      id,
+		Type:    typ,
+		Status:  JobPending,
+		Payload: payload,
+	}
+
+	if params != nil {
+		p, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+
+		job.Params = p
+	}
+
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+
+	return job, nil
+}
+
+// Bind はParamsをdstに読み込む
+func (job *Job) Bind(dst interface{}) error {
+	if len(job.Params) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(job.Params, dst)
+}
+
+// BindCheckpoint はCheckpointをdstに読み込む。保存されていない場合は何もしない
+func (job *Job) BindCheckpoint(dst interface{}) error {
+	if len(job.Checkpoint) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(job.Checkpoint, dst)
+}
+
+// Save はJobを保存する
+//
+// PayloadとResultは内容を変更しないため、まだJobChunkに保存していない場合のみ保存する
+func (job *Job) Save(g ds.Client) error {
+	if len(job.Payload) > 0 && job.PayloadChunks == 0 {
+		n, err := putJobChunks(g, job.ID, jobPayload, job.Payload)
+		if err != nil {
+			return err
+		}
+
+		job.PayloadChunks = n
+	}
+
+	if len(job.Result) > 0 && job.ResultChunks == 0 {
+		n, err := putJobChunks(g, job.ID, jobResult, job.Result)
+		if err != nil {
+			return err
+		}
+
+		job.ResultChunks = n
+	}
+
+	job.UpdatedAt = time.Now()
+
+	return g.Put(job)
+}
+
+const (
+	jobPayload = "payload"
+	jobResult  = "result"
+)
+
+// JobChunk はDatastoreのentityの上限を超えるJobの入力と結果を分割して保存するentity
+type JobChunk struct {
+	// ID は"<JobのID>-<payload|result>-<番号>"
+	ID   string `datastore:"-" goon:"id"`
+	Data []byte `datastore:",noindex"`
+}
+
+// jobChunks はJobのIDのn件のJobChunkを返す
+func jobChunks(id, name string, n int) []*JobChunk {
+	chunks := make([]*JobChunk, n)
+	for i := range chunks {
+		chunks[i] = &JobChunk{ID: fmt.Sprintf("%s-%s-%d", id, name, i)}
+	}
+
+	return chunks
+}
+
+// putJobChunks はbをjobChunkSize毎のJobChunkに分割して保存し、件数を返す
+func putJobChunks(g ds.Client, id, name string, b []byte) (int, error) {
+	chunks := jobChunks(id, name, (len(b)+jobChunkSize-1)/jobChunkSize)
+	for i, chunk := range chunks {
+		end := (i + 1) * jobChunkSize
+		if end > len(b) {
+			end = len(b)
+		}
+
+		chunk.Data = b[i*jobChunkSize : end]
+	}
+
+	if err := g.PutMulti(chunks); err != nil {
+		return 0, err
+	}
+
+	return len(chunks), nil
+}
+
+// getJobChunks はn件のJobChunkを読み込み、連結して返す
+func getJobChunks(g ds.Client, id, name string, n int) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+
+	chunks := jobChunks(id, name, n)
+	if err := g.GetMulti(chunks); err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	for _, chunk := range chunks {
+		b = append(b, chunk.Data...)
+	}
+
+	return b, nil
+}
+
+// JobStore はJobを管理する
+type JobStore struct{}
+
+// Get はJobを1件取得する
+func (store *JobStore) Get(g ds.Client, id string) (*Job, error) {
+	if id == "" {
+		return nil, ErrIDRequired
+	}
+
+	job := &Job{
+		ID: id,
+	}
+	if err := g.Get(job); err != nil {
+		return nil, err
+	}
+
+	result, err := getJobChunks(g, job.ID, jobResult, job.ResultChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Result = result
+
+	return job, nil
+}
+
+// LoadPayload はjobのPayloadを読み込む
+func (store *JobStore) LoadPayload(g ds.Client, job *Job) error {
+	payload, err := getJobChunks(g, job.ID, jobPayload, job.PayloadChunks)
+	if err != nil {
+		return err
+	}
+
+	job.Payload = payload
+
+	return nil
+}
+
+// DeletePayload はjobのPayloadを保存したJobChunkを削除する
+//
+// PayloadChunksは変更しないため、PurgeFinishedで再度削除しても問題ない
+func (store *JobStore) DeletePayload(g ds.Client, job *Job) error {
+	job.Payload = nil
+	if job.PayloadChunks == 0 {
+		return nil
+	}
+
+	return Retry(g.Context(), "job.delete_payload", func() error {
+		return g.DeleteMulti(jobChunks(job.ID, jobPayload, job.PayloadChunks))
+	})
+}
+
+// JobRetention は完了したJobを保持する期間
+const JobRetention = 7 * 24 * time.Hour
+
+// purgeBatchSize はPurgeFinishedでまとめて取得、削除するJobの件数
+const purgeBatchSize = 100
+
+// PurgeFinished はbeforeより前に更新された完了済みのJobを削除し、削除した件数を返す
+//
+// 実行中のJobは削除しないため、何度実行しても結果は変わらない
+func (store *JobStore) PurgeFinished(g ds.Client, before time.Time) (int, error) {
+	q := ds.NewQuery(g.Kind(Job{})).Filter("UpdatedAt", "<", before).Limit(purgeBatchSize)
+
+	purged := 0
+	cursor := ""
+
+	for {
+		list, next, err := store.finishedBefore(g, q, cursor)
+		if err != nil {
+			return purged, err
+		}
+
+		if len(list) > 0 {
+			// JobChunkを先に削除し、失敗した場合はJobを残して次回に削除し直す
+			var chunks []*JobChunk
+			for _, job := range list {
+				chunks = append(chunks, jobChunks(job.ID, jobPayload, job.PayloadChunks)...)
+				chunks = append(chunks, jobChunks(job.ID, jobResult, job.ResultChunks)...)
+			}
+
+			for len(chunks) > 0 {
+				n := purgeBatchSize
+				if n > len(chunks) {
+					n = len(chunks)
+				}
+
+				if err := Retry(g.Context(), "job.purge", func() error {
+					return g.DeleteMulti(chunks[:n])
+				}); err != nil {
+					return purged, err
+				}
+
+				chunks = chunks[n:]
+			}
+
+			if err := Retry(g.Context(), "job.purge", func() error {
+				return g.DeleteMulti(list)
+			}); err != nil {
+				return purged, err
+			}
+
+			purged += len(list)
+		}
+
+		if next == "" {
+			return purged, nil
+		}
+
+		cursor = next
+	}
+}
+
+// finishedBefore はcursorから最大purgeBatchSize件を読み込み、完了済みのJobと次のCursorを返す
+//
+// 最後まで読み込んだ場合、次のCursorは空となる
+func (store *JobStore) finishedBefore(g ds.Client, q *ds.Query, cursor string) ([]*Job, string, error) {
+	if cursor != "" {
+		q = q.Start(cursor)
+	}
+
+	it := g.Run(q)
+
+	var list []*Job
+	n := 0
+
+	for {
+		job := &Job{}
+		id, err := it.Next(job)
+		if err == ds.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		n++
+
+		job.ID = id
+		if job.Status.Finished() {
+			list = append(list, job)
+		}
+	}
+
+	if n < purgeBatchSize {
+		return list, "", nil
+	}
+
+	next, err := it.Cursor()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return list, next, nil
+}
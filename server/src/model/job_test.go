@@ -0,0 +1,137 @@
+package model_test
+
+import (
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewJob(t *testing.T) {
+	job, err := model.NewJob("hoge.import", map[string]string{"format": "csv"}, []byte("id\n"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if job.ID == "" || job.Status != model.JobPending {
+		t.Errorf("job: unexpected, actual: `%s`, status: `%s`", job.ID, job.Status)
+	}
+
+	p := map[string]string{}
+	if err := job.Bind(&p); err != nil {
+		t.Fatal(err.Error())
+	}
+	if p["format"] != "csv" {
+		t.Errorf("params: unexpected, actual: `%v`", p)
+	}
+
+	if model.JobPending.Finished() || model.JobRunning.Finished() || !model.JobSucceeded.Finished() || !model.JobFailed.Finished() {
+		t.Error("Finished: unexpected")
+	}
+}
+
+func TestJob_Save(t *testing.T) {
+	g := dstest.NewClient()
+	store := &model.JobStore{}
+
+	payload := []byte(strings.Repeat("a", 2*1024*1024))
+	job, err := model.NewJob("hoge.import", nil, payload)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := job.Save(g); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	t.Run("PayloadがJobChunkに分割して保存されること", func(t *testing.T) {
+		if n := g.Store().Len("JobChunk"); n != 3 {
+			t.Errorf("JobChunk: unexpected, actual: `%d`, expected: `%d`", n, 3)
+		}
+
+		v, err := store.Get(g, job.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if v.Payload != nil {
+			t.Errorf("Payload: unexpected, actual: `%d` bytes", len(v.Payload))
+		}
+
+		if err := store.LoadPayload(g, v); err != nil {
+			t.Fatal(err.Error())
+		}
+		if string(v.Payload) != string(payload) {
+			t.Errorf("Payload: unexpected, actual: `%d` bytes, expected: `%d` bytes", len(v.Payload), len(payload))
+		}
+	})
+
+	t.Run("Resultが保存され、Getで読み込まれること", func(t *testing.T) {
+		job.Result = []byte(`{"created":1}`)
+		if err := job.Save(g); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		v, err := store.Get(g, job.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if string(v.Result) != `{"created":1}` {
+			t.Errorf("Result: unexpected, actual: `%s`", v.Result)
+		}
+	})
+
+	t.Run("DeletePayloadでPayloadのJobChunkのみ削除されること", func(t *testing.T) {
+		if err := store.DeletePayload(g, job); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if n := g.Store().Len("JobChunk"); n != 1 {
+			t.Errorf("JobChunk: unexpected, actual: `%d`, expected: `%d`", n, 1)
+		}
+	})
+}
+
+func TestJobStore_PurgeFinished(t *testing.T) {
+	g := dstest.NewClient()
+	statuses := []model.JobStatus{model.JobSucceeded, model.JobFailed, model.JobRunning, model.JobPending}
+	for i := 0; i < 10; i++ {
+		job := &model.Job{ID: fmt.Sprintf("job%d", i), Status: statuses[i%len(statuses)], Payload: []byte("payload")}
+		if err := job.Save(g); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		job.UpdatedAt = time.Now().Add(-time.Hour)
+		if err := g.Put(job); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	store := &model.JobStore{}
+
+	purged, err := store.PurgeFinished(g, time.Now())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if purged != 6 {
+		t.Errorf("purged: unexpected, actual: `%d`, expected: `%d`", purged, 6)
+	}
+
+	var deleted []string
+	for i := 0; i < 10; i++ {
+		job := &model.Job{ID: fmt.Sprintf("job%d", i)}
+		if err := g.Get(job); err == ds.ErrNoSuchEntity {
+			deleted = append(deleted, job.ID)
+		} else if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if v, expected := strings.Join(deleted, ","), "job0,job1,job4,job5,job8,job9"; v != expected {
+		t.Errorf("deleted: unexpected, actual: `%s`, expected: `%s`", v, expected)
+	}
+	if n := g.Store().Len("JobChunk"); n != 4 {
+		t.Errorf("JobChunk: unexpected, actual: `%d`, expected: `%d`", n, 4)
+	}
+}
@@ -0,0 +1,192 @@
+package model
+
+import (
+	"encoding/json"
+	"gaego-gin/server/src/ds"
+	"time"
+)
+
+// OutboxStatus はOutboxEventの配信の状態
+type OutboxStatus string
+
+const (
+	// OutboxPending は配信していない配信先が残っている
+	OutboxPending OutboxStatus = "pending"
+	// OutboxDelivered は全ての配信先に配信した
+	OutboxDelivered OutboxStatus = "delivered"
+	// OutboxFailed は試行回数の上限に達しても配信できなかった
+	OutboxFailed OutboxStatus = "failed"
+)
+
+// OutboxRetention は配信を終えたOutboxEventを保持する期間
+const OutboxRetention = 7 * 24 * time.Hour
+
+// OutboxStore はOutboxEventを操作するメソッドをまとめる
+type OutboxStore struct{}
+
+// OutboxEvent は変更と同じトランザクションで保存した、配信待ちのドメインイベント
+type OutboxEvent struct {
+	// ID はHogeEventのID。配信先で重複を取り除くために利用する
+	ID     string          `json:"id" datastore:"-" goon:"id"`
+	Type   EventType       `json:"type" datastore:",noindex"`
+	Event  json.RawMessage `json:"event" datastore:",noindex"`
+	Status OutboxStatus    `json:"status"`
+	// Delivered は配信済みの配信先の名前。リトライでは残りの配信先のみに配信する
+	Delivered []string `json:"delivered" datastore:",noindex"`
+	// Attempts は配信を試行した回数
+	Attempts  int       `json:"attempts" datastore:",noindex"`
+	Error     string    `json:"error,omitempty" datastore:",noindex"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Bind はEventをHogeEventとして読み込む
+func (e *OutboxEvent) Bind() (*HogeEvent, error) {
+	event := &HogeEvent{}
+	if err := json.Unmarshal(e.Event, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// IsDelivered はnameの配信先に配信済みか判定する
+func (e *OutboxEvent) IsDelivered(name string) bool {
+	for _, v := range e.Delivered {
+		if v == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add はeventを配信待ちのOutboxEventとして保存する
+//
+// gはRunInTransactionのトランザクション内のClientとし、変更と共にコミットする
+func (store *OutboxStore) Add(g ds.Client, event *HogeEvent) (*OutboxEvent, error) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &OutboxEvent{
+		ID:        event.ID,
+		Type:      event.Type,
+		Event:     b,
+		Status:    OutboxPending,
+		Delivered: []string{},
+		CreatedAt: event.OccurredAt,
+		UpdatedAt: event.OccurredAt,
+	}
+	if err := g.Put(e); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Get はOutboxEventを1件取得する
+func (store *OutboxStore) Get(g ds.Client, id string) (*OutboxEvent, error) {
+	if id == "" {
+		return nil, ErrIDRequired
+	}
+
+	e := &OutboxEvent{
+		ID: id,
+	}
+	if err := g.Get(e); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Save はOutboxEventを保存する
+func (store *OutboxStore) Save(g ds.Client, e *OutboxEvent) error {
+	e.UpdatedAt = time.Now()
+
+	return g.Put(e)
+}
+
+// ListPending はbeforeより前に更新した配信待ちのOutboxEventを古い順にlimit件まで取得する
+func (store *OutboxStore) ListPending(g ds.Client, before time.Time, limit int) ([]*OutboxEvent, error) {
+	q := ds.NewQuery(g.Kind(OutboxEvent{})).
+		Filter("Status", "=", string(OutboxPending)).
+		Filter("UpdatedAt", "<", before).
+		Order("UpdatedAt").
+		Limit(limit)
+
+	it := g.Run(q)
+
+	list := []*OutboxEvent{}
+	for {
+		e := &OutboxEvent{}
+		id, err := it.Next(e)
+		if err == ds.Done {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e.ID = id
+		list = append(list, e)
+	}
+}
+
+// PurgeFinished はbeforeより前に配信を終えたOutboxEventを削除し、削除した件数を返す
+func (store *OutboxStore) PurgeFinished(g ds.Client, before time.Time) (int, error) {
+	purged := 0
+
+	for _, status := range []OutboxStatus{OutboxDelivered, OutboxFailed} {
+		q := ds.NewQuery(g.Kind(OutboxEvent{})).
+			Filter("Status", "=", string(status)).
+			Filter("UpdatedAt", "<", before).
+			Limit(purgeBatchSize)
+
+		for {
+			it := g.Run(q)
+
+			var list []*OutboxEvent
+			for {
+				e := &OutboxEvent{}
+				id, err := it.Next(e)
+				if err == ds.Done {
+					break
+				}
+				if err != nil {
+					return purged, err
+				}
+
+				e.ID = id
+				list = append(list, e)
+			}
+
+			if len(list) == 0 {
+				break
+			}
+
+			if err := Retry(g.Context(), "outbox.purge", func() error {
+				return g.DeleteMulti(list)
+			}); err != nil {
+				return purged, err
+			}
+
+			purged += len(list)
+
+			if len(list) < purgeBatchSize {
+				break
+			}
+
+			next, err := it.Cursor()
+			if err != nil {
+				return purged, err
+			}
+
+			q = q.Start(next)
+		}
+	}
+
+	return purged, nil
+}
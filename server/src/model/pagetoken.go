@@ -0,0 +1,149 @@
+package model
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidPageToken はページトークンの形式が不正か、復号できない場合のエラー
+	ErrInvalidPageToken = errors.New("invalid page token")
+	// ErrPageTokenMismatch はページトークンの発行時とクエリの条件が異なる場合のエラー
+	ErrPageTokenMismatch = errors.New("page token does not match the query")
+	// ErrPageTokenExpired はページトークンの有効期限が切れている場合のエラー
+	ErrPageTokenExpired = errors.New("page token has expired")
+)
+
+// PageTokenCodec はDatastoreのCursorとクエリの条件をAES-GCMで暗号化した不透明なページトークンに変換する
+//
+// クライアントにはCursorを暗号化して返すため、Cursorからクエリの構造が漏れず、改ざんも検出できる
+type PageTokenCodec struct {
+	aead cipher.AEAD
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewPageTokenCodec はsecretから求めた鍵で暗号化し、ttlの間有効なページトークンを発行するPageTokenCodecを生成する
+// secretが空の場合はランダムな鍵を生成し、ttlが0の場合は有効期限を設けない
+func NewPageTokenCodec(secret []byte, ttl time.Duration) *PageTokenCodec {
+	if len(secret) == 0 {
+		secret = randomSecret()
+	}
+
+	// secretの長さに関わらずAES-256の鍵とする
+	key := sha256.Sum256(secret)
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+
+	return &PageTokenCodec{
+		aead: aead,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+// DefaultPageTokenCodec はHogeStore.Listが利用するPageTokenCodec
+//
+// 鍵はプロセス毎にランダムに生成されるため、テストと開発用以外では共通の鍵で置き換える
+// app.LoadConfigはPAGE_TOKEN_SECRETが未設定の場合、DEBUGが有効な場合を除いて起動時にエラーを返す
+var DefaultPageTokenCodec = NewPageTokenCodec(nil, 24*time.Hour)
+
+func randomSecret() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+// pageToken はページトークンの内容
+type pageToken struct {
+	// Cursor はDatastoreのCursor
+	Cursor string `json:"c"`
+	// Query はクエリの条件のハッシュ
+	Query string `json:"q"`
+	// Backward がtrueの場合、Cursorより前のページを表す
+	Backward bool `json:"b,omitempty"`
+	// ExpiresAt は有効期限のUNIX時間。0の場合は無期限
+	ExpiresAt int64 `json:"e,omitempty"`
+}
+
+var tokenEncoding = base64.RawURLEncoding
+
+// Encode はcursorとクエリの条件queryからページトークンを生成する
+// backwardがtrueの場合はcursorより前のページを表すトークンとなる
+func (c *PageTokenCodec) Encode(cursor, query string, backward bool) string {
+	t := &pageToken{
+		Cursor:   cursor,
+		Query:    queryHash(query),
+		Backward: backward,
+	}
+	if c.ttl > 0 {
+		t.ExpiresAt = c.now().Add(c.ttl).Unix()
+	}
+
+	payload, err := json.Marshal(t)
+	if err != nil {
+		panic(err)
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+
+	return tokenEncoding.EncodeToString(c.aead.Seal(nonce, nonce, payload, nil))
+}
+
+// Decode はページトークンを復号し、CursorとCursorより前のページを表すトークンかを返す
+//
+// 形式が不正か復号できない場合はErrInvalidPageToken、クエリの条件が異なる場合はErrPageTokenMismatch、
+// 有効期限が切れている場合はErrPageTokenExpiredを返す
+func (c *PageTokenCodec) Decode(token, query string) (cursor string, backward bool, err error) {
+	b, err := tokenEncoding.DecodeString(token)
+	if err != nil || len(b) < c.aead.NonceSize() {
+		return "", false, ErrInvalidPageToken
+	}
+
+	n := c.aead.NonceSize()
+	payload, err := c.aead.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return "", false, ErrInvalidPageToken
+	}
+
+	t := &pageToken{}
+	if err := json.Unmarshal(payload, t); err != nil {
+		return "", false, ErrInvalidPageToken
+	}
+
+	if t.Query != queryHash(query) {
+		return "", false, ErrPageTokenMismatch
+	}
+
+	if t.ExpiresAt != 0 && t.ExpiresAt < c.now().Unix() {
+		return "", false, ErrPageTokenExpired
+	}
+
+	return t.Cursor, t.Backward, nil
+}
+
+// queryHash はクエリの条件をトークンに含めるためのハッシュ
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return tokenEncoding.EncodeToString(sum[:12])
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestPageTokenCodec(t *testing.T) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newCodec := func() *PageTokenCodec {
+		c := NewPageTokenCodec([]byte("secret"), time.Hour)
+		c.now = func() time.Time { return now }
+		return c
+	}
+
+	t.Run("発行したトークンからCursorが復元できること", func(t *testing.T) {
+		c := newCodec()
+
+		for _, backward := range []bool{false, true} {
+			cursor, b, err := c.Decode(c.Encode("cursor", "limit=3", backward), "limit=3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cursor != "cursor" {
+				t.Errorf("cursor = %q, want %q", cursor, "cursor")
+			}
+			if b != backward {
+				t.Errorf("backward = %v, want %v", b, backward)
+			}
+		}
+	})
+
+	t.Run("トークンを復号しなければCursorが読み取れないこと", func(t *testing.T) {
+		c := newCodec()
+		cursor := "CkMSPWoSdW5pdHRlc3R-dW5pdHRlc3RyJwsSBEhvZ2UiB2hvZ2UwMDEM"
+		token := c.Encode(cursor, "limit=3", false)
+
+		b, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(b, []byte(cursor)) || bytes.Contains([]byte(token), []byte(cursor)) {
+			t.Errorf("token contains the cursor: %q", token)
+		}
+		for _, v := range []string{`"c"`, `"q"`} {
+			if bytes.Contains(b, []byte(v)) {
+				t.Errorf("token contains the field %s: %q", v, token)
+			}
+		}
+	})
+
+	t.Run("同じ内容でも発行する度に異なるトークンとなること", func(t *testing.T) {
+		c := newCodec()
+
+		if c.Encode("cursor", "limit=3", false) == c.Encode("cursor", "limit=3", false) {
+			t.Errorf("tokens are identical")
+		}
+	})
+
+	t.Run("改ざんされた場合、ErrInvalidPageTokenとなること", func(t *testing.T) {
+		c := newCodec()
+		token := c.Encode("cursor", "limit=3", false)
+
+		for _, v := range []string{"", "invalid", token + "x", "x" + token, NewPageTokenCodec([]byte("other"), time.Hour).Encode("cursor", "limit=3", false)} {
+			if _, _, err := c.Decode(v, "limit=3"); err != ErrInvalidPageToken {
+				t.Errorf("Decode(%q) = %v, want %v", v, err, ErrInvalidPageToken)
+			}
+		}
+	})
+
+	t.Run("条件が異なる場合、ErrPageTokenMismatchとなること", func(t *testing.T) {
+		c := newCodec()
+
+		if _, _, err := c.Decode(c.Encode("cursor", "limit=3", false), "limit=4"); err != ErrPageTokenMismatch {
+			t.Errorf("err = %v, want %v", err, ErrPageTokenMismatch)
+		}
+	})
+
+	t.Run("有効期限が切れた場合、ErrPageTokenExpiredとなること", func(t *testing.T) {
+		c := newCodec()
+		token := c.Encode("cursor", "limit=3", false)
+
+		now = now.Add(2 * time.Hour)
+		defer func() { now = now.Add(-2 * time.Hour) }()
+
+		if _, _, err := c.Decode(token, "limit=3"); err != ErrPageTokenExpired {
+			t.Errorf("err = %v, want %v", err, ErrPageTokenExpired)
+		}
+	})
+
+	t.Run("ttlが0の場合、有効期限が設けられないこと", func(t *testing.T) {
+		c := NewPageTokenCodec([]byte("secret"), 0)
+		token := c.Encode("cursor", "limit=3", false)
+
+		c.now = func() time.Time { return now.Add(24 * 365 * time.Hour) }
+
+		if _, _, err := c.Decode(token, "limit=3"); err != nil {
+			t.Errorf("err = %v", err)
+		}
+	})
+}
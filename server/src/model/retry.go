@@ -0,0 +1,135 @@
+package model
+
+import (
+	"context"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/metrics"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy はDatastoreの操作のリトライ方針
+type RetryPolicy struct {
+	// MaxAttempts は最初の試行を含む最大の試行回数
+	MaxAttempts int
+	// InitialBackoff は1回目のリトライまでの待機時間の上限
+	InitialBackoff time.Duration
+	// MaxBackoff は待機時間の上限
+	MaxBackoff time.Duration
+	// Multiplier はリトライ毎に待機時間の上限を増やす倍率
+	Multiplier float64
+	// Retryable はエラーがリトライの対象か判定する
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy はRunInTransactionとRetryが利用するリトライ方針
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Retryable:      IsRetryable,
+}
+
+// IsRetryable はトランザクションの競合と一時的なエラーをリトライの対象とする
+func IsRetryable(err error) bool {
+	return err == ds.ErrConcurrentTransaction || ds.IsTransient(err)
+}
+
+// RetryError はリトライの上限に達した場合のエラー
+type RetryError struct {
+	// Attempts は試行回数
+	Attempts int
+	// Err は最後の試行のエラー
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+// RunInTransaction はDefaultRetryPolicyに従ってリトライしながらトランザクション内でfを実行する
+// opはメトリクスとログに用いる操作名
+func RunInTransaction(g ds.Client, op string, f func(tg ds.Client) error) error {
+	return DefaultRetryPolicy.RunInTransaction(g, op, f)
+}
+
+// Retry はDefaultRetryPolicyに従ってリトライしながらfを実行する
+func Retry(ctx context.Context, op string, f func() error) error {
+	return DefaultRetryPolicy.Do(ctx, op, f)
+}
+
+// RunInTransaction はpに従ってリトライしながらトランザクション内でfを実行する
+//
+// fに渡すClientでAfterCommitに登録した処理は、コミットに成功した試行の分のみ実行する
+func (p *RetryPolicy) RunInTransaction(g ds.Client, op string, f func(tg ds.Client) error) error {
+	return p.Do(g.Context(), op, func() error {
+		var tx *txClient
+		if err := g.RunInTransaction(func(tg ds.Client) error {
+			tx = &txClient{Client: tg}
+			return f(tx)
+
+		}); err != nil {
+			return err
+		}
+
+		if tx != nil {
+			for _, after := range tx.afterCommit {
+				after(g)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Do はpに従ってリトライしながらfを実行する
+//
+// リトライの対象外のエラーはそのまま返し、上限に達した場合はRetryErrorを返す
+// 待機中にctxが終了した場合はctxのエラーを返す
+func (p *RetryPolicy) Do(ctx context.Context, op string, f func() error) error {
+	backoff := p.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || !p.Retryable(err) {
+			return err
+		}
+
+		reason := retryReason(err)
+		if attempt >= p.MaxAttempts {
+			metrics.RecordRetryExhausted(op, reason)
+			log.Warningf(ctx, "%s: giving up after %d attempts: %v", op, attempt, err)
+
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		metrics.RecordRetry(op, reason)
+		log.Debugf(ctx, "%s: retrying after attempt %d: %v", op, attempt, err)
+
+		// Full Jitter: [0, backoff)の範囲でランダムに待機する
+		wait := time.Duration(0)
+		if backoff > 0 {
+			wait = time.Duration(rand.Int63n(int64(backoff)))
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff = time.Duration(float64(backoff) * p.Multiplier)
+		if p.MaxBackoff < backoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+func retryReason(err error) string {
+	if err == ds.ErrConcurrentTransaction {
+		return "conflict"
+	}
+
+	return "transient"
+}
@@ -0,0 +1,86 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"testing"
+	"time"
+)
+
+func newPolicy() *model.RetryPolicy {
+	return &model.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		Multiplier:     2,
+		Retryable:      model.IsRetryable,
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("トランザクションが競合した場合、成功するまでリトライすること", func(t *testing.T) {
+		attempts := 0
+		err := newPolicy().Do(ctx, "test", func() error {
+			attempts++
+			if attempts < 3 {
+				return ds.ErrConcurrentTransaction
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if attempts != 3 {
+			t.Errorf("attempts: unexpected, actual: `%d`, expected: `%d`", attempts, 3)
+		}
+	})
+
+	t.Run("上限に達した場合、RetryErrorを返すこと", func(t *testing.T) {
+		err := newPolicy().Do(ctx, "test", func() error {
+			return &ds.TransientError{Err: errors.New("timeout")}
+		})
+
+		rerr, ok := err.(*model.RetryError)
+		if !ok {
+			t.Fatalf("unexpected error: `%v`", err)
+		}
+		if rerr.Attempts != 3 {
+			t.Errorf("Attempts: unexpected, actual: `%d`, expected: `%d`", rerr.Attempts, 3)
+		}
+	})
+
+	t.Run("リトライの対象外のエラーはリトライせずに返すこと", func(t *testing.T) {
+		attempts := 0
+		err := newPolicy().Do(ctx, "test", func() error {
+			attempts++
+			return ds.ErrNoSuchEntity
+		})
+
+		if err != ds.ErrNoSuchEntity {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, ds.ErrNoSuchEntity)
+		}
+		if attempts != 1 {
+			t.Errorf("attempts: unexpected, actual: `%d`, expected: `%d`", attempts, 1)
+		}
+	})
+
+	t.Run("待機中にcontextが終了した場合、contextのエラーを返すこと", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		p := newPolicy()
+		p.InitialBackoff = time.Hour
+		err := p.Do(ctx, "test", func() error {
+			return ds.ErrConcurrentTransaction
+		})
+
+		if err != context.Canceled {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, context.Canceled)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"gaego-gin/server/src/ds"
+	"time"
+)
+
+// hogeStatsID はHogeStatsのentityのID
+const hogeStatsID = "hoge"
+
+// recountBatchSize はHogeStatsStore.Recountで1回のクエリで数える件数
+const recountBatchSize = 1000
+
+// HogeStats はHogeの件数の集計。HogeStatsStore.Recountで定期的に数え直す
+//
+// Hogeの作成、削除では更新しないため、CountedAt以降の変更は含まない
+type HogeStats struct {
+	ID string `json:"-" datastore:"-" goon:"id"`
+	// Total は全てのHogeの件数。HogeQuery.Countと異なり上限はない
+	Total int `json:"total" datastore:",noindex"`
+	// CountedAt は数え直した日時。一度も数えていない場合はゼロ値
+	CountedAt time.Time `json:"countedAt" datastore:",noindex"`
+}
+
+// HogeStatsStore はHogeの件数の集計を操作するメソッドをまとめる
+type HogeStatsStore struct{}
+
+// Get はHogeの件数の集計を取得する。一度も数えていない場合はTotalが0の集計を返す
+func (store *HogeStatsStore) Get(g ds.Client) (*HogeStats, error) {
+	stats := &HogeStats{
+		ID: hogeStatsID,
+	}
+	if err := g.Get(stats); err != nil && err != ds.ErrNoSuchEntity {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Recount は全てのHogeをキーのみのクエリで数え直して保存する
+//
+// recountBatchSize件数える毎にprogressに数えた件数を渡す。何度実行しても結果は変わらない
+func (store *HogeStatsStore) Recount(g ds.Client, progress func(done int) error) (*HogeStats, error) {
+	q := ds.NewQuery(g.Kind(Hoge{})).KeysOnly().Limit(recountBatchSize)
+
+	stats := &HogeStats{
+		ID:        hogeStatsID,
+		CountedAt: time.Now(),
+	}
+
+	cursor := ""
+
+	for {
+		if cursor != "" {
+			q = q.Start(cursor)
+		}
+
+		it := g.Run(q)
+
+		n := 0
+		for {
+			if _, err := it.Next(nil); err != nil {
+				if err == ds.Done {
+					break
+				}
+
+				return nil, err
+			}
+
+			n++
+		}
+
+		stats.Total += n
+
+		if n < recountBatchSize {
+			break
+		}
+
+		if err := progress(stats.Total); err != nil {
+			return nil, err
+		}
+
+		next, err := it.Cursor()
+		if err != nil {
+			return nil, err
+		}
+
+		cursor = next
+	}
+
+	if err := Retry(g.Context(), "hogestats.put", func() error {
+		return g.Put(stats)
+	}); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
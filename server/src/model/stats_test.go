@@ -0,0 +1,51 @@
+package model_test
+
+import (
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"testing"
+)
+
+func TestHogeStatsStore(t *testing.T) {
+	store := &model.HogeStatsStore{}
+
+	t.Run("数え直していない場合、Totalが0となること", func(t *testing.T) {
+		stats, err := store.Get(dstest.NewClient())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if stats.Total != 0 || !stats.CountedAt.IsZero() {
+			t.Errorf("stats: unexpected, actual: `%+v`", stats)
+		}
+	})
+
+	t.Run("HogeQuery.Countの上限を超える件数でも全件を数え、保存されること", func(t *testing.T) {
+		n := model.MaxHogeCount*2 + 1
+		g, _ := newHogesClient(t, n)
+
+		var progress []int
+		stats, err := store.Recount(g, func(done int) error {
+			progress = append(progress, done)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if stats.Total != n {
+			t.Errorf("Total: unexpected, actual: `%d`, expected: `%d`", stats.Total, n)
+		}
+		if len(progress) != 2 {
+			t.Errorf("progress: unexpected, actual: `%v`", progress)
+		}
+
+		saved, err := store.Get(g)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if saved.Total != n || saved.CountedAt.IsZero() {
+			t.Errorf("saved: unexpected, actual: `%+v`", saved)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package model
+
+import "gaego-gin/server/src/ds"
+
+// txClient はRunInTransactionがfに渡すトランザクション内のClient
+type txClient struct {
+	ds.Client
+	afterCommit []func(g ds.Client)
+}
+
+// InTransaction はgがRunInTransactionのトランザクション内のClientか判定する
+func InTransaction(g ds.Client) bool {
+	_, ok := g.(*txClient)
+	return ok
+}
+
+// AfterCommit はトランザクションのコミット後にfを実行する
+//
+// fにはトランザクション外のClientを渡す
+// gがRunInTransactionのトランザクション内のClientでない場合は、直ちにgでfを実行する
+func AfterCommit(g ds.Client, f func(g ds.Client)) {
+	tx, ok := g.(*txClient)
+	if !ok {
+		f(g)
+		return
+	}
+
+	tx.afterCommit = append(tx.afterCommit, f)
+}
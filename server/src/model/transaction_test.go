@@ -0,0 +1,100 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"testing"
+)
+
+// txClient はRunInTransactionの結果を順に返すds.Client
+type txClient struct {
+	ds.Client
+	results []error
+}
+
+func (c *txClient) Context() context.Context {
+	return context.Background()
+}
+
+func (c *txClient) RunInTransaction(f func(tg ds.Client) error) error {
+	if err := f(c); err != nil {
+		return err
+	}
+
+	err := c.results[0]
+	c.results = c.results[1:]
+
+	return err
+}
+
+func TestAfterCommit(t *testing.T) {
+	t.Run("コミットに成功した試行で登録した処理のみ、コミット後に実行されること", func(t *testing.T) {
+		g := &txClient{results: []error{ds.ErrConcurrentTransaction, nil}}
+
+		attempts := 0
+		var called []int
+		err := newPolicy().RunInTransaction(g, "test", func(tg ds.Client) error {
+			attempts++
+			if !model.InTransaction(tg) {
+				t.Error("InTransaction: expected true")
+			}
+
+			n := attempts
+			model.AfterCommit(tg, func(g ds.Client) {
+				if model.InTransaction(g) {
+					t.Error("InTransaction: expected false after commit")
+				}
+				called = append(called, n)
+			})
+
+			if len(called) != 0 {
+				t.Error("AfterCommit: called before commit")
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(called) != 1 || called[0] != 2 {
+			t.Errorf("called: unexpected, actual: `%v`, expected: `[2]`", called)
+		}
+	})
+
+	t.Run("トランザクションが失敗した場合、実行されないこと", func(t *testing.T) {
+		g := &txClient{}
+
+		called := false
+		err := newPolicy().RunInTransaction(g, "test", func(tg ds.Client) error {
+			model.AfterCommit(tg, func(g ds.Client) {
+				called = true
+			})
+			return errors.New("failed")
+		})
+
+		if err == nil {
+			t.Fatal("err: expected error")
+		}
+		if called {
+			t.Error("called: expected false")
+		}
+	})
+
+	t.Run("トランザクション外の場合、直ちに実行されること", func(t *testing.T) {
+		g := &txClient{}
+
+		called := false
+		model.AfterCommit(g, func(g ds.Client) {
+			called = true
+		})
+
+		if !called {
+			t.Error("called: expected true")
+		}
+		if model.InTransaction(g) {
+			t.Error("InTransaction: expected false")
+		}
+	})
+}
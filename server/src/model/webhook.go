@@ -0,0 +1,304 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidWebhookURL はWebhookの通知先のURLが不正な場合のエラー
+	ErrInvalidWebhookURL = errors.New("url must be an absolute http or https URL")
+	// ErrInsecureWebhookURL はWebhookの通知先のURLがhttpsでない場合のエラー
+	ErrInsecureWebhookURL = errors.New("url must use https")
+	// ErrWebhookAddressNotAllowed はWebhookの通知先がループバックや内部ネットワークのアドレスの場合のエラー
+	ErrWebhookAddressNotAllowed = errors.New("url must not point to a loopback, private, link-local or unspecified address")
+	// ErrInvalidEventType はWebhookで通知するイベントの種類が不正な場合のエラー
+	ErrInvalidEventType = errors.New("events must be hoge.created, hoge.updated or hoge.deleted")
+)
+
+// AllowInsecureWebhooks がtrueの場合、httpのURLとループバックや内部ネットワークのアドレスへの通知を許可する
+//
+// 開発用サーバーとテストでのみ有効にする
+var AllowInsecureWebhooks = false
+
+// privateNetworks はWebhookの通知先として許可しない内部ネットワークのアドレスの範囲
+//
+// 0.0.0.0/8は未指定のアドレスだけでなく範囲全体が「このネットワーク」を表し、通知先として正当なアドレスではないため含める
+var privateNetworks = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets
+}()
+
+// CheckWebhookIP はipがWebhookの通知先として許可されるか判定する
+//
+// ループバック、内部ネットワーク、リンクローカル(メタデータサーバーを含む)、未指定(0.0.0.0/8)、マルチキャストのアドレスはErrWebhookAddressNotAllowedを返す
+func CheckWebhookIP(ip net.IP) error {
+	if AllowInsecureWebhooks {
+		return nil
+	}
+
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return ErrWebhookAddressNotAllowed
+	}
+
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+
+	return nil
+}
+
+// MaxDeadLetters はListDeadLettersで取得する件数の上限
+const MaxDeadLetters = 100
+
+// WebhookStore はWebhookを操作するメソッドをまとめる
+type WebhookStore struct{}
+
+// Webhook はHogeの変更を通知する先
+type Webhook struct {
+	ID  string `json:"id" datastore:"-" goon:"id"`
+	URL string `json:"url" datastore:",noindex"`
+	// Events は通知するイベントの種類。空の場合は全てのイベントを通知する
+	Events []EventType `json:"events" datastore:",noindex"`
+	// Secret は署名の鍵。作成時とsecretを指定した更新時のレスポンスのみに含める
+	Secret    string    `json:"secret,omitempty" datastore:",noindex"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Validate はWebhookを保存できるか検証する
+//
+// 名前解決の結果は通知時に変わる場合があるため、ホスト名のアドレスは送信時に検証する
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	if !AllowInsecureWebhooks {
+		if u.Scheme != "https" {
+			return ErrInsecureWebhookURL
+		}
+
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if err := CheckWebhookIP(ip); err != nil {
+			return err
+		}
+	}
+
+	for _, typ := range w.Events {
+		if !typ.Valid() {
+			return ErrInvalidEventType
+		}
+	}
+
+	return nil
+}
+
+// Subscribes はtypのイベントを通知するか判定する
+func (w *Webhook) Subscribes(typ EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, v := range w.Events {
+		if v == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Create はWebhookを新規作成する。Secretが空の場合は生成する
+//
+// IDは1回だけ生成し、保存のみをリトライするため、リトライしても別のIDのWebhookは作成しない
+func (store *WebhookStore) Create(g ds.Client, w *Webhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	w.ID = id
+
+	if w.Secret == "" {
+		if w.Secret, err = newID(); err != nil {
+			return err
+		}
+	}
+	if w.Events == nil {
+		w.Events = []EventType{}
+	}
+
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+
+	return Retry(g.Context(), "webhook.create", func() error {
+		return g.Put(w)
+	})
+}
+
+// Get はWebhookを1件取得する
+func (store *WebhookStore) Get(g ds.Client, id string) (*Webhook, error) {
+	if id == "" {
+		return nil, ErrIDRequired
+	}
+
+	w := &Webhook{
+		ID: id,
+	}
+	if err := g.Get(w); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Exists はWebhookが1件以上登録されているか判定する
+func (store *WebhookStore) Exists(g ds.Client) (bool, error) {
+	it := g.Run(ds.NewQuery(g.Kind(Webhook{})).KeysOnly().Limit(1))
+
+	_, err := it.Next(nil)
+	if err == ds.Done {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// List は全てのWebhookを取得する
+func (store *WebhookStore) List(g ds.Client) ([]*Webhook, error) {
+	it := g.Run(ds.NewQuery(g.Kind(Webhook{})))
+
+	list := []*Webhook{}
+	for {
+		w := &Webhook{}
+		id, err := it.Next(w)
+		if err == ds.Done {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		w.ID = id
+		list = append(list, w)
+	}
+}
+
+// Update はWebhookを更新する。Secretが空の場合は既存の値を引き継ぐ
+func (store *WebhookStore) Update(g ds.Client, w *Webhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	old, err := store.Get(g, w.ID)
+	if err != nil {
+		return err
+	}
+
+	if w.Secret == "" {
+		w.Secret = old.Secret
+	}
+	if w.Events == nil {
+		w.Events = []EventType{}
+	}
+
+	w.CreatedAt = old.CreatedAt
+	w.UpdatedAt = time.Now()
+
+	return g.Put(w)
+}
+
+// Delete はWebhookを削除する
+func (store *WebhookStore) Delete(g ds.Client, id string) error {
+	if id == "" {
+		return ErrIDRequired
+	}
+
+	return g.Delete(&Webhook{ID: id})
+}
+
+// WebhookDeadLetter はリトライの上限に達しても通知できなかったイベント
+type WebhookDeadLetter struct {
+	// ID はWebhookとイベントの組み合わせ。同じイベントは1件のみ保存する
+	ID        string          `json:"id" datastore:"-" goon:"id"`
+	WebhookID string          `json:"webhookId"`
+	Event     json.RawMessage `json:"event" datastore:",noindex"`
+	Attempts  int             `json:"attempts"`
+	// StatusCode は最後の試行のレスポンスのステータスコード。レスポンスを受信できなかった場合は0
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error" datastore:",noindex"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// AddDeadLetter はWebhookDeadLetterを保存する
+func (store *WebhookStore) AddDeadLetter(g ds.Client, webhookID string, event *HogeEvent, attempts, statusCode int, cause error) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return g.Put(&WebhookDeadLetter{
+		ID:         webhookID + "-" + event.ID,
+		WebhookID:  webhookID,
+		Event:      b,
+		Attempts:   attempts,
+		StatusCode: statusCode,
+		Error:      cause.Error(),
+		CreatedAt:  time.Now(),
+	})
+}
+
+// ListDeadLetters はWebhookのWebhookDeadLetterを新しい順にMaxDeadLetters件まで取得する
+func (store *WebhookStore) ListDeadLetters(g ds.Client, webhookID string) ([]*WebhookDeadLetter, error) {
+	q := ds.NewQuery(g.Kind(WebhookDeadLetter{})).
+		Filter("WebhookID", "=", webhookID).
+		Order("-CreatedAt").
+		Limit(MaxDeadLetters)
+
+	it := g.Run(q)
+
+	list := []*WebhookDeadLetter{}
+	for {
+		dl := &WebhookDeadLetter{}
+		id, err := it.Next(dl)
+		if err == ds.Done {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		dl.ID = id
+		list = append(list, dl)
+	}
+}
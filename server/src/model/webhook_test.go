@@ -0,0 +1,107 @@
+package model_test
+
+import (
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"testing"
+)
+
+func TestWebhook_Validate(t *testing.T) {
+	defer func(v bool) { model.AllowInsecureWebhooks = v }(model.AllowInsecureWebhooks)
+
+	tests := []struct {
+		name     string
+		webhook  *model.Webhook
+		insecure bool
+		expected error
+	}{
+		{"httpsのURLの場合、エラーとならないこと", &model.Webhook{URL: "https://example.com/hook"}, false, nil},
+		{"httpsのURLとイベントを指定した場合、エラーとならないこと", &model.Webhook{URL: "https://203.0.113.1/hook", Events: []model.EventType{model.HogeCreated, model.HogeDeleted}}, false, nil},
+		{"URLが空の場合、エラーとなること", &model.Webhook{}, false, model.ErrInvalidWebhookURL},
+		{"相対URLの場合、エラーとなること", &model.Webhook{URL: "/hook"}, false, model.ErrInvalidWebhookURL},
+		{"http以外のURLの場合、エラーとなること", &model.Webhook{URL: "ftp://example.com/hook"}, false, model.ErrInvalidWebhookURL},
+		{"httpのURLの場合、エラーとなること", &model.Webhook{URL: "http://example.com/hook"}, false, model.ErrInsecureWebhookURL},
+		{"localhostの場合、エラーとなること", &model.Webhook{URL: "https://localhost/hook"}, false, model.ErrWebhookAddressNotAllowed},
+		{"ループバックのアドレスの場合、エラーとなること", &model.Webhook{URL: "https://127.0.0.1/hook"}, false, model.ErrWebhookAddressNotAllowed},
+		{"IPv6のループバックのアドレスの場合、エラーとなること", &model.Webhook{URL: "https://[::1]/hook"}, false, model.ErrWebhookAddressNotAllowed},
+		{"内部ネットワークのアドレスの場合、エラーとなること", &model.Webhook{URL: "https://10.0.0.1/hook"}, false, model.ErrWebhookAddressNotAllowed},
+		{"メタデータサーバーのアドレスの場合、エラーとなること", &model.Webhook{URL: "https://169.254.169.254/hook"}, false, model.ErrWebhookAddressNotAllowed},
+		{"未指定のアドレスの場合、エラーとなること", &model.Webhook{URL: "https://0.0.0.0/hook"}, false, model.ErrWebhookAddressNotAllowed},
+		{"0.0.0.0/8のアドレスの場合、エラーとなること", &model.Webhook{URL: "https://0.1.2.3/hook"}, false, model.ErrWebhookAddressNotAllowed},
+		{"AllowInsecureWebhooksの場合、httpとlocalhostを許可すること", &model.Webhook{URL: "http://localhost:8080/hook"}, true, nil},
+		{"不明なイベントの場合、エラーとなること", &model.Webhook{URL: "https://example.com/hook", Events: []model.EventType{"hoge.moved"}}, false, model.ErrInvalidEventType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model.AllowInsecureWebhooks = tt.insecure
+
+			if err := tt.webhook.Validate(); err != tt.expected {
+				t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWebhook_Subscribes(t *testing.T) {
+	t.Run("イベントが空の場合、全てのイベントを通知すること", func(t *testing.T) {
+		w := &model.Webhook{}
+		for _, typ := range model.EventTypes {
+			if !w.Subscribes(typ) {
+				t.Errorf("Subscribes(%s): expected true", typ)
+			}
+		}
+	})
+
+	t.Run("指定したイベントのみ通知すること", func(t *testing.T) {
+		w := &model.Webhook{Events: []model.EventType{model.HogeUpdated}}
+		if !w.Subscribes(model.HogeUpdated) {
+			t.Error("Subscribes(hoge.updated): expected true")
+		}
+		if w.Subscribes(model.HogeCreated) || w.Subscribes(model.HogeDeleted) {
+			t.Error("Subscribes: expected false for other events")
+		}
+	})
+}
+
+// flakyPutClient は最初のPutのみ一時的なエラーとなるClient
+type flakyPutClient struct {
+	*dstest.Client
+	puts int
+}
+
+func (c *flakyPutClient) Put(src interface{}) error {
+	c.puts++
+	if c.puts == 1 {
+		return &ds.TransientError{Err: errors.New("unavailable")}
+	}
+
+	return c.Client.Put(src)
+}
+
+func TestWebhookStore_Create(t *testing.T) {
+	defer func(p *model.RetryPolicy) { model.DefaultRetryPolicy = p }(model.DefaultRetryPolicy)
+	model.DefaultRetryPolicy = &model.RetryPolicy{MaxAttempts: 2, Retryable: model.IsRetryable}
+
+	t.Run("保存をリトライした場合、最初に生成したIDで1件のみ作成すること", func(t *testing.T) {
+		g := &flakyPutClient{Client: dstest.NewClient()}
+
+		w := &model.Webhook{URL: "https://example.com/hook"}
+		if err := (&model.WebhookStore{}).Create(g, w); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if g.puts != 2 {
+			t.Errorf("puts: unexpected, actual: `%d`, expected: `2`", g.puts)
+		}
+		if n := g.Store().Len(g.Kind(model.Webhook{})); n != 1 {
+			t.Errorf("Len: unexpected, actual: `%d`, expected: `1`", n)
+		}
+
+		if _, err := (&model.WebhookStore{}).Get(g, w.ID); err != nil {
+			t.Errorf("Get(%s): %s", w.ID, err.Error())
+		}
+	})
+}
@@ -0,0 +1,16 @@
+//go:build !appengine
+// +build !appengine
+
+package outbox
+
+import (
+	"context"
+	"net/http"
+)
+
+var client = &http.Client{Timeout: SendTimeout}
+
+// httpClient は配信先に送信するhttp.Clientを返す
+func httpClient(ctx context.Context) *http.Client {
+	return client
+}
@@ -0,0 +1,21 @@
+//go:build appengine
+// +build appengine
+
+package outbox
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/appengine/urlfetch"
+)
+
+// httpClient は配信先に送信するhttp.Clientを返す
+//
+// 第1世代のApp Engineでは外部への通信にURL Fetchを利用する
+func httpClient(ctx context.Context) *http.Client {
+	c := urlfetch.Client(ctx)
+	c.Timeout = SendTimeout
+
+	return c
+}
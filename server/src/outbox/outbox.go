@@ -0,0 +1,270 @@
+// Package outbox はHogeのドメインイベントを変更と同じトランザクションで保存し、配信先(Sink)に配信する
+//
+// イベントはmodel.OutboxEventとして保存し、コミットした場合のみ配信するJobを登録する
+// 配信に失敗したイベントはcronから定期的に再配信するため、配信先には少なくとも1回配信する
+// 同じイベントを複数回配信する場合があるため、配信先はイベントのIDで重複を取り除く
+package outbox
+
+import (
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/model"
+	"strings"
+	"time"
+)
+
+var log = logger.New("outbox")
+
+// Sink はイベントの配信先
+type Sink interface {
+	// Name は配信先の名前。配信済みの配信先の記録とログに利用する
+	Name() string
+	// Send はeventを配信する。eventのIDで重複を取り除けるようにする
+	Send(g ds.Client, event *model.HogeEvent) error
+}
+
+// Sinks はイベントの配信先
+var Sinks []Sink
+
+// MaxAttempts はイベントの配信を失敗とするまでの最大の試行回数
+var MaxAttempts = 10
+
+// SweepDelay はSweepで再配信するまでの、最後の試行からの経過時間
+//
+// 登録直後のJobによる配信と重複しないように待つ
+var SweepDelay = time.Minute
+
+// sweepBatchSize はSweepで1回に再配信するイベントの件数
+const sweepBatchSize = 100
+
+const deliverJobType = "outbox.deliver"
+
+func init() {
+	jobs.Register(deliverJobType, deliver)
+}
+
+// Add はeventを配信待ちとして保存し、配信するJobを登録する
+//
+// gはmodel.RunInTransactionのトランザクション内のClientとし、変更と共にコミットした場合のみ配信する
+func Add(g ds.Client, event *model.HogeEvent) error {
+	store := &model.OutboxStore{}
+
+	if _, err := store.Add(g, event); err != nil {
+		return err
+	}
+
+	job, err := model.NewJob(deliverJobType, &deliverParams{ID: event.ID}, nil)
+	if err != nil {
+		return err
+	}
+
+	return jobs.Enqueue(g, job)
+}
+
+// Publish はhogeの変更を変更履歴に保存し、配信待ちのイベントとして保存する
+//
+// HTTPとgRPCのAPIでHogeを変更した場合に、変更と同じトランザクション内のgで呼び出す
+func Publish(g ds.Client, typ model.EventType, hoge *model.Hoge) error {
+	event, err := model.NewHogeEvent(typ, hoge)
+	if err != nil {
+		return err
+	}
+
+	store := &model.HogeChangeStore{}
+	if _, err := store.Append(g, event); err != nil {
+		return err
+	}
+
+	return Add(g, event)
+}
+
+// PublishEvents はeventsを変更履歴に保存し、配信待ちのイベントとして保存する
+//
+// インポートのように1つのトランザクションで複数のHogeを変更する場合に、変更と同じトランザクション内のgで呼び出す
+// 配信するJobはeventsにつき1件のみ登録する
+func PublishEvents(g ds.Client, events []*model.HogeEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	changes := &model.HogeChangeStore{}
+	store := &model.OutboxStore{}
+
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		if _, err := changes.Append(g, event); err != nil {
+			return err
+		}
+
+		if _, err := store.Add(g, event); err != nil {
+			return err
+		}
+
+		ids = append(ids, event.ID)
+	}
+
+	job, err := model.NewJob(deliverJobType, &deliverParams{IDs: ids}, nil)
+	if err != nil {
+		return err
+	}
+
+	return jobs.Enqueue(g, job)
+}
+
+// deliverParams はoutbox.deliverのパラメータ。IDとIDsのイベントを配信する
+type deliverParams struct {
+	ID  string   `json:"id,omitempty"`
+	IDs []string `json:"ids,omitempty"`
+}
+
+// deliverResult はoutbox.deliverのイベント毎の結果
+type deliverResult struct {
+	ID        string             `json:"id"`
+	Status    model.OutboxStatus `json:"status"`
+	Delivered []string           `json:"delivered"`
+}
+
+// deliver はイベントを配信し、失敗した場合はリトライする
+//
+// リトライした場合は配信を終えたイベントを配信しない
+// リトライの上限に達した場合もイベントは配信待ちのまま残し、Sweepで再配信する
+func deliver(g ds.Client, job *model.Job) (interface{}, error) {
+	p := &deliverParams{}
+	if err := job.Bind(p); err != nil {
+		return nil, err
+	}
+
+	ids := p.IDs
+	if p.ID != "" {
+		ids = append([]string{p.ID}, ids...)
+	}
+
+	results := make([]*deliverResult, 0, len(ids))
+	var errs []string
+
+	for _, id := range ids {
+		e, err := Deliver(g, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Status == model.OutboxPending {
+			errs = append(errs, e.ID+": "+e.Error)
+		}
+
+		results = append(results, &deliverResult{ID: e.ID, Status: e.Status, Delivered: e.Delivered})
+	}
+
+	if len(errs) > 0 {
+		return nil, &jobs.RetryableError{Err: fmt.Errorf("outbox: %s", strings.Join(errs, "; "))}
+	}
+
+	return results, nil
+}
+
+// Deliver はIDのイベントを配信していない配信先に配信し、結果を保存したOutboxEventを返す
+//
+// 配信を終えたイベントは配信しない。MaxAttemptsに達しても配信できなかったイベントは失敗として保存する
+func Deliver(g ds.Client, id string) (*model.OutboxEvent, error) {
+	store := &model.OutboxStore{}
+
+	var e *model.OutboxEvent
+	if err := model.Retry(g.Context(), "outbox.get", func() error {
+		var err error
+		e, err = store.Get(g, id)
+		return err
+
+	}); err != nil {
+		return nil, err
+	}
+
+	if e.Status != model.OutboxPending {
+		return e, nil
+	}
+
+	event, err := e.Bind()
+	if err != nil {
+		return nil, err
+	}
+
+	// 配信はトランザクションの外で行い、結果のみをトランザクションで保存する
+	var (
+		delivered []string
+		errs      []string
+	)
+	for _, sink := range Sinks {
+		if e.IsDelivered(sink.Name()) {
+			continue
+		}
+
+		if err := sink.Send(g, event); err != nil {
+			log.Warningf(g.Context(), "event %s: %s failed: %v", e.ID, sink.Name(), err)
+			errs = append(errs, sink.Name()+": "+err.Error())
+			continue
+		}
+
+		delivered = append(delivered, sink.Name())
+	}
+
+	if err := model.RunInTransaction(g, "outbox.deliver", func(tg ds.Client) error {
+		var err error
+		if e, err = store.Get(tg, id); err != nil {
+			return err
+		}
+
+		for _, name := range delivered {
+			if !e.IsDelivered(name) {
+				e.Delivered = append(e.Delivered, name)
+			}
+		}
+
+		e.Attempts++
+		e.Error = strings.Join(errs, "; ")
+
+		switch {
+		case len(errs) == 0:
+			e.Status = model.OutboxDelivered
+		case e.Attempts >= MaxAttempts:
+			e.Status = model.OutboxFailed
+			log.Errorf(tg.Context(), "event %s gave up after %d attempts: %s", e.ID, e.Attempts, e.Error)
+		}
+
+		return store.Save(tg, e)
+
+	}); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Sweep はSweepDelayより前に試行した配信待ちのイベントを再配信し、配信を終えた件数を返す
+func Sweep(g ds.Client) (int, error) {
+	store := &model.OutboxStore{}
+
+	var list []*model.OutboxEvent
+	if err := model.Retry(g.Context(), "outbox.sweep", func() error {
+		var err error
+		list, err = store.ListPending(g, time.Now().Add(-SweepDelay), sweepBatchSize)
+		return err
+
+	}); err != nil {
+		return 0, err
+	}
+
+	finished := 0
+	for _, v := range list {
+		e, err := Deliver(g, v.ID)
+		if err != nil {
+			return finished, err
+		}
+
+		if e.Status != model.OutboxPending {
+			finished++
+		}
+	}
+
+	return finished, nil
+}
@@ -0,0 +1,338 @@
+package outbox_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink は配信したイベントを記録し、failuresの回数だけ失敗する配信先
+type fakeSink struct {
+	name     string
+	failures int
+	events   []*model.HogeEvent
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Send(g ds.Client, event *model.HogeEvent) error {
+	s.events = append(s.events, event)
+
+	if s.failures != 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	defer func(sinks []outbox.Sink, maxAttempts, jobAttempts int, delay time.Duration) {
+		outbox.Sinks = sinks
+		outbox.MaxAttempts = maxAttempts
+		jobs.MaxAttempts = jobAttempts
+		outbox.SweepDelay = delay
+	}(outbox.Sinks, outbox.MaxAttempts, jobs.MaxAttempts, outbox.SweepDelay)
+	outbox.MaxAttempts = 4
+	jobs.MaxAttempts = 2
+	outbox.SweepDelay = 0
+
+	add := func(t *testing.T, g ds.Client, fail bool) *model.HogeEvent {
+		event, err := model.NewHogeEvent(model.HogeCreated, &model.Hoge{ID: "hoge", Value: "hogehoge"})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		errAbort := errors.New("abort")
+		err = model.RunInTransaction(g, "test", func(tg ds.Client) error {
+			if err := outbox.Add(tg, event); err != nil {
+				return err
+			}
+
+			if fail {
+				return errAbort
+			}
+			return nil
+		})
+		if fail && err != errAbort || !fail && err != nil {
+			t.Fatalf("err: unexpected, actual: `%v`", err)
+		}
+
+		return event
+	}
+
+	get := func(t *testing.T, g ds.Client, id string) *model.OutboxEvent {
+		store := &model.OutboxStore{}
+		e, err := store.Get(g, id)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return e
+	}
+
+	t.Run("コミットした場合、全ての配信先に配信されること", func(t *testing.T) {
+		a, b := &fakeSink{name: "a"}, &fakeSink{name: "b"}
+		outbox.Sinks = []outbox.Sink{a, b}
+
+		g := dstest.NewClient()
+		event := add(t, g, false)
+
+		if len(a.events) != 1 || len(b.events) != 1 {
+			t.Fatalf("events: unexpected, actual: `%d`, `%d`", len(a.events), len(b.events))
+		}
+		if a.events[0].ID != event.ID || a.events[0].Hoge.Value != "hogehoge" {
+			t.Errorf("event: unexpected, actual: `%+v`", a.events[0])
+		}
+
+		e := get(t, g, event.ID)
+		if e.Status != model.OutboxDelivered || e.Attempts != 1 {
+			t.Errorf("outbox: unexpected, actual: `%s`, attempts: `%d`", e.Status, e.Attempts)
+		}
+	})
+
+	t.Run("ロールバックした場合、配信されないこと", func(t *testing.T) {
+		a := &fakeSink{name: "a"}
+		outbox.Sinks = []outbox.Sink{a}
+
+		add(t, dstest.NewClient(), true)
+
+		if len(a.events) != 0 {
+			t.Errorf("events: unexpected, actual: `%d`, expected: `%d`", len(a.events), 0)
+		}
+	})
+
+	t.Run("失敗した配信先のみに再配信され、Sweepで配信を終えること", func(t *testing.T) {
+		a, b := &fakeSink{name: "a"}, &fakeSink{name: "b", failures: 2}
+		outbox.Sinks = []outbox.Sink{a, b}
+
+		g := dstest.NewClient()
+		event := add(t, g, false)
+
+		// JobのリトライでもbはMaxAttemptsまで失敗するため、配信待ちのまま残る
+		e := get(t, g, event.ID)
+		if e.Status != model.OutboxPending || e.Attempts != jobs.MaxAttempts || e.Error == "" {
+			t.Fatalf("outbox: unexpected, actual: `%s`, attempts: `%d`, error: `%s`", e.Status, e.Attempts, e.Error)
+		}
+
+		n, err := outbox.Sweep(g)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if n != 1 {
+			t.Errorf("Sweep: unexpected, actual: `%d`, expected: `%d`", n, 1)
+		}
+
+		if len(a.events) != 1 || len(b.events) != 3 {
+			t.Errorf("events: unexpected, actual: `%d`, `%d`", len(a.events), len(b.events))
+		}
+		for _, v := range b.events {
+			if v.ID != event.ID {
+				t.Errorf("event.ID: unexpected, actual: `%s`, expected: `%s`", v.ID, event.ID)
+			}
+		}
+
+		e = get(t, g, event.ID)
+		if e.Status != model.OutboxDelivered || e.Error != "" {
+			t.Errorf("outbox: unexpected, actual: `%s`, error: `%s`", e.Status, e.Error)
+		}
+	})
+
+	t.Run("試行回数の上限に達した場合、失敗となること", func(t *testing.T) {
+		a := &fakeSink{name: "a", failures: -1}
+		outbox.Sinks = []outbox.Sink{a}
+
+		g := dstest.NewClient()
+		event := add(t, g, false)
+
+		for i := 0; i < 2; i++ {
+			if _, err := outbox.Sweep(g); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		e := get(t, g, event.ID)
+		if e.Status != model.OutboxFailed || e.Attempts != outbox.MaxAttempts {
+			t.Errorf("outbox: unexpected, actual: `%s`, attempts: `%d`", e.Status, e.Attempts)
+		}
+		if len(a.events) != outbox.MaxAttempts {
+			t.Errorf("events: unexpected, actual: `%d`, expected: `%d`", len(a.events), outbox.MaxAttempts)
+		}
+	})
+
+	t.Run("PublishEventsの場合、全てのイベントが変更履歴に保存され、1件のJobで配信されること", func(t *testing.T) {
+		a := &fakeSink{name: "a"}
+		outbox.Sinks = []outbox.Sink{a}
+
+		g := dstest.NewClient()
+
+		var events []*model.HogeEvent
+		for _, id := range []string{"hoge0", "hoge1"} {
+			event, err := model.NewHogeEvent(model.HogeCreated, &model.Hoge{ID: id})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			events = append(events, event)
+		}
+
+		if err := model.RunInTransaction(g, "test", func(tg ds.Client) error {
+			return outbox.PublishEvents(tg, events)
+		}); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(a.events) != 2 || a.events[0].ID != events[0].ID || a.events[1].ID != events[1].ID {
+			t.Fatalf("events: unexpected, actual: `%d`", len(a.events))
+		}
+		for _, event := range events {
+			if e := get(t, g, event.ID); e.Status != model.OutboxDelivered {
+				t.Errorf("outbox: unexpected, actual: `%s`", e.Status)
+			}
+		}
+		if n := g.Store().Len("HogeChange"); n != 2 {
+			t.Errorf("len(HogeChange): unexpected, actual: `%d`, expected: `%d`", n, 2)
+		}
+		if n := g.Store().Len("Job"); n != 1 {
+			t.Errorf("len(Job): unexpected, actual: `%d`, expected: `%d`", n, 1)
+		}
+	})
+}
+
+func TestHTTPSink(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	event, err := model.NewHogeEvent(model.HogeUpdated, &model.Hoge{ID: "hoge"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	sink := &outbox.HTTPSink{URL: server.URL}
+	if err := sink.Send(dstest.NewClient(), event); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if v := header.Get(outbox.IdempotencyKeyHeader); v != event.ID {
+		t.Errorf("Idempotency-Key: unexpected, actual: `%s`, expected: `%s`", v, event.ID)
+	}
+
+	v := &model.HogeEvent{}
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatal(err.Error())
+	}
+	if v.ID != event.ID || v.Type != model.HogeUpdated {
+		t.Errorf("event: unexpected, actual: `%+v`", v)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	sink = &outbox.HTTPSink{URL: missing.URL}
+	if err := sink.Send(dstest.NewClient(), event); err == nil {
+		t.Error("err: expected an error for 404")
+	}
+}
+
+func TestPubSubSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		created  bool
+		requests []string
+		messages []map[string]interface{}
+	)
+	emulator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method + " " + r.URL.Path {
+		case "PUT /v1/projects/test/topics/hoge-events":
+			created = true
+
+		case "POST /v1/projects/test/topics/hoge-events:publish":
+			if !created {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			req := &struct {
+				Messages []map[string]interface{} `json:"messages"`
+			}{}
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			messages = append(messages, req.Messages...)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer emulator.Close()
+
+	event, err := model.NewHogeEvent(model.HogeDeleted, &model.Hoge{ID: "hoge"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	sink := &outbox.PubSubSink{Host: emulator.Listener.Addr().String(), Project: "test", Topic: "hoge-events"}
+	for i := 0; i < 2; i++ {
+		if err := sink.Send(dstest.NewClient(), event); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	expected := []string{
+		"POST /v1/projects/test/topics/hoge-events:publish",
+		"PUT /v1/projects/test/topics/hoge-events",
+		"POST /v1/projects/test/topics/hoge-events:publish",
+		"POST /v1/projects/test/topics/hoge-events:publish",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("requests: unexpected, actual: `%v`, expected: `%v`", requests, expected)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("messages: unexpected, actual: `%d`, expected: `%d`", len(messages), 2)
+	}
+
+	attrs := messages[0]["attributes"].(map[string]interface{})
+	if attrs["eventId"] != event.ID || attrs["eventType"] != string(model.HogeDeleted) {
+		t.Errorf("attributes: unexpected, actual: `%v`", attrs)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(messages[0]["data"].(string))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	v := &model.HogeEvent{}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err.Error())
+	}
+	if v.ID != event.ID {
+		t.Errorf("event.ID: unexpected, actual: `%s`, expected: `%s`", v.ID, event.ID)
+	}
+}
@@ -0,0 +1,176 @@
+package outbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SendTimeout は1回の配信の処理時間の上限
+const SendTimeout = 10 * time.Second
+
+// IdempotencyKeyHeader はHTTPSinkでイベントのIDを表すリクエストヘッダー
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// LogSink はイベントをログに出力する配信先。動作の確認に利用する
+type LogSink struct{}
+
+// Name は"log"を返す
+func (s *LogSink) Name() string {
+	return "log"
+}
+
+// Send はeventをログに出力する
+func (s *LogSink) Send(g ds.Client, event *model.HogeEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	log.Infof(g.Context(), "event %s %s: %s", event.ID, event.Type, b)
+	return nil
+}
+
+// HTTPSink はイベントをURLにPOSTする配信先
+//
+// イベントのIDをIdempotency-Keyヘッダーに含め、2xx以外のレスポンスは失敗とする
+type HTTPSink struct {
+	URL string
+}
+
+// Name は"http"を返す
+func (s *HTTPSink) Name() string {
+	return "http"
+}
+
+// Send はeventをJSONとしてPOSTする
+func (s *HTTPSink) Send(g ds.Client, event *model.HogeEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, event.ID)
+
+	return send(g, req)
+}
+
+// PubSubSink はイベントをPub/Subのトピックに公開する配信先
+//
+// Pub/SubエミュレーターのREST APIを利用する。トピックが存在しない場合は作成する
+// メッセージのdataはイベントのJSONとし、属性のeventIdで重複を取り除けるようにする
+type PubSubSink struct {
+	// Host はエミュレーターの"host:port"。PUBSUB_EMULATOR_HOSTと同じ形式
+	Host    string
+	Project string
+	Topic   string
+}
+
+// Name は"pubsub"を返す
+func (s *PubSubSink) Name() string {
+	return "pubsub"
+}
+
+// pubsubPublishRequest はtopics.publishのリクエストボディ
+type pubsubPublishRequest struct {
+	Messages []*pubsubMessage `json:"messages"`
+}
+
+type pubsubMessage struct {
+	Data       string            `json:"data"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// Send はeventをトピックに公開する
+func (s *PubSubSink) Send(g ds.Client, event *model.HogeEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(&pubsubPublishRequest{
+		Messages: []*pubsubMessage{{
+			Data: base64.StdEncoding.EncodeToString(b),
+			Attributes: map[string]string{
+				"eventId":   event.ID,
+				"eventType": string(event.Type),
+			},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	publish := func() error {
+		req, err := http.NewRequest(http.MethodPost, s.topicURL()+":publish", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		return send(g, req)
+	}
+
+	err = publish()
+	if serr, ok := err.(*statusError); !ok || serr.code != http.StatusNotFound {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, s.topicURL(), nil)
+	if err != nil {
+		return err
+	}
+
+	// 別のインスタンスが同時に作成した場合は409となる
+	if err := send(g, req); err != nil {
+		if serr, ok := err.(*statusError); !ok || serr.code != http.StatusConflict {
+			return err
+		}
+	}
+
+	return publish()
+}
+
+func (s *PubSubSink) topicURL() string {
+	return fmt.Sprintf("http://%s/v1/projects/%s/topics/%s", s.Host, url.PathEscape(s.Project), url.PathEscape(s.Topic))
+}
+
+// statusError は2xx以外のレスポンスを受信した場合のエラー
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("responded %d", e.code)
+}
+
+// send はリクエストを送信し、2xx以外のレスポンスをstatusErrorとして返す
+func send(g ds.Client, req *http.Request) error {
+	resp, err := httpClient(g.Context()).Do(req.WithContext(g.Context()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 接続を再利用できるようにレスポンスボディを読み捨てる
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024)) // nolint: errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode}
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build !appengine
+// +build !appengine
+
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"gaego-gin/server/src/model"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError はエラーに対応するコードのgRPCのステータスを返す
+//
+// HTTPのAPIと同様に、呼び出しのcontextがタイムアウトまたはキャンセルされている場合はそのエラーを優先し、
+// Internalの場合は元のエラーをエラーIDと共にログに出力し、クライアントにはエラーIDのみを返す
+func statusError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
+	code := errorCode(err)
+	if code != codes.Internal {
+		return status.Error(code, err.Error())
+	}
+
+	id := newErrorID()
+	method, _ := grpc.Method(ctx)
+	log.With("errorId", id).Errorf(ctx, "%s: %s: %v", method, code, err)
+
+	return status.Error(code, fmt.Sprintf("internal error (error id: %s)", id))
+}
+
+// errorCode はエラーに対応するgRPCのステータスコードを返す
+//
+// HTTPのAPIと共通のmodel.ClassifyErrorの分類を用いる。HTTPでは409となるトランザクションの競合はAborted、既に存在する場合はAlreadyExistsとする
+func errorCode(err error) codes.Code {
+	return errorKindCodes[model.ClassifyError(err)]
+}
+
+// errorKindCodes はエラーの分類毎のgRPCのステータスコード
+var errorKindCodes = map[model.ErrorKind]codes.Code{
+	model.ErrorInternal:         codes.Internal,
+	model.ErrorInvalidArgument:  codes.InvalidArgument,
+	model.ErrorNotFound:         codes.NotFound,
+	model.ErrorAlreadyExists:    codes.AlreadyExists,
+	model.ErrorAborted:          codes.Aborted,
+	model.ErrorUnavailable:      codes.Unavailable,
+	model.ErrorDeadlineExceeded: codes.DeadlineExceeded,
+	model.ErrorCanceled:         codes.Canceled,
+}
+
+// newErrorID はログとステータスを関連付けるためのエラーIDを生成する
+func newErrorID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
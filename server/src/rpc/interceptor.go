@@ -0,0 +1,49 @@
+//go:build !appengine
+// +build !appengine
+
+package rpc
+
+import (
+	"context"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/metrics"
+	"gaego-gin/server/src/tracing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// interceptors はHTTPのAPIのミドルウェアと同様に、呼び出し毎のspan、ログの項目、メトリクスと処理時間の上限を設定するServerOptionを返す
+//
+// Watchは切断されるまで終わらないため、ストリームには処理時間の上限を設定しない
+func interceptors(timeout time.Duration) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			tracing.UnaryServerInterceptor(),
+			logger.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor(),
+			timeoutInterceptor(timeout),
+		),
+		grpc.ChainStreamInterceptor(
+			tracing.StreamServerInterceptor(),
+			logger.StreamServerInterceptor(),
+			metrics.StreamServerInterceptor(),
+		),
+	}
+}
+
+// timeoutInterceptor は呼び出しのcontextに処理時間の上限を設定する。0以下の場合は上限なし
+//
+// contextはDatastoreの操作にも伝播し、上限を超えた場合はDeadlineExceededを返す
+func timeoutInterceptor(d time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if d <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,176 @@
+//go:build !appengine
+// +build !appengine
+
+// Package rpc はHogeのAPIと同じ操作をgRPCのHogeServiceとして提供する
+//
+// HTTPのAPIと同じくmodelのHogeStoreとHogeChangeStoreを利用し、変更は変更履歴とアウトボックスに保存する
+package rpc
+
+import (
+	"context"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/hogepb"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/outbox"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var log = logger.New("rpc")
+
+// HogeServer はHogeServiceを実装する
+type HogeServer struct {
+	hogepb.UnimplementedHogeServiceServer
+
+	// Datastore は呼び出し毎のDatastoreのClientを生成する
+	Datastore ds.Factory
+}
+
+// NewServer はHogeServiceを登録したgrpc.Serverを生成する
+//
+// timeoutは1回の呼び出しあたりの処理時間の上限。0の場合は上限なし
+func NewServer(factory ds.Factory, timeout time.Duration, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(append(interceptors(timeout), opts...)...)
+	hogepb.RegisterHogeServiceServer(s, &HogeServer{Datastore: factory})
+
+	return s
+}
+
+// client は呼び出しのcontextを引き継ぐClientを生成する
+//
+// ds.Factoryはリクエストを受け取るため、メソッド名をパスとするリクエストを生成する
+func (s *HogeServer) client(ctx context.Context) (ds.Client, error) {
+	method, _ := grpc.Method(ctx)
+
+	r, err := http.NewRequest(http.MethodPost, method, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Datastore(r.WithContext(ctx)), nil
+}
+
+// Get はHogeを1件取得する
+func (s *HogeServer) Get(ctx context.Context, req *hogepb.GetHogeRequest) (*hogepb.Hoge, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	g, err := s.client(ctx)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	store := &model.HogeStore{}
+	hoge, err := store.Get(g, req.GetId())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return hogepb.NewHoge(hoge), nil
+}
+
+// List はHogeの一覧を取得する
+func (s *HogeServer) List(ctx context.Context, req *hogepb.ListHogeRequest) (*hogepb.HogeListResp, error) {
+	hq := model.HogeQuery{
+		Cursor: req.GetCursor(),
+		Limit:  int(req.GetLimit()),
+		Sort:   req.GetSort(),
+		Value:  req.GetValue(),
+		Count:  req.GetCount(),
+	}
+
+	g, err := s.client(ctx)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	store := &model.HogeStore{}
+
+	var resp *model.HogeListResp
+	if err := model.Retry(ctx, "hoge.list", func() error {
+		var err error
+		resp, err = store.List(g, hq)
+		return err
+
+	}); err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return hogepb.NewHogeListResp(resp), nil
+}
+
+// Create はHogeを新規作成する
+func (s *HogeServer) Create(ctx context.Context, req *hogepb.CreateHogeRequest) (*hogepb.Hoge, error) {
+	return s.save(ctx, req.GetHoge(), "hoge.insert", model.HogeCreated, (*model.Hoge).Insert)
+}
+
+// Update はHogeを更新する
+func (s *HogeServer) Update(ctx context.Context, req *hogepb.UpdateHogeRequest) (*hogepb.Hoge, error) {
+	return s.save(ctx, req.GetHoge(), "hoge.update", model.HogeUpdated, (*model.Hoge).Update)
+}
+
+// save はトランザクション内でputでHogeを保存し、変更をtypのイベントとして保存する
+func (s *HogeServer) save(ctx context.Context, x *hogepb.Hoge, name string, typ model.EventType, put func(*model.Hoge, ds.Client) error) (*hogepb.Hoge, error) {
+	if x.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	g, err := s.client(ctx)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	hoge := x.Model()
+
+	if err := model.RunInTransaction(g, name, func(tg ds.Client) error {
+		if err := put(hoge, tg); err != nil {
+			return err
+		}
+
+		return outbox.Publish(tg, typ, hoge)
+
+	}); err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return hogepb.NewHoge(hoge), nil
+}
+
+// Delete はHogeを削除する
+func (s *HogeServer) Delete(ctx context.Context, req *hogepb.DeleteHogeRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	g, err := s.client(ctx)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	store := &model.HogeStore{}
+
+	if err := model.RunInTransaction(g, "hoge.delete", func(tg ds.Client) error {
+		deleted, err := store.Delete(tg, req.GetId())
+		if err != nil {
+			return err
+		}
+		// 存在しないHogeの場合は変更がないため、イベントを配信しない。イベントには削除したHogeの値を含める
+		if deleted == nil {
+			return nil
+		}
+
+		return outbox.Publish(tg, model.HogeDeleted, deleted)
+
+	}); err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,312 @@
+//go:build !appengine
+// +build !appengine
+
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/hogepb"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/rpc"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// txErrClient はerrが設定されている場合、RunInTransactionでfを実行せずにerrを返すds.Client
+type txErrClient struct {
+	*dstest.Client
+	err error
+}
+
+func (c *txErrClient) RunInTransaction(f func(tg ds.Client) error) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.Client.RunInTransaction(f)
+}
+
+type rpcTestHelper struct {
+	store   *dstest.Store
+	err     error
+	timeout time.Duration
+}
+
+// dial はbufconnで待ち受けるHogeServiceのクライアントを返す
+func (h *rpcTestHelper) dial(t *testing.T) hogepb.HogeServiceClient {
+	lis := bufconn.Listen(1024 * 1024)
+
+	s := rpc.NewServer(func(r *http.Request) ds.Client {
+		return &txErrClient{Client: h.store.Client(r.Context()), err: h.err}
+	}, h.timeout)
+	go s.Serve(lis) // nolint: errcheck
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() { conn.Close() }) // nolint: errcheck
+
+	return hogepb.NewHogeServiceClient(conn)
+}
+
+func newRPCTestHelper() *rpcTestHelper {
+	return &rpcTestHelper{store: dstest.NewStore()}
+}
+
+// AssertEquals は実値と期待値が同値か判定する
+func AssertEquals(t *testing.T, title string, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("%s: unexpected, actual: `%v`, expected: `%v`", title, actual, expected)
+	}
+}
+
+// AssertCodeEquals はgRPCのステータスコードの実値と期待値が同値か判定する
+func AssertCodeEquals(t *testing.T, err error, expected codes.Code) {
+	if actual := status.Code(err); actual != expected {
+		// 以降のassertは必ずFAILEDとなるため、テストの実行を終了する
+		t.Fatalf("unexpected status code: actual: `%s`, expected: `%s`, err: `%v`", actual, expected, err)
+	}
+}
+
+func TestHogeServer(t *testing.T) {
+	h := newRPCTestHelper()
+	client := h.dial(t)
+	ctx := context.Background()
+
+	t.Run("作成、取得、更新、削除できること", func(t *testing.T) {
+		created, err := client.Create(ctx, &hogepb.CreateHogeRequest{Hoge: &hogepb.Hoge{Id: "hoge", Value: "hogehoge"}})
+		AssertCodeEquals(t, err, codes.OK)
+		AssertEquals(t, "Id", created.GetId(), "hoge")
+		AssertEquals(t, "Value", created.GetValue(), "hogehoge")
+		AssertEquals(t, "CreatedAt", created.GetCreatedAt() != nil, true)
+
+		got, err := client.Get(ctx, &hogepb.GetHogeRequest{Id: "hoge"})
+		AssertCodeEquals(t, err, codes.OK)
+		AssertEquals(t, "Get", proto.Equal(got, created), true)
+
+		updated, err := client.Update(ctx, &hogepb.UpdateHogeRequest{Hoge: &hogepb.Hoge{Id: "hoge", Value: "fugafuga"}})
+		AssertCodeEquals(t, err, codes.OK)
+		AssertEquals(t, "Value", updated.GetValue(), "fugafuga")
+		AssertEquals(t, "CreatedAt", proto.Equal(updated.GetCreatedAt(), created.GetCreatedAt()), true)
+
+		_, err = client.Delete(ctx, &hogepb.DeleteHogeRequest{Id: "hoge"})
+		AssertCodeEquals(t, err, codes.OK)
+
+		_, err = client.Get(ctx, &hogepb.GetHogeRequest{Id: "hoge"})
+		AssertCodeEquals(t, err, codes.NotFound)
+	})
+
+	t.Run("一覧を取得できること", func(t *testing.T) {
+		for _, id := range []string{"list1", "list2", "list3"} {
+			_, err := client.Create(ctx, &hogepb.CreateHogeRequest{Hoge: &hogepb.Hoge{Id: id, Value: id}})
+			AssertCodeEquals(t, err, codes.OK)
+		}
+
+		resp, err := client.List(ctx, &hogepb.ListHogeRequest{Limit: 2, Count: true})
+		AssertCodeEquals(t, err, codes.OK)
+		AssertEquals(t, "len(List)", len(resp.GetList()), 2)
+		AssertEquals(t, "List[0].Id", resp.GetList()[0].GetId(), "list1")
+		AssertEquals(t, "List[1].Id", resp.GetList()[1].GetId(), "list2")
+		AssertEquals(t, "Cursor", resp.GetCursor() != "", true)
+		AssertEquals(t, "Total", resp.GetTotal(), int32(3))
+
+		resp, err = client.List(ctx, &hogepb.ListHogeRequest{})
+		AssertCodeEquals(t, err, codes.OK)
+		AssertEquals(t, "Total", resp.Total == nil, true)
+	})
+
+	t.Run("HTTPのAPIの400、404、409に対応するステータスコードを返すこと", func(t *testing.T) {
+		_, err := client.Get(ctx, &hogepb.GetHogeRequest{})
+		AssertCodeEquals(t, err, codes.InvalidArgument)
+
+		_, err = client.Create(ctx, &hogepb.CreateHogeRequest{})
+		AssertCodeEquals(t, err, codes.InvalidArgument)
+
+		_, err = client.List(ctx, &hogepb.ListHogeRequest{Sort: "value"})
+		AssertCodeEquals(t, err, codes.InvalidArgument)
+
+		_, err = client.List(ctx, &hogepb.ListHogeRequest{Cursor: "invalid"})
+		AssertCodeEquals(t, err, codes.InvalidArgument)
+
+		_, err = client.Update(ctx, &hogepb.UpdateHogeRequest{Hoge: &hogepb.Hoge{Id: "missing"}})
+		AssertCodeEquals(t, err, codes.NotFound)
+
+		_, err = client.Create(ctx, &hogepb.CreateHogeRequest{Hoge: &hogepb.Hoge{Id: "list1"}})
+		AssertCodeEquals(t, err, codes.AlreadyExists)
+	})
+}
+
+func TestHogeServer_Error(t *testing.T) {
+	policy := model.DefaultRetryPolicy
+	defer func() { model.DefaultRetryPolicy = policy }()
+	model.DefaultRetryPolicy = &model.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+		Retryable:      model.IsRetryable,
+	}
+
+	cases := []struct {
+		title    string
+		err      error
+		expected codes.Code
+		message  string
+	}{
+		{"トランザクションの競合が続く場合、Abortedとなること", ds.ErrConcurrentTransaction, codes.Aborted, ""},
+		{"一時的なエラーが続く場合、Unavailableとなること", &ds.TransientError{Err: errors.New("unavailable")}, codes.Unavailable, ""},
+		{"それ以外のエラーの場合、元のエラーを含まないInternalとなること", errors.New("secret"), codes.Internal, "internal error (error id: "},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			h := newRPCTestHelper()
+			h.err = c.err
+			client := h.dial(t)
+
+			_, err := client.Delete(context.Background(), &hogepb.DeleteHogeRequest{Id: "hoge"})
+			AssertCodeEquals(t, err, c.expected)
+			AssertEquals(t, "message", strings.HasPrefix(status.Convert(err).Message(), c.message), true)
+		})
+	}
+}
+
+func TestHogeServer_Interceptors(t *testing.T) {
+	t.Run("呼び出し毎にリクエストIDを生成し、ヘッダーで返すこと", func(t *testing.T) {
+		client := newRPCTestHelper().dial(t)
+
+		var header metadata.MD
+		_, err := client.Get(context.Background(), &hogepb.GetHogeRequest{Id: "missing"}, grpc.Header(&header))
+		AssertCodeEquals(t, err, codes.NotFound)
+		AssertEquals(t, "len(x-request-id)", len(header.Get("x-request-id")), 1)
+		AssertEquals(t, "x-request-id", header.Get("x-request-id")[0] != "", true)
+	})
+
+	t.Run("メタデータのリクエストIDを引き継ぐこと", func(t *testing.T) {
+		client := newRPCTestHelper().dial(t)
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "request-1")
+
+		var header metadata.MD
+		_, err := client.Get(ctx, &hogepb.GetHogeRequest{Id: "missing"}, grpc.Header(&header))
+		AssertCodeEquals(t, err, codes.NotFound)
+		AssertEquals(t, "x-request-id", strings.Join(header.Get("x-request-id"), ","), "request-1")
+	})
+
+	t.Run("処理時間の上限を超えた場合、DeadlineExceededとなること", func(t *testing.T) {
+		h := newRPCTestHelper()
+		h.timeout = time.Nanosecond
+		client := h.dial(t)
+
+		_, err := client.Get(context.Background(), &hogepb.GetHogeRequest{Id: "missing"})
+		AssertCodeEquals(t, err, codes.DeadlineExceeded)
+	})
+}
+
+func TestHogeServer_Watch(t *testing.T) {
+	interval, lag := rpc.WatchPollInterval, model.HogeChangeReadLag
+	defer func() { rpc.WatchPollInterval, model.HogeChangeReadLag = interval, lag }()
+	rpc.WatchPollInterval = 10 * time.Millisecond
+	model.HogeChangeReadLag = 0
+
+	h := newRPCTestHelper()
+	client := h.dial(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.Create(ctx, &hogepb.CreateHogeRequest{Hoge: &hogepb.Hoge{Id: "before"}})
+	AssertCodeEquals(t, err, codes.OK)
+
+	// created は"hoge"を作成した変更の位置
+	var created string
+
+	t.Run("last_event_idを指定しない場合、呼び出した後の変更のみ送信されること", func(t *testing.T) {
+		wctx, wcancel := context.WithCancel(ctx)
+		defer wcancel()
+
+		stream, err := client.Watch(wctx, &hogepb.WatchHogeRequest{})
+		AssertCodeEquals(t, err, codes.OK)
+
+		// 開始位置を決定するまで待つため、ヘッダーを受信してから変更する
+		if _, err := stream.Header(); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		_, err = client.Create(ctx, &hogepb.CreateHogeRequest{Hoge: &hogepb.Hoge{Id: "hoge", Value: "hogehoge"}})
+		AssertCodeEquals(t, err, codes.OK)
+
+		change, err := stream.Recv()
+		AssertCodeEquals(t, err, codes.OK)
+		AssertEquals(t, "Position", change.GetPosition() != "", true)
+		AssertEquals(t, "Type", change.GetType(), string(model.HogeCreated))
+		AssertEquals(t, "Hoge.Id", change.GetHoge().GetId(), "hoge")
+		AssertEquals(t, "Hoge.Value", change.GetHoge().GetValue(), "hogehoge")
+		AssertEquals(t, "EventId", change.GetEventId() != "", true)
+		AssertEquals(t, "OccurredAt", change.GetOccurredAt() != nil, true)
+
+		created = change.GetPosition()
+	})
+
+	t.Run("last_event_idとprefixを指定した場合、その位置より後のprefixに一致する変更が送信されること", func(t *testing.T) {
+		_, err := client.Update(ctx, &hogepb.UpdateHogeRequest{Hoge: &hogepb.Hoge{Id: "before", Value: "updated"}})
+		AssertCodeEquals(t, err, codes.OK)
+		_, err = client.Delete(ctx, &hogepb.DeleteHogeRequest{Id: "hoge"})
+		AssertCodeEquals(t, err, codes.OK)
+
+		wctx, wcancel := context.WithCancel(ctx)
+		defer wcancel()
+
+		stream, err := client.Watch(wctx, &hogepb.WatchHogeRequest{LastEventId: created, Prefix: "ho"})
+		AssertCodeEquals(t, err, codes.OK)
+
+		change, err := stream.Recv()
+		AssertCodeEquals(t, err, codes.OK)
+		AssertEquals(t, "Position", change.GetPosition() > created, true)
+		AssertEquals(t, "Type", change.GetType(), string(model.HogeDeleted))
+		AssertEquals(t, "Hoge.Id", change.GetHoge().GetId(), "hoge")
+	})
+
+	t.Run("last_event_idの変更履歴が削除されている場合、resetで現在の位置が送信されること", func(t *testing.T) {
+		wctx, wcancel := context.WithCancel(ctx)
+		defer wcancel()
+
+		stream, err := client.Watch(wctx, &hogepb.WatchHogeRequest{LastEventId: "00000000000000000001-expired"})
+		AssertCodeEquals(t, err, codes.OK)
+
+		change, err := stream.Recv()
+		AssertCodeEquals(t, err, codes.OK)
+		AssertEquals(t, "Type", change.GetType(), "reset")
+		AssertEquals(t, "Position", change.GetPosition() > created, true)
+		AssertEquals(t, "Hoge", change.GetHoge() == nil, true)
+	})
+
+	t.Run("last_event_idの形式が不正な場合、InvalidArgumentとなること", func(t *testing.T) {
+		stream, err := client.Watch(ctx, &hogepb.WatchHogeRequest{LastEventId: "1"})
+		AssertCodeEquals(t, err, codes.OK)
+
+		_, err = stream.Recv()
+		AssertCodeEquals(t, err, codes.InvalidArgument)
+	})
+}
@@ -0,0 +1,126 @@
+//go:build !appengine
+// +build !appengine
+
+package rpc
+
+import (
+	"encoding/json"
+	"gaego-gin/server/src/hogepb"
+	"gaego-gin/server/src/model"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WatchPollInterval は変更履歴を確認する間隔
+var WatchPollInterval = time.Second
+
+// watchReset は指定した位置の変更履歴が削除されているため、現在の位置から送信することを表す変更の種類
+const watchReset = "reset"
+
+// Watch はHogeの変更を逐次送信する
+//
+// GET /api/hoge:watchと同じく変更履歴を定期的に確認する。切断した場合は最後に受信したpositionをlast_event_idに指定して再開する
+// last_event_idの変更履歴が削除されている場合は、typeがresetの変更で現在の位置を送信し、その後の変更から送信する
+// 変更履歴の取得に失敗した場合は、送信を終えてエラーを返す
+func (s *HogeServer) Watch(req *hogepb.WatchHogeRequest, stream hogepb.HogeService_WatchServer) error {
+	ctx := stream.Context()
+
+	g, err := s.client(ctx)
+	if err != nil {
+		return statusError(ctx, err)
+	}
+
+	store := &model.HogeChangeStore{}
+
+	last, reset := req.GetLastEventId(), false
+	if last != "" {
+		if reset, err = store.Expired(last); err != nil {
+			return status.Error(codes.InvalidArgument, "last_event_id must be a position of a received change")
+		}
+	}
+	if last == "" || reset {
+		last = store.Latest()
+	}
+
+	// 開始位置を決定したことをクライアントに通知するため、変更がなくてもヘッダーを送信する
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	if reset {
+		if err := stream.Send(&hogepb.HogeChange{Position: last, Type: watchReset}); err != nil {
+			return err
+		}
+	}
+
+	poll := time.NewTicker(WatchPollInterval)
+	defer poll.Stop()
+
+	for {
+		var changes []*model.HogeChange
+		if err := model.Retry(ctx, "hoge.watch", func() error {
+			var err error
+			changes, err = store.List(g, last)
+			return err
+
+		}); err != nil {
+			return statusError(ctx, err)
+		}
+
+		for _, change := range changes {
+			last = change.Position
+
+			if !strings.HasPrefix(change.HogeID, req.GetPrefix()) {
+				continue
+			}
+
+			x, err := newHogeChange(change)
+			if err != nil {
+				return statusError(ctx, err)
+			}
+
+			if err := stream.Send(x); err != nil {
+				return err
+			}
+		}
+
+		// 取得の上限に達した場合は続きをすぐに取得する
+		if len(changes) >= model.MaxHogeChanges {
+			if ctx.Err() != nil {
+				return nil
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+		}
+	}
+}
+
+// newHogeChange は変更履歴をHogeChangeに変換する
+func newHogeChange(change *model.HogeChange) (*hogepb.HogeChange, error) {
+	event := &model.HogeEvent{}
+	if err := json.Unmarshal(change.Event, event); err != nil {
+		return nil, err
+	}
+
+	x := &hogepb.HogeChange{
+		Position:   change.Position,
+		Type:       string(change.Type),
+		EventId:    event.ID,
+		OccurredAt: timestamppb.New(event.OccurredAt),
+	}
+	if event.Hoge != nil {
+		x.Hoge = hogepb.NewHoge(event.Hoge)
+	}
+
+	return x, nil
+}
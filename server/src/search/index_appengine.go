@@ -0,0 +1,223 @@
+//go:build appengine
+// +build appengine
+
+package search
+
+import (
+	"context"
+	"encoding/hex"
+	"gaego-gin/server/src/model"
+	"strings"
+	"unicode/utf8"
+
+	aesearch "google.golang.org/appengine/search"
+)
+
+// AppEngine はApp Engine SearchのIndex
+//
+// App Engine Searchの単語の分割に依存しないように、単語と接頭辞は16進数に変換した1つの単語として保存する
+// ドキュメントのIDはHogeのIDを16進数に変換した値とする
+type AppEngine struct {
+	// Name はインデックスの名前。空の場合は"hoge"とする
+	Name string
+}
+
+// document はApp Engine Searchに保存するドキュメント
+type document struct {
+	// ID は並び替えに利用するHogeのID
+	ID aesearch.Atom
+	// IDTerms はIDの完全一致の単語と接頭辞の単語
+	IDTerms string
+	// Terms はValueの単語を順に並べたもの。フレーズの検索に利用する
+	Terms string
+	// Prefixes はValueの単語の接頭辞
+	Prefixes string
+}
+
+// 単語の種類毎の接頭辞
+const (
+	termPrefix   = "t"
+	prefixPrefix = "p"
+	idPrefix     = "i"
+)
+
+// searchSortLimit は並び替える検索結果の件数の上限
+const searchSortLimit = 10000
+
+// countAccuracy は検索結果の件数を正確に数える上限
+const countAccuracy = 1000
+
+func (x *AppEngine) open() (*aesearch.Index, error) {
+	name := x.Name
+	if name == "" {
+		name = "hoge"
+	}
+
+	return aesearch.Open(name)
+}
+
+// Put はhogeをドキュメントとして保存する
+func (x *AppEngine) Put(ctx context.Context, hoge *model.Hoge) error {
+	index, err := x.open()
+	if err != nil {
+		return err
+	}
+
+	id := strings.ToLower(hoge.ID)
+	idTerms := []string{idPrefix + hex.EncodeToString([]byte(id))}
+	idTerms = append(idTerms, prefixTerms(id)...)
+
+	tokens := Tokenize(hoge.Value)
+	terms := make([]string, 0, len(tokens))
+	var prefixes []string
+	for _, token := range tokens {
+		terms = append(terms, termPrefix+hex.EncodeToString([]byte(token)))
+		prefixes = append(prefixes, prefixTerms(token)...)
+	}
+
+	_, err = index.Put(ctx, hex.EncodeToString([]byte(hoge.ID)), &document{
+		ID:       aesearch.Atom(hoge.ID),
+		IDTerms:  strings.Join(idTerms, " "),
+		Terms:    strings.Join(terms, " "),
+		Prefixes: strings.Join(prefixes, " "),
+	})
+	return err
+}
+
+// Delete はIDのドキュメントを削除する
+func (x *AppEngine) Delete(ctx context.Context, id string) error {
+	index, err := x.open()
+	if err != nil {
+		return err
+	}
+
+	return index.Delete(ctx, hex.EncodeToString([]byte(id)))
+}
+
+// Search はqに一致するドキュメントをID順に検索する
+func (x *AppEngine) Search(ctx context.Context, q *Query, cursor string, limit int) (*Result, error) {
+	index, err := x.open()
+	if err != nil {
+		return nil, err
+	}
+
+	// 次のページの有無を判定するため1件多く取得する
+	it := index.Search(ctx, q.appEngineQuery(), &aesearch.SearchOptions{
+		Limit:         limit + 1,
+		IDsOnly:       true,
+		Cursor:        aesearch.Cursor(cursor),
+		CountAccuracy: countAccuracy,
+		Sort: &aesearch.SortOptions{
+			// Reverseがtrueの場合に昇順となる
+			Expressions: []aesearch.SortExpression{{Expr: "ID", Reverse: true, Default: ""}},
+			Limit:       searchSortLimit,
+		},
+	})
+
+	res := &Result{IDs: []string{}}
+
+	var last aesearch.Cursor
+	for {
+		docID, err := it.Next(nil)
+		if err == aesearch.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(res.IDs) == limit {
+			res.Cursor = string(last)
+			break
+		}
+
+		id, err := hex.DecodeString(docID)
+		if err != nil {
+			return nil, err
+		}
+
+		res.IDs = append(res.IDs, string(id))
+		last = it.Cursor()
+	}
+
+	res.Total = it.Count()
+
+	return res, nil
+}
+
+// IDs は全てのドキュメントのHogeのIDをドキュメントのID順にfに渡す
+func (x *AppEngine) IDs(ctx context.Context, f func(id string) error) error {
+	index, err := x.open()
+	if err != nil {
+		return err
+	}
+
+	it := index.List(ctx, &aesearch.ListOptions{IDsOnly: true})
+	for {
+		docID, err := it.Next(nil)
+		if err == aesearch.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		id, err := hex.DecodeString(docID)
+		if err != nil {
+			return err
+		}
+
+		if err := f(string(id)); err != nil {
+			return err
+		}
+	}
+}
+
+// appEngineQuery はApp Engine Searchのクエリに変換する
+func (q *Query) appEngineQuery() string {
+	var clauses []string
+
+	for _, c := range q.Clauses {
+		switch {
+		case c.Field == FieldID && c.Prefix:
+			clauses = append(clauses, "IDTerms:"+prefixPrefix+hex.EncodeToString([]byte(c.Text)))
+
+		case c.Field == FieldID:
+			clauses = append(clauses, "IDTerms:"+idPrefix+hex.EncodeToString([]byte(c.Text)))
+
+		case c.Prefix:
+			clauses = append(clauses, "Prefixes:"+prefixPrefix+hex.EncodeToString([]byte(c.Text)))
+
+		default:
+			var terms []string
+			for _, w := range strings.Split(c.Text, " ") {
+				terms = append(terms, termPrefix+hex.EncodeToString([]byte(w)))
+			}
+
+			clauses = append(clauses, `Terms:"`+strings.Join(terms, " ")+`"`)
+		}
+	}
+
+	return strings.Join(clauses, " AND ")
+}
+
+// prefixTerms はsの先頭からMaxPrefixLength文字までの接頭辞の単語を返す
+func prefixTerms(s string) []string {
+	var terms []string
+
+	n := 0
+	for i := range s {
+		if n > 0 {
+			terms = append(terms, prefixPrefix+hex.EncodeToString([]byte(s[:i])))
+		}
+		if n++; n > MaxPrefixLength {
+			return terms
+		}
+	}
+
+	if utf8.RuneCountInString(s) <= MaxPrefixLength {
+		terms = append(terms, prefixPrefix+hex.EncodeToString([]byte(s)))
+	}
+
+	return terms
+}
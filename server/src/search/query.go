@@ -0,0 +1,196 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidQuery は検索条件の形式が不正な場合のエラー
+var ErrInvalidQuery = errors.New("q must be terms, \"phrases\", prefixes* or id:/value: fields")
+
+// Field は検索の対象とするHogeのフィールド
+type Field string
+
+const (
+	// FieldID はHoge.ID。値全体と一致するか、前方一致する場合に一致する
+	FieldID Field = "id"
+	// FieldValue はHoge.Value。単語毎に一致するか判定する
+	FieldValue Field = "value"
+)
+
+// Clause は検索条件の1つの項
+type Clause struct {
+	Field Field
+	// Text は小文字に変換した単語、フレーズ、または接頭辞
+	Text string
+	// Phrase はTextの単語が連続して含まれる場合に一致する
+	Phrase bool
+	// Prefix はTextで始まる単語が含まれる場合に一致する
+	Prefix bool
+}
+
+// Query は全ての項に一致するHogeを検索する条件
+type Query struct {
+	Clauses []*Clause
+}
+
+// ParseQuery は検索条件を解釈する
+//
+// 空白で区切った項は全てに一致するHogeを検索する。各項は次の形式で指定する
+//
+//	word           valueに単語が含まれる
+//	"some words"   valueにフレーズが含まれる
+//	pre*           valueに"pre"で始まる単語が含まれる
+//	id:hoge1       idが一致する。id:hoge*の場合は前方一致
+//	value:word     フィールドを指定する。フレーズと接頭辞も指定できる
+//
+// 大文字と小文字は区別しない。接頭辞はMaxPrefixLength文字までを比較する
+func ParseQuery(s string) (*Query, error) {
+	q := &Query{}
+
+	rs := []rune(strings.TrimSpace(s))
+	for i := 0; i < len(rs); {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		c := &Clause{Field: FieldValue}
+
+		// field:の指定を読み込む
+		if j := indexRune(rs[i:], ':'); j > 0 && !containsSpaceOrQuote(rs[i:i+j]) {
+			switch Field(strings.ToLower(string(rs[i : i+j]))) {
+			case FieldID:
+				c.Field = FieldID
+			case FieldValue:
+				c.Field = FieldValue
+			default:
+				return nil, ErrInvalidQuery
+			}
+
+			i += j + 1
+		}
+
+		if i < len(rs) && rs[i] == '"' {
+			end := indexRune(rs[i+1:], '"')
+			if end < 0 {
+				return nil, ErrInvalidQuery
+			}
+
+			c.Text = string(rs[i+1 : i+1+end])
+			c.Phrase = true
+			i += end + 2
+		} else {
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) {
+				i++
+			}
+
+			c.Text = string(rs[start:i])
+			if strings.HasSuffix(c.Text, "*") {
+				c.Text = strings.TrimSuffix(c.Text, "*")
+				c.Prefix = true
+			}
+		}
+
+		if c.Field == FieldValue {
+			c.Text = strings.Join(Tokenize(c.Text), " ")
+			// 1単語のフレーズは単語として扱う
+			if c.Phrase && !strings.Contains(c.Text, " ") {
+				c.Phrase = false
+			}
+			if c.Prefix && strings.Contains(c.Text, " ") {
+				return nil, ErrInvalidQuery
+			}
+		} else {
+			c.Text = strings.ToLower(c.Text)
+		}
+
+		if c.Text == "" {
+			return nil, ErrInvalidQuery
+		}
+
+		if c.Prefix {
+			if rs := []rune(c.Text); len(rs) > MaxPrefixLength {
+				c.Text = string(rs[:MaxPrefixLength])
+			}
+		}
+
+		q.Clauses = append(q.Clauses, c)
+	}
+
+	if len(q.Clauses) == 0 {
+		return nil, ErrInvalidQuery
+	}
+
+	return q, nil
+}
+
+// Tokenize は文字と数字の連続を単語として、小文字に変換した単語の一覧を返す
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func indexRune(rs []rune, r rune) int {
+	for i, v := range rs {
+		if v == r {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func containsSpaceOrQuote(rs []rune) bool {
+	for _, r := range rs {
+		if unicode.IsSpace(r) || r == '"' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match はIDがid、Valueの単語がtokensのHogeが全ての項に一致するか判定する
+//
+// tokensはTokenizeで分割した単語とする
+func (q *Query) Match(id string, tokens []string) bool {
+	for _, c := range q.Clauses {
+		if !c.match(id, tokens) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Clause) match(id string, tokens []string) bool {
+	if c.Field == FieldID {
+		id = strings.ToLower(id)
+		if c.Prefix {
+			return strings.HasPrefix(id, c.Text)
+		}
+
+		return id == c.Text
+	}
+
+	words := strings.Split(c.Text, " ")
+	for i := 0; i+len(words) <= len(tokens); i++ {
+		matched := true
+		for j, w := range words {
+			if c.Prefix && !strings.HasPrefix(tokens[i+j], w) || !c.Prefix && tokens[i+j] != w {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
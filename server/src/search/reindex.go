@@ -0,0 +1,126 @@
+package search
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+)
+
+// reindexBatchSize はReindexで1回に存在を確認するIDの件数
+const reindexBatchSize = 100
+
+// ReindexResult はインデックスの再構築の結果
+type ReindexResult struct {
+	// Indexed はインデックスに追加したHogeの件数
+	Indexed int `json:"indexed"`
+	// Removed はDatastoreに存在しないためインデックスから削除したHogeの件数
+	Removed int `json:"removed"`
+}
+
+// Reindex はDatastoreの全てのHogeをDefaultIndexに追加し、Datastoreに存在しないHogeをDefaultIndexから削除する
+//
+// progressにはバッチ毎にインデックスに追加したHogeの件数を渡す。nilの場合は呼び出さない
+// 再構築中に変更したHogeはSinkが反映するため、削除はDatastoreで存在しないことを確認したIDのみとする
+// DefaultIndexがnilの場合はErrNoIndexを返す
+func Reindex(g ds.Client, progress func(done int) error) (*ReindexResult, error) {
+	if DefaultIndex == nil {
+		return nil, ErrNoIndex
+	}
+
+	store := &model.HogeStore{}
+	res := &ReindexResult{}
+
+	if err := store.Export(g, model.HogeQuery{}, func(hoge *model.Hoge, cursor string) error {
+		if err := DefaultIndex.Put(g.Context(), hoge); err != nil {
+			return err
+		}
+
+		res.Indexed++
+
+		// 各バッチの最後のHogeにのみcursorを渡す
+		if cursor != "" && progress != nil {
+			return progress(res.Indexed)
+		}
+
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	flush := func() error {
+		removed, err := removeMissing(g, ids)
+		if err != nil {
+			return err
+		}
+
+		res.Removed += removed
+		ids = ids[:0]
+		return nil
+	}
+
+	if err := DefaultIndex.IDs(g.Context(), func(id string) error {
+		ids = append(ids, id)
+		if len(ids) < reindexBatchSize {
+			return nil
+		}
+
+		return flush()
+
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	log.Infof(g.Context(), "reindexed %d hoge, removed %d", res.Indexed, res.Removed)
+
+	return res, nil
+}
+
+// removeMissing はidsのうちDatastoreに存在しないHogeをDefaultIndexから削除し、削除した件数を返す
+func removeMissing(g ds.Client, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	list := make([]*model.Hoge, len(ids))
+	for i, id := range ids {
+		list[i] = &model.Hoge{ID: id}
+	}
+
+	var merr ds.MultiError
+	if err := model.Retry(g.Context(), "search.reindex", func() error {
+		err := g.GetMulti(list)
+		if m, ok := err.(ds.MultiError); ok {
+			merr = m
+			return nil
+		}
+
+		merr = nil
+		return err
+
+	}); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for i, err := range merr {
+		if err == nil {
+			continue
+		}
+		if err != ds.ErrNoSuchEntity {
+			return removed, err
+		}
+
+		if err := DefaultIndex.Delete(g.Context(), ids[i]); err != nil {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,100 @@
+// Package search はHogeの全文検索のインデックスを管理する
+//
+// インデックスはHogeの変更を配信するoutbox.Sinkとして更新し、Reindexで再構築する
+// 第1世代のApp EngineではApp Engine Searchを利用する。それ以外ではインスタンス間で共有できるインデックスがないため、全文検索を提供しない
+package search
+
+import (
+	"context"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/model"
+)
+
+var log = logger.New("search")
+
+var (
+	// ErrInvalidCursor は検索結果のカーソルの形式が不正な場合のエラー
+	ErrInvalidCursor = errors.New("search: invalid cursor")
+	// ErrNoIndex はDefaultIndexが設定されていない場合のエラー
+	ErrNoIndex = errors.New("search: no index is configured")
+)
+
+// MaxPrefixLength は接頭辞で検索する場合に比較する最大の文字数
+//
+// 長い接頭辞はこの文字数に切り詰めて検索する
+const MaxPrefixLength = 20
+
+// Index はHogeの全文検索のインデックス
+type Index interface {
+	// Put はhogeをインデックスに追加する。同じIDのHogeが存在する場合は置き換える
+	Put(ctx context.Context, hoge *model.Hoge) error
+	// Delete はIDのHogeをインデックスから削除する。存在しない場合は何もしない
+	Delete(ctx context.Context, id string) error
+	// Search はqに一致するHogeのIDをID順にlimit件まで検索する
+	//
+	// cursorには前のページのResult.Cursorを指定する。空の場合は最初から検索する
+	Search(ctx context.Context, q *Query, cursor string, limit int) (*Result, error)
+	// IDs はインデックスに含まれる全てのHogeのIDを順にfに渡す
+	//
+	// fがエラーを返した場合はその時点で中断し、そのエラーを返す
+	IDs(ctx context.Context, f func(id string) error) error
+}
+
+// Result は検索結果
+type Result struct {
+	IDs []string
+	// Cursor は次のページのカーソル。次のページが存在しない場合は空
+	Cursor string
+	// Total は検索条件に一致するHogeの件数。App Engine Searchでは概算となる
+	Total int
+}
+
+// DefaultIndex はHogeの検索とインデックスの更新に利用するIndex
+//
+// nilの場合は全文検索を提供せず、Sinkはインデックスを更新しない
+var DefaultIndex Index
+
+// Sink はHogeの変更をDefaultIndexに反映するoutbox.Sink
+//
+// イベントの内容ではなくDatastoreの現在のHogeを反映するため、
+// 配信の重複や順序の入れ替わりがあってもインデックスは最新の状態となる
+type Sink struct{}
+
+// Name は"search"を返す
+func (s *Sink) Name() string {
+	return "search"
+}
+
+// Send はeventのHogeの現在の状態をインデックスに反映する
+func (s *Sink) Send(g ds.Client, event *model.HogeEvent) error {
+	return Sync(g, event.Hoge.ID)
+}
+
+// Sync はIDのHogeをDatastoreから取得し、存在する場合はインデックスに追加し、存在しない場合は削除する
+//
+// DefaultIndexがnilの場合は何もしない
+func Sync(g ds.Client, id string) error {
+	if DefaultIndex == nil {
+		return nil
+	}
+
+	store := &model.HogeStore{}
+
+	var hoge *model.Hoge
+	if err := model.Retry(g.Context(), "search.sync", func() error {
+		var err error
+		hoge, err = store.Get(g, id)
+		return err
+
+	}); err != nil {
+		if err == ds.ErrNoSuchEntity {
+			return DefaultIndex.Delete(g.Context(), id)
+		}
+
+		return err
+	}
+
+	return DefaultIndex.Put(g.Context(), hoge)
+}
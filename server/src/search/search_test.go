@@ -0,0 +1,123 @@
+package search_test
+
+import (
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/search"
+	"gaego-gin/server/src/search/searchtest"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Run("各形式の項が解釈できること", func(t *testing.T) {
+		q, err := search.ParseQuery(`Hello "Big  World" pre* id:Hoge1 id:ho* value:"one"`)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		expected := []search.Clause{
+			{Field: search.FieldValue, Text: "hello"},
+			{Field: search.FieldValue, Text: "big world", Phrase: true},
+			{Field: search.FieldValue, Text: "pre", Prefix: true},
+			{Field: search.FieldID, Text: "hoge1"},
+			{Field: search.FieldID, Text: "ho", Prefix: true},
+			{Field: search.FieldValue, Text: "one"},
+		}
+		if len(q.Clauses) != len(expected) {
+			t.Fatalf("clauses: unexpected, actual: `%d`, expected: `%d`", len(q.Clauses), len(expected))
+		}
+		for i, c := range q.Clauses {
+			if *c != expected[i] {
+				t.Errorf("clause %d: unexpected, actual: `%+v`, expected: `%+v`", i, *c, expected[i])
+			}
+		}
+	})
+
+	t.Run("接頭辞がMaxPrefixLength文字に切り詰められること", func(t *testing.T) {
+		q, err := search.ParseQuery(strings.Repeat("あ", search.MaxPrefixLength+5) + "*")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if q.Clauses[0].Text != strings.Repeat("あ", search.MaxPrefixLength) {
+			t.Errorf("text: unexpected, actual: `%s`", q.Clauses[0].Text)
+		}
+	})
+
+	for _, s := range []string{"", "  ", `"unterminated`, "unknown:field", "*", `"!?"`, `"two words"*`} {
+		t.Run("不正な検索条件 "+s+" の場合、ErrInvalidQueryとなること", func(t *testing.T) {
+			if _, err := search.ParseQuery(s); err != search.ErrInvalidQuery {
+				t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, search.ErrInvalidQuery)
+			}
+		})
+	}
+}
+
+func TestSink(t *testing.T) {
+	defer func(index search.Index) { search.DefaultIndex = index }(search.DefaultIndex)
+
+	index := searchtest.NewMemory()
+	search.DefaultIndex = index
+
+	g := dstest.NewClient()
+	if err := g.Put(&model.Hoge{ID: "hoge1", Value: "current value"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	sink := &search.Sink{}
+
+	count := func(t *testing.T, query string) int {
+		q, err := search.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		res, err := index.Search(g.Context(), q, "", 10)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return res.Total
+	}
+
+	t.Run("イベントの内容ではなく、Datastoreの現在のHogeが追加されること", func(t *testing.T) {
+		event := &model.HogeEvent{ID: "e1", Type: model.HogeCreated, Hoge: &model.Hoge{ID: "hoge1", Value: "old value"}}
+		if err := sink.Send(g, event); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		AssertEquals(t, "current", count(t, "current"), 1)
+		AssertEquals(t, "old", count(t, "old"), 0)
+	})
+
+	t.Run("DefaultIndexがnilの場合、何もしないこと", func(t *testing.T) {
+		search.DefaultIndex = nil
+		defer func() { search.DefaultIndex = index }()
+
+		event := &model.HogeEvent{ID: "e3", Type: model.HogeUpdated, Hoge: &model.Hoge{ID: "hoge1"}}
+		if err := sink.Send(g, event); err != nil {
+			t.Fatal(err.Error())
+		}
+	})
+
+	t.Run("Datastoreに存在しないHogeが削除されること", func(t *testing.T) {
+		if err := g.Delete(&model.Hoge{ID: "hoge1"}); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		event := &model.HogeEvent{ID: "e2", Type: model.HogeDeleted, Hoge: &model.Hoge{ID: "hoge1"}}
+		if err := sink.Send(g, event); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		AssertEquals(t, "current", count(t, "current"), 0)
+	})
+}
+
+// AssertEquals は実値と期待値が同値か判定する
+func AssertEquals(t *testing.T, title string, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("%s: unexpected, actual: `%v`, expected: `%v`", title, actual, expected)
+	}
+}
@@ -0,0 +1,108 @@
+// Package searchtest はテスト用にプロセス内のメモリに保持するsearch.Indexを提供する
+package searchtest
+
+import (
+	"context"
+	"encoding/base64"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/search"
+	"sort"
+	"sync"
+)
+
+// Memory はプロセス内のメモリに保持するsearch.Index
+//
+// インスタンス間で共有できないため、テストでのみ利用する
+type Memory struct {
+	mu   sync.RWMutex
+	docs map[string][]string
+}
+
+// NewMemory は空のMemoryを生成する
+func NewMemory() *Memory {
+	return &Memory{docs: map[string][]string{}}
+}
+
+// Put はhogeのValueを単語に分割して保持する
+func (m *Memory) Put(ctx context.Context, hoge *model.Hoge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.docs[hoge.ID] = search.Tokenize(hoge.Value)
+	return nil
+}
+
+// Delete はIDのHogeを削除する
+func (m *Memory) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.docs, id)
+	return nil
+}
+
+// Search はqに一致するHogeのIDをID順に検索する
+//
+// カーソルは前のページの最後のIDとし、その次のIDから検索する
+func (m *Memory) Search(ctx context.Context, q *search.Query, cursor string, limit int) (*search.Result, error) {
+	var after string
+	if cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(b) == 0 {
+			return nil, search.ErrInvalidCursor
+		}
+
+		after = string(b)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := &search.Result{IDs: []string{}}
+
+	for _, id := range m.sortedIDs() {
+		if !q.Match(id, m.docs[id]) {
+			continue
+		}
+
+		res.Total++
+
+		if cursor != "" && id <= after {
+			continue
+		}
+
+		if len(res.IDs) == limit {
+			res.Cursor = base64.RawURLEncoding.EncodeToString([]byte(res.IDs[len(res.IDs)-1]))
+			continue
+		}
+
+		res.IDs = append(res.IDs, id)
+	}
+
+	return res, nil
+}
+
+// IDs は全てのHogeのIDをID順にfに渡す
+func (m *Memory) IDs(ctx context.Context, f func(id string) error) error {
+	m.mu.RLock()
+	ids := m.sortedIDs()
+	m.mu.RUnlock()
+
+	for _, id := range ids {
+		if err := f(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) sortedIDs() []string {
+	ids := make([]string, 0, len(m.docs))
+	for id := range m.docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
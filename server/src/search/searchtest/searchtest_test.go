@@ -0,0 +1,111 @@
+package searchtest_test
+
+import (
+	"context"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/search"
+	"gaego-gin/server/src/search/searchtest"
+	"strings"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+
+	index := searchtest.NewMemory()
+	for _, hoge := range []*model.Hoge{
+		{ID: "hoge1", Value: "The quick brown fox"},
+		{ID: "hoge2", Value: "quick-thinking BROWN bear"},
+		{ID: "hoge3", Value: "brown quick fox"},
+		{ID: "fuga1", Value: "素早い 茶色の狐"},
+	} {
+		if err := index.Put(ctx, hoge); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	ids := func(t *testing.T, query string) string {
+		q, err := search.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		res, err := index.Search(ctx, q, "", 10)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return strings.Join(res.IDs, ",")
+	}
+
+	for query, expected := range map[string]string{
+		"quick":             "hoge1,hoge2,hoge3",
+		"brown fox":         "hoge1,hoge3",
+		`"quick brown"`:     "hoge1",
+		`"brown fox"`:       "hoge1",
+		"qui*":              "hoge1,hoge2,hoge3",
+		"thinking":          "hoge2",
+		"id:HOGE2":          "hoge2",
+		"id:fu*":            "fuga1",
+		"id:hoge* bear":     "hoge2",
+		"茶色の狐":              "fuga1",
+		"素*":                "fuga1",
+		"value:\"the fox\"": "",
+		"cat":               "",
+	} {
+		t.Run(query+" で検索した場合、一致するHogeがID順に返ること", func(t *testing.T) {
+			AssertEquals(t, "IDs", ids(t, query), expected)
+		})
+	}
+
+	t.Run("カーソルで次のページが取得でき、最後のページのカーソルが空となること", func(t *testing.T) {
+		q, err := search.ParseQuery("quick")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		res, err := index.Search(ctx, q, "", 2)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		AssertEquals(t, "IDs", strings.Join(res.IDs, ","), "hoge1,hoge2")
+		AssertEquals(t, "Total", res.Total, 3)
+		AssertEquals(t, "Cursor", res.Cursor != "", true)
+
+		res, err = index.Search(ctx, q, res.Cursor, 2)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		AssertEquals(t, "IDs", strings.Join(res.IDs, ","), "hoge3")
+		AssertEquals(t, "Total", res.Total, 3)
+		AssertEquals(t, "Cursor", res.Cursor, "")
+	})
+
+	t.Run("不正なカーソルの場合、ErrInvalidCursorとなること", func(t *testing.T) {
+		q, err := search.ParseQuery("quick")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if _, err := index.Search(ctx, q, "!!", 2); err != search.ErrInvalidCursor {
+			t.Errorf("err: unexpected, actual: `%v`, expected: `%v`", err, search.ErrInvalidCursor)
+		}
+	})
+
+	t.Run("削除したHogeが検索されないこと", func(t *testing.T) {
+		if err := index.Delete(ctx, "hoge1"); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		AssertEquals(t, "IDs", ids(t, "fox"), "hoge3")
+	})
+}
+
+// AssertEquals は実値と期待値が同値か判定する
+func AssertEquals(t *testing.T, title string, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("%s: unexpected, actual: `%v`, expected: `%v`", title, actual, expected)
+	}
+}
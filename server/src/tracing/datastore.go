@@ -0,0 +1,176 @@
+//go:build !appengine
+// +build !appengine
+
+package tracing
+
+import (
+	"context"
+	"gaego-gin/server/src/ds"
+	"net/http"
+	"reflect"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentFactory はDatastoreの操作毎にspanを記録するClientを生成するFactoryを返す
+func InstrumentFactory(f ds.Factory) ds.Factory {
+	return func(r *http.Request) ds.Client {
+		return InstrumentClient(f(r))
+	}
+}
+
+// InstrumentClient はDatastoreの操作毎にspanを記録するClientを返す
+//
+// spanはClientのcontextに含まれるspanの子となる
+func InstrumentClient(c ds.Client) ds.Client {
+	return &client{c: c, ctx: c.Context()}
+}
+
+type client struct {
+	c   ds.Client
+	ctx context.Context
+}
+
+// start はKindとopを属性に持つspanを開始し、spanのcontextで操作するClientを返す
+//
+// ClientがDatastoreのRPCにcontextを引き継ぐことで、RPCやそのリトライがspanの子となる
+func (c *client) start(op, kind string) (ds.Client, trace.Span) {
+	ctx, span := tracer().Start(c.ctx, "datastore."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "datastore"),
+			attribute.String("db.operation", op),
+			attribute.String("datastore.kind", kind),
+		),
+	)
+
+	return ds.WithContext(c.c, ctx), span
+}
+
+// end はエラーを記録してspanを終了する
+func end(span trace.Span, err error) {
+	switch err {
+	case nil, ds.Done, ds.ErrNoSuchEntity:
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// multiKind は[]*Tの要素のKind名を返す
+func (c *client) multiKind(src interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(src))
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		return ""
+	}
+
+	return c.c.Kind(v.Index(0).Interface())
+}
+
+func (c *client) Context() context.Context {
+	return c.ctx
+}
+
+func (c *client) WithContext(ctx context.Context) ds.Client {
+	return &client{c: ds.WithContext(c.c, ctx), ctx: ctx}
+}
+
+func (c *client) Kind(src interface{}) string {
+	return c.c.Kind(src)
+}
+
+func (c *client) Get(dst interface{}) error {
+	g, span := c.start("get", c.c.Kind(dst))
+	err := g.Get(dst)
+	end(span, err)
+
+	return err
+}
+
+func (c *client) GetMulti(dst interface{}) error {
+	g, span := c.start("get_multi", c.multiKind(dst))
+	err := g.GetMulti(dst)
+	end(span, err)
+
+	return err
+}
+
+func (c *client) Put(src interface{}) error {
+	g, span := c.start("put", c.c.Kind(src))
+	err := g.Put(src)
+	end(span, err)
+
+	return err
+}
+
+func (c *client) PutMulti(src interface{}) error {
+	g, span := c.start("put_multi", c.multiKind(src))
+	err := g.PutMulti(src)
+	end(span, err)
+
+	return err
+}
+
+func (c *client) Delete(src interface{}) error {
+	g, span := c.start("delete", c.c.Kind(src))
+	err := g.Delete(src)
+	end(span, err)
+
+	return err
+}
+
+func (c *client) DeleteMulti(src interface{}) error {
+	g, span := c.start("delete_multi", c.multiKind(src))
+	err := g.DeleteMulti(src)
+	end(span, err)
+
+	return err
+}
+
+func (c *client) Run(q *ds.Query) ds.Iterator {
+	g, span := c.start("query", q.Kind())
+	return &iterator{it: g.Run(q), span: span}
+}
+
+func (c *client) RunInTransaction(f func(tg ds.Client) error) error {
+	g, span := c.start("transaction", "")
+
+	attempts := 0
+	err := g.RunInTransaction(func(tg ds.Client) error {
+		attempts++
+
+		// トランザクション内の操作はトランザクションのspanの子とする
+		return f(&client{c: tg, ctx: trace.ContextWithSpan(c.ctx, span)})
+	})
+	span.SetAttributes(attribute.Int("datastore.transaction.attempts", attempts))
+	end(span, err)
+
+	return err
+}
+
+// iterator はクエリの実行開始から最初の結果を取得するまでをspanとして記録する
+//
+// 呼び出し側が結果を最後まで読まずに終了することがあるため、最初の結果でspanを終了する
+type iterator struct {
+	it   ds.Iterator
+	span trace.Span
+	done bool
+}
+
+func (it *iterator) Next(dst interface{}) (string, error) {
+	id, err := it.it.Next(dst)
+	if !it.done {
+		it.done = true
+		end(it.span, err)
+	}
+
+	return id, err
+}
+
+func (it *iterator) Cursor() (string, error) {
+	return it.it.Cursor()
+}
@@ -0,0 +1,112 @@
+//go:build !appengine
+// +build !appengine
+
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor はMiddlewareと同様に、gRPCの呼び出し毎のspanを開始する
+//
+// メタデータのtraceparentが指定されている場合は、そのtraceを引き継ぐ
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startRPC(ctx, info.FullMethod)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		endRPC(span, err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor はストリーム毎のspanを開始する
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startRPC(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		endRPC(span, err)
+
+		return err
+	}
+}
+
+// startRPC は"/hoge.HogeService/Get"のようなメソッドのspanを開始する
+func startRPC(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	name := strings.TrimPrefix(method, "/")
+	service, rpcMethod := name, ""
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		service, rpcMethod = name[:i], name[i+1:]
+	}
+
+	return tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", rpcMethod),
+		),
+	)
+}
+
+// endRPC はステータスコードを記録し、HTTPの5xxに相当するコードの場合はspanをエラーとする
+func endRPC(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded, codes.Unimplemented:
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+}
+
+// metadataCarrier はgRPCのメタデータをTextMapCarrierとして扱う
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// serverStream はContextがctxを返すgrpc.ServerStream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
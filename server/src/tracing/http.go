@@ -0,0 +1,48 @@
+//go:build !appengine
+// +build !appengine
+
+package tracing
+
+import (
+	"gaego-gin/server/src/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware はリクエスト毎のspanを開始する
+//
+// traceparentヘッダーが指定されている場合は、そのtraceを引き継ぐ
+// spanはリクエストのcontextに設定されるため、Datastoreの操作のspanはこのspanの子となる
+func Middleware(r *gin.Engine) gin.HandlerFunc {
+	routes := middleware.NewRouteResolver(r)
+
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := routes.Template(c)
+		ctx, span := tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("http.target", c.Request.URL.RequestURI()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//go:build !appengine
+// +build !appengine
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName はこのアプリケーションが生成するspanの計装名
+const instrumentationName = "gaego-gin/server/src/tracing"
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start はctxのspanの子としてnameのspanを開始する
+//
+// 戻り値の関数はエラーを記録してspanを終了する
+func Start(ctx context.Context, name string) (context.Context, func(error)) {
+	ctx, span := tracer().Start(ctx, name)
+
+	return ctx, func(err error) {
+		end(span, err)
+	}
+}
+
+// NewExporter は名前に対応するspanのエクスポーターを生成する
+//
+//	stdout: 標準出力にJSONで出力する
+//	otlp: OTEL_EXPORTER_OTLP_ENDPOINTにOTLP/HTTPで送信する
+//	none, 空文字: エクスポートしない(nilを返す)
+func NewExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		return otlptracehttp.New(ctx)
+	}
+
+	return nil, fmt.Errorf("tracing: unknown exporter %q", name)
+}
+
+// Setup はexporterにspanを送信するTracerProviderとW3C Trace Contextのプロパゲーターを設定する
+//
+// exporterがnilの場合はspanを記録しない
+// 戻り値の関数は未送信のspanを送信してTracerProviderを終了する
+func Setup(exporter sdktrace.SpanExporter, opts ...sdktrace.TracerProviderOption) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }
+	}
+
+	tp := sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithBatcher(exporter)}, opts...)...)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown
+}
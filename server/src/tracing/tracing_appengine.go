@@ -0,0 +1,31 @@
+//go:build appengine
+// +build appengine
+
+package tracing
+
+import (
+	"context"
+	"gaego-gin/server/src/ds"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 第1世代のApp Engine(go1.9)ではOpenTelemetryをビルドできないため、spanを記録しない
+// リクエストとDatastoreのRPCはApp Engineが自動でCloud Traceに記録する
+
+// Middleware は何もしないミドルウェアを返す
+func Middleware(r *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+	}
+}
+
+// InstrumentFactory はfをそのまま返す
+func InstrumentFactory(f ds.Factory) ds.Factory {
+	return f
+}
+
+// Start はctxをそのまま返す。戻り値の関数は何もしない
+func Start(ctx context.Context, name string) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
@@ -0,0 +1,155 @@
+//go:build !appengine
+// +build !appengine
+
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"gaego-gin/server/src/api"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/tracing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID = "00f067aa0ba902b7"
+)
+
+func TestTracing(t *testing.T) {
+	store := dstest.NewStore()
+	if err := store.Client(context.Background()).Put(&model.Hoge{ID: "hoge"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var spans []trace.SpanContext
+
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.Setup(exporter, sdktrace.WithSyncer(exporter))
+	defer shutdown(context.Background())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(tracing.Middleware(r))
+	r.Use(func(c *gin.Context) {
+		c.Request = ds.WithFactory(c.Request, tracing.InstrumentFactory(func(r *http.Request) ds.Client {
+			return &spanClient{Client: store.Client(r.Context()), spans: &spans}
+		}))
+		c.Next()
+	})
+	api.SetupHoge(r.Group("/api"))
+
+	t.Run("traceparentのtraceを引き継ぎ、Datastoreの操作がトランザクションの子spanとなること", func(t *testing.T) {
+		defer exporter.Reset()
+
+		req := httptest.NewRequest("PUT", "/api/hoge/hoge", bytes.NewBufferString(`{"id":"hoge","value":"updated"}`))
+		req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`, body: `%s`", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		// コミット後に実行するJobの操作と区別するため、名前毎に最初に終了したspanを用いる
+		spans := map[string]tracetest.SpanStub{}
+		for _, s := range exporter.GetSpans() {
+			if _, ok := spans[s.Name]; !ok {
+				spans[s.Name] = s
+			}
+		}
+
+		server, ok := spans["PUT /api/hoge/:id"]
+		if !ok {
+			t.Fatalf("server span is not found: %v", spans)
+		}
+		if server.SpanContext.TraceID().String() != traceID {
+			t.Errorf("server.TraceID: unexpected, actual: `%s`, expected: `%s`", server.SpanContext.TraceID(), traceID)
+		}
+		if server.Parent.SpanID().String() != parentSpanID {
+			t.Errorf("server.Parent: unexpected, actual: `%s`, expected: `%s`", server.Parent.SpanID(), parentSpanID)
+		}
+
+		tx, ok := spans["datastore.transaction"]
+		if !ok {
+			t.Fatalf("transaction span is not found: %v", spans)
+		}
+		if tx.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Errorf("transaction.Parent: unexpected, actual: `%s`, expected: `%s`", tx.Parent.SpanID(), server.SpanContext.SpanID())
+		}
+
+		for _, name := range []string{"datastore.get", "datastore.put"} {
+			s, ok := spans[name]
+			if !ok {
+				t.Fatalf("%s span is not found: %v", name, spans)
+			}
+			if s.Parent.SpanID() != tx.SpanContext.SpanID() {
+				t.Errorf("%s.Parent: unexpected, actual: `%s`, expected: `%s`", name, s.Parent.SpanID(), tx.SpanContext.SpanID())
+			}
+		}
+	})
+	t.Run("Datastoreの操作とリクエストボディのデコードのspanが記録されること", func(t *testing.T) {
+		defer exporter.Reset()
+		spans = nil
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/api/hoge", bytes.NewBufferString(`{"id":"hoge2","value":"hogehoge"}`)))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status code: actual: `%d`, expected: `%d`, body: `%s`", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		var server, decode tracetest.SpanStub
+		gets := map[trace.SpanID]bool{}
+		for _, s := range exporter.GetSpans() {
+			switch s.Name {
+			case "POST /api/hoge":
+				server = s
+			case "decode application/json":
+				decode = s
+			case "datastore.get":
+				gets[s.SpanContext.SpanID()] = true
+			}
+		}
+
+		if decode.Name == "" || decode.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Errorf("decode span: unexpected, actual: `%v`", decode)
+		}
+
+		// Datastoreの操作はその操作のspanのcontextで実行されること
+		if len(spans) == 0 {
+			t.Fatal("Get: not called")
+		}
+		for _, sc := range spans {
+			if !gets[sc.SpanID()] {
+				t.Errorf("Get context: unexpected, actual: `%s` is not a datastore.get span", sc.SpanID())
+			}
+		}
+	})
+}
+
+// spanClient はGetを実行したcontextのspanを記録するClient
+type spanClient struct {
+	*dstest.Client
+	spans *[]trace.SpanContext
+}
+
+func (c *spanClient) WithContext(ctx context.Context) ds.Client {
+	return &spanClient{Client: c.Client.WithContext(ctx).(*dstest.Client), spans: c.spans}
+}
+
+func (c *spanClient) Get(dst interface{}) error {
+	*c.spans = append(*c.spans, trace.SpanContextFromContext(c.Context()))
+	return c.Client.Get(dst)
+}
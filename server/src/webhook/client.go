@@ -0,0 +1,57 @@
+//go:build !appengine
+// +build !appengine
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"gaego-gin/server/src/model"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// client はWebhookに送信するhttp.Client
+//
+// DNSリバインディングで検証を回避できないように、名前解決した後の接続先のアドレスをcheckDialで検証する
+// プロキシを経由すると接続先を検証できないため、環境変数のプロキシは利用しない
+var client = &http.Client{
+	Timeout: DeliveryTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   DeliveryTimeout,
+			KeepAlive: 30 * time.Second,
+			Control:   checkDial,
+		}).DialContext,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: DeliveryTimeout,
+	},
+}
+
+// httpClient はWebhookに送信するhttp.Clientを返す
+func httpClient(ctx context.Context) *http.Client {
+	return client
+}
+
+// checkDial は接続する直前に、接続先のアドレスがWebhookの通知先として許可されるか検証する
+func checkDial(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return model.ErrWebhookAddressNotAllowed
+	}
+
+	return model.CheckWebhookIP(ip)
+}
+
+// isAddressNotAllowed はerrが許可されない接続先への送信によるエラーか判定する
+func isAddressNotAllowed(err error) bool {
+	return errors.Is(err, model.ErrWebhookAddressNotAllowed)
+}
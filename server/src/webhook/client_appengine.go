@@ -0,0 +1,29 @@
+//go:build appengine
+// +build appengine
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/appengine/urlfetch"
+)
+
+// httpClient はWebhookに送信するhttp.Clientを返す
+//
+// 第1世代のApp Engineでは外部への通信にURL Fetchを利用する
+// URL FetchはApp Engineの外部から送信するため、内部ネットワークやメタデータサーバーには接続できない
+func httpClient(ctx context.Context) *http.Client {
+	c := urlfetch.Client(ctx)
+	c.Timeout = DeliveryTimeout
+
+	return c
+}
+
+// isAddressNotAllowed はerrが許可されない接続先への送信によるエラーか判定する
+//
+// URL Fetchは接続先を検証しないため、常にfalseを返す
+func isAddressNotAllowed(err error) bool {
+	return false
+}
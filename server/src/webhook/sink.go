@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/model"
+)
+
+// Sink はイベントを登録されたWebhookに通知するoutbox.Sink
+type Sink struct{}
+
+// Name は"webhook"を返す
+func (s *Sink) Name() string {
+	return "webhook"
+}
+
+// Send はeventを通知するJobを登録する
+func (s *Sink) Send(g ds.Client, event *model.HogeEvent) error {
+	return Publish(g, event)
+}
@@ -0,0 +1,240 @@
+// Package webhook はHogeの変更をWebhookに通知する
+//
+// outboxからSinkとして通知を振り分けるJobを登録し、Webhook毎の配信Jobがリトライしながら送信する
+// リトライの上限に達したイベントはmodel.WebhookDeadLetterとして保存する
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/logger"
+	"gaego-gin/server/src/model"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+var log = logger.New("webhook")
+
+const (
+	// EventHeader はイベントの種類を表すリクエストヘッダー
+	EventHeader = "X-Hoge-Event"
+	// DeliveryHeader はイベントのIDを表すリクエストヘッダー。リトライしても同じ値となる
+	DeliveryHeader = "X-Hoge-Delivery"
+	// SignatureHeader はリクエストボディの署名を表すリクエストヘッダー
+	SignatureHeader = "X-Hoge-Signature"
+)
+
+// DeliveryTimeout は1回の送信の処理時間の上限
+const DeliveryTimeout = 10 * time.Second
+
+const (
+	dispatchJobType = "webhook.dispatch"
+	deliverJobType  = "webhook.deliver"
+)
+
+func init() {
+	jobs.Register(dispatchJobType, dispatch)
+	jobs.Register(deliverJobType, deliver)
+}
+
+// Sign はsecretを鍵としたbodyのHMAC-SHA256を"sha256=<hex>"の形式で返す
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify はsignatureがsecretによるbodyの署名か判定する
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Publish はeventを通知するJobを登録する
+//
+// gがmodel.RunInTransactionのトランザクション内のClientの場合、変更と共にコミットした場合のみ通知する
+// Webhookが登録されていない場合はJobを登録しない。トランザクション内ではクエリを実行できないため、dispatchで判定する
+func Publish(g ds.Client, event *model.HogeEvent) error {
+	if !model.InTransaction(g) {
+		store := &model.WebhookStore{}
+
+		var exists bool
+		if err := model.Retry(g.Context(), "webhook.exists", func() error {
+			var err error
+			exists, err = store.Exists(g)
+			return err
+
+		}); err != nil {
+			return err
+		}
+
+		if !exists {
+			return nil
+		}
+	}
+
+	job, err := model.NewJob(dispatchJobType, event, nil)
+	if err != nil {
+		return err
+	}
+
+	return jobs.Enqueue(g, job)
+}
+
+// dispatchResult はwebhook.dispatchの結果
+type dispatchResult struct {
+	Webhooks int `json:"webhooks"`
+}
+
+// dispatch はイベントを通知するWebhook毎に配信Jobを登録する
+//
+// リトライした場合は配信Jobが重複する場合があるため、受信側はDeliveryHeaderで重複を取り除く
+func dispatch(g ds.Client, job *model.Job) (interface{}, error) {
+	event := &model.HogeEvent{}
+	if err := job.Bind(event); err != nil {
+		return nil, err
+	}
+
+	store := &model.WebhookStore{}
+
+	list, err := store.List(g)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dispatchResult{}
+	for _, w := range list {
+		if !w.Subscribes(event.Type) {
+			continue
+		}
+
+		dj, err := model.NewJob(deliverJobType, &deliverParams{WebhookID: w.ID, Event: event}, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := jobs.Enqueue(g, dj); err != nil {
+			return nil, err
+		}
+
+		result.Webhooks++
+	}
+
+	return result, nil
+}
+
+// deliverParams はwebhook.deliverのパラメータ
+type deliverParams struct {
+	WebhookID string           `json:"webhookId"`
+	Event     *model.HogeEvent `json:"event"`
+}
+
+// deliverResult はwebhook.deliverの結果
+type deliverResult struct {
+	StatusCode int  `json:"statusCode,omitempty"`
+	Skipped    bool `json:"skipped,omitempty"`
+}
+
+// deliveryError は送信に失敗した場合のエラー
+type deliveryError struct {
+	statusCode int
+	err        error
+}
+
+func (e *deliveryError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+
+	return fmt.Sprintf("webhook responded %d", e.statusCode)
+}
+
+// Unwrap は通信のエラーを返す
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+// deliver はイベントをWebhookに送信する
+//
+// 2xx以外のレスポンスや通信のエラーはリトライし、最後の試行で失敗した場合はWebhookDeadLetterを保存する
+// 接続先のアドレスが許可されない場合はリトライせずにWebhookDeadLetterを保存する
+// Webhookが削除されている場合は送信しない
+func deliver(g ds.Client, job *model.Job) (interface{}, error) {
+	p := &deliverParams{}
+	if err := job.Bind(p); err != nil {
+		return nil, err
+	}
+
+	store := &model.WebhookStore{}
+
+	w, err := store.Get(g, p.WebhookID)
+	if err == ds.ErrNoSuchEntity {
+		return &deliverResult{Skipped: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := send(g, w, p.Event)
+	if err == nil {
+		return &deliverResult{StatusCode: code}, nil
+	}
+
+	log.Warningf(g.Context(), "webhook %s: delivery %s attempt %d failed: %v", w.ID, p.Event.ID, job.Attempts, err)
+
+	blocked := isAddressNotAllowed(err)
+
+	if blocked || jobs.LastAttempt(job) {
+		if derr := model.Retry(g.Context(), "webhook.deadletter", func() error {
+			return store.AddDeadLetter(g, w.ID, p.Event, job.Attempts, code, err)
+		}); derr != nil {
+			return nil, derr
+		}
+	}
+
+	if blocked {
+		return nil, err
+	}
+
+	return nil, &jobs.RetryableError{Err: err}
+}
+
+// send はイベントを署名してPOSTし、レスポンスのステータスコードを返す
+func send(g ds.Client, w *model.Webhook, event *model.HogeEvent) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, &deliveryError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, event.ID)
+	req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+
+	resp, err := httpClient(g.Context()).Do(req.WithContext(g.Context()))
+	if err != nil {
+		return 0, &deliveryError{err: err}
+	}
+	defer resp.Body.Close()
+
+	// 接続を再利用できるようにレスポンスボディを読み捨てる
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024)) // nolint: errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, &deliveryError{statusCode: resp.StatusCode}
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,259 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"errors"
+	"gaego-gin/server/src/ds"
+	"gaego-gin/server/src/ds/dstest"
+	"gaego-gin/server/src/jobs"
+	"gaego-gin/server/src/model"
+	"gaego-gin/server/src/webhook"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// receiver はWebhookの通知を受信するhttptest.Server
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(failures int) *receiver {
+	r := &receiver{failures: failures}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		body, _ := ioutil.ReadAll(req.Body)
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+
+		if r.failures != 0 {
+			r.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.requests)
+}
+
+func TestPublish(t *testing.T) {
+	defer func(n int) { jobs.MaxAttempts = n }(jobs.MaxAttempts)
+	jobs.MaxAttempts = 3
+
+	// receiverはループバックのアドレスのhttpで待ち受ける
+	defer func(v bool) { model.AllowInsecureWebhooks = v }(model.AllowInsecureWebhooks)
+	model.AllowInsecureWebhooks = true
+
+	setup := func(t *testing.T, failures int, events ...model.EventType) (*dstest.Client, *receiver, *model.Webhook) {
+		g := dstest.NewClient()
+		r := newReceiver(failures)
+
+		w := &model.Webhook{URL: r.URL + "/hook", Events: events}
+		store := &model.WebhookStore{}
+		if err := store.Create(g, w); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return g, r, w
+	}
+
+	publish := func(t *testing.T, g ds.Client, typ model.EventType) *model.HogeEvent {
+		event, err := model.NewHogeEvent(typ, &model.Hoge{ID: "hoge", Value: "hogehoge"})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if err := webhook.Publish(g, event); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return event
+	}
+
+	t.Run("署名したイベントが送信されること", func(t *testing.T) {
+		g, r, w := setup(t, 0)
+		defer r.Close()
+
+		event := publish(t, g, model.HogeCreated)
+
+		if r.count() != 1 {
+			t.Fatalf("requests: unexpected, actual: `%d`, expected: `%d`", r.count(), 1)
+		}
+
+		req, body := r.requests[0], r.bodies[0]
+		if !webhook.Verify(w.Secret, body, req.Header.Get(webhook.SignatureHeader)) {
+			t.Errorf("signature: invalid, actual: `%s`", req.Header.Get(webhook.SignatureHeader))
+		}
+		if v := req.Header.Get(webhook.EventHeader); v != string(model.HogeCreated) {
+			t.Errorf("event header: unexpected, actual: `%s`", v)
+		}
+		if v := req.Header.Get(webhook.DeliveryHeader); v != event.ID {
+			t.Errorf("delivery header: unexpected, actual: `%s`, expected: `%s`", v, event.ID)
+		}
+
+		v := &model.HogeEvent{}
+		if err := json.Unmarshal(body, v); err != nil {
+			t.Fatal(err.Error())
+		}
+		if v.ID != event.ID || v.Type != model.HogeCreated || v.Hoge.Value != "hogehoge" {
+			t.Errorf("event: unexpected, actual: `%+v`", v)
+		}
+	})
+
+	t.Run("購読していない種類のイベントは送信されないこと", func(t *testing.T) {
+		g, r, _ := setup(t, 0, model.HogeDeleted)
+		defer r.Close()
+
+		publish(t, g, model.HogeUpdated)
+		publish(t, g, model.HogeDeleted)
+
+		if r.count() != 1 {
+			t.Errorf("requests: unexpected, actual: `%d`, expected: `%d`", r.count(), 1)
+		}
+	})
+
+	t.Run("送信に失敗した場合、リトライされること", func(t *testing.T) {
+		g, r, w := setup(t, 2)
+		defer r.Close()
+
+		publish(t, g, model.HogeCreated)
+
+		if r.count() != 3 {
+			t.Errorf("requests: unexpected, actual: `%d`, expected: `%d`", r.count(), 3)
+		}
+
+		store := &model.WebhookStore{}
+		list, err := store.ListDeadLetters(g, w.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(list) != 0 {
+			t.Errorf("dead letters: unexpected, actual: `%d`, expected: `%d`", len(list), 0)
+		}
+	})
+
+	t.Run("リトライの上限に達した場合、dead letterとして保存されること", func(t *testing.T) {
+		g, r, w := setup(t, -1)
+		defer r.Close()
+
+		event := publish(t, g, model.HogeCreated)
+
+		if r.count() != jobs.MaxAttempts {
+			t.Errorf("requests: unexpected, actual: `%d`, expected: `%d`", r.count(), jobs.MaxAttempts)
+		}
+
+		store := &model.WebhookStore{}
+		list, err := store.ListDeadLetters(g, w.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(list) != 1 {
+			t.Fatalf("dead letters: unexpected, actual: `%d`, expected: `%d`", len(list), 1)
+		}
+
+		dl := list[0]
+		if dl.WebhookID != w.ID || dl.Attempts != jobs.MaxAttempts || dl.StatusCode != http.StatusInternalServerError {
+			t.Errorf("dead letter: unexpected, actual: `%+v`", dl)
+		}
+
+		v := &model.HogeEvent{}
+		if err := json.Unmarshal(dl.Event, v); err != nil {
+			t.Fatal(err.Error())
+		}
+		if v.ID != event.ID {
+			t.Errorf("dead letter event: unexpected, actual: `%s`, expected: `%s`", v.ID, event.ID)
+		}
+	})
+
+	t.Run("Webhookが登録されていない場合、Jobが登録されないこと", func(t *testing.T) {
+		g := dstest.NewClient()
+
+		publish(t, g, model.HogeCreated)
+
+		if n := g.Store().Len("Job"); n != 0 {
+			t.Errorf("jobs: unexpected, actual: `%d`, expected: `%d`", n, 0)
+		}
+	})
+
+	t.Run("接続先のアドレスが許可されない場合、リトライせずにdead letterとして保存されること", func(t *testing.T) {
+		g, r, w := setup(t, 0)
+		defer r.Close()
+
+		// 登録後に名前解決の結果が変わった場合と同様に、送信時に接続先を検証する
+		model.AllowInsecureWebhooks = false
+		defer func() { model.AllowInsecureWebhooks = true }()
+
+		publish(t, g, model.HogeCreated)
+
+		if r.count() != 0 {
+			t.Errorf("requests: unexpected, actual: `%d`, expected: `%d`", r.count(), 0)
+		}
+
+		store := &model.WebhookStore{}
+		list, err := store.ListDeadLetters(g, w.ID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(list) != 1 || list[0].Attempts != 1 {
+			t.Fatalf("dead letters: unexpected, actual: `%d`", len(list))
+		}
+	})
+
+	t.Run("トランザクションが失敗した場合、送信されないこと", func(t *testing.T) {
+		g, r, _ := setup(t, 0)
+		defer r.Close()
+
+		errAbort := errors.New("abort")
+		err := model.RunInTransaction(g, "test", func(tg ds.Client) error {
+			event, err := model.NewHogeEvent(model.HogeCreated, &model.Hoge{ID: "hoge"})
+			if err != nil {
+				return err
+			}
+
+			if err := webhook.Publish(tg, event); err != nil {
+				return err
+			}
+
+			return errAbort
+		})
+
+		if err != errAbort {
+			t.Fatalf("err: unexpected, actual: `%v`", err)
+		}
+		if r.count() != 0 {
+			t.Errorf("requests: unexpected, actual: `%d`, expected: `%d`", r.count(), 0)
+		}
+	})
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"hoge"}`)
+	sig := webhook.Sign("secret", body)
+
+	if !webhook.Verify("secret", body, sig) {
+		t.Error("Verify: expected true")
+	}
+	if webhook.Verify("other", body, sig) {
+		t.Error("Verify: expected false for another secret")
+	}
+	if webhook.Verify("secret", []byte(`{"id":"fuga"}`), sig) {
+		t.Error("Verify: expected false for another body")
+	}
+}